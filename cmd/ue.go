@@ -93,7 +93,6 @@ func ueFunc(cmd *cobra.Command, args []string) {
 	if err != nil {
 		panic(err)
 	}
-	baseUeTunnelDevice := ueConfig.Ue.UeTunnelDevice
 
 	for i := 0; i < num; i += 1 {
 		startStopWg.Add(1)
@@ -104,7 +103,7 @@ func ueFunc(cmd *cobra.Command, args []string) {
 			defer func() { <-semaphore }()
 
 			ueConfigCopy := ueConfig
-			updateUeConfig(&ueConfigCopy, baseMsinInt, baseUeTunnelDevice, index)
+			updateUeConfig(&ueConfigCopy, baseMsinInt, index)
 
 			logger := logger.NewUeLogger(loggergoUtil.LogLevelString(ueConfigCopy.Logger.Level), "", true)
 			ue := ue.NewUe(&ueConfigCopy, &logger)
@@ -135,7 +134,14 @@ func ueFunc(cmd *cobra.Command, args []string) {
 	<-sigCh
 }
 
-func updateUeConfig(ueConfig *model.UeConfig, baseMsinInt int, baseUeTunnelDevice string, num int) {
+// the pdu session list is copied, it is shared with the other UEs otherwise
+func updateUeConfig(ueConfig *model.UeConfig, baseMsinInt int, num int) {
 	ueConfig.Ue.Msin = fmt.Sprintf("%010d", baseMsinInt+num)
-	ueConfig.Ue.UeTunnelDevice = fmt.Sprintf("%s%d", baseUeTunnelDevice, num)
+
+	pduSessionList := make([]model.PduSessionIE, len(ueConfig.Ue.PduSessionList))
+	for i, pduSession := range ueConfig.Ue.PduSessionList {
+		pduSession.UeTunnelDevice = fmt.Sprintf("%s%d", pduSession.UeTunnelDevice, num)
+		pduSessionList[i] = pduSession
+	}
+	ueConfig.Ue.PduSessionList = pduSessionList
 }
@@ -101,3 +101,150 @@ func (cs *console) handleConsoleGnbUeNrdcModify(c *gin.Context) {
 
 	c.Data(response.StatusCode, constant.APPLICATION_JSON, response.Body)
 }
+
+func (cs *console) handleConsoleGnbNgReset(c *gin.Context) {
+	cs.GnbLog.Infoln("Attempting to reset gNB NG interface")
+
+	if err := authticate(c, cs.jwt.secret); err != nil {
+		cs.AuthLog.Warnln(err)
+		c.JSON(http.StatusUnauthorized, model.ConsoleGnbNgResetResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var request model.ConsoleGnbNgResetRequest
+	rawBody, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		cs.GnbLog.Warnf("Failed to read body: %v", err)
+		c.JSON(http.StatusBadRequest, model.ConsoleGnbNgResetResponse{
+			Message: fmt.Sprintf("Failed to read body: %v", err),
+		})
+		return
+	}
+
+	if err := json.Unmarshal(rawBody, &request); err != nil {
+		cs.GnbLog.Warnf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, model.ConsoleGnbNgResetResponse{
+			Message: fmt.Sprintf("Failed to bind JSON: %v", err),
+		})
+		return
+	}
+
+	uri := fmt.Sprintf("http://%s:%d%s", request.Ip, request.Port, constant.API_REQUEST_GNB_NG_RESET)
+
+	response, err := util.SendHttpRequest(uri, constant.API_REQUEST_GNB_NG_RESET_METHOD, nil, rawBody)
+	if err != nil {
+		cs.GnbLog.Warnln(err)
+		c.JSON(http.StatusInternalServerError, model.ConsoleGnbNgResetResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for key, values := range response.Headers {
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+
+	c.Data(response.StatusCode, constant.APPLICATION_JSON, response.Body)
+}
+
+func (cs *console) handleConsoleGnbUeXnHandover(c *gin.Context) {
+	cs.GnbLog.Infoln("Attempting to trigger gNB UE Xn handover")
+
+	if err := authticate(c, cs.jwt.secret); err != nil {
+		cs.AuthLog.Warnln(err)
+		c.JSON(http.StatusUnauthorized, model.ConsoleGnbUeXnHandoverResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var request model.ConsoleGnbUeXnHandoverRequest
+	rawBody, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		cs.GnbLog.Warnf("Failed to read body: %v", err)
+		c.JSON(http.StatusBadRequest, model.ConsoleGnbUeXnHandoverResponse{
+			Message: fmt.Sprintf("Failed to read body: %v", err),
+		})
+		return
+	}
+
+	if err := json.Unmarshal(rawBody, &request); err != nil {
+		cs.GnbLog.Warnf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, model.ConsoleGnbUeXnHandoverResponse{
+			Message: fmt.Sprintf("Failed to bind JSON: %v", err),
+		})
+		return
+	}
+
+	uri := fmt.Sprintf("http://%s:%d%s", request.Ip, request.Port, constant.API_REQUEST_GNB_UE_XN_HANDOVER)
+
+	response, err := util.SendHttpRequest(uri, constant.API_REQUEST_GNB_UE_XN_HANDOVER_METHOD, nil, rawBody)
+	if err != nil {
+		cs.GnbLog.Warnln(err)
+		c.JSON(http.StatusInternalServerError, model.ConsoleGnbUeXnHandoverResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for key, values := range response.Headers {
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+
+	c.Data(response.StatusCode, constant.APPLICATION_JSON, response.Body)
+}
+
+func (cs *console) handleConsoleGnbUeN2Handover(c *gin.Context) {
+	cs.GnbLog.Infoln("Attempting to trigger gNB UE N2 handover")
+
+	if err := authticate(c, cs.jwt.secret); err != nil {
+		cs.AuthLog.Warnln(err)
+		c.JSON(http.StatusUnauthorized, model.ConsoleGnbUeN2HandoverResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var request model.ConsoleGnbUeN2HandoverRequest
+	rawBody, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		cs.GnbLog.Warnf("Failed to read body: %v", err)
+		c.JSON(http.StatusBadRequest, model.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("Failed to read body: %v", err),
+		})
+		return
+	}
+
+	if err := json.Unmarshal(rawBody, &request); err != nil {
+		cs.GnbLog.Warnf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, model.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("Failed to bind JSON: %v", err),
+		})
+		return
+	}
+
+	uri := fmt.Sprintf("http://%s:%d%s", request.Ip, request.Port, constant.API_REQUEST_GNB_UE_N2_HANDOVER)
+
+	response, err := util.SendHttpRequest(uri, constant.API_REQUEST_GNB_UE_N2_HANDOVER_METHOD, nil, rawBody)
+	if err != nil {
+		cs.GnbLog.Warnln(err)
+		c.JSON(http.StatusInternalServerError, model.ConsoleGnbUeN2HandoverResponse{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for key, values := range response.Headers {
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+
+	c.Data(response.StatusCode, constant.APPLICATION_JSON, response.Body)
+}
@@ -143,5 +143,23 @@ func (cs *console) initRoutes() util.Routes {
 			Pattern:     "/gnb/ue/nrdc",
 			HandlerFunc: cs.handleConsoleGnbUeNrdcModify,
 		},
+		{
+			Name:        "Console GNB NG Reset",
+			Method:      http.MethodPost,
+			Pattern:     "/gnb/ngreset",
+			HandlerFunc: cs.handleConsoleGnbNgReset,
+		},
+		{
+			Name:        "Console GNB UE Xn Handover",
+			Method:      http.MethodPost,
+			Pattern:     "/gnb/ue/xn-handover",
+			HandlerFunc: cs.handleConsoleGnbUeXnHandover,
+		},
+		{
+			Name:        "Console GNB UE N2 Handover",
+			Method:      http.MethodPost,
+			Pattern:     "/gnb/ue/n2-handover",
+			HandlerFunc: cs.handleConsoleGnbUeN2Handover,
+		},
 	}
 }
@@ -18,6 +18,9 @@ type GnbInfo struct {
 
 	Snssai SnssaiIE `json:"snssai"`
 
+	SupportedTaList []SupportedTaInfo `json:"supportedTaList"`
+	AmfList         []AmfInfo         `json:"amfList"`
+
 	RanUeList []RanUeInfo `json:"ranUeList"`
 	XnUeList  []XnUeInfo  `json:"xnUeList"`
 }
@@ -27,6 +30,31 @@ type SnssaiIE struct {
 	Sd  string `json:"sd"`
 }
 
+type SupportedTaInfo struct {
+	Tac               string          `json:"tac"`
+	BroadcastPlmnList []PlmnSliceInfo `json:"broadcastPlmnList"`
+}
+
+type PlmnSliceInfo struct {
+	PlmnId     string     `json:"plmnId"`
+	SnssaiList []SnssaiIE `json:"snssaiList"`
+}
+
+type AmfInfo struct {
+	AmfName string `json:"amfName"`
+	Ip      string `json:"ip"`
+	Port    int    `json:"port"`
+	Ready   bool   `json:"ready"`
+
+	ServedGuamiList []GuamiInfo     `json:"servedGuamiList"`
+	PlmnSupportList []PlmnSliceInfo `json:"plmnSupportList"`
+}
+
+type GuamiInfo struct {
+	PlmnId string `json:"plmnId"`
+	AmfId  string `json:"amfId"`
+}
+
 type RanUeInfo struct {
 	Imsi          string `json:"imsi"`
 	NrdcIndicator bool   `json:"nrdcIndicator"`
@@ -45,3 +73,35 @@ type ConsoleGnbUeNrdcModifyRequest struct {
 type ConsoleGnbUeNrdcModifyResponse struct {
 	Message string `json:"message"`
 }
+
+type ConsoleGnbNgResetRequest struct {
+	Ip       string   `json:"ip"`
+	Port     int      `json:"port"`
+	ImsiList []string `json:"imsiList"`
+}
+
+type ConsoleGnbNgResetResponse struct {
+	Message string `json:"message"`
+}
+
+type ConsoleGnbUeXnHandoverRequest struct {
+	Ip   string `json:"ip"`
+	Port int    `json:"port"`
+	Imsi string `json:"imsi"`
+}
+
+type ConsoleGnbUeXnHandoverResponse struct {
+	Message string `json:"message"`
+}
+
+type ConsoleGnbUeN2HandoverRequest struct {
+	Ip          string `json:"ip"`
+	Port        int    `json:"port"`
+	Imsi        string `json:"imsi"`
+	TargetGnbId string `json:"targetGnbId"`
+	TargetTac   string `json:"targetTac"`
+}
+
+type ConsoleGnbUeN2HandoverResponse struct {
+	Message string `json:"message"`
+}
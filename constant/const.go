@@ -1,9 +1,18 @@
 package constant
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type UeType string
 
+type GmmState string
+
+type GsmState string
+
+type RanUeMessageType uint8
+
 // for cmd
 const (
 	BASIC_UE_NUM            = 1
@@ -13,23 +22,121 @@ const (
 // for RAN
 const (
 	NGAP_PPID uint32 = 0x3c000000
+	// an NGAP message is read in one SCTP record, a message carrying several pdu sessions or a handover container
+	// is larger than a few kilobytes
+	NGAP_MAX_MESSAGE_LEN = 65535
 
 	UE_TYPE_RAN UeType = "ran"
 	UE_TYPE_XN  UeType = "xn"
+
+	N2_SETUP_RETRY_INITIAL_INTERVAL = 1 * time.Second
+	N2_SETUP_RETRY_MAX_INTERVAL     = 32 * time.Second
+
+	UE_RADIO_LINK_FAILURE_TIMER  = 1 * time.Second
+	UE_CONTEXT_RELEASE_TIMEOUT   = 5 * time.Second
+	UE_INACTIVITY_CHECK_INTERVAL = 1 * time.Second
+
+	HANDOVER_TIMEOUT = 5 * time.Second
 )
 
 // for UE
 const (
-	PDU_SESSION_ID = 4
+	PDU_SESSION_TYPE_IPV4   = "IPv4"
+	PDU_SESSION_TYPE_IPV4V6 = "IPv4v6"
+
+	SUCI_PROTECTION_SCHEME_NULL      = "null"
+	SUCI_PROTECTION_SCHEME_PROFILE_A = "profileA"
+	SUCI_PROTECTION_SCHEME_PROFILE_B = "profileB"
+
+	// ECIES profile A and B share the key lengths of TS 33.501 annex C.3.4
+	SUCI_ENC_KEY_LEN = 16
+	SUCI_ICB_LEN     = 16
+	SUCI_MAC_KEY_LEN = 32
+	SUCI_MAC_TAG_LEN = 8
+
+	// NAS timers of the UE, TS 24.501 table 10.2.1 and 10.3.1
+	UE_T3510 = 15 * time.Second
+	UE_T3511 = 10 * time.Second
+	UE_T3517 = 15 * time.Second
+	UE_T3521 = 15 * time.Second
+	UE_T3580 = 16 * time.Second
+
+	// a failed procedure is attempted up to the registration attempt counter of TS 24.501 5.5.1.2.7
+	UE_PROCEDURE_MAX_ATTEMPTS = 5
+
+	// the requested NSSAI carries up to 8 S-NSSAIs, TS 24.501 9.11.3.37
+	UE_REQUESTED_NSSAI_MAX_LEN = 8
+
+	// the largest SQN step accepted by the UE, TS 33.102 annex C.2.1
+	UE_SQN_DELTA = 1 << 28
+)
+
+// 5GMM states of the UE and 5GSM states of its pdu sessions, TS 24.501 5.1.3 and 6.1.3
+const (
+	UE_GMM_STATE_DEREGISTERED              GmmState = "5GMM-DEREGISTERED"
+	UE_GMM_STATE_REGISTERED_INITIATED      GmmState = "5GMM-REGISTERED-INITIATED"
+	UE_GMM_STATE_REGISTERED                GmmState = "5GMM-REGISTERED"
+	UE_GMM_STATE_SERVICE_REQUEST_INITIATED GmmState = "5GMM-SERVICE-REQUEST-INITIATED"
+	UE_GMM_STATE_DEREGISTERED_INITIATED    GmmState = "5GMM-DEREGISTERED-INITIATED"
+
+	UE_GSM_STATE_PDU_SESSION_INACTIVE       GsmState = "PDU SESSION INACTIVE"
+	UE_GSM_STATE_PDU_SESSION_ACTIVE_PENDING GsmState = "PDU SESSION ACTIVE PENDING"
+	UE_GSM_STATE_PDU_SESSION_ACTIVE         GsmState = "PDU SESSION ACTIVE"
+)
+
+// EAP-AKA' between UE and AUSF, RFC 3748 and RFC 9048
+const (
+	EAP_CODE_REQUEST  = 1
+	EAP_CODE_RESPONSE = 2
+	EAP_CODE_SUCCESS  = 3
+	EAP_CODE_FAILURE  = 4
+
+	EAP_TYPE_AKA_PRIME = 50
+
+	EAP_AKA_SUBTYPE_CHALLENGE               = 1
+	EAP_AKA_SUBTYPE_AUTHENTICATION_REJECT   = 2
+	EAP_AKA_SUBTYPE_SYNCHRONIZATION_FAILURE = 4
+	EAP_AKA_SUBTYPE_CLIENT_ERROR            = 14
+
+	EAP_AKA_ATTRIBUTE_RAND              = 1
+	EAP_AKA_ATTRIBUTE_AUTN              = 2
+	EAP_AKA_ATTRIBUTE_RES               = 3
+	EAP_AKA_ATTRIBUTE_AUTS              = 4
+	EAP_AKA_ATTRIBUTE_MAC               = 11
+	EAP_AKA_ATTRIBUTE_CLIENT_ERROR_CODE = 22
+	EAP_AKA_ATTRIBUTE_KDF_INPUT         = 23
+	EAP_AKA_ATTRIBUTE_KDF               = 24
+
+	EAP_AKA_PRIME_KDF = 1
+	EAP_AKA_MAC_LEN   = 16
 )
 
 // between RAN and UE
 const (
 	UE_DATA_PLANE_INITIAL_PACKET = "initial packet"
-	UE_TUNNEL_UPDATE             = "tunnel update"
 	UE_IMSI_PREFIX               = "imsi-"
 )
 
+// the messages on the control plane connection between RAN and UE, framed by the message type and the payload length
+const (
+	RAN_UE_MESSAGE_TYPE_NAS RanUeMessageType = iota + 1
+	RAN_UE_MESSAGE_TYPE_CONNECTION_SETUP_REQUEST
+	RAN_UE_MESSAGE_TYPE_CONNECTION_SETUP
+	RAN_UE_MESSAGE_TYPE_CONNECTION_RELEASE
+	RAN_UE_MESSAGE_TYPE_TUNNEL_UPDATE
+	RAN_UE_MESSAGE_TYPE_HANDOVER_COMMAND
+	RAN_UE_MESSAGE_TYPE_HANDOVER_COMPLETE
+	// a UE in CM-IDLE opens a connection with the idle camp only, to be reached by the paging
+	RAN_UE_MESSAGE_TYPE_IDLE_CAMP
+	RAN_UE_MESSAGE_TYPE_PAGING
+)
+
+const (
+	RAN_UE_MESSAGE_HEADER_LEN = 3
+	// the payload length is carried in 2 octets, like the NAS message container
+	RAN_UE_MESSAGE_MAX_PAYLOAD_LEN = 65535
+)
+
 // for logger
 const (
 	CONFIG_TAG = "CONFIG"
@@ -87,6 +194,15 @@ const (
 
 	API_GNB_UE_NRDC        = "/ue/nrdc"
 	API_GNB_UE_NRDC_METHOD = http.MethodPost
+
+	API_GNB_NG_RESET        = "/ngreset"
+	API_GNB_NG_RESET_METHOD = http.MethodPost
+
+	API_GNB_UE_XN_HANDOVER        = "/ue/xn-handover"
+	API_GNB_UE_XN_HANDOVER_METHOD = http.MethodPost
+
+	API_GNB_UE_N2_HANDOVER        = "/ue/n2-handover"
+	API_GNB_UE_N2_HANDOVER_METHOD = http.MethodPost
 )
 
 // for console
@@ -98,4 +214,13 @@ const (
 
 	API_REQUEST_GNB_UE_NRDC        = API_PREFIX_GNB + API_GNB_UE_NRDC
 	API_REQUEST_GNB_UE_NRDC_METHOD = API_GNB_UE_NRDC_METHOD
+
+	API_REQUEST_GNB_NG_RESET        = API_PREFIX_GNB + API_GNB_NG_RESET
+	API_REQUEST_GNB_NG_RESET_METHOD = API_GNB_NG_RESET_METHOD
+
+	API_REQUEST_GNB_UE_XN_HANDOVER        = API_PREFIX_GNB + API_GNB_UE_XN_HANDOVER
+	API_REQUEST_GNB_UE_XN_HANDOVER_METHOD = API_GNB_UE_XN_HANDOVER_METHOD
+
+	API_REQUEST_GNB_UE_N2_HANDOVER        = API_PREFIX_GNB + API_GNB_UE_N2_HANDOVER
+	API_REQUEST_GNB_UE_N2_HANDOVER_METHOD = API_GNB_UE_N2_HANDOVER_METHOD
 )
@@ -0,0 +1,195 @@
+package gnb
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/sctp"
+)
+
+type Amf struct {
+	amfN2IpList []string
+	amfN2Port   int
+	ranN2Port   int
+
+	n2Conn          *sctp.SCTPConn
+	n2NumOutStreams uint16
+	n2ConnMtx       sync.RWMutex
+
+	amfName         string
+	servedGuamiList []models.Guami
+	plmnSupportList []models.PlmnSnssai
+
+	ready atomic.Bool
+
+	ngResetAcknowledgeChan chan struct{}
+}
+
+func NewAmf(amfN2IpList []string, amfN2Port, ranN2Port int) *Amf {
+	return &Amf{
+		amfN2IpList: amfN2IpList,
+		amfN2Port:   amfN2Port,
+		ranN2Port:   ranN2Port,
+
+		n2ConnMtx: sync.RWMutex{},
+
+		servedGuamiList: []models.Guami{},
+		plmnSupportList: []models.PlmnSnssai{},
+
+		ngResetAcknowledgeChan: make(chan struct{}, 1),
+	}
+}
+
+// the primary N2 address of the AMF
+func (a *Amf) GetAmfN2Ip() string {
+	return a.amfN2IpList[0]
+}
+
+func (a *Amf) GetAmfN2IpList() []string {
+	return a.amfN2IpList
+}
+
+func (a *Amf) GetAmfN2Port() int {
+	return a.amfN2Port
+}
+
+func (a *Amf) GetRanN2Port() int {
+	return a.ranN2Port
+}
+
+func (a *Amf) GetN2Conn() *sctp.SCTPConn {
+	a.n2ConnMtx.RLock()
+	defer a.n2ConnMtx.RUnlock()
+	return a.n2Conn
+}
+
+func (a *Amf) GetAmfName() string {
+	return a.amfName
+}
+
+func (a *Amf) GetServedGuamiList() []models.Guami {
+	return a.servedGuamiList
+}
+
+func (a *Amf) GetPlmnSupportList() []models.PlmnSnssai {
+	return a.plmnSupportList
+}
+
+func (a *Amf) GetNgResetAcknowledgeChan() chan struct{} {
+	return a.ngResetAcknowledgeChan
+}
+
+func (a *Amf) SetN2Conn(n2Conn *sctp.SCTPConn, n2NumOutStreams uint16) {
+	a.n2ConnMtx.Lock()
+	defer a.n2ConnMtx.Unlock()
+	a.n2Conn = n2Conn
+	a.n2NumOutStreams = n2NumOutStreams
+}
+
+// non UE-associated NGAP procedures are sent on stream 0
+func (a *Amf) WriteNonUeAssociated(b []byte) (int, error) {
+	a.n2ConnMtx.RLock()
+	defer a.n2ConnMtx.RUnlock()
+	return a.n2Conn.SCTPWrite(b, &sctp.SndRcvInfo{
+		Stream: 0,
+		PPID:   constant.NGAP_PPID,
+	})
+}
+
+// UE-associated NGAP procedures of the same UE always go through the same stream to keep them in order
+func (a *Amf) WriteUeAssociated(ranUeNgapId int64, b []byte) (int, error) {
+	a.n2ConnMtx.RLock()
+	defer a.n2ConnMtx.RUnlock()
+	return a.n2Conn.SCTPWrite(b, &sctp.SndRcvInfo{
+		Stream: getUeAssociatedStream(ranUeNgapId, a.n2NumOutStreams),
+		PPID:   constant.NGAP_PPID,
+	})
+}
+
+func (a *Amf) SetAmfName(amfName string) {
+	a.amfName = amfName
+}
+
+func (a *Amf) SetServedGuamiList(servedGuamiList []models.Guami) {
+	a.servedGuamiList = servedGuamiList
+}
+
+func (a *Amf) SetPlmnSupportList(plmnSupportList []models.PlmnSnssai) {
+	a.plmnSupportList = plmnSupportList
+}
+
+func (a *Amf) IsReady() bool {
+	return a.ready.Load()
+}
+
+func (a *Amf) SetReady(ready bool) {
+	a.ready.Store(ready)
+}
+
+// AMF ID is composed of AMF Region ID (8 bits), AMF Set ID (10 bits) and AMF Pointer (6 bits), TS 23.003 2.10.1
+func isSameAmfSet(amfIdA, amfIdB string) bool {
+	a, err := strconv.ParseUint(amfIdA, 16, 32)
+	if err != nil {
+		return false
+	}
+	b, err := strconv.ParseUint(amfIdB, 16, 32)
+	if err != nil {
+		return false
+	}
+	return a>>6 == b>>6
+}
+
+func isSamePlmn(plmnIdA, plmnIdB *models.PlmnIdNid) bool {
+	if plmnIdA == nil || plmnIdB == nil {
+		return false
+	}
+	return plmnIdA.Mcc == plmnIdB.Mcc && plmnIdA.Mnc == plmnIdB.Mnc
+}
+
+// select the AMF serving the GUAMI, otherwise an AMF in the same AMF set, nil if none of them matches
+func selectAmfByGuami(amfList []*Amf, guami models.Guami) *Amf {
+	for _, amf := range amfList {
+		for _, servedGuami := range amf.GetServedGuamiList() {
+			if isSamePlmn(servedGuami.PlmnId, guami.PlmnId) && servedGuami.AmfId == guami.AmfId {
+				return amf
+			}
+		}
+	}
+
+	for _, amf := range amfList {
+		for _, servedGuami := range amf.GetServedGuamiList() {
+			if isSamePlmn(servedGuami.PlmnId, guami.PlmnId) && isSameAmfSet(servedGuami.AmfId, guami.AmfId) {
+				return amf
+			}
+		}
+	}
+
+	return nil
+}
+
+// the 5G-S-TMSI carries the AMF Set ID and AMF Pointer without the AMF Region ID, select the AMF whose
+// served GUAMI matches both, otherwise an AMF in the same AMF set, nil if none of them matches, TS 23.003 2.11
+func selectAmfByAmfSetAndPointer(amfList []*Amf, amfSetId uint16, amfPointer uint8) *Amf {
+	amfSetAndPointer := uint64(amfSetId)<<6 | uint64(amfPointer)
+
+	for _, amf := range amfList {
+		for _, servedGuami := range amf.GetServedGuamiList() {
+			if amfId, err := strconv.ParseUint(servedGuami.AmfId, 16, 32); err == nil && amfId&0xffff == amfSetAndPointer {
+				return amf
+			}
+		}
+	}
+
+	for _, amf := range amfList {
+		for _, servedGuami := range amf.GetServedGuamiList() {
+			if amfId, err := strconv.ParseUint(servedGuami.AmfId, 16, 32); err == nil && (amfId&0xffff)>>6 == uint64(amfSetId) {
+				return amf
+			}
+		}
+	}
+
+	return nil
+}
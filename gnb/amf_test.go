@@ -0,0 +1,120 @@
+package gnb
+
+import (
+	"testing"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/go-playground/assert"
+)
+
+func newTestAmf(amfN2Ip string, servedGuamiList []models.Guami) *Amf {
+	amf := NewAmf([]string{amfN2Ip}, 38412, 38413)
+	amf.SetServedGuamiList(servedGuamiList)
+	return amf
+}
+
+var testAmfList = []*Amf{
+	newTestAmf("10.0.1.1", []models.Guami{
+		{
+			PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"},
+			AmfId:  "cafe00",
+		},
+	}),
+	newTestAmf("10.0.1.2", []models.Guami{
+		{
+			PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"},
+			AmfId:  "cafe01",
+		},
+	}),
+}
+
+var testSelectAmfByGuamiCases = []struct {
+	name          string
+	guami         models.Guami
+	expectedAmfIp string
+}{
+	{
+		name: "testSelectAmfByGuamiExactMatch",
+		guami: models.Guami{
+			PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"},
+			AmfId:  "cafe01",
+		},
+		expectedAmfIp: "10.0.1.2",
+	},
+	{
+		name: "testSelectAmfByGuamiSameAmfSet",
+		guami: models.Guami{
+			PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"},
+			AmfId:  "cafe3f",
+		},
+		expectedAmfIp: "10.0.1.1",
+	},
+	{
+		name: "testSelectAmfByGuamiDifferentAmfSet",
+		guami: models.Guami{
+			PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"},
+			AmfId:  "ca0000",
+		},
+		expectedAmfIp: "",
+	},
+	{
+		name: "testSelectAmfByGuamiDifferentPlmn",
+		guami: models.Guami{
+			PlmnId: &models.PlmnIdNid{Mcc: "466", Mnc: "92"},
+			AmfId:  "cafe00",
+		},
+		expectedAmfIp: "",
+	},
+}
+
+func TestSelectAmfByGuami(t *testing.T) {
+	for _, testCase := range testSelectAmfByGuamiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			amf := selectAmfByGuami(testAmfList, testCase.guami)
+			if testCase.expectedAmfIp == "" {
+				assert.Equal(t, true, amf == nil)
+			} else {
+				assert.Equal(t, testCase.expectedAmfIp, amf.GetAmfN2Ip())
+			}
+		})
+	}
+}
+
+var testSelectAmfByAmfSetAndPointerCases = []struct {
+	name          string
+	amfSetId      uint16
+	amfPointer    uint8
+	expectedAmfIp string
+}{
+	{
+		name:          "testSelectAmfByAmfSetAndPointerExactMatch",
+		amfSetId:      0x3f8,
+		amfPointer:    0x01,
+		expectedAmfIp: "10.0.1.2",
+	},
+	{
+		name:          "testSelectAmfByAmfSetAndPointerSameAmfSet",
+		amfSetId:      0x3f8,
+		amfPointer:    0x3f,
+		expectedAmfIp: "10.0.1.1",
+	},
+	{
+		name:          "testSelectAmfByAmfSetAndPointerDifferentAmfSet",
+		amfSetId:      0x000,
+		amfPointer:    0x00,
+		expectedAmfIp: "",
+	},
+}
+
+func TestSelectAmfByAmfSetAndPointer(t *testing.T) {
+	for _, testCase := range testSelectAmfByAmfSetAndPointerCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			amf := selectAmfByAmfSetAndPointer(testAmfList, testCase.amfSetId, testCase.amfPointer)
+			if testCase.expectedAmfIp == "" {
+				assert.Equal(t, true, amf == nil)
+			} else {
+				assert.Equal(t, testCase.expectedAmfIp, amf.GetAmfN2Ip())
+			}
+		})
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	consoleModel "github.com/Alonza0314/free-ran-ue/console/model"
@@ -18,6 +21,8 @@ import (
 	"github.com/Alonza0314/free-ran-ue/util"
 	"github.com/free5gc/aper"
 	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
 	"github.com/free5gc/ngap"
 	"github.com/free5gc/ngap/ngapConvert"
 	"github.com/free5gc/ngap/ngapType"
@@ -48,15 +53,13 @@ type api struct {
 }
 
 type Gnb struct {
-	amfN2Ip string
-	ranN2Ip string
-	upfN3Ip string
-	ranN3Ip string
+	ranN2IpList []string
+	upfN3Ip     string
+	ranN3Ip     string
 
 	ranControlPlaneIp string
 	ranDataPlaneIp    string
 
-	amfN2Port int
 	ranN2Port int
 	upfN3Port int
 	ranN3Port int
@@ -64,18 +67,24 @@ type Gnb struct {
 	ranControlPlanePort int
 	ranDataPlanePort    int
 
-	n2Conn *sctp.SCTPConn
+	n2Sctp model.SctpIE
+
+	amfList            []*Amf
+	amfRoundRobinIndex atomic.Uint64
+
 	n3Conn *net.UDPConn
 
 	gnbId   []byte
 	gnbName string
 
-	plmnId ngapType.PLMNIdentity
-	tai    ngapType.TAI
-	snssai ngapType.SNSSAI
+	plmnId          ngapType.PLMNIdentity
+	supportedTaList ngapType.SupportedTAList
+	tai             ngapType.TAI
 
 	staticNrdc bool
 
+	ueInactivityTimer time.Duration
+
 	xnInterface
 
 	ranControlPlaneListener *net.Listener
@@ -84,9 +93,12 @@ type Gnb struct {
 
 	ranUeConns            sync.Map // ranUeId -> *RanUe
 	xnUeConns             sync.Map // *XnUe -> struct{}
-	dlTeidToUe            sync.Map // dlTeid -> *(Ran/Xn)Ue
-	addressToUe           sync.Map // UDP address -> *(Ran/Xn)Ue
-	imsiTodlTeidAndUeType sync.Map // imsi -> dlTeidAndUeType
+	dlTeidToUe            sync.Map // dlTeid -> *RanUePduSession / *XnUe
+	addressToUe           sync.Map // UDP address -> *RanUePduSession / *XnUe
+	imsiTodlTeidAndUeType sync.Map // "imsi pduSessionId" for RAN UE, imsi for XN UE -> dlTeidAndUeType
+	idleUeConns           sync.Map // 5G-S-TMSI -> connection of the UE camping in CM-IDLE
+	xnHandoverContexts    sync.Map // imsi -> *xnHandoverContext of the UE handed over from the source gNB
+	n2HandoverRanUes      sync.Map // imsi -> *RanUe prepared by the handover request from the AMF
 
 	gtpChannel chan []byte
 
@@ -116,55 +128,52 @@ func NewGnb(config *model.GnbConfig, gnbLogger *logger.GnbLogger) *Gnb {
 		return nil
 	}
 
-	tai, err := util.TaiToNgap(models.Tai{
-		Tac: config.Gnb.Tai.Tac,
-		PlmnId: &models.PlmnId{
-			Mcc: config.Gnb.Tai.BroadcastPlmnId.Mcc,
-			Mnc: config.Gnb.Tai.BroadcastPlmnId.Mnc,
-		},
-	})
+	supportedTaList, err := util.SupportedTaListToNgap(config.Gnb.SupportedTaList)
 	if err != nil {
-		gnbLogger.CfgLog.Errorf("Error converting tai to ngap: %v", err)
+		gnbLogger.CfgLog.Errorf("Error converting supported TA list to ngap: %v", err)
 		return nil
 	}
 
-	sstInt, err := strconv.Atoi(config.Gnb.Snssai.Sst)
-	if err != nil {
-		gnbLogger.CfgLog.Errorf("Error converting sst to int: %v", err)
-		return nil
+	// the first broadcast PLMN of the first supported TA is the TAI reported for the UEs
+	tai := ngapType.TAI{
+		PLMNIdentity: supportedTaList.List[0].BroadcastPLMNList.List[0].PLMNIdentity,
+		TAC:          supportedTaList.List[0].TAC,
 	}
-	snssai, err := util.SNssaiToNgap(models.Snssai{
-		Sst: int32(sstInt),
-		Sd:  config.Gnb.Snssai.Sd,
-	})
-	if err != nil {
-		gnbLogger.CfgLog.Errorf("Error converting snssai to ngap: %v", err)
-		return nil
+
+	// each AMF association binds its own local port, starting from ranN2Port
+	amfList := make([]*Amf, 0, len(config.Gnb.AmfN2List))
+	for i, amfN2 := range config.Gnb.AmfN2List {
+		amfList = append(amfList, NewAmf(amfN2.IpList, amfN2.Port, config.Gnb.RanN2Port+i))
 	}
 
 	return &Gnb{
-		amfN2Ip:           config.Gnb.AmfN2Ip,
-		ranN2Ip:           config.Gnb.RanN2Ip,
+		ranN2IpList:       config.Gnb.RanN2IpList,
 		upfN3Ip:           config.Gnb.UpfN3Ip,
 		ranN3Ip:           config.Gnb.RanN3Ip,
 		ranControlPlaneIp: config.Gnb.RanControlPlaneIp,
 		ranDataPlaneIp:    config.Gnb.RanDataPlaneIp,
 
-		amfN2Port:           config.Gnb.AmfN2Port,
 		ranN2Port:           config.Gnb.RanN2Port,
 		upfN3Port:           config.Gnb.UpfN3Port,
 		ranN3Port:           config.Gnb.RanN3Port,
 		ranControlPlanePort: config.Gnb.RanControlPlanePort,
 		ranDataPlanePort:    config.Gnb.RanDataPlanePort,
 
+		n2Sctp: config.Gnb.Sctp,
+
+		amfList: amfList,
+
 		gnbId:   gnbId,
 		gnbName: config.Gnb.GnbName,
 
-		plmnId: plmnId,
-		tai:    tai,
-		snssai: snssai,
+		plmnId:          plmnId,
+		supportedTaList: supportedTaList,
+		tai:             tai,
 
 		staticNrdc: config.Gnb.StaticNrdc,
+
+		ueInactivityTimer: time.Duration(config.Gnb.UeInactivityTimer) * time.Second,
+
 		xnInterface: xnInterface{
 			enable:       config.Gnb.XnInterface.Enable,
 			xnListenIp:   config.Gnb.XnInterface.XnListenIp,
@@ -199,24 +208,14 @@ func NewGnb(config *model.GnbConfig, gnbLogger *logger.GnbLogger) *Gnb {
 func (g *Gnb) Start(ctx context.Context) error {
 	g.RanLog.Infoln("Starting GNB")
 
-	if err := g.connectToAmf(); err != nil {
-		g.SctpLog.Errorf("Error connecting to AMF: %v", err)
-		return err
-	}
-
-	if err := g.setupN2(); err != nil {
-		g.NgapLog.Errorf("Error setting up N2: %v", err)
-		if err := g.n2Conn.Close(); err != nil {
-			g.SctpLog.Errorf("Error closing N2 connection: %v", err)
-		}
+	if err := g.establishAllN2(ctx); err != nil {
+		g.NgapLog.Errorf("Error establishing N2: %v", err)
 		return err
 	}
 
 	if err := g.connectToUpf(); err != nil {
 		g.GtpLog.Errorf("Error connecting to UPF: %v", err)
-		if err := g.n2Conn.Close(); err != nil {
-			g.SctpLog.Errorf("Error closing N2 connection: %v", err)
-		}
+		g.closeAllN2()
 		return err
 	}
 
@@ -227,15 +226,11 @@ func (g *Gnb) Start(ctx context.Context) error {
 			if err := g.n3Conn.Close(); err != nil {
 				g.GtpLog.Errorf("Error closing N3 connection: %v", err)
 			}
-			if err := g.n2Conn.Close(); err != nil {
-				g.SctpLog.Errorf("Error closing N2 connection: %v", err)
-			}
+			g.closeAllN2()
 			return err
 		}
 	}
 
-	go g.ngapDispatcher.start(g)
-
 	if err := g.startRanControlPlaneListener(); err != nil {
 		g.RanLog.Errorf("Error starting ran control plane listener: %v", err)
 
@@ -247,9 +242,7 @@ func (g *Gnb) Start(ctx context.Context) error {
 		if err := g.n3Conn.Close(); err != nil {
 			g.GtpLog.Errorf("Error closing N3 connection: %v", err)
 		}
-		if err := g.n2Conn.Close(); err != nil {
-			g.SctpLog.Errorf("Error closing N2 connection: %v", err)
-		}
+		g.closeAllN2()
 		return err
 	}
 
@@ -265,9 +258,7 @@ func (g *Gnb) Start(ctx context.Context) error {
 		if err := g.n3Conn.Close(); err != nil {
 			g.GtpLog.Errorf("Error closing N3 connection: %v", err)
 		}
-		if err := g.n2Conn.Close(); err != nil {
-			g.SctpLog.Errorf("Error closing N2 connection: %v", err)
-		}
+		g.closeAllN2()
 		return err
 	}
 
@@ -305,13 +296,7 @@ func (g *Gnb) Start(ctx context.Context) error {
 				continue
 			}
 			g.RanLog.Infof("New UE connection accepted from: %v", conn.RemoteAddr())
-			ranUe := NewRanUe(conn, g.ranUeNgapIdGenerator)
-			if g.staticNrdc {
-				ranUe.ActivateNrdc()
-			}
-
-			g.ranUeConns.Store(ranUe.GetRanUeId(), ranUe)
-			go g.handleRanConnection(ctx, ranUe)
+			go g.handleUeConnection(ctx, util.NewRanUeConn(conn))
 		}
 	}()
 
@@ -375,32 +360,45 @@ func (g *Gnb) Stop() {
 	g.GtpLog.Tracef("N3 connection closed at %s:%d", g.ranN3Ip, g.ranN3Port)
 	g.GtpLog.Debugln("N3 connection closed")
 
-	if err := g.n2Conn.Close(); err != nil {
-		g.SctpLog.Errorf("Error stopping N2 connection: %v", err)
-		return
-	}
-	g.SctpLog.Tracef("N2 connection closed at %s:%d", g.ranN2Ip, g.ranN2Port)
-	g.SctpLog.Debugln("N2 connection closed")
+	g.closeAllN2()
+	g.SctpLog.Debugln("N2 connections closed")
 
 	g.RanLog.Infoln("GNB stopped")
 }
 
-func (g *Gnb) connectToAmf() error {
-	g.RanLog.Infoln("Connecting to AMF")
+func (g *Gnb) connectToAmf(amf *Amf) error {
+	g.RanLog.Infof("Connecting to AMF %v:%d", amf.GetAmfN2IpList(), amf.GetAmfN2Port())
 
-	amfAddr, gnbAddr, err := getAmfAndGnbSctpN2Addr(g.amfN2Ip, g.ranN2Ip, g.amfN2Port, g.ranN2Port)
+	amfAddr, gnbAddr, err := getAmfAndGnbSctpN2Addr(amf.GetAmfN2IpList(), g.ranN2IpList, amf.GetAmfN2Port(), amf.GetRanN2Port())
 	if err != nil {
 		return err
 	}
 	g.SctpLog.Tracef("AMF N2 address: %v", amfAddr.String())
 	g.SctpLog.Tracef("GNB N2 address: %v", gnbAddr.String())
 
-	conn, err := sctp.DialSCTP("sctp", gnbAddr, amfAddr)
+	conn, err := sctp.DialSCTPExt("sctp", gnbAddr, amfAddr, getSctpInitMsg(g.n2Sctp), getSctpRtoInfo(g.n2Sctp), nil, 0)
 	if err != nil {
 		return fmt.Errorf("error connecting to AMF: %v", err)
 	}
 	g.SctpLog.Debugln("Dial SCTP to AMF success")
 
+	if g.n2Sctp.HeartbeatInterval != 0 {
+		if err := setSctpHeartbeatInterval(conn, g.n2Sctp.HeartbeatInterval); err != nil {
+			if err := conn.Close(); err != nil {
+				g.SctpLog.Warnf("Error closing N2 connection: %v", err)
+			}
+			return err
+		}
+		g.SctpLog.Tracef("N2 connection heartbeat interval: %d ms", g.n2Sctp.HeartbeatInterval)
+	}
+
+	numOutStreams, err := getSctpOutStreams(conn)
+	if err != nil {
+		g.SctpLog.Warnf("Error getting N2 outbound streams, UE-associated signalling falls back to stream 0: %v", err)
+		numOutStreams = 1
+	}
+	g.SctpLog.Tracef("N2 connection outbound streams: %d", numOutStreams)
+
 	info, err := conn.GetDefaultSentParam()
 	if err != nil {
 		return err
@@ -412,7 +410,7 @@ func (g *Gnb) connectToAmf() error {
 		return fmt.Errorf("error setting default sent param: %v", err)
 	}
 
-	g.n2Conn = conn
+	amf.SetN2Conn(conn, numOutStreams)
 
 	g.RanLog.Infof("Connected to AMF: %v", amfAddr.String())
 	return nil
@@ -441,24 +439,24 @@ func (g *Gnb) connectToUpf() error {
 	return nil
 }
 
-func (g *Gnb) setupN2() error {
-	g.RanLog.Infoln("Setting up N2")
+func (g *Gnb) setupN2(amf *Amf) error {
+	g.RanLog.Infof("Setting up N2 with AMF %s:%d", amf.GetAmfN2Ip(), amf.GetAmfN2Port())
 
-	request, err := getNgapSetupRequest(g.gnbId, g.gnbName, g.plmnId, g.tai, g.snssai)
+	request, err := getNgapSetupRequest(g.gnbId, g.gnbName, g.plmnId, g.supportedTaList)
 	if err != nil {
 		return fmt.Errorf("error getting NGAP setup request: %v", err)
 	}
 	g.NgapLog.Tracef("NGAP setup request: %+v", request)
 
-	n, err := g.n2Conn.Write(request)
+	n, err := amf.WriteNonUeAssociated(request)
 	if err != nil {
 		return fmt.Errorf("error sending NGAP setup request: %v", err)
 	}
 	g.NgapLog.Tracef("Sent %d bytes of NGAP setup request", n)
 	g.NgapLog.Debugln("Sent NGAP setup request to AMF")
 
-	responseRaw := make([]byte, 2048)
-	n, err = g.n2Conn.Read(responseRaw)
+	responseRaw := make([]byte, constant.NGAP_MAX_MESSAGE_LEN)
+	n, err = amf.GetN2Conn().Read(responseRaw)
 	if err != nil {
 		return fmt.Errorf("error reading NGAP setup response: %v", err)
 	}
@@ -471,10 +469,34 @@ func (g *Gnb) setupN2() error {
 	g.NgapLog.Tracef("NGAP setup response: %+v", response)
 	g.NgapLog.Debugln("Received NGAP setup response from AMF")
 
+	if (response.Present == ngapType.NGAPPDUPresentUnsuccessfulOutcome) && (response.UnsuccessfulOutcome.ProcedureCode.Value == ngapType.ProcedureCodeNGSetup) {
+		return getNgSetupFailureError(response.UnsuccessfulOutcome.Value.NGSetupFailure)
+	}
+
 	if (response.Present != ngapType.NGAPPDUPresentSuccessfulOutcome) || (response.SuccessfulOutcome.ProcedureCode.Value != ngapType.ProcedureCodeNGSetup) {
 		return fmt.Errorf("error NGAP setup response: %+v", response)
 	}
 
+	for _, ie := range response.SuccessfulOutcome.Value.NGSetupResponse.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFName:
+			amf.SetAmfName(ie.Value.AMFName.Value)
+		case ngapType.ProtocolIEIDServedGUAMIList:
+			servedGuamiList := make([]models.Guami, 0, len(ie.Value.ServedGUAMIList.List))
+			for _, servedGuamiItem := range ie.Value.ServedGUAMIList.List {
+				servedGuamiList = append(servedGuamiList, util.GuamiToModels(servedGuamiItem.GUAMI))
+			}
+			amf.SetServedGuamiList(servedGuamiList)
+		case ngapType.ProtocolIEIDRelativeAMFCapacity:
+		case ngapType.ProtocolIEIDPLMNSupportList:
+			plmnSupportList := make([]models.PlmnSnssai, 0, len(ie.Value.PLMNSupportList.List))
+			for _, plmnSupportItem := range ie.Value.PLMNSupportList.List {
+				plmnSupportList = append(plmnSupportList, util.PlmnSupportItemToModels(plmnSupportItem))
+			}
+			amf.SetPlmnSupportList(plmnSupportList)
+		}
+	}
+
 	g.NgapLog.Infoln("============= gNB Info =============")
 
 	g.NgapLog.Infof("gNB ID: %s, name: %s", hex.EncodeToString(g.gnbId), g.gnbName)
@@ -482,11 +504,23 @@ func (g *Gnb) setupN2() error {
 	plmnId := ngapConvert.PlmnIdToModels(g.plmnId)
 	g.NgapLog.Infof("PLMN ID: %v", plmnId)
 
-	tai := ngapConvert.TaiToModels(g.tai)
-	g.NgapLog.Infof("TAC: %v, broadcast PLMN ID: %v", tai.Tac, tai.PlmnId)
+	for _, supportedTaItem := range g.supportedTaList.List {
+		for _, broadcastPlmnItem := range supportedTaItem.BroadcastPLMNList.List {
+			g.NgapLog.Infof("TAC: %s, broadcast PLMN ID: %v", hex.EncodeToString(supportedTaItem.TAC.Value), util.PlmnIdToModels(broadcastPlmnItem.PLMNIdentity))
+			for _, sliceSupportItem := range broadcastPlmnItem.TAISliceSupportList.List {
+				snssai := util.SNssaiToModels(sliceSupportItem.SNSSAI)
+				g.NgapLog.Infof("SST: %v, SD: %v", snssai.Sst, snssai.Sd)
+			}
+		}
+	}
 
-	snssai := ngapConvert.SNssaiToModels(g.snssai)
-	g.NgapLog.Infof("SST: %v, SD: %v", snssai.Sst, snssai.Sd)
+	g.NgapLog.Infof("AMF name: %s, address: %s:%d", amf.GetAmfName(), amf.GetAmfN2Ip(), amf.GetAmfN2Port())
+	for _, servedGuami := range amf.GetServedGuamiList() {
+		g.NgapLog.Infof("Served GUAMI: PLMN ID: %v, AMF ID: %s", servedGuami.PlmnId, servedGuami.AmfId)
+	}
+	for _, plmnSupport := range amf.GetPlmnSupportList() {
+		g.NgapLog.Infof("Supported PLMN ID: %v, S-NSSAI list: %v", plmnSupport.PlmnId, plmnSupport.SNssaiList)
+	}
 
 	g.NgapLog.Infoln("====================================")
 
@@ -497,25 +531,51 @@ func (g *Gnb) setupN2() error {
 func (g *Gnb) setupN1(ranUe *RanUe) error {
 	g.RanLog.Infoln("Setting up N1")
 
-	// ue initialization
-	if err := g.processUeInitialization(ranUe); err != nil {
-		return fmt.Errorf("error process ue initialization: %v", err)
+	if err := g.processUeConnectionSetup(ranUe); err != nil {
+		return fmt.Errorf("error process ue connection setup: %v", err)
 	}
 
-	// pdu session establishment
-	ranUe.SetDlTeid(g.teidGenerator.AllocateTeid())
-	if err := g.processUePduSessionEstablishment(ranUe); err != nil {
-		return err
+	// registration request, or service request from CM-IDLE
+	messageType, initialNasMessage, err := ranUe.GetN1Conn().ReadMessage()
+	if err != nil {
+		return fmt.Errorf("error receive initial nas message from UE: %v", err)
 	}
+	g.NasLog.Tracef("Received %d bytes of initial message from UE", len(initialNasMessage))
 
-	g.dlTeidToUe.Store(hex.EncodeToString(ranUe.GetDlTeid()), ranUe)
-	g.GtpLog.Debugf("Stored RAN UE %s with DL TEID %s to dlTeidToUe", ranUe.GetMobileIdentityIMSI(), hex.EncodeToString(ranUe.GetDlTeid()))
+	// a handed over UE brings its context instead of a NAS message
+	if messageType == constant.RAN_UE_MESSAGE_TYPE_HANDOVER_COMPLETE {
+		processUeHandoverCompletion := g.processUeN2HandoverCompletion
+		if _, exists := g.xnHandoverContexts.Load(ranUe.GetMobileIdentityIMSI()); exists {
+			processUeHandoverCompletion = g.processUeXnHandoverCompletion
+		}
+		if err := processUeHandoverCompletion(ranUe); err != nil {
+			return fmt.Errorf("error process ue handover completion: %v", err)
+		}
+		g.RanLog.Infof("UE %s N1 setup complete", ranUe.GetMobileIdentityIMSI())
+		return nil
+	}
+	if messageType != constant.RAN_UE_MESSAGE_TYPE_NAS {
+		return fmt.Errorf("unexpected initial message type from UE: %d", messageType)
+	}
 
-	g.imsiTodlTeidAndUeType.Store(ranUe.GetMobileIdentityIMSI(), dlTeidAndUeType{
-		dlTeid: ranUe.GetDlTeid(),
-		ueType: constant.UE_TYPE_RAN,
-	})
-	g.GtpLog.Debugf("Sent DL TEID %s to imsiTodlTeidAndUeType", hex.EncodeToString(ranUe.GetDlTeid()))
+	initialNas, err := decodeInitialNasMessage(initialNasMessage)
+	if err != nil {
+		return fmt.Errorf("error decode initial nas message from UE: %v", err)
+	}
+
+	switch initialNas.GmmHeader.GetMessageType() {
+	case nas.MsgTypeRegistrationRequest:
+		if err := g.processUeInitialization(ranUe, initialNasMessage, initialNas.RegistrationRequest); err != nil {
+			return fmt.Errorf("error process ue initialization: %v", err)
+		}
+	case nas.MsgTypeServiceRequest:
+		// the pdu sessions are set up again by the initial context setup
+		if err := g.processUeServiceRequest(ranUe, initialNasMessage, initialNas.ServiceRequest); err != nil {
+			return fmt.Errorf("error process ue service request: %v", err)
+		}
+	default:
+		return fmt.Errorf("unexpected initial nas message type: %d", initialNas.GmmHeader.GetMessageType())
+	}
 
 	g.RanLog.Infof("UE %s N1 setup complete", ranUe.GetMobileIdentityIMSI())
 	return nil
@@ -540,7 +600,7 @@ func (g *Gnb) startGtpProcessor(ctx context.Context) {
 	go forwardGtpPacketToN3Conn(ctx, g.n3Conn, g.gtpChannel, g.GnbLogger)
 	g.GtpLog.Debugln("Forward GTP packet to N3 connection started")
 
-	go receiveGtpPacketFromN3Conn(ctx, g.n3Conn, g.ranDataPlaneServer, g.GnbLogger, &g.dlTeidToUe)
+	go receiveGtpPacketFromN3Conn(ctx, g.n3Conn, g.ranDataPlaneServer, g.gtpChannel, g.GnbLogger, &g.dlTeidToUe)
 	g.GtpLog.Debugln("Receive GTP packet from N3 connection started")
 
 	g.GtpLog.Infoln("GTP processor started")
@@ -597,21 +657,49 @@ func (g *Gnb) startRanDataPlaneServer() error {
 	return nil
 }
 
+// a UE camping in CM-IDLE gets no RAN UE context
+func (g *Gnb) handleUeConnection(ctx context.Context, ranUeConn *util.RanUeConn) {
+	messageType, err := ranUeConn.PeekMessageType()
+	if err != nil {
+		g.RanLog.Errorf("Error receive first message from UE: %v", err)
+		if err := ranUeConn.Close(); err != nil {
+			g.RanLog.Errorf("Error closing UE connection: %v", err)
+		}
+		return
+	}
+
+	if messageType == constant.RAN_UE_MESSAGE_TYPE_IDLE_CAMP {
+		g.handleUeIdleCamp(ranUeConn)
+		return
+	}
+
+	ranUe := NewRanUe(ranUeConn, g.ranUeNgapIdGenerator)
+	if g.staticNrdc {
+		ranUe.ActivateNrdc()
+	}
+
+	g.ranUeConns.Store(ranUe.GetRanUeId(), ranUe)
+	g.handleRanConnection(ctx, ranUe)
+}
+
 func (g *Gnb) handleRanConnection(ctx context.Context, ranUe *RanUe) {
 	defer func() {
-		if err := ranUe.GetN1Conn().Close(); err != nil {
+		if err := ranUe.GetN1Conn().Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			g.RanLog.Errorf("Error closing UE connection: %v", err)
 		}
 		g.RanLog.Infof("Closed UE connection from: %v", ranUe.GetN1Conn().RemoteAddr())
-		ranUe.Release(g.ranUeNgapIdGenerator, g.teidGenerator)
+		for _, pduSession := range ranUe.GetPduSessionList() {
+			g.releaseUePduSessionResource(ranUe, pduSession)
+		}
+		// a late ue context release command must not find the closed channels
 		g.ranUeConns.Delete(ranUe.GetRanUeId())
+		ranUe.Release(g.ranUeNgapIdGenerator)
 	}()
 
 	if err := g.setupN1(ranUe); err != nil {
 		g.RanLog.Errorf("Error setting up N1: %v", err)
 		return
 	}
-	g.GtpLog.Debugf("DL TEID: %s, UL TEID: %s", hex.EncodeToString(ranUe.GetDlTeid()), hex.EncodeToString(ranUe.GetUlTeid()))
 
 	if err := g.releaseN1(ranUe); err != nil {
 		g.RanLog.Errorf("Error releasing N1: %v", err)
@@ -620,6 +708,65 @@ func (g *Gnb) handleRanConnection(ctx context.Context, ranUe *RanUe) {
 	g.RanLog.Infof("UE %s N1 released", ranUe.GetMobileIdentityIMSI())
 }
 
+// handleRanConnection releases the RAN UE resources afterwards
+func (g *Gnb) releaseRanUe(ranUe *RanUe) {
+	if err := ranUe.GetN1Conn().Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		g.RanLog.Warnf("Error closing UE connection: %v", err)
+	}
+	g.RanLog.Infof("Released UE %v with ranUeNgapId %d", ranUe.GetN1Conn().RemoteAddr(), ranUe.GetRanUeId())
+}
+
+// the UE binds its data plane address with the initial packet
+func (g *Gnb) addUePduSessionResource(ranUe *RanUe, pduSessionId int64, snssai ngapType.SNSSAI, pduSessionResourceSetupRequestTransfer *ngapType.PDUSessionResourceSetupRequestTransfer) *RanUePduSession {
+	pduSession := NewRanUePduSession(ranUe.GetMobileIdentityIMSI(), pduSessionId, snssai, g.teidGenerator.AllocateTeid())
+	for _, ie := range pduSessionResourceSetupRequestTransfer.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDPDUSessionAggregateMaximumBitRate:
+		case ngapType.ProtocolIEIDULNGUUPTNLInformation:
+			pduSession.SetUlTeid(ie.Value.ULNGUUPTNLInformation.GTPTunnel.GTPTEID.Value)
+		case ngapType.ProtocolIEIDAdditionalULNGUUPTNLInformation:
+		case ngapType.ProtocolIEIDPDUSessionType:
+		case ngapType.ProtocolIEIDQosFlowSetupRequestList:
+			for _, qosFlowSetupRequestItem := range ie.Value.QosFlowSetupRequestList.List {
+				fiveQi, _ := util.QosCharacteristicsToFiveQi(qosFlowSetupRequestItem.QosFlowLevelQosParameters.QosCharacteristics)
+				pduSession.SetQosFlow(qosFlowSetupRequestItem.QosFlowIdentifier.Value, fiveQi)
+				g.NgapLog.Debugf("Setup QoS flow %d with 5QI %d for PDU session %d", qosFlowSetupRequestItem.QosFlowIdentifier.Value, fiveQi, pduSessionId)
+			}
+		}
+	}
+	ranUe.AddPduSession(pduSession)
+	g.GtpLog.Debugf("UE %s PDU session %d DL TEID: %s, UL TEID: %s", ranUe.GetMobileIdentityIMSI(), pduSessionId, hex.EncodeToString(pduSession.GetDlTeid()), hex.EncodeToString(pduSession.GetUlTeid()))
+
+	g.dlTeidToUe.Store(hex.EncodeToString(pduSession.GetDlTeid()), pduSession)
+	g.GtpLog.Debugf("Stored RAN UE %s PDU session %d with DL TEID %s to dlTeidToUe", ranUe.GetMobileIdentityIMSI(), pduSessionId, hex.EncodeToString(pduSession.GetDlTeid()))
+
+	g.imsiTodlTeidAndUeType.Store(pduSession.GetDataPlaneKey(), dlTeidAndUeType{
+		dlTeid: pduSession.GetDlTeid(),
+		ueType: constant.UE_TYPE_RAN,
+	})
+	g.GtpLog.Debugf("Sent DL TEID %s to imsiTodlTeidAndUeType", hex.EncodeToString(pduSession.GetDlTeid()))
+
+	return pduSession
+}
+
+// handleRanConnection only cleans up what is left when the UE leaves
+func (g *Gnb) releaseUePduSessionResource(ranUe *RanUe, pduSession *RanUePduSession) {
+	dlTeid := hex.EncodeToString(pduSession.GetDlTeid())
+	g.dlTeidToUe.Delete(dlTeid)
+	g.imsiTodlTeidAndUeType.Delete(pduSession.GetDataPlaneKey())
+	g.GtpLog.Debugf("Removed RAN UE %s PDU session %d with DL TEID %s from dlTeidToUe", ranUe.GetMobileIdentityIMSI(), pduSession.GetPduSessionId(), dlTeid)
+
+	if pduSession.GetDataPlaneAddress() != nil {
+		g.addressToUe.Delete(pduSession.GetDataPlaneAddress().String())
+		pduSession.SetDataPlaneAddress(nil)
+	}
+
+	g.teidGenerator.ReleaseTeid(pduSession.GetDlTeid())
+	ranUe.DeletePduSession(pduSession.GetPduSessionId())
+
+	g.RanLog.Infof("UE %s PDU session %d resource released", ranUe.GetMobileIdentityIMSI(), pduSession.GetPduSessionId())
+}
+
 func (g *Gnb) startDataPlaneProcessor() {
 	buffer := make([]byte, 4096)
 	for {
@@ -645,13 +792,14 @@ func (g *Gnb) startDataPlaneProcessor() {
 	}
 }
 
-func (g *Gnb) handleUeDataPlaneInitialPacket(ueAddress *net.UDPAddr, imsi string) {
+// the data plane key is "imsi pduSessionId" for a RAN UE and the imsi only for a XN UE
+func (g *Gnb) handleUeDataPlaneInitialPacket(ueAddress *net.UDPAddr, dataPlaneKey string) {
 	var dlTeidAndUeTypeInstance dlTeidAndUeType
 	for try := 0; ; try += 1 {
-		dlTeidAndUeTypeValue, exists := g.imsiTodlTeidAndUeType.Load(imsi)
+		dlTeidAndUeTypeValue, exists := g.imsiTodlTeidAndUeType.Load(dataPlaneKey)
 		if !exists {
 			if try == 100 {
-				g.RanLog.Errorf("No DL TEID and UE type found for IMSI: %s", imsi)
+				g.RanLog.Errorf("No DL TEID and UE type found for: %s", dataPlaneKey)
 				return
 			}
 			time.Sleep(100 * time.Millisecond)
@@ -661,7 +809,7 @@ func (g *Gnb) handleUeDataPlaneInitialPacket(ueAddress *net.UDPAddr, imsi string
 		}
 	}
 
-	g.imsiTodlTeidAndUeType.Delete(imsi)
+	g.imsiTodlTeidAndUeType.Delete(dataPlaneKey)
 
 	ue, exists := g.dlTeidToUe.Load(hex.EncodeToString(dlTeidAndUeTypeInstance.dlTeid))
 	if !exists {
@@ -671,9 +819,9 @@ func (g *Gnb) handleUeDataPlaneInitialPacket(ueAddress *net.UDPAddr, imsi string
 
 	switch dlTeidAndUeTypeInstance.ueType {
 	case constant.UE_TYPE_RAN:
-		ue.(*RanUe).SetDataPlaneAddress(ueAddress)
+		ue.(*RanUePduSession).SetDataPlaneAddress(ueAddress)
 		g.addressToUe.Store(ueAddress.String(), ue)
-		g.RanLog.Infof("Set data plane address %s for UE: %s", ueAddress.String(), ue.(*RanUe).GetMobileIdentityIMSI())
+		g.RanLog.Infof("Set data plane address %s for UE: %s, PDU session: %d", ueAddress.String(), ue.(*RanUePduSession).GetIMSI(), ue.(*RanUePduSession).GetPduSessionId())
 	case constant.UE_TYPE_XN:
 		ue.(*XnUe).SetDataPlaneAddress(ueAddress)
 		g.addressToUe.Store(ueAddress.String(), ue)
@@ -681,6 +829,49 @@ func (g *Gnb) handleUeDataPlaneInitialPacket(ueAddress *net.UDPAddr, imsi string
 	}
 }
 
+// the camping connection is kept until the UE closes it
+func (g *Gnb) handleUeIdleCamp(ranUeConn *util.RanUeConn) {
+	defer func() {
+		if err := ranUeConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			g.RanLog.Errorf("Error closing UE camping connection: %v", err)
+		}
+	}()
+
+	_, payload, err := ranUeConn.ReadMessage()
+	if err != nil {
+		g.RanLog.Errorf("Error receive idle camp from UE: %v", err)
+		return
+	}
+	fiveGSTmsi := string(payload)
+	g.idleUeConns.Store(fiveGSTmsi, ranUeConn)
+	g.RanLog.Infof("UE with 5G-S-TMSI %s camps in CM-IDLE from %v", fiveGSTmsi, ranUeConn.RemoteAddr())
+
+	for {
+		if _, _, err := ranUeConn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	g.idleUeConns.CompareAndDelete(fiveGSTmsi, ranUeConn)
+	g.RanLog.Debugf("UE with 5G-S-TMSI %s stops camping", fiveGSTmsi)
+}
+
+// the paging consumes the camping entry
+func (g *Gnb) pageUe(fiveGSTmsi string) error {
+	ranUeConn, exists := g.idleUeConns.LoadAndDelete(fiveGSTmsi)
+	if !exists {
+		return fmt.Errorf("no UE camps with 5G-S-TMSI %s", fiveGSTmsi)
+	}
+
+	n, err := ranUeConn.(*util.RanUeConn).WriteMessage(constant.RAN_UE_MESSAGE_TYPE_PAGING, nil)
+	if err != nil {
+		return fmt.Errorf("error send paging to UE: %v", err)
+	}
+	g.RanLog.Tracef("Sent %d bytes of paging to UE", n)
+
+	g.RanLog.Infof("Paged UE with 5G-S-TMSI %s", fiveGSTmsi)
+	return nil
+}
+
 func (g *Gnb) handleUeDataPlanePacket(ueAddress *net.UDPAddr, buffer []byte) {
 	ue, exists := g.addressToUe.Load(ueAddress.String())
 	if !exists {
@@ -689,164 +880,169 @@ func (g *Gnb) handleUeDataPlanePacket(ueAddress *net.UDPAddr, buffer []byte) {
 	}
 
 	switch u := ue.(type) {
-	case *RanUe:
+	case *RanUePduSession:
+		u.UpdateLastActivityTime()
 		go formatGtpPacketAndWriteToGtpChannel(u.GetUlTeid(), buffer, g.gtpChannel, g.GnbLogger)
 	case *XnUe:
 		go formatGtpPacketAndWriteToGtpChannel(u.GetUlTeid(), buffer, g.gtpChannel, g.GnbLogger)
 	}
 }
 
-func (g *Gnb) processUeInitialization(ranUe *RanUe) error {
-	g.RanLog.Infoln("Processing UE initialization")
+func (g *Gnb) processUeInitialization(ranUe *RanUe, ueRegistrationRequest []byte, registrationRequest *nasMessage.RegistrationRequest) error {
+	g.RanLog.Infof("Processing UE initialization, registration type: %d", registrationRequest.NgksiAndRegistrationType5GS.GetRegistrationType5GS())
 
-	// receive ue registration request from UE and send to AMF
-	ueRegistrationRequest := make([]byte, 1024)
-	n, err := ranUe.GetN1Conn().Read(ueRegistrationRequest)
+	// send ue registration request from UE to AMF
+	ranUe.SetMobileIdentity5GS(registrationRequest.MobileIdentity5GS)
+	g.NasLog.Debugf("Receive UE %s registration request from UE", ranUe.GetMobileIdentityIMSI())
+
+	amf, err := g.selectAmf(registrationRequest.MobileIdentity5GS)
 	if err != nil {
-		return fmt.Errorf("error receive ue registration request from UE: %v", err)
+		return fmt.Errorf("error select AMF: %v", err)
 	}
-	g.NasLog.Tracef("Received %d bytes of UE registration request from UE", n)
+	ranUe.SetAmf(amf)
+	g.NgapLog.Debugf("Selected AMF %s:%d for UE %s", amf.GetAmfN2Ip(), amf.GetAmfN2Port(), ranUe.GetMobileIdentityIMSI())
 
-	nasMessage := nas.NewMessage()
-	if err := nasMessage.GmmMessageDecode(&ueRegistrationRequest); err != nil {
-		return fmt.Errorf("error decode ue registration request from UE: %v", err)
+	// registration update is mobile originated signalling
+	rrcEstablishmentCause := aper.Enumerated(ngapType.RRCEstablishmentCausePresentMtAccess)
+	if registrationRequest.NgksiAndRegistrationType5GS.GetRegistrationType5GS() != nasMessage.RegistrationType5GSInitialRegistration {
+		rrcEstablishmentCause = ngapType.RRCEstablishmentCausePresentMoSignalling
 	}
-	ranUe.SetMobileIdentity5GS(nasMessage.GmmMessage.RegistrationRequest.MobileIdentity5GS)
-	g.NasLog.Debugf("Receive UE %s registration request from UE", ranUe.GetMobileIdentityIMSI())
 
-	ueInitialMessage, err := getInitialUeMessage(ranUe.GetRanUeId(), ueRegistrationRequest, g.plmnId, g.tai)
+	ueInitialMessage, err := getInitialUeMessage(ranUe.GetRanUeId(), ueRegistrationRequest, g.plmnId, g.tai, rrcEstablishmentCause, nil)
 	if err != nil {
 		return fmt.Errorf("error get initial ue message: %v", err)
 	}
 	g.NgapLog.Tracef("Get initial UE message: %+v", ueInitialMessage)
 
-	if n, err = g.n2Conn.Write(ueInitialMessage); err != nil {
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), ueInitialMessage)
+	if err != nil {
 		return fmt.Errorf("error send initial ue message to AMF: %v", err)
 	}
 	g.NgapLog.Tracef("Sent %d bytes of initial UE message to AMF", n)
 	g.NgapLog.Debugln("Sent initial UE message to AMF")
 
-	// wait dispatcher to receive nas authentication request from AMF and send to UE
+	// the AMF may skip authentication and security mode with a valid security context
+
+	g.RanLog.Infof("UE %s initialized", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
 
-	// receive nas authentication response from UE and send to AMF
-	nasAuthenticationResponse := make([]byte, 1024)
-	n, err = ranUe.GetN1Conn().Read(nasAuthenticationResponse)
+// the NAS mobile identity may be a SUCI or a 5G-S-TMSI
+func (g *Gnb) processUeConnectionSetup(ranUe *RanUe) error {
+	messageType, connectionSetupRequest, err := ranUe.GetN1Conn().ReadMessage()
 	if err != nil {
-		return fmt.Errorf("error receive nas authentication response from UE: %v", err)
+		return fmt.Errorf("error receive connection setup request from UE: %v", err)
 	}
-	g.NasLog.Tracef("Received %d bytes of NAS Authentication Response from UE", n)
-	g.NasLog.Debugln("Receive NAS Authentication Response from UE")
+	g.RanLog.Tracef("Received %d bytes of connection setup request from UE", len(connectionSetupRequest))
 
-	uplinkNasTransport, err := getUplinkNasTransport(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai, nasAuthenticationResponse[:n])
-	if err != nil {
-		return fmt.Errorf("error get uplink nas transport: %v", err)
+	if messageType != constant.RAN_UE_MESSAGE_TYPE_CONNECTION_SETUP_REQUEST || len(connectionSetupRequest) == 0 {
+		return fmt.Errorf("unexpected connection setup request from UE, message type: %d, payload: %q", messageType, connectionSetupRequest)
 	}
-	g.NgapLog.Tracef("Get uplink NAS transport: %+v", uplinkNasTransport)
+	ranUe.SetIMSI(string(connectionSetupRequest))
 
-	n, err = g.n2Conn.Write(uplinkNasTransport)
+	// the TAI stands in for the system information
+	servingPlmnId := util.PlmnIdToModels(g.tai.PLMNIdentity)
+	n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_CONNECTION_SETUP, []byte(strings.Join([]string{hex.EncodeToString(g.tai.TAC.Value), servingPlmnId.Mcc, servingPlmnId.Mnc}, " ")))
 	if err != nil {
-		return fmt.Errorf("error send uplink nas transport to AMF: %v", err)
+		return fmt.Errorf("error send connection setup to UE: %v", err)
 	}
-	g.NgapLog.Tracef("Sent %d bytes of uplink NAS transport to AMF", n)
-	g.NgapLog.Debugln("Sent uplink NAS transport to AMF")
+	g.RanLog.Tracef("Sent %d bytes of connection setup to UE", n)
 
-	// wait dispatcher to receive nas security mode command message from AMF and send to UE
+	g.RanLog.Debugf("UE %s connection setup", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
 
-	// receive nas security mode complete message from UE and send to AMF
-	nasSecurityModeComplete := make([]byte, 1024)
-	n, err = ranUe.GetN1Conn().Read(nasSecurityModeComplete)
-	if err != nil {
-		return fmt.Errorf("error receive nas security mode complete from UE: %v", err)
+// the initial NAS message is never ciphered
+func decodeInitialNasMessage(initialNasMessage []byte) (*nas.Message, error) {
+	if len(initialNasMessage) < 3 {
+		return nil, fmt.Errorf("initial nas message too short: %d bytes", len(initialNasMessage))
 	}
-	g.NasLog.Tracef("Received %d bytes of NAS Security Mode Complete from UE", n)
-	g.NasLog.Debugln("Receive NAS Security Mode Complete from UE")
 
-	uplinkNasTransport, err = getUplinkNasTransport(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai, nasSecurityModeComplete[:n])
-	if err != nil {
-		return fmt.Errorf("error get uplink nas transport: %v", err)
+	plainNasMessage := initialNasMessage
+	if nas.GetSecurityHeaderType(initialNasMessage) != nas.SecurityHeaderTypePlainNas {
+		// extended protocol discriminator, security header type, message authentication code and sequence number
+		if len(initialNasMessage) < 10 {
+			return nil, fmt.Errorf("security protected initial nas message too short: %d bytes", len(initialNasMessage))
+		}
+		plainNasMessage = initialNasMessage[7:]
 	}
-	g.NgapLog.Tracef("Get uplink NAS transport: %+v", uplinkNasTransport)
 
-	n, err = g.n2Conn.Write(uplinkNasTransport)
-	if err != nil {
-		return fmt.Errorf("error send uplink nas transport to AMF: %v", err)
+	message := nas.NewMessage()
+	if err := message.GmmMessageDecode(&plainNasMessage); err != nil {
+		return nil, err
 	}
-	g.NgapLog.Tracef("Sent %d bytes of uplink NAS transport to AMF", n)
-	g.NgapLog.Debugln("Sent uplink NAS transport to AMF")
+	return message, nil
+}
 
-	// wait dispatcher to receive ngap initial context setup request from AMF
+func (g *Gnb) processUeServiceRequest(ranUe *RanUe, ueServiceRequest []byte, serviceRequest *nasMessage.ServiceRequest) error {
+	g.RanLog.Infoln("Processing UE service request")
 
-	// receive nas registration complete message from UE and send to AMF
-	nasRegistrationComplete := make([]byte, 1024)
-	n, err = ranUe.GetN1Conn().Read(nasRegistrationComplete)
+	fiveGSTmsiString, _, _ := serviceRequest.TMSI5GS.Get5GSTMSI()
+	fiveGSTmsi, err := util.FiveGSTmsiToNgap(fiveGSTmsiString)
 	if err != nil {
-		return fmt.Errorf("error receive nas registration complete from UE: %v", err)
+		return fmt.Errorf("error convert 5G-S-TMSI %s: %v", fiveGSTmsiString, err)
 	}
-	g.NasLog.Tracef("Received %d bytes of NAS Registration Complete from UE", n)
-	g.NasLog.Debugln("Receive NAS Registration Complete from UE")
+	g.NasLog.Debugf("Receive UE %s service request with 5G-S-TMSI %s from UE", ranUe.GetMobileIdentityIMSI(), fiveGSTmsiString)
 
-	uplinkNasTransport, err = getUplinkNasTransport(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai, nasRegistrationComplete[:n])
-	if err != nil {
-		return fmt.Errorf("error get uplink nas transport: %v", err)
+	g.idleUeConns.Delete(fiveGSTmsiString)
+
+	mobileIdentity5GS := nasType.MobileIdentity5GS{
+		Len:    serviceRequest.TMSI5GS.GetLen(),
+		Buffer: serviceRequest.TMSI5GS.Octet[:],
 	}
-	g.NgapLog.Tracef("Get uplink NAS transport: %+v", uplinkNasTransport)
+	ranUe.SetMobileIdentity5GS(mobileIdentity5GS)
 
-	n, err = g.n2Conn.Write(uplinkNasTransport)
+	amf, err := g.selectAmf(mobileIdentity5GS)
 	if err != nil {
-		return fmt.Errorf("error send uplink nas transport to AMF: %v", err)
+		return fmt.Errorf("error select AMF: %v", err)
 	}
-	g.NgapLog.Tracef("Sent %d bytes of uplink NAS transport to AMF", n)
-	g.NgapLog.Debugln("Send NAS Registration Complete to AMF")
-
-	// wait dispatcher to receive ue configuration update command message from AMF
-
-	g.RanLog.Infof("UE %s initialized", ranUe.GetMobileIdentityIMSI())
-	return nil
-}
-
-func (g *Gnb) processUePduSessionEstablishment(ranUe *RanUe) error {
-	g.NgapLog.Infof("Processing UE %s PDU session establishment", ranUe.GetMobileIdentityIMSI())
+	ranUe.SetAmf(amf)
+	g.NgapLog.Debugf("Selected AMF %s:%d for UE %s", amf.GetAmfN2Ip(), amf.GetAmfN2Port(), ranUe.GetMobileIdentityIMSI())
 
-	// receive pdu session establishment request from UE and send to AMF
-	pduSessionEstablishmentRequest := make([]byte, 1024)
-	n, err := ranUe.GetN1Conn().Read(pduSessionEstablishmentRequest)
-	if err != nil {
-		return fmt.Errorf("error receive pdu session establishment request from UE: %v", err)
+	rrcEstablishmentCause := ngapType.RRCEstablishmentCausePresentMoSignalling
+	switch serviceRequest.GetServiceTypeValue() {
+	case nasMessage.ServiceTypeData:
+		rrcEstablishmentCause = ngapType.RRCEstablishmentCausePresentMoData
+	case nasMessage.ServiceTypeMobileTerminatedServices:
+		rrcEstablishmentCause = ngapType.RRCEstablishmentCausePresentMtAccess
 	}
-	g.NasLog.Tracef("Received %d bytes of PDU Session Establishment Request from UE", n)
-	g.NasLog.Debugln("Receive PDU Session Establishment Request from UE")
 
-	uplinkNasTransport, err := getUplinkNasTransport(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai, pduSessionEstablishmentRequest[:n])
+	ueInitialMessage, err := getInitialUeMessage(ranUe.GetRanUeId(), ueServiceRequest, g.plmnId, g.tai, rrcEstablishmentCause, &fiveGSTmsi)
 	if err != nil {
-		return fmt.Errorf("error get uplink nas transport: %v", err)
+		return fmt.Errorf("error get initial ue message: %v", err)
 	}
-	g.NgapLog.Tracef("Get uplink NAS transport: %+v", uplinkNasTransport)
+	g.NgapLog.Tracef("Get initial UE message: %+v", ueInitialMessage)
 
-	n, err = g.n2Conn.Write(uplinkNasTransport)
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), ueInitialMessage)
 	if err != nil {
-		return fmt.Errorf("error send uplink nas transport to AMF: %v", err)
+		return fmt.Errorf("error send initial ue message to AMF: %v", err)
 	}
-	g.NgapLog.Tracef("Sent %d bytes of uplink NAS transport to AMF", n)
-	g.NgapLog.Debugln("Send PDU Session Establishment Request to AMF")
+	g.NgapLog.Tracef("Sent %d bytes of initial UE message to AMF", n)
+	g.NgapLog.Debugln("Sent initial UE message to AMF")
 
-	// wait dispatcher to receive ngap pdu session resource setup request from AMF
+	// wait dispatcher to receive ngap initial context setup request with the service accept from AMF
 
-	<-ranUe.GetPduSessionEstablishmentCompleteChan()
-	g.NgapLog.Infof("UE %s PDU session establishment completed", ranUe.GetMobileIdentityIMSI())
+	g.RanLog.Infof("UE %s service request sent", ranUe.GetMobileIdentityIMSI())
 	return nil
 }
 
 func (g *Gnb) processUePduSessionModifyIndication(ranUe *RanUe) error {
 	g.NgapLog.Infoln("Processing UE PDU Session Modify Indication")
 
-	pduSessionModifyIndicationTransfer, err := getPDUSessionResourceModifyIndicationTransfer(ranUe.GetDlTeid(), g.ranN3Ip, 1)
+	// only the default pdu session is dual connected
+	pduSession := ranUe.GetDefaultPduSession()
+	if pduSession == nil {
+		return fmt.Errorf("error default pdu session of UE %s not found", ranUe.GetMobileIdentityIMSI())
+	}
+
+	pduSessionModifyIndicationTransfer, err := getPDUSessionResourceModifyIndicationTransfer(pduSession.GetDlTeid(), g.ranN3Ip, 1)
 	if err != nil {
 		return fmt.Errorf("error get pdu session modify indication transfer: %v", err)
 	}
 	g.NgapLog.Tracef("Get pdu session modify indication transfer: %+v", pduSessionModifyIndicationTransfer)
 
 	// send ngap pdu session resource modify indication to AMF
-	pduSessionModifyIndication, err := getPDUSessionResourceModifyIndication(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), constant.PDU_SESSION_ID, pduSessionModifyIndicationTransfer)
+	pduSessionModifyIndication, err := getPDUSessionResourceModifyIndication(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), pduSession.GetPduSessionId(), pduSessionModifyIndicationTransfer)
 	if err != nil {
 		return fmt.Errorf("error get pdu session modify indication: %v", err)
 	}
@@ -858,7 +1054,7 @@ func (g *Gnb) processUePduSessionModifyIndication(ranUe *RanUe) error {
 	}
 	g.XnLog.Tracef("Get pdu session modify indication: %+v", pduSessionModifyIndication)
 
-	n, err := g.n2Conn.Write(pduSessionModifyIndication)
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), pduSessionModifyIndication)
 	if err != nil {
 		return fmt.Errorf("error send pdu session modify indication to AMF: %v", err)
 	}
@@ -872,35 +1068,498 @@ func (g *Gnb) processUePduSessionModifyIndication(ranUe *RanUe) error {
 	return nil
 }
 
+// forward uplink NAS messages until the AMF releases the UE context
 func (g *Gnb) processUeDeRegistration(ranUe *RanUe) error {
 	g.RanLog.Infoln("Waiting for UE to deregister")
 
-	// receive ue deregistration request from UE and send to AMF
-	ueDeRegistrationRequest := make([]byte, 1024)
-	n, err := ranUe.GetN1Conn().Read(ueDeRegistrationRequest)
+	n1ErrChan := make(chan error, 1)
+	go func() {
+		for {
+			messageType, uplinkNasPdu, err := ranUe.GetN1Conn().ReadMessage()
+			if err != nil {
+				n1ErrChan <- err
+				return
+			}
+			if messageType != constant.RAN_UE_MESSAGE_TYPE_NAS {
+				g.RanLog.Warnf("Unexpected message type from UE %s: %d", ranUe.GetMobileIdentityIMSI(), messageType)
+				continue
+			}
+			g.NasLog.Tracef("Received %d bytes of uplink NAS message from UE", len(uplinkNasPdu))
+
+			uplinkNasTransport, err := getUplinkNasTransport(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai, uplinkNasPdu)
+			if err != nil {
+				g.NgapLog.Errorf("Error get uplink nas transport: %v", err)
+				continue
+			}
+			g.NgapLog.Tracef("Get uplink NAS transport: %+v", uplinkNasTransport)
+
+			n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), uplinkNasTransport)
+			if err != nil {
+				g.NgapLog.Errorf("Error send uplink nas transport to AMF: %v", err)
+				continue
+			}
+			g.NgapLog.Tracef("Sent %d bytes of uplink NAS transport to AMF", n)
+			g.NgapLog.Debugf("Send uplink NAS message of UE %s to AMF", ranUe.GetMobileIdentityIMSI())
+		}
+	}()
+
+	// a nil channel disables the inactivity check
+	var inactivityCheckChan <-chan time.Time
+	if g.ueInactivityTimer > 0 {
+		inactivityCheckTicker := time.NewTicker(constant.UE_INACTIVITY_CHECK_INTERVAL)
+		defer inactivityCheckTicker.Stop()
+		inactivityCheckChan = inactivityCheckTicker.C
+	}
+
+	// wait dispatcher to receive ue context release command from AMF
+	for {
+		select {
+		case <-ranUe.GetUeContextReleaseCompleteChan():
+			g.RanLog.Infoln("UE deregistration complete")
+			return nil
+		case err := <-n1ErrChan:
+			// give the AMF time to release the UE context, or the target gNB time to switch the path
+			radioLinkFailureTimer := constant.UE_RADIO_LINK_FAILURE_TIMER
+			if ranUe.IsHandoverOngoing() {
+				radioLinkFailureTimer = constant.HANDOVER_TIMEOUT
+			}
+			select {
+			case <-ranUe.GetUeContextReleaseCompleteChan():
+				g.RanLog.Infoln("UE deregistration complete")
+				return nil
+			case <-time.After(radioLinkFailureTimer):
+			}
+
+			g.RanLog.Warnf("UE %s N1 connection lost: %v", ranUe.GetMobileIdentityIMSI(), err)
+			if err := g.processUeContextReleaseRequest(ranUe, ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost); err != nil {
+				return fmt.Errorf("error process ue context release request: %v", err)
+			}
+			return nil
+		case <-inactivityCheckChan:
+			if time.Since(ranUe.GetLastActivityTime()) < g.ueInactivityTimer {
+				continue
+			}
+
+			g.RanLog.Infof("UE %s user plane inactive for %v", ranUe.GetMobileIdentityIMSI(), g.ueInactivityTimer)
+			if err := g.processUeContextReleaseRequest(ranUe, ngapType.CauseRadioNetworkPresentUserInactivity); err != nil {
+				return fmt.Errorf("error process ue context release request: %v", err)
+			}
+
+			// the N1 connection is closed afterwards by handleRanConnection
+			n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_CONNECTION_RELEASE, nil)
+			if err != nil {
+				return fmt.Errorf("error send connection release to UE: %v", err)
+			}
+			g.RanLog.Tracef("Sent %d bytes of connection release to UE", n)
+			g.RanLog.Infof("UE %s moved to CM-IDLE", ranUe.GetMobileIdentityIMSI())
+			return nil
+		}
+	}
+}
+
+// the ue context release command is completed by the dispatcher
+func (g *Gnb) processUeContextReleaseRequest(ranUe *RanUe, radioNetworkCause aper.Enumerated) error {
+	g.NgapLog.Infof("Processing UE %s context release request", ranUe.GetMobileIdentityIMSI())
+
+	pduSessionIdList := []int64{}
+	for _, pduSession := range ranUe.GetPduSessionList() {
+		pduSessionIdList = append(pduSessionIdList, pduSession.GetPduSessionId())
+	}
+
+	ueContextReleaseRequest, err := getUeContextReleaseRequest(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), pduSessionIdList, ngapType.Cause{
+		Present:      ngapType.CausePresentRadioNetwork,
+		RadioNetwork: &ngapType.CauseRadioNetwork{Value: radioNetworkCause},
+	})
+	if err != nil {
+		return fmt.Errorf("error get ue context release request: %v", err)
+	}
+	g.NgapLog.Tracef("Get ue context release request: %+v", ueContextReleaseRequest)
+
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), ueContextReleaseRequest)
+	if err != nil {
+		return fmt.Errorf("error send ue context release request to AMF: %v", err)
+	}
+	g.NgapLog.Tracef("Sent %d bytes of ue context release request to AMF", n)
+	g.NgapLog.Debugln("Send UE Context Release Request to AMF")
+
+	// wait dispatcher to receive ue context release command from AMF
+	select {
+	case <-ranUe.GetUeContextReleaseCompleteChan():
+	case <-time.After(constant.UE_CONTEXT_RELEASE_TIMEOUT):
+		return fmt.Errorf("error wait for ue context release command: timeout after %v", constant.UE_CONTEXT_RELEASE_TIMEOUT)
+	}
+
+	g.NgapLog.Infof("UE %s context released", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
+
+// the RAN UE is released once the target gNB has switched the path
+func (g *Gnb) processUeXnHandover(ranUe *RanUe) error {
+	g.XnLog.Infof("Processing UE %s Xn handover", ranUe.GetMobileIdentityIMSI())
+
+	if !g.xnInterface.enable {
+		return fmt.Errorf("error xn interface is not enabled")
+	}
+	if ranUe.GetAmf() == nil || ranUe.GetAmfUeId() == -1 {
+		return fmt.Errorf("error UE %s is not registered", ranUe.GetMobileIdentityIMSI())
+	}
+	if ranUe.IsNrdcActivated() {
+		return fmt.Errorf("error UE %s is dual connected", ranUe.GetMobileIdentityIMSI())
+	}
+	if ranUe.IsHandoverOngoing() {
+		return fmt.Errorf("error UE %s handover is ongoing", ranUe.GetMobileIdentityIMSI())
+	}
+
+	pduSessionIdList, snssaiList, handoverRequestTransferList := []int64{}, []ngapType.SNSSAI{}, [][]byte{}
+	for _, pduSession := range ranUe.GetPduSessionList() {
+		qosFlowIdList := pduSession.GetQosFlowIdList()
+		fiveQiList := make([]int64, 0, len(qosFlowIdList))
+		for _, qosFlowId := range qosFlowIdList {
+			fiveQi, _ := pduSession.GetQosFlow(qosFlowId)
+			fiveQiList = append(fiveQiList, fiveQi)
+		}
+
+		handoverRequestTransfer, err := getHandoverRequestTransfer(pduSession.GetUlTeid(), g.upfN3Ip, qosFlowIdList, fiveQiList)
+		if err != nil {
+			return fmt.Errorf("error get handover request transfer: %v", err)
+		}
+		g.XnLog.Tracef("Get handover request transfer of PDU session %d: %+v", pduSession.GetPduSessionId(), handoverRequestTransfer)
+
+		pduSessionIdList = append(pduSessionIdList, pduSession.GetPduSessionId())
+		snssaiList = append(snssaiList, pduSession.GetSnssai())
+		handoverRequestTransferList = append(handoverRequestTransferList, handoverRequestTransfer)
+	}
+
+	handoverRequest, err := getHandoverRequest(ranUe.GetAmfUeId(), ranUe.GetUeSecurityCapabilities(), ranUe.GetGuami(), pduSessionIdList, snssaiList, handoverRequestTransferList)
+	if err != nil {
+		return fmt.Errorf("error get handover request: %v", err)
+	}
+	g.XnLog.Tracef("Get handover request: %+v", handoverRequest)
+
+	handoverCommand, err := g.xnHandoverRequest(ranUe.GetMobileIdentityIMSI(), handoverRequest)
+	if err != nil {
+		return fmt.Errorf("error xn handover request: %v", err)
+	}
+
+	// the N1 connection loss is expected, wait for the target gNB
+	ranUe.SetHandoverOngoing(true)
+
+	n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_HANDOVER_COMMAND, handoverCommand)
+	if err != nil {
+		ranUe.SetHandoverOngoing(false)
+		return fmt.Errorf("error send handover command to UE: %v", err)
+	}
+	g.RanLog.Tracef("Sent %d bytes of handover command to UE", n)
+
+	g.XnLog.Infof("UE %s handover command sent", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
+
+// the source gNB releases the UE after the path switch
+func (g *Gnb) processUeXnHandoverCompletion(ranUe *RanUe) error {
+	g.XnLog.Infof("Processing UE %s Xn handover completion", ranUe.GetMobileIdentityIMSI())
+
+	value, exists := g.xnHandoverContexts.LoadAndDelete(ranUe.GetMobileIdentityIMSI())
+	if !exists {
+		return fmt.Errorf("error handover context of UE %s not found", ranUe.GetMobileIdentityIMSI())
+	}
+	handoverContext := value.(*xnHandoverContext)
+
+	ranUe.SetAmf(handoverContext.amf)
+	ranUe.SetAmfUeId(handoverContext.sourceAmfUeNgapId)
+	ranUe.SetUeSecurityCapabilities(handoverContext.ueSecurityCapabilities)
+	ranUe.SetGuami(handoverContext.guami)
+
+	pduSessionIdList, pathSwitchRequestTransferList := []int64{}, [][]byte{}
+	for _, pduSessionItem := range handoverContext.pduSessionList {
+		var handoverRequestTransfer ngapType.PDUSessionResourceSetupRequestTransfer
+		if err := aper.UnmarshalWithParams(pduSessionItem.HandoverRequestTransfer, &handoverRequestTransfer, "valueExt"); err != nil {
+			return fmt.Errorf("error unmarshal handover request transfer: %v", err)
+		}
+		g.XnLog.Tracef("Get handover request transfer of PDU session %d: %+v", pduSessionItem.PDUSessionID.Value, handoverRequestTransfer)
+
+		pduSession := g.addUePduSessionResource(ranUe, pduSessionItem.PDUSessionID.Value, pduSessionItem.SNSSAI, &handoverRequestTransfer)
+
+		pathSwitchRequestTransfer, err := getPathSwitchRequestTransfer(pduSession.GetDlTeid(), g.ranN3Ip, pduSession.GetQosFlowIdList())
+		if err != nil {
+			return fmt.Errorf("error get path switch request transfer: %v", err)
+		}
+		g.NgapLog.Tracef("Get path switch request transfer of PDU session %d: %+v", pduSession.GetPduSessionId(), pathSwitchRequestTransfer)
+
+		pduSessionIdList = append(pduSessionIdList, pduSession.GetPduSessionId())
+		pathSwitchRequestTransferList = append(pathSwitchRequestTransferList, pathSwitchRequestTransfer)
+	}
+
+	pathSwitchRequest, err := getPathSwitchRequest(ranUe.GetRanUeId(), ranUe.GetAmfUeId(), g.plmnId, g.tai, ranUe.GetUeSecurityCapabilities(), pduSessionIdList, pathSwitchRequestTransferList)
+	if err != nil {
+		return fmt.Errorf("error get path switch request: %v", err)
+	}
+	g.NgapLog.Tracef("Get path switch request: %+v", pathSwitchRequest)
+
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), pathSwitchRequest)
+	if err != nil {
+		return fmt.Errorf("error send path switch request to AMF: %v", err)
+	}
+	g.NgapLog.Tracef("Sent %d bytes of path switch request to AMF", n)
+	g.NgapLog.Debugln("Send Path Switch Request to AMF")
+
+	// wait dispatcher to receive path switch request acknowledge from AMF
+	select {
+	case <-ranUe.GetPathSwitchRequestAcknowledgeChan():
+	case <-time.After(constant.HANDOVER_TIMEOUT):
+		return fmt.Errorf("error wait for path switch request acknowledge: timeout after %v", constant.HANDOVER_TIMEOUT)
+	}
+
+	ueContextReleaseCommand, err := getUeContextReleaseCommand(ranUe.GetAmfUeId(), ngapType.Cause{
+		Present:      ngapType.CausePresentRadioNetwork,
+		RadioNetwork: &ngapType.CauseRadioNetwork{Value: ngapType.CauseRadioNetworkPresentSuccessfulHandover},
+	})
+	if err != nil {
+		return fmt.Errorf("error get ue context release command: %v", err)
+	}
+	g.XnLog.Tracef("Get ue context release command: %+v", ueContextReleaseCommand)
+
+	if err := g.xnUeContextReleaseCommand(ranUe.GetMobileIdentityIMSI(), ueContextReleaseCommand); err != nil {
+		// the source gNB releases the UE on the N1 connection loss
+		g.XnLog.Warnf("Error xn ue context release command: %v", err)
+	}
+
+	g.XnLog.Infof("UE %s Xn handover completed", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
+
+// the source gNB forwards DL data until the AMF releases the UE context
+func (g *Gnb) processUeN2Handover(ranUe *RanUe, targetGnbId []byte, targetTai ngapType.TAI) error {
+	g.NgapLog.Infof("Processing UE %s N2 handover", ranUe.GetMobileIdentityIMSI())
+
+	if ranUe.GetAmf() == nil || ranUe.GetAmfUeId() == -1 {
+		return fmt.Errorf("error UE %s is not registered", ranUe.GetMobileIdentityIMSI())
+	}
+	if ranUe.IsNrdcActivated() {
+		return fmt.Errorf("error UE %s is dual connected", ranUe.GetMobileIdentityIMSI())
+	}
+	if ranUe.IsHandoverOngoing() {
+		return fmt.Errorf("error UE %s handover is ongoing", ranUe.GetMobileIdentityIMSI())
+	}
+
+	pduSessionIdList, handoverRequiredTransferList := []int64{}, [][]byte{}
+	for _, pduSession := range ranUe.GetPduSessionList() {
+		handoverRequiredTransfer, err := getHandoverRequiredTransfer()
+		if err != nil {
+			return fmt.Errorf("error get handover required transfer: %v", err)
+		}
+		g.NgapLog.Tracef("Get handover required transfer of PDU session %d: %+v", pduSession.GetPduSessionId(), handoverRequiredTransfer)
+
+		pduSessionIdList = append(pduSessionIdList, pduSession.GetPduSessionId())
+		handoverRequiredTransferList = append(handoverRequiredTransferList, handoverRequiredTransfer)
+	}
+	if len(pduSessionIdList) == 0 {
+		return fmt.Errorf("error UE %s has no pdu session to hand over", ranUe.GetMobileIdentityIMSI())
+	}
+
+	sourceToTargetTransparentContainer, err := getSourceToTargetTransparentContainer(ranUe.GetMobileIdentityIMSI(), g.plmnId, g.gnbId, targetTai, targetGnbId)
+	if err != nil {
+		return fmt.Errorf("error get source to target transparent container: %v", err)
+	}
+	g.NgapLog.Tracef("Get source to target transparent container: %+v", sourceToTargetTransparentContainer)
+
+	handoverRequired, err := getHandoverRequired(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), targetGnbId, targetTai, pduSessionIdList, handoverRequiredTransferList, sourceToTargetTransparentContainer)
+	if err != nil {
+		return fmt.Errorf("error get handover required: %v", err)
+	}
+	g.NgapLog.Tracef("Get handover required: %+v", handoverRequired)
+
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), handoverRequired)
+	if err != nil {
+		return fmt.Errorf("error send handover required to AMF: %v", err)
+	}
+	g.NgapLog.Tracef("Sent %d bytes of handover required to AMF", n)
+	g.NgapLog.Debugln("Send Handover Required to AMF")
+
+	// wait dispatcher to receive handover command or handover preparation failure from AMF
+	select {
+	case err := <-ranUe.GetHandoverPreparationResultChan():
+		if err != nil {
+			return fmt.Errorf("error handover preparation: %v", err)
+		}
+	case <-time.After(constant.HANDOVER_TIMEOUT):
+		if err := g.sendHandoverCancel(ranUe, ngapType.CauseRadioNetworkPresentTngrelocprepExpiry); err != nil {
+			g.NgapLog.Warnf("Error send handover cancel: %v", err)
+		}
+		return fmt.Errorf("error wait for handover command: timeout after %v", constant.HANDOVER_TIMEOUT)
+	}
+
+	g.NgapLog.Infof("UE %s handover command sent", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
+
+func (g *Gnb) sendHandoverCancel(ranUe *RanUe, radioNetworkCause aper.Enumerated) error {
+	handoverCancel, err := getHandoverCancel(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), ngapType.Cause{
+		Present:      ngapType.CausePresentRadioNetwork,
+		RadioNetwork: &ngapType.CauseRadioNetwork{Value: radioNetworkCause},
+	})
 	if err != nil {
-		return fmt.Errorf("error reading from UE connection: %v", err)
+		return fmt.Errorf("error get handover cancel: %v", err)
 	}
-	g.RanLog.Tracef("Received %d bytes of UE deregistration request from UE: %+v", n, ueDeRegistrationRequest[:n])
-	g.RanLog.Tracef("Received %d bytes of UE deregistration request from UE", n)
+	g.NgapLog.Tracef("Get handover cancel: %+v", handoverCancel)
 
-	uplinkNasTransport, err := getUplinkNasTransport(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai, ueDeRegistrationRequest[:n])
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), handoverCancel)
 	if err != nil {
-		return fmt.Errorf("error get uplink nas transport: %v", err)
+		return fmt.Errorf("error send handover cancel to AMF: %v", err)
+	}
+	g.NgapLog.Tracef("Sent %d bytes of handover cancel to AMF", n)
+	g.NgapLog.Debugln("Send Handover Cancel to AMF")
+	return nil
+}
+
+// the connected UE adopts the RAN UE NGAP ID the AMF already knows
+func (g *Gnb) processUeN2HandoverCompletion(ranUe *RanUe) error {
+	g.NgapLog.Infof("Processing UE %s N2 handover completion", ranUe.GetMobileIdentityIMSI())
+
+	value, exists := g.n2HandoverRanUes.LoadAndDelete(ranUe.GetMobileIdentityIMSI())
+	if !exists {
+		return fmt.Errorf("error prepared UE context of UE %s not found", ranUe.GetMobileIdentityIMSI())
+	}
+	preparedRanUe := value.(*RanUe)
+
+	g.ranUeConns.Delete(ranUe.GetRanUeId())
+	g.ranUeNgapIdGenerator.ReleaseRanUeId(ranUe.GetRanUeId())
+	ranUe.SetRanUeId(preparedRanUe.GetRanUeId())
+	g.ranUeConns.Store(ranUe.GetRanUeId(), ranUe)
+
+	ranUe.SetAmf(preparedRanUe.GetAmf())
+	ranUe.SetAmfUeId(preparedRanUe.GetAmfUeId())
+	ranUe.SetUeSecurityCapabilities(preparedRanUe.GetUeSecurityCapabilities())
+	ranUe.SetGuami(preparedRanUe.GetGuami())
+	for _, pduSession := range preparedRanUe.GetPduSessionList() {
+		ranUe.AddPduSession(pduSession)
 	}
-	g.NgapLog.Tracef("Get uplink NAS transport: %+v", uplinkNasTransport)
 
-	n, err = g.n2Conn.Write(uplinkNasTransport)
+	handoverNotify, err := getHandoverNotify(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), g.plmnId, g.tai)
 	if err != nil {
-		return fmt.Errorf("error send uplink nas transport to AMF: %v", err)
+		return fmt.Errorf("error get handover notify: %v", err)
 	}
-	g.NgapLog.Tracef("Sent %d bytes of uplink NAS transport to AMF", n)
-	g.NgapLog.Debugln("Send UE deregistration request to AMF")
+	g.NgapLog.Tracef("Get handover notify: %+v", handoverNotify)
 
-	// wait dispatcher to receive ue deregistration accept from AMF
+	n, err := ranUe.GetAmf().WriteUeAssociated(ranUe.GetRanUeId(), handoverNotify)
+	if err != nil {
+		return fmt.Errorf("error send handover notify to AMF: %v", err)
+	}
+	g.NgapLog.Tracef("Sent %d bytes of handover notify to AMF", n)
+	g.NgapLog.Debugln("Send Handover Notify to AMF")
+
+	g.NgapLog.Infof("UE %s N2 handover completed", ranUe.GetMobileIdentityIMSI())
+	return nil
+}
+
+// the prepared UE context has no UE connection to release it
+func (g *Gnb) releaseN2HandoverRanUe(ranUe *RanUe) {
+	for _, pduSession := range ranUe.GetPduSessionList() {
+		g.releaseUePduSessionResource(ranUe, pduSession)
+	}
+	ranUe.Release(g.ranUeNgapIdGenerator)
+}
+
+func (g *Gnb) findN2HandoverRanUe(ranUeNgapId int64) *RanUe {
+	var ranUe *RanUe
+	g.n2HandoverRanUes.Range(func(key, value any) bool {
+		if value.(*RanUe).GetRanUeId() == ranUeNgapId {
+			ranUe = value.(*RanUe)
+			return false
+		}
+		return true
+	})
+	return ranUe
+}
+
+// sent on every AMF association serving one of the UEs, or on all of them without UEs
+func (g *Gnb) processGnbNgReset(imsiList []string) error {
+	g.NgapLog.Infoln("Processing gNB NG Reset")
+
+	released := 0
+	for _, amf := range g.amfList {
+		if !amf.IsReady() {
+			continue
+		}
+
+		ranUeList := []*RanUe{}
+		g.ranUeConns.Range(func(key, value any) bool {
+			ranUe := value.(*RanUe)
+			if ranUe.GetAmf() != amf {
+				return true
+			}
+			if len(imsiList) == 0 || slices.Contains(imsiList, ranUe.GetMobileIdentityIMSI()) {
+				ranUeList = append(ranUeList, ranUe)
+			}
+			return true
+		})
+
+		var partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList
+		if len(imsiList) != 0 {
+			if len(ranUeList) == 0 {
+				continue
+			}
+			partOfNgInterface = new(ngapType.UEAssociatedLogicalNGConnectionList)
+			for _, ranUe := range ranUeList {
+				item := ngapType.UEAssociatedLogicalNGConnectionItem{
+					RANUENGAPID: &ngapType.RANUENGAPID{Value: ranUe.GetRanUeId()},
+				}
+				if ranUe.GetAmfUeId() != -1 {
+					item.AMFUENGAPID = &ngapType.AMFUENGAPID{Value: ranUe.GetAmfUeId()}
+				}
+				partOfNgInterface.List = append(partOfNgInterface.List, item)
+			}
+		}
+
+		if err := g.sendNgReset(amf, partOfNgInterface, ranUeList); err != nil {
+			return fmt.Errorf("error ng reset with AMF %s:%d: %v", amf.GetAmfN2Ip(), amf.GetAmfN2Port(), err)
+		}
+		released += len(ranUeList)
+	}
+
+	if len(imsiList) != 0 && released == 0 {
+		return fmt.Errorf("error no UE found for NG reset: %v", imsiList)
+	}
+
+	g.NgapLog.Infof("NG reset completed, released %d UE(s)", released)
+	return nil
+}
+
+func (g *Gnb) sendNgReset(amf *Amf, partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList, ranUeList []*RanUe) error {
+	ngReset, err := getNgReset(ngapType.Cause{
+		Present: ngapType.CausePresentMisc,
+		Misc:    &ngapType.CauseMisc{Value: ngapType.CauseMiscPresentOmIntervention},
+	}, partOfNgInterface)
+	if err != nil {
+		return fmt.Errorf("error get ng reset: %v", err)
+	}
+	g.NgapLog.Tracef("Get NG reset: %+v", ngReset)
+
+	// drop a stale acknowledge left over from a previous reset
+	select {
+	case <-amf.GetNgResetAcknowledgeChan():
+	default:
+	}
+
+	n, err := amf.WriteNonUeAssociated(ngReset)
+	if err != nil {
+		return fmt.Errorf("error send ng reset to AMF: %v", err)
+	}
+	g.NgapLog.Tracef("Sent %d bytes of NG reset to AMF", n)
+	g.NgapLog.Debugln("Send NG reset to AMF")
+
+	for _, ranUe := range ranUeList {
+		g.releaseRanUe(ranUe)
+	}
+
+	// wait dispatcher to receive ng reset acknowledge from AMF
+
+	select {
+	case <-amf.GetNgResetAcknowledgeChan():
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("error wait ng reset acknowledge from AMF: timeout")
+	}
 
-	<-ranUe.GetUeContextReleaseCompleteChan()
-	g.RanLog.Infoln("UE deregistration complete")
 	return nil
 }
 
@@ -1050,6 +1709,103 @@ func (g *Gnb) xnPduSessionResourceModifyConfirm(imsi string, ngapPduSessionResou
 	return nil, nil
 }
 
+// forwarded to the UE by the source gNB as it is
+func (g *Gnb) getUeHandoverCommand() []byte {
+	return []byte(fmt.Sprintf("%s %s", net.JoinHostPort(g.ranControlPlaneIp, strconv.Itoa(g.ranControlPlanePort)), net.JoinHostPort(g.ranDataPlaneIp, strconv.Itoa(g.ranDataPlanePort))))
+}
+
+func (g *Gnb) xnHandoverRequest(imsi string, ngapHandoverRequestRaw []byte) ([]byte, error) {
+	g.XnLog.Infoln("Processing XN Handover Request")
+
+	xnConn, err := util.TcpDialWithOptionalLocalAddress(g.xnInterface.xnDialIp, g.xnInterface.xnDialPort, "")
+	if err != nil {
+		return nil, fmt.Errorf("error dial xn: %v", err)
+	}
+	defer func() {
+		if err := xnConn.Close(); err != nil {
+			g.XnLog.Warnf("Error close xn connection: %v", err)
+		}
+	}()
+	g.XnLog.Debugf("Dial XN at %s:%d", g.xnInterface.xnDialIp, g.xnInterface.xnDialPort)
+
+	xnPdu := NewXnPdu(imsi, ngapHandoverRequestRaw)
+	xnPduBytes, err := xnPdu.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("error marshal xn pdu: %v", err)
+	}
+
+	n, err := xnConn.Write(xnPduBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error send ngap handover request to xn: %v", err)
+	}
+	g.XnLog.Tracef("Sent %d bytes of NGAP Handover Request to XN", n)
+	g.XnLog.Debugln("Send NGAP Handover Request to XN")
+
+	if err = xnConn.SetReadDeadline(time.Now().Add(constant.HANDOVER_TIMEOUT)); err != nil {
+		return nil, fmt.Errorf("error set read deadline: %v", err)
+	}
+	buffer := make([]byte, 4096)
+	n, err = xnConn.Read(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("error read ngap handover request acknowledge from xn: %v", err)
+	}
+	g.XnLog.Tracef("Received %d bytes of NGAP Handover Request Acknowledge from XN", n)
+
+	xnPdu = &XnPdu{}
+	if err := xnPdu.Unmarshal(buffer[:n]); err != nil {
+		return nil, fmt.Errorf("error unmarshal xn pdu: %v", err)
+	}
+	g.XnLog.Tracef("Received XN PDU: %+v", xnPdu)
+
+	ngapPdu, err := ngap.Decoder(xnPdu.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decode ngap handover request acknowledge: %v", err)
+	}
+	if ngapPdu.Present != ngapType.NGAPPDUPresentSuccessfulOutcome || ngapPdu.SuccessfulOutcome.ProcedureCode.Value != ngapType.ProcedureCodeHandoverResourceAllocation {
+		return nil, fmt.Errorf("error unexpected ngap pdu from xn: present %d", ngapPdu.Present)
+	}
+	g.XnLog.Debugln("Receive NGAP Handover Request Acknowledge from XN")
+
+	// the target to source transparent container carries the handover command for the UE
+	for _, ie := range ngapPdu.SuccessfulOutcome.Value.HandoverRequestAcknowledge.ProtocolIEs.List {
+		if ie.Id.Value == ngapType.ProtocolIEIDTargetToSourceTransparentContainer {
+			g.XnLog.Infoln("XN Handover Request completed")
+			return ie.Value.TargetToSourceTransparentContainer.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("error target to source transparent container not found")
+}
+
+func (g *Gnb) xnUeContextReleaseCommand(imsi string, ngapUeContextReleaseCommandRaw []byte) error {
+	g.XnLog.Infoln("Processing XN UE Context Release Command")
+
+	xnConn, err := util.TcpDialWithOptionalLocalAddress(g.xnInterface.xnDialIp, g.xnInterface.xnDialPort, "")
+	if err != nil {
+		return fmt.Errorf("error dial xn: %v", err)
+	}
+	g.XnLog.Debugf("Dial XN at %s:%d", g.xnInterface.xnDialIp, g.xnInterface.xnDialPort)
+
+	xnPdu := NewXnPdu(imsi, ngapUeContextReleaseCommandRaw)
+	xnPduBytes, err := xnPdu.Marshal()
+	if err != nil {
+		return fmt.Errorf("error marshal xn pdu: %v", err)
+	}
+
+	n, err := xnConn.Write(xnPduBytes)
+	if err != nil {
+		return fmt.Errorf("error send ngap ue context release command to xn: %v", err)
+	}
+	g.XnLog.Tracef("Sent %d bytes of NGAP UE Context Release Command to XN", n)
+	g.XnLog.Debugln("Send NGAP UE Context Release Command to XN")
+
+	if err := xnConn.Close(); err != nil {
+		return fmt.Errorf("error close xn connection: %v", err)
+	}
+
+	g.XnLog.Infoln("XN UE Context Release Command completed")
+	return nil
+}
+
 func (g *Gnb) startApiServer() {
 	g.ApiLog.Infoln("Starting API server")
 
@@ -1102,6 +1858,24 @@ func (g *Gnb) initApiRoutes() util.Routes {
 			Pattern:     constant.API_GNB_UE_NRDC,
 			HandlerFunc: g.handleConsoleGnbUeNrdcModify,
 		},
+		{
+			Name:        "Console GNB NG Reset",
+			Method:      constant.API_GNB_NG_RESET_METHOD,
+			Pattern:     constant.API_GNB_NG_RESET,
+			HandlerFunc: g.handleConsoleGnbNgReset,
+		},
+		{
+			Name:        "Console GNB UE Xn Handover",
+			Method:      constant.API_GNB_UE_XN_HANDOVER_METHOD,
+			Pattern:     constant.API_GNB_UE_XN_HANDOVER,
+			HandlerFunc: g.handleConsoleGnbUeXnHandover,
+		},
+		{
+			Name:        "Console GNB UE N2 Handover",
+			Method:      constant.API_GNB_UE_N2_HANDOVER_METHOD,
+			Pattern:     constant.API_GNB_UE_N2_HANDOVER,
+			HandlerFunc: g.handleConsoleGnbUeN2Handover,
+		},
 	}
 }
 
@@ -1109,7 +1883,64 @@ func (g *Gnb) handleConsoleGnbInfo(c *gin.Context) {
 	g.ApiLog.Infoln("Handling console get gnb info")
 
 	plmnId := util.PlmnIdToModels(g.plmnId)
-	snssai := util.SNssaiToModels(g.snssai)
+	snssai := util.SNssaiToModels(g.supportedTaList.List[0].BroadcastPLMNList.List[0].TAISliceSupportList.List[0].SNSSAI)
+
+	supportedTaList := make([]consoleModel.SupportedTaInfo, 0, len(g.supportedTaList.List))
+	for _, supportedTaItem := range g.supportedTaList.List {
+		broadcastPlmnList := make([]consoleModel.PlmnSliceInfo, 0, len(supportedTaItem.BroadcastPLMNList.List))
+		for _, broadcastPlmnItem := range supportedTaItem.BroadcastPLMNList.List {
+			broadcastPlmnId := util.PlmnIdToModels(broadcastPlmnItem.PLMNIdentity)
+			snssaiList := make([]consoleModel.SnssaiIE, 0, len(broadcastPlmnItem.TAISliceSupportList.List))
+			for _, sliceSupportItem := range broadcastPlmnItem.TAISliceSupportList.List {
+				sliceSnssai := util.SNssaiToModels(sliceSupportItem.SNSSAI)
+				snssaiList = append(snssaiList, consoleModel.SnssaiIE{
+					Sst: strconv.Itoa(int(sliceSnssai.Sst)),
+					Sd:  sliceSnssai.Sd,
+				})
+			}
+			broadcastPlmnList = append(broadcastPlmnList, consoleModel.PlmnSliceInfo{
+				PlmnId:     broadcastPlmnId.Mcc + broadcastPlmnId.Mnc,
+				SnssaiList: snssaiList,
+			})
+		}
+		supportedTaList = append(supportedTaList, consoleModel.SupportedTaInfo{
+			Tac:               hex.EncodeToString(supportedTaItem.TAC.Value),
+			BroadcastPlmnList: broadcastPlmnList,
+		})
+	}
+
+	amfList := make([]consoleModel.AmfInfo, 0, len(g.amfList))
+	for _, amf := range g.amfList {
+		servedGuamiList := make([]consoleModel.GuamiInfo, 0, len(amf.GetServedGuamiList()))
+		for _, servedGuami := range amf.GetServedGuamiList() {
+			servedGuamiList = append(servedGuamiList, consoleModel.GuamiInfo{
+				PlmnId: servedGuami.PlmnId.Mcc + servedGuami.PlmnId.Mnc,
+				AmfId:  servedGuami.AmfId,
+			})
+		}
+		plmnSupportList := make([]consoleModel.PlmnSliceInfo, 0, len(amf.GetPlmnSupportList()))
+		for _, plmnSupport := range amf.GetPlmnSupportList() {
+			snssaiList := make([]consoleModel.SnssaiIE, 0, len(plmnSupport.SNssaiList))
+			for _, supportedSnssai := range plmnSupport.SNssaiList {
+				snssaiList = append(snssaiList, consoleModel.SnssaiIE{
+					Sst: strconv.Itoa(int(supportedSnssai.Sst)),
+					Sd:  supportedSnssai.Sd,
+				})
+			}
+			plmnSupportList = append(plmnSupportList, consoleModel.PlmnSliceInfo{
+				PlmnId:     plmnSupport.PlmnId.Mcc + plmnSupport.PlmnId.Mnc,
+				SnssaiList: snssaiList,
+			})
+		}
+		amfList = append(amfList, consoleModel.AmfInfo{
+			AmfName:         amf.GetAmfName(),
+			Ip:              amf.GetAmfN2Ip(),
+			Port:            amf.GetAmfN2Port(),
+			Ready:           amf.IsReady(),
+			ServedGuamiList: servedGuamiList,
+			PlmnSupportList: plmnSupportList,
+		})
+	}
 
 	ranUeList := []consoleModel.RanUeInfo{}
 	g.ranUeConns.Range(func(key, value any) bool {
@@ -1143,6 +1974,9 @@ func (g *Gnb) handleConsoleGnbInfo(c *gin.Context) {
 				Sd:  snssai.Sd,
 			},
 
+			SupportedTaList: supportedTaList,
+			AmfList:         amfList,
+
 			RanUeList: ranUeList,
 			XnUeList:  xnUeList,
 		},
@@ -1192,3 +2026,137 @@ func (g *Gnb) handleConsoleGnbUeNrdcModify(c *gin.Context) {
 
 	g.ApiLog.Infof("Console gnb ue %s nrdc control completed", request.Imsi)
 }
+
+func (g *Gnb) handleConsoleGnbNgReset(c *gin.Context) {
+	g.ApiLog.Infoln("Handling console gnb ng reset")
+
+	var request consoleModel.ConsoleGnbNgResetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		g.ApiLog.Warnf("Error bind console gnb ng reset request: %v", err)
+		c.JSON(http.StatusBadRequest, consoleModel.ConsoleGnbNgResetResponse{
+			Message: fmt.Sprintf("Error bind console gnb ng reset request: %v", err),
+		})
+		return
+	}
+
+	if err := g.processGnbNgReset(request.ImsiList); err != nil {
+		g.ApiLog.Errorf("Error process gnb ng reset: %v", err)
+		c.JSON(http.StatusInternalServerError, consoleModel.ConsoleGnbNgResetResponse{
+			Message: fmt.Sprintf("Error process gnb ng reset: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, consoleModel.ConsoleGnbNgResetResponse{
+		Message: "NG reset success",
+	})
+
+	g.ApiLog.Infoln("Console gnb ng reset completed")
+}
+
+func (g *Gnb) handleConsoleGnbUeXnHandover(c *gin.Context) {
+	g.ApiLog.Infoln("Handling console gnb ue xn handover")
+
+	var request consoleModel.ConsoleGnbUeXnHandoverRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		g.ApiLog.Warnf("Error bind console gnb ue xn handover request: %v", err)
+		c.JSON(http.StatusBadRequest, consoleModel.ConsoleGnbUeXnHandoverResponse{
+			Message: fmt.Sprintf("Error bind console gnb ue xn handover request: %v", err),
+		})
+		return
+	}
+
+	var ranUe *RanUe
+	g.ranUeConns.Range(func(key, value any) bool {
+		if value.(*RanUe).GetMobileIdentityIMSI() == request.Imsi {
+			ranUe = value.(*RanUe)
+		}
+		return true
+	})
+
+	if ranUe == nil {
+		g.ApiLog.Warnf("UE %s not found", request.Imsi)
+		c.JSON(http.StatusNotFound, consoleModel.ConsoleGnbUeXnHandoverResponse{
+			Message: fmt.Sprintf("UE %s not found", request.Imsi),
+		})
+		return
+	}
+	if err := g.processUeXnHandover(ranUe); err != nil {
+		g.ApiLog.Errorf("Error process ue xn handover: %v", err)
+		c.JSON(http.StatusInternalServerError, consoleModel.ConsoleGnbUeXnHandoverResponse{
+			Message: fmt.Sprintf("Error process ue xn handover: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, consoleModel.ConsoleGnbUeXnHandoverResponse{
+		Message: fmt.Sprintf("UE %s Xn handover triggered", request.Imsi),
+	})
+
+	g.ApiLog.Infof("Console gnb ue %s xn handover completed", request.Imsi)
+}
+
+func (g *Gnb) handleConsoleGnbUeN2Handover(c *gin.Context) {
+	g.ApiLog.Infoln("Handling console gnb ue n2 handover")
+
+	var request consoleModel.ConsoleGnbUeN2HandoverRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		g.ApiLog.Warnf("Error bind console gnb ue n2 handover request: %v", err)
+		c.JSON(http.StatusBadRequest, consoleModel.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("Error bind console gnb ue n2 handover request: %v", err),
+		})
+		return
+	}
+
+	targetGnbId, err := hex.DecodeString(request.TargetGnbId)
+	if err != nil || len(targetGnbId) < 3 || len(targetGnbId) > 4 {
+		g.ApiLog.Warnf("Invalid target gNB ID: %s", request.TargetGnbId)
+		c.JSON(http.StatusBadRequest, consoleModel.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("Invalid target gNB ID: %s", request.TargetGnbId),
+		})
+		return
+	}
+	targetTac, err := hex.DecodeString(request.TargetTac)
+	if err != nil || len(targetTac) != 3 {
+		g.ApiLog.Warnf("Invalid target TAC: %s", request.TargetTac)
+		c.JSON(http.StatusBadRequest, consoleModel.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("Invalid target TAC: %s", request.TargetTac),
+		})
+		return
+	}
+
+	var ranUe *RanUe
+	g.ranUeConns.Range(func(key, value any) bool {
+		if value.(*RanUe).GetMobileIdentityIMSI() == request.Imsi {
+			ranUe = value.(*RanUe)
+		}
+		return true
+	})
+
+	if ranUe == nil {
+		g.ApiLog.Warnf("UE %s not found", request.Imsi)
+		c.JSON(http.StatusNotFound, consoleModel.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("UE %s not found", request.Imsi),
+		})
+		return
+	}
+
+	// the target gNB serves the same PLMN
+	targetTai := ngapType.TAI{
+		PLMNIdentity: g.tai.PLMNIdentity,
+		TAC:          ngapType.TAC{Value: targetTac},
+	}
+	if err := g.processUeN2Handover(ranUe, targetGnbId, targetTai); err != nil {
+		g.ApiLog.Errorf("Error process ue n2 handover: %v", err)
+		c.JSON(http.StatusInternalServerError, consoleModel.ConsoleGnbUeN2HandoverResponse{
+			Message: fmt.Sprintf("Error process ue n2 handover: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, consoleModel.ConsoleGnbUeN2HandoverResponse{
+		Message: fmt.Sprintf("UE %s N2 handover triggered", request.Imsi),
+	})
+
+	g.ApiLog.Infof("Console gnb ue %s n2 handover completed", request.Imsi)
+}
@@ -95,7 +95,7 @@ func forwardGtpPacketToN3Conn(ctx context.Context, n3Conn *net.UDPConn, gtpChann
 }
 
 // receive GTP packet from N3 connection and forward to UE according to the GTP header's TEID
-func receiveGtpPacketFromN3Conn(ctx context.Context, n3Conn *net.UDPConn, ranDataPlaneServer *net.UDPConn, gnbLogger *logger.GnbLogger, dlTeidToUe *sync.Map) {
+func receiveGtpPacketFromN3Conn(ctx context.Context, n3Conn *net.UDPConn, ranDataPlaneServer *net.UDPConn, gtpChannel chan []byte, gnbLogger *logger.GnbLogger, dlTeidToUe *sync.Map) {
 	buffer := make([]byte, 4096)
 	for {
 		n, err := n3Conn.Read(buffer)
@@ -110,7 +110,7 @@ func receiveGtpPacketFromN3Conn(ctx context.Context, n3Conn *net.UDPConn, ranDat
 
 		tmp := make([]byte, n)
 		copy(tmp, buffer[:n])
-		forwardPacketToUe(tmp, ranDataPlaneServer, dlTeidToUe, gnbLogger)
+		forwardPacketToUe(tmp, ranDataPlaneServer, gtpChannel, dlTeidToUe, gnbLogger)
 	}
 }
 
@@ -132,8 +132,8 @@ func formatGtpPacketAndWriteToGtpChannel(teid aper.OctetString, packet []byte, g
 	gnbLogger.GtpLog.Debugln("Wrote GTP packet to gtpChannel")
 }
 
-// forward packet to UE according to the GTP header's TEID
-func forwardPacketToUe(gtpPacket []byte, ranDataPlaneServer *net.UDPConn, dlTeidToUe *sync.Map, gnbLogger *logger.GnbLogger) {
+// forward packet to UE according to the GTP header's TEID, or back to the UPF when the UE is being handed over
+func forwardPacketToUe(gtpPacket []byte, ranDataPlaneServer *net.UDPConn, gtpChannel chan []byte, dlTeidToUe *sync.Map, gnbLogger *logger.GnbLogger) {
 	teid, payload, err := parseGtpPacket(gtpPacket)
 	if err != nil {
 		gnbLogger.GtpLog.Warnf("Error parsing GTP packet: %v", err)
@@ -148,11 +148,17 @@ func forwardPacketToUe(gtpPacket []byte, ranDataPlaneServer *net.UDPConn, dlTeid
 	}
 
 	switch u := ue.(type) {
-	case *RanUe:
-		gnbLogger.GtpLog.Debugf("Loaded UE %s for DL TEID: %s", u.GetMobileIdentityIMSI(), teid)
+	case *RanUePduSession:
+		gnbLogger.GtpLog.Debugf("Loaded UE %s PDU session %d for DL TEID: %s", u.GetIMSI(), u.GetPduSessionId(), teid)
+		u.UpdateLastActivityTime()
+		if dlForwardingTeid := u.GetDlForwardingTeid(); len(dlForwardingTeid) > 0 {
+			gnbLogger.GtpLog.Debugf("Forwarding GTP packet of RAN UE %s PDU session %d to DL forwarding TEID: %s", u.GetIMSI(), u.GetPduSessionId(), hex.EncodeToString(dlForwardingTeid))
+			formatGtpPacketAndWriteToGtpChannel(dlForwardingTeid, payload, gtpChannel, gnbLogger)
+			return
+		}
 		dataPlaneAddress := u.GetDataPlaneAddress()
 		if dataPlaneAddress == nil {
-			gnbLogger.GtpLog.Warnf("RAN UE %s data plane address not set yet, dropping packet", u.GetMobileIdentityIMSI())
+			gnbLogger.GtpLog.Warnf("RAN UE %s PDU session %d data plane address not set yet, dropping packet", u.GetIMSI(), u.GetPduSessionId())
 			return
 		}
 		n, err := ranDataPlaneServer.WriteToUDP(payload, dataPlaneAddress)
@@ -0,0 +1,249 @@
+package gnb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/free5gc/aper"
+	"github.com/free5gc/nas/nasConvert"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/ngap/ngapType"
+)
+
+type ngSetupFailureError struct {
+	cause      string
+	timeToWait time.Duration
+}
+
+func (e *ngSetupFailureError) Error() string {
+	if e.timeToWait == 0 {
+		return fmt.Sprintf("NG setup failure, cause: %s", e.cause)
+	}
+	return fmt.Sprintf("NG setup failure, cause: %s, time to wait: %v", e.cause, e.timeToWait)
+}
+
+func getNgSetupFailureError(ngSetupFailure *ngapType.NGSetupFailure) *ngSetupFailureError {
+	ngSetupFailureErr := &ngSetupFailureError{
+		cause:      "unknown",
+		timeToWait: 0,
+	}
+	if ngSetupFailure == nil {
+		return ngSetupFailureErr
+	}
+
+	for _, ie := range ngSetupFailure.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDCause:
+			if ie.Value.Cause != nil {
+				ngSetupFailureErr.cause = ngapCauseToString(*ie.Value.Cause)
+			}
+		case ngapType.ProtocolIEIDTimeToWait:
+			if ie.Value.TimeToWait != nil {
+				ngSetupFailureErr.timeToWait = timeToWaitToDuration(*ie.Value.TimeToWait)
+			}
+		case ngapType.ProtocolIEIDCriticalityDiagnostics:
+		}
+	}
+
+	return ngSetupFailureErr
+}
+
+func newRadioNetworkCause(radioNetworkCause aper.Enumerated) ngapType.Cause {
+	return ngapType.Cause{
+		Present:      ngapType.CausePresentRadioNetwork,
+		RadioNetwork: &ngapType.CauseRadioNetwork{Value: radioNetworkCause},
+	}
+}
+
+func ngapCauseToString(cause ngapType.Cause) string {
+	switch cause.Present {
+	case ngapType.CausePresentRadioNetwork:
+		return fmt.Sprintf("radio network %d", cause.RadioNetwork.Value)
+	case ngapType.CausePresentTransport:
+		return fmt.Sprintf("transport %d", cause.Transport.Value)
+	case ngapType.CausePresentNas:
+		return fmt.Sprintf("nas %d", cause.Nas.Value)
+	case ngapType.CausePresentProtocol:
+		return fmt.Sprintf("protocol %d", cause.Protocol.Value)
+	case ngapType.CausePresentMisc:
+		return fmt.Sprintf("misc %d", cause.Misc.Value)
+	default:
+		return "unknown"
+	}
+}
+
+func timeToWaitToDuration(timeToWait ngapType.TimeToWait) time.Duration {
+	switch timeToWait.Value {
+	case ngapType.TimeToWaitPresentV1s:
+		return 1 * time.Second
+	case ngapType.TimeToWaitPresentV2s:
+		return 2 * time.Second
+	case ngapType.TimeToWaitPresentV5s:
+		return 5 * time.Second
+	case ngapType.TimeToWaitPresentV10s:
+		return 10 * time.Second
+	case ngapType.TimeToWaitPresentV20s:
+		return 20 * time.Second
+	case ngapType.TimeToWaitPresentV60s:
+		return 60 * time.Second
+	default:
+		return 0
+	}
+}
+
+// the retry interval is doubled after every failed attempt and capped at N2_SETUP_RETRY_MAX_INTERVAL,
+// a TimeToWait received in NG Setup Failure takes precedence if it is longer
+func getN2SetupRetryInterval(retryInterval time.Duration, err error) (time.Duration, time.Duration) {
+	waitInterval := retryInterval
+
+	var ngSetupFailureErr *ngSetupFailureError
+	if errors.As(err, &ngSetupFailureErr) && ngSetupFailureErr.timeToWait > waitInterval {
+		waitInterval = ngSetupFailureErr.timeToWait
+	}
+
+	nextRetryInterval := retryInterval * 2
+	if nextRetryInterval > constant.N2_SETUP_RETRY_MAX_INTERVAL {
+		nextRetryInterval = constant.N2_SETUP_RETRY_MAX_INTERVAL
+	}
+
+	return waitInterval, nextRetryInterval
+}
+
+func isN2AssociationLost(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ENOTCONN) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// block until the first AMF association is set up, the others keep retrying in the background
+func (g *Gnb) establishAllN2(ctx context.Context) error {
+	if len(g.amfList) == 0 {
+		return fmt.Errorf("error establishing N2: no AMF configured")
+	}
+
+	readyChan := make(chan struct{}, len(g.amfList))
+	for _, amf := range g.amfList {
+		go func(amf *Amf) {
+			if err := g.establishN2(ctx, amf); err != nil {
+				g.NgapLog.Warnf("Error establishing N2 with AMF %s:%d: %v", amf.GetAmfN2Ip(), amf.GetAmfN2Port(), err)
+				return
+			}
+			go g.ngapDispatcher.start(ctx, g, amf)
+			readyChan <- struct{}{}
+		}(amf)
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("error establishing N2: %v", ctx.Err())
+	case <-readyChan:
+		return nil
+	}
+}
+
+func (g *Gnb) establishN2(ctx context.Context, amf *Amf) error {
+	retryInterval := constant.N2_SETUP_RETRY_INITIAL_INTERVAL
+
+	for {
+		err := g.connectToAmf(amf)
+		if err == nil {
+			if err = g.setupN2(amf); err == nil {
+				amf.SetReady(true)
+				return nil
+			}
+			if err := amf.GetN2Conn().Close(); err != nil {
+				g.SctpLog.Warnf("Error closing N2 connection: %v", err)
+			}
+		}
+
+		var waitInterval time.Duration
+		waitInterval, retryInterval = getN2SetupRetryInterval(retryInterval, err)
+		g.NgapLog.Warnf("Error establishing N2 with AMF %s:%d: %v, retry in %v", amf.GetAmfN2Ip(), amf.GetAmfN2Port(), err, waitInterval)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error establishing N2: %v", ctx.Err())
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+func (g *Gnb) reestablishN2(ctx context.Context, amf *Amf) error {
+	g.SctpLog.Warnf("N2 association with AMF %s:%d lost, re-establishing N2", amf.GetAmfN2Ip(), amf.GetAmfN2Port())
+
+	amf.SetReady(false)
+	if err := amf.GetN2Conn().Close(); err != nil {
+		g.SctpLog.Debugf("Error closing lost N2 connection: %v", err)
+	}
+
+	// the AMF has lost the UE contexts together with the association, release the RAN UEs as well
+	g.ranUeConns.Range(func(key, value any) bool {
+		if ranUe := value.(*RanUe); ranUe.GetAmf() == amf {
+			g.releaseRanUe(ranUe)
+		}
+		return true
+	})
+
+	if err := g.establishN2(ctx, amf); err != nil {
+		return err
+	}
+
+	g.SctpLog.Infof("N2 with AMF %s:%d re-established", amf.GetAmfN2Ip(), amf.GetAmfN2Port())
+	return nil
+}
+
+func (g *Gnb) closeAllN2() {
+	for _, amf := range g.amfList {
+		amf.SetReady(false)
+		if amf.GetN2Conn() == nil {
+			continue
+		}
+		if err := amf.GetN2Conn().Close(); err != nil {
+			g.SctpLog.Errorf("Error closing N2 connection: %v", err)
+			continue
+		}
+		g.SctpLog.Tracef("N2 connection to AMF %s:%d closed", amf.GetAmfN2Ip(), amf.GetAmfN2Port())
+	}
+}
+
+// a UE registering with a 5G-GUTI goes back to the AMF (set) that allocated it, other UEs are spread by round-robin
+func (g *Gnb) selectAmf(mobileIdentity5GS nasType.MobileIdentity5GS) (*Amf, error) {
+	readyAmfList := make([]*Amf, 0, len(g.amfList))
+	for _, amf := range g.amfList {
+		if amf.IsReady() {
+			readyAmfList = append(readyAmfList, amf)
+		}
+	}
+	if len(readyAmfList) == 0 {
+		return nil, fmt.Errorf("no AMF available")
+	}
+
+	if mobileIdentity5GS.Len > 0 && nasConvert.GetTypeOfIdentity(mobileIdentity5GS.Buffer[0]) == nasMessage.MobileIdentity5GSType5gGuti {
+		if guami, _, err := nasConvert.GutiToStringWithError(mobileIdentity5GS.GetMobileIdentity5GSContents()); err == nil {
+			if amf := selectAmfByGuami(readyAmfList, guami); amf != nil {
+				return amf, nil
+			}
+		}
+	}
+
+	// 5G-S-TMSI of a UE coming back from CM-IDLE: type octet, AMF Set ID (10 bits), AMF Pointer (6 bits) and 5G-TMSI
+	if mobileIdentity5GS.Len >= 3 && nasConvert.GetTypeOfIdentity(mobileIdentity5GS.Buffer[0]) == nasMessage.MobileIdentity5GSType5gSTmsi {
+		amfSetId := uint16(mobileIdentity5GS.Buffer[1])<<2 | uint16(mobileIdentity5GS.Buffer[2])>>6
+		amfPointer := mobileIdentity5GS.Buffer[2] & 0x3f
+		if amf := selectAmfByAmfSetAndPointer(readyAmfList, amfSetId, amfPointer); amf != nil {
+			return amf, nil
+		}
+	}
+
+	index := g.amfRoundRobinIndex.Add(1) - 1
+	return readyAmfList[index%uint64(len(readyAmfList))], nil
+}
@@ -0,0 +1,158 @@
+package gnb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/free5gc/aper"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/go-playground/assert"
+)
+
+var testGetNgSetupFailureErrorCases = []struct {
+	name               string
+	ngSetupFailure     *ngapType.NGSetupFailure
+	expectedCause      string
+	expectedTimeToWait time.Duration
+}{
+	{
+		name:               "testGetNgSetupFailureErrorWithNilFailure",
+		ngSetupFailure:     nil,
+		expectedCause:      "unknown",
+		expectedTimeToWait: 0,
+	},
+	{
+		name: "testGetNgSetupFailureErrorWithCauseOnly",
+		ngSetupFailure: &ngapType.NGSetupFailure{
+			ProtocolIEs: ngapType.ProtocolIEContainerNGSetupFailureIEs{
+				List: []ngapType.NGSetupFailureIEs{
+					{
+						Id: ngapType.ProtocolIEID{Value: ngapType.ProtocolIEIDCause},
+						Value: ngapType.NGSetupFailureIEsValue{
+							Present: ngapType.NGSetupFailureIEsPresentCause,
+							Cause: &ngapType.Cause{
+								Present: ngapType.CausePresentMisc,
+								Misc:    &ngapType.CauseMisc{Value: ngapType.CauseMiscPresentUnknownPLMN},
+							},
+						},
+					},
+				},
+			},
+		},
+		expectedCause:      fmt.Sprintf("misc %d", ngapType.CauseMiscPresentUnknownPLMN),
+		expectedTimeToWait: 0,
+	},
+	{
+		name: "testGetNgSetupFailureErrorWithTimeToWait",
+		ngSetupFailure: &ngapType.NGSetupFailure{
+			ProtocolIEs: ngapType.ProtocolIEContainerNGSetupFailureIEs{
+				List: []ngapType.NGSetupFailureIEs{
+					{
+						Id: ngapType.ProtocolIEID{Value: ngapType.ProtocolIEIDCause},
+						Value: ngapType.NGSetupFailureIEsValue{
+							Present: ngapType.NGSetupFailureIEsPresentCause,
+							Cause: &ngapType.Cause{
+								Present: ngapType.CausePresentMisc,
+								Misc:    &ngapType.CauseMisc{Value: ngapType.CauseMiscPresentControlProcessingOverload},
+							},
+						},
+					},
+					{
+						Id: ngapType.ProtocolIEID{Value: ngapType.ProtocolIEIDTimeToWait},
+						Value: ngapType.NGSetupFailureIEsValue{
+							Present:    ngapType.NGSetupFailureIEsPresentTimeToWait,
+							TimeToWait: &ngapType.TimeToWait{Value: aper.Enumerated(ngapType.TimeToWaitPresentV10s)},
+						},
+					},
+				},
+			},
+		},
+		expectedCause:      fmt.Sprintf("misc %d", ngapType.CauseMiscPresentControlProcessingOverload),
+		expectedTimeToWait: 10 * time.Second,
+	},
+}
+
+func TestGetNgSetupFailureError(t *testing.T) {
+	for _, testCase := range testGetNgSetupFailureErrorCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ngSetupFailureErr := getNgSetupFailureError(testCase.ngSetupFailure)
+			assert.Equal(t, testCase.expectedCause, ngSetupFailureErr.cause)
+			assert.Equal(t, testCase.expectedTimeToWait, ngSetupFailureErr.timeToWait)
+		})
+	}
+}
+
+var testGetN2SetupRetryIntervalCases = []struct {
+	name                      string
+	retryInterval             time.Duration
+	err                       error
+	expectedWaitInterval      time.Duration
+	expectedNextRetryInterval time.Duration
+}{
+	{
+		name:                      "testGetN2SetupRetryIntervalWithConnectionError",
+		retryInterval:             constant.N2_SETUP_RETRY_INITIAL_INTERVAL,
+		err:                       errors.New("error connecting to AMF"),
+		expectedWaitInterval:      constant.N2_SETUP_RETRY_INITIAL_INTERVAL,
+		expectedNextRetryInterval: constant.N2_SETUP_RETRY_INITIAL_INTERVAL * 2,
+	},
+	{
+		name:                      "testGetN2SetupRetryIntervalWithMaxInterval",
+		retryInterval:             constant.N2_SETUP_RETRY_MAX_INTERVAL,
+		err:                       errors.New("error connecting to AMF"),
+		expectedWaitInterval:      constant.N2_SETUP_RETRY_MAX_INTERVAL,
+		expectedNextRetryInterval: constant.N2_SETUP_RETRY_MAX_INTERVAL,
+	},
+	{
+		name:                      "testGetN2SetupRetryIntervalWithTimeToWait",
+		retryInterval:             constant.N2_SETUP_RETRY_INITIAL_INTERVAL,
+		err:                       &ngSetupFailureError{cause: "misc 0", timeToWait: 60 * time.Second},
+		expectedWaitInterval:      60 * time.Second,
+		expectedNextRetryInterval: constant.N2_SETUP_RETRY_INITIAL_INTERVAL * 2,
+	},
+}
+
+func TestGetN2SetupRetryInterval(t *testing.T) {
+	for _, testCase := range testGetN2SetupRetryIntervalCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			waitInterval, nextRetryInterval := getN2SetupRetryInterval(testCase.retryInterval, testCase.err)
+			assert.Equal(t, testCase.expectedWaitInterval, waitInterval)
+			assert.Equal(t, testCase.expectedNextRetryInterval, nextRetryInterval)
+		})
+	}
+}
+
+var testIsN2AssociationLostCases = []struct {
+	name     string
+	err      error
+	expected bool
+}{
+	{
+		name:     "testIsN2AssociationLostWithEOF",
+		err:      io.EOF,
+		expected: true,
+	},
+	{
+		name:     "testIsN2AssociationLostWithConnectionReset",
+		err:      syscall.ECONNRESET,
+		expected: true,
+	},
+	{
+		name:     "testIsN2AssociationLostWithInterrupt",
+		err:      syscall.EINTR,
+		expected: false,
+	},
+}
+
+func TestIsN2AssociationLost(t *testing.T) {
+	for _, testCase := range testIsN2AssociationLostCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, isN2AssociationLost(testCase.err))
+		})
+	}
+}
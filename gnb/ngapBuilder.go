@@ -9,7 +9,7 @@ import (
 	"github.com/free5gc/ngap/ngapType"
 )
 
-func buildNgapSetupRequest(gnbId []byte, gnbName string, plmnId ngapType.PLMNIdentity, tai ngapType.TAI, snssai ngapType.SNSSAI) ngapType.NGAPPDU {
+func buildNgapSetupRequest(gnbId []byte, gnbName string, plmnId ngapType.PLMNIdentity, supportedTaList ngapType.SupportedTAList) ngapType.NGAPPDU {
 	pdu := ngapType.NGAPPDU{}
 
 	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
@@ -65,25 +65,7 @@ func buildNgapSetupRequest(gnbId []byte, gnbName string, plmnId ngapType.PLMNIde
 	ie.Value.SupportedTAList = new(ngapType.SupportedTAList)
 
 	supportedTAList := ie.Value.SupportedTAList
-
-	supportedTAItem := ngapType.SupportedTAItem{}
-	supportedTAItem.TAC.Value = aper.OctetString(tai.TAC.Value)
-
-	broadcastPLMNList := &supportedTAItem.BroadcastPLMNList
-	broadcastPLMNItem := ngapType.BroadcastPLMNItem{}
-	broadcastPLMNItem.PLMNIdentity.Value = tai.PLMNIdentity.Value
-
-	sliceSupportList := &broadcastPLMNItem.TAISliceSupportList
-	sliceSupportItem := ngapType.SliceSupportItem{}
-	sliceSupportItem.SNSSAI.SST.Value = aper.OctetString(snssai.SST.Value)
-	sliceSupportItem.SNSSAI.SD = new(ngapType.SD)
-	sliceSupportItem.SNSSAI.SD.Value = aper.OctetString(snssai.SD.Value)
-
-	sliceSupportList.List = append(sliceSupportList.List, sliceSupportItem)
-
-	broadcastPLMNList.List = append(broadcastPLMNList.List, broadcastPLMNItem)
-
-	supportedTAList.List = append(supportedTAList.List, supportedTAItem)
+	supportedTAList.List = append(supportedTAList.List, supportedTaList.List...)
 
 	nGSetupRequestIEs.List = append(nGSetupRequestIEs.List, ie)
 
@@ -100,11 +82,12 @@ func buildNgapSetupRequest(gnbId []byte, gnbName string, plmnId ngapType.PLMNIde
 	return pdu
 }
 
-func getNgapSetupRequest(gnbId []byte, gnbName string, plmnId ngapType.PLMNIdentity, tai ngapType.TAI, snssai ngapType.SNSSAI) ([]byte, error) {
-	return ngap.Encoder(buildNgapSetupRequest(gnbId, gnbName, plmnId, tai, snssai))
+func getNgapSetupRequest(gnbId []byte, gnbName string, plmnId ngapType.PLMNIdentity, supportedTaList ngapType.SupportedTAList) ([]byte, error) {
+	return ngap.Encoder(buildNgapSetupRequest(gnbId, gnbName, plmnId, supportedTaList))
 }
 
-func buildInitialUeMessage(ranUeNgapId int64, ueRegistrationRequest []byte, plmnId ngapType.PLMNIdentity, tai ngapType.TAI) ngapType.NGAPPDU {
+// the 5G-S-TMSI is only given when the UE comes back from CM-IDLE with a service request
+func buildInitialUeMessage(ranUeNgapId int64, initialNasMessage []byte, plmnId ngapType.PLMNIdentity, tai ngapType.TAI, rrcEstablishmentCause aper.Enumerated, fiveGSTmsi *ngapType.FiveGSTMSI) ngapType.NGAPPDU {
 	pdu := ngapType.NGAPPDU{}
 
 	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
@@ -140,7 +123,7 @@ func buildInitialUeMessage(ranUeNgapId int64, ueRegistrationRequest []byte, plmn
 	ie.Value.NASPDU = new(ngapType.NASPDU)
 
 	nasPDU := ie.Value.NASPDU
-	nasPDU.Value = ueRegistrationRequest
+	nasPDU.Value = initialNasMessage
 
 	initialUEMessageIEs.List = append(initialUEMessageIEs.List, ie)
 
@@ -174,10 +157,21 @@ func buildInitialUeMessage(ranUeNgapId int64, ueRegistrationRequest []byte, plmn
 	ie.Value.RRCEstablishmentCause = new(ngapType.RRCEstablishmentCause)
 
 	rRCEstablishmentCause := ie.Value.RRCEstablishmentCause
-	rRCEstablishmentCause.Value = ngapType.RRCEstablishmentCausePresentMtAccess
+	rRCEstablishmentCause.Value = rrcEstablishmentCause
 
 	initialUEMessageIEs.List = append(initialUEMessageIEs.List, ie)
 
+	// 5G-S-TMSI
+	if fiveGSTmsi != nil {
+		ie = ngapType.InitialUEMessageIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDFiveGSTMSI
+		ie.Criticality.Value = ngapType.CriticalityPresentReject
+		ie.Value.Present = ngapType.InitialUEMessageIEsPresentFiveGSTMSI
+		ie.Value.FiveGSTMSI = fiveGSTmsi
+
+		initialUEMessageIEs.List = append(initialUEMessageIEs.List, ie)
+	}
+
 	// UE Context Request
 	ie = ngapType.InitialUEMessageIEs{}
 	ie.Id.Value = ngapType.ProtocolIEIDUEContextRequest
@@ -190,8 +184,8 @@ func buildInitialUeMessage(ranUeNgapId int64, ueRegistrationRequest []byte, plmn
 	return pdu
 }
 
-func getInitialUeMessage(ranUeNgapId int64, ueRegistrationRequest []byte, plmnId ngapType.PLMNIdentity, tai ngapType.TAI) ([]byte, error) {
-	initialUeMessage := buildInitialUeMessage(ranUeNgapId, ueRegistrationRequest, plmnId, tai)
+func getInitialUeMessage(ranUeNgapId int64, initialNasMessage []byte, plmnId ngapType.PLMNIdentity, tai ngapType.TAI, rrcEstablishmentCause aper.Enumerated, fiveGSTmsi *ngapType.FiveGSTMSI) ([]byte, error) {
+	initialUeMessage := buildInitialUeMessage(ranUeNgapId, initialNasMessage, plmnId, tai, rrcEstablishmentCause, fiveGSTmsi)
 	return ngap.Encoder(initialUeMessage)
 }
 
@@ -278,7 +272,8 @@ func getUplinkNasTransport(amfUeNgapId int64, ranUeNgapId int64, plmnId ngapType
 	return ngap.Encoder(uplinkNasTransport)
 }
 
-func buildNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId int64) ngapType.NGAPPDU {
+// the pdu session resource setup list is only included when the AMF asks to set up pdu sessions, e.g. for a service request
+func buildNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, pduSessionResourceSetupResponseTransferMessageList [][]byte) ngapType.NGAPPDU {
 	pdu := ngapType.NGAPPDU{}
 
 	pdu.Present = ngapType.NGAPPDUPresentSuccessfulOutcome
@@ -318,11 +313,34 @@ func buildNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId int64) ngapTy
 
 	initialContextSetupResponseIEs.List = append(initialContextSetupResponseIEs.List, ie)
 
+	// PDU Session Resource Setup Response List
+	if len(pduSessionIdList) > 0 {
+		ie = ngapType.InitialContextSetupResponseIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceSetupListCxtRes
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.InitialContextSetupResponseIEsPresentPDUSessionResourceSetupListCxtRes
+		ie.Value.PDUSessionResourceSetupListCxtRes = new(ngapType.PDUSessionResourceSetupListCxtRes)
+
+		pDUSessionResourceSetupListCxtRes := ie.Value.PDUSessionResourceSetupListCxtRes
+
+		// PDU Session Resource Setup Response Item in PDU Session Resource Setup Response List
+		for i, pduSessionId := range pduSessionIdList {
+			pDUSessionResourceSetupItemCxtRes := ngapType.PDUSessionResourceSetupItemCxtRes{}
+			pDUSessionResourceSetupItemCxtRes.PDUSessionID.Value = pduSessionId
+
+			pDUSessionResourceSetupItemCxtRes.PDUSessionResourceSetupResponseTransfer = pduSessionResourceSetupResponseTransferMessageList[i]
+
+			pDUSessionResourceSetupListCxtRes.List = append(pDUSessionResourceSetupListCxtRes.List, pDUSessionResourceSetupItemCxtRes)
+		}
+
+		initialContextSetupResponseIEs.List = append(initialContextSetupResponseIEs.List, ie)
+	}
+
 	return pdu
 }
 
-func getNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId int64) ([]byte, error) {
-	initialContextSetupResponse := buildNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId)
+func getNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, pduSessionResourceSetupResponseTransferMessageList [][]byte) ([]byte, error) {
+	initialContextSetupResponse := buildNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionIdList, pduSessionResourceSetupResponseTransferMessageList)
 	return ngap.Encoder(initialContextSetupResponse)
 }
 
@@ -364,7 +382,7 @@ func getPduSessionResourceSetupResponseTransfer(dlTeid []byte, ranN3Ip string, q
 	return encodedTransferMessage, nil
 }
 
-func buildPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionId int64, pduSessionResourceSetupResponseTransferMessage []byte) ngapType.NGAPPDU {
+func buildPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, pduSessionResourceSetupResponseTransferMessageList [][]byte) ngapType.NGAPPDU {
 	pdu := ngapType.NGAPPDU{}
 
 	pdu.Present = ngapType.NGAPPDUPresentSuccessfulOutcome
@@ -414,20 +432,22 @@ func buildPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionId
 	pDUSessionResourceSetupListSURes := ie.Value.PDUSessionResourceSetupListSURes
 
 	// PDU Session Resource Setup Response Item in PDU Session Resource Setup Response List
-	pDUSessionResourceSetupItemSURes := ngapType.PDUSessionResourceSetupItemSURes{}
-	pDUSessionResourceSetupItemSURes.PDUSessionID.Value = pduSessionId
+	for i, pduSessionId := range pduSessionIdList {
+		pDUSessionResourceSetupItemSURes := ngapType.PDUSessionResourceSetupItemSURes{}
+		pDUSessionResourceSetupItemSURes.PDUSessionID.Value = pduSessionId
 
-	pDUSessionResourceSetupItemSURes.PDUSessionResourceSetupResponseTransfer = pduSessionResourceSetupResponseTransferMessage
+		pDUSessionResourceSetupItemSURes.PDUSessionResourceSetupResponseTransfer = pduSessionResourceSetupResponseTransferMessageList[i]
 
-	pDUSessionResourceSetupListSURes.List = append(pDUSessionResourceSetupListSURes.List, pDUSessionResourceSetupItemSURes)
+		pDUSessionResourceSetupListSURes.List = append(pDUSessionResourceSetupListSURes.List, pDUSessionResourceSetupItemSURes)
+	}
 
 	pDUSessionResourceSetupResponseIEs.List = append(pDUSessionResourceSetupResponseIEs.List, ie)
 
 	return pdu
 }
 
-func getPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionId int64, pduSessionResourceSetupResponseTransferMessage []byte) ([]byte, error) {
-	pduSessionResourceSetupResponse := buildPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionId, pduSessionResourceSetupResponseTransferMessage)
+func getPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, pduSessionResourceSetupResponseTransferMessageList [][]byte) ([]byte, error) {
+	pduSessionResourceSetupResponse := buildPduSessionResourceSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionIdList, pduSessionResourceSetupResponseTransferMessageList)
 	return ngap.Encoder(pduSessionResourceSetupResponse)
 }
 
@@ -609,4 +629,1301 @@ func buildPDUSessionResourceModifyIndication(amfUeNgapId, ranUeNgapId int64, pdu
 func getPDUSessionResourceModifyIndication(amfUeNgapId, ranUeNgapId int64, pduSessionId int64, pduSessionResourceModifyIndicationTransferMessage []byte) ([]byte, error) {
 	pduSessionResourceModifyIndication := buildPDUSessionResourceModifyIndication(amfUeNgapId, ranUeNgapId, pduSessionId, pduSessionResourceModifyIndicationTransferMessage)
 	return ngap.Encoder(pduSessionResourceModifyIndication)
-}
\ No newline at end of file
+}
+
+func buildPduSessionResourceReleaseResponseTransfer() ngapType.PDUSessionResourceReleaseResponseTransfer {
+	return ngapType.PDUSessionResourceReleaseResponseTransfer{}
+}
+
+func getPduSessionResourceReleaseResponseTransfer() ([]byte, error) {
+	transferMessage := buildPduSessionResourceReleaseResponseTransfer()
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal pdu session resource release response transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+func buildPduSessionResourceReleaseResponse(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, pduSessionResourceReleaseResponseTransferMessage []byte) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentSuccessfulOutcome
+	pdu.SuccessfulOutcome = new(ngapType.SuccessfulOutcome)
+
+	successfulOutcome := pdu.SuccessfulOutcome
+	successfulOutcome.ProcedureCode.Value = ngapType.ProcedureCodePDUSessionResourceRelease
+	successfulOutcome.Criticality.Value = ngapType.CriticalityPresentReject
+
+	successfulOutcome.Value.Present = ngapType.SuccessfulOutcomePresentPDUSessionResourceReleaseResponse
+	successfulOutcome.Value.PDUSessionResourceReleaseResponse = new(ngapType.PDUSessionResourceReleaseResponse)
+
+	pDUSessionResourceReleaseResponse := successfulOutcome.Value.PDUSessionResourceReleaseResponse
+	pDUSessionResourceReleaseResponseIEs := &pDUSessionResourceReleaseResponse.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.PDUSessionResourceReleaseResponseIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PDUSessionResourceReleaseResponseIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	pDUSessionResourceReleaseResponseIEs.List = append(pDUSessionResourceReleaseResponseIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.PDUSessionResourceReleaseResponseIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PDUSessionResourceReleaseResponseIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	pDUSessionResourceReleaseResponseIEs.List = append(pDUSessionResourceReleaseResponseIEs.List, ie)
+
+	// PDU Session Resource Released List
+	ie = ngapType.PDUSessionResourceReleaseResponseIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceReleasedListRelRes
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PDUSessionResourceReleaseResponseIEsPresentPDUSessionResourceReleasedListRelRes
+	ie.Value.PDUSessionResourceReleasedListRelRes = new(ngapType.PDUSessionResourceReleasedListRelRes)
+
+	pDUSessionResourceReleasedListRelRes := ie.Value.PDUSessionResourceReleasedListRelRes
+
+	// PDU Session Resource Released Item in PDU Session Resource Released List
+	for _, pduSessionId := range pduSessionIdList {
+		pDUSessionResourceReleasedItemRelRes := ngapType.PDUSessionResourceReleasedItemRelRes{}
+		pDUSessionResourceReleasedItemRelRes.PDUSessionID.Value = pduSessionId
+		pDUSessionResourceReleasedItemRelRes.PDUSessionResourceReleaseResponseTransfer = pduSessionResourceReleaseResponseTransferMessage
+
+		pDUSessionResourceReleasedListRelRes.List = append(pDUSessionResourceReleasedListRelRes.List, pDUSessionResourceReleasedItemRelRes)
+	}
+
+	pDUSessionResourceReleaseResponseIEs.List = append(pDUSessionResourceReleaseResponseIEs.List, ie)
+
+	return pdu
+}
+
+func getPduSessionResourceReleaseResponse(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, pduSessionResourceReleaseResponseTransferMessage []byte) ([]byte, error) {
+	pduSessionResourceReleaseResponse := buildPduSessionResourceReleaseResponse(amfUeNgapId, ranUeNgapId, pduSessionIdList, pduSessionResourceReleaseResponseTransferMessage)
+	return ngap.Encoder(pduSessionResourceReleaseResponse)
+}
+
+func buildPduSessionResourceModifyResponseTransfer(qosFlowIdList []int64, failedQosFlowIdList []int64) ngapType.PDUSessionResourceModifyResponseTransfer {
+	transferMessage := ngapType.PDUSessionResourceModifyResponseTransfer{}
+
+	// QoS Flow Add or Modify Response List
+	if len(qosFlowIdList) != 0 {
+		transferMessage.QosFlowAddOrModifyResponseList = new(ngapType.QosFlowAddOrModifyResponseList)
+		for _, qosFlowId := range qosFlowIdList {
+			qosFlowAddOrModifyResponseItem := ngapType.QosFlowAddOrModifyResponseItem{}
+			qosFlowAddOrModifyResponseItem.QosFlowIdentifier.Value = qosFlowId
+			transferMessage.QosFlowAddOrModifyResponseList.List = append(transferMessage.QosFlowAddOrModifyResponseList.List, qosFlowAddOrModifyResponseItem)
+		}
+	}
+
+	// QoS Flow Failed to Add or Modify List
+	if len(failedQosFlowIdList) != 0 {
+		transferMessage.QosFlowFailedToAddOrModifyList = new(ngapType.QosFlowListWithCause)
+		for _, qosFlowId := range failedQosFlowIdList {
+			qosFlowWithCauseItem := ngapType.QosFlowWithCauseItem{}
+			qosFlowWithCauseItem.QosFlowIdentifier.Value = qosFlowId
+			qosFlowWithCauseItem.Cause.Present = ngapType.CausePresentRadioNetwork
+			qosFlowWithCauseItem.Cause.RadioNetwork = &ngapType.CauseRadioNetwork{Value: ngapType.CauseRadioNetworkPresentUnspecified}
+			transferMessage.QosFlowFailedToAddOrModifyList.List = append(transferMessage.QosFlowFailedToAddOrModifyList.List, qosFlowWithCauseItem)
+		}
+	}
+
+	return transferMessage
+}
+
+func getPduSessionResourceModifyResponseTransfer(qosFlowIdList []int64, failedQosFlowIdList []int64) ([]byte, error) {
+	transferMessage := buildPduSessionResourceModifyResponseTransfer(qosFlowIdList, failedQosFlowIdList)
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal pdu session resource modify response transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+// the modified and the failed pdu sessions are listed separately, an empty list is left out
+func buildPduSessionResourceModifyResponse(amfUeNgapId, ranUeNgapId int64, modifiedItemList []ngapType.PDUSessionResourceModifyItemModRes, failedItemList []ngapType.PDUSessionResourceFailedToModifyItemModRes) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentSuccessfulOutcome
+	pdu.SuccessfulOutcome = new(ngapType.SuccessfulOutcome)
+
+	successfulOutcome := pdu.SuccessfulOutcome
+	successfulOutcome.ProcedureCode.Value = ngapType.ProcedureCodePDUSessionResourceModify
+	successfulOutcome.Criticality.Value = ngapType.CriticalityPresentReject
+
+	successfulOutcome.Value.Present = ngapType.SuccessfulOutcomePresentPDUSessionResourceModifyResponse
+	successfulOutcome.Value.PDUSessionResourceModifyResponse = new(ngapType.PDUSessionResourceModifyResponse)
+
+	pDUSessionResourceModifyResponse := successfulOutcome.Value.PDUSessionResourceModifyResponse
+	pDUSessionResourceModifyResponseIEs := &pDUSessionResourceModifyResponse.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.PDUSessionResourceModifyResponseIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PDUSessionResourceModifyResponseIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	pDUSessionResourceModifyResponseIEs.List = append(pDUSessionResourceModifyResponseIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.PDUSessionResourceModifyResponseIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PDUSessionResourceModifyResponseIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	pDUSessionResourceModifyResponseIEs.List = append(pDUSessionResourceModifyResponseIEs.List, ie)
+
+	// PDU Session Resource Modify Response List
+	if len(modifiedItemList) != 0 {
+		ie = ngapType.PDUSessionResourceModifyResponseIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceModifyListModRes
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.PDUSessionResourceModifyResponseIEsPresentPDUSessionResourceModifyListModRes
+		ie.Value.PDUSessionResourceModifyListModRes = &ngapType.PDUSessionResourceModifyListModRes{
+			List: modifiedItemList,
+		}
+
+		pDUSessionResourceModifyResponseIEs.List = append(pDUSessionResourceModifyResponseIEs.List, ie)
+	}
+
+	// PDU Session Resource Failed to Modify List
+	if len(failedItemList) != 0 {
+		ie = ngapType.PDUSessionResourceModifyResponseIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceFailedToModifyListModRes
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.PDUSessionResourceModifyResponseIEsPresentPDUSessionResourceFailedToModifyListModRes
+		ie.Value.PDUSessionResourceFailedToModifyListModRes = &ngapType.PDUSessionResourceFailedToModifyListModRes{
+			List: failedItemList,
+		}
+
+		pDUSessionResourceModifyResponseIEs.List = append(pDUSessionResourceModifyResponseIEs.List, ie)
+	}
+
+	return pdu
+}
+
+func getPduSessionResourceModifyResponse(amfUeNgapId, ranUeNgapId int64, modifiedItemList []ngapType.PDUSessionResourceModifyItemModRes, failedItemList []ngapType.PDUSessionResourceFailedToModifyItemModRes) ([]byte, error) {
+	pduSessionResourceModifyResponse := buildPduSessionResourceModifyResponse(amfUeNgapId, ranUeNgapId, modifiedItemList, failedItemList)
+	return ngap.Encoder(pduSessionResourceModifyResponse)
+}
+
+func getPduSessionResourceModifyUnsuccessfulTransfer(cause ngapType.Cause) ([]byte, error) {
+	transferMessage := ngapType.PDUSessionResourceModifyUnsuccessfulTransfer{
+		Cause: cause,
+	}
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal pdu session resource modify unsuccessful transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+func buildNgReset(cause ngapType.Cause, partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeNGReset
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentReject
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentNGReset
+	initiatingMessage.Value.NGReset = new(ngapType.NGReset)
+
+	nGReset := initiatingMessage.Value.NGReset
+	nGResetIEs := &nGReset.ProtocolIEs
+
+	// Cause
+	ie := ngapType.NGResetIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.NGResetIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	*ie.Value.Cause = cause
+
+	nGResetIEs.List = append(nGResetIEs.List, ie)
+
+	// Reset Type
+	ie = ngapType.NGResetIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDResetType
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.NGResetIEsPresentResetType
+	ie.Value.ResetType = new(ngapType.ResetType)
+
+	resetType := ie.Value.ResetType
+	if partOfNgInterface == nil {
+		resetType.Present = ngapType.ResetTypePresentNGInterface
+		resetType.NGInterface = new(ngapType.ResetAll)
+		resetType.NGInterface.Value = ngapType.ResetAllPresentResetAll
+	} else {
+		resetType.Present = ngapType.ResetTypePresentPartOfNGInterface
+		resetType.PartOfNGInterface = partOfNgInterface
+	}
+
+	nGResetIEs.List = append(nGResetIEs.List, ie)
+
+	return pdu
+}
+
+func getNgReset(cause ngapType.Cause, partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList) ([]byte, error) {
+	return ngap.Encoder(buildNgReset(cause, partOfNgInterface))
+}
+
+func buildNgResetAcknowledge(partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentSuccessfulOutcome
+	pdu.SuccessfulOutcome = new(ngapType.SuccessfulOutcome)
+
+	successfulOutcome := pdu.SuccessfulOutcome
+	successfulOutcome.ProcedureCode.Value = ngapType.ProcedureCodeNGReset
+	successfulOutcome.Criticality.Value = ngapType.CriticalityPresentReject
+
+	successfulOutcome.Value.Present = ngapType.SuccessfulOutcomePresentNGResetAcknowledge
+	successfulOutcome.Value.NGResetAcknowledge = new(ngapType.NGResetAcknowledge)
+
+	nGResetAcknowledge := successfulOutcome.Value.NGResetAcknowledge
+	nGResetAcknowledgeIEs := &nGResetAcknowledge.ProtocolIEs
+	nGResetAcknowledgeIEs.List = []ngapType.NGResetAcknowledgeIEs{}
+
+	// UE-associated Logical NG-connection List, only present when part of the NG interface was reset
+	if partOfNgInterface != nil && len(partOfNgInterface.List) > 0 {
+		ie := ngapType.NGResetAcknowledgeIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDUEAssociatedLogicalNGConnectionList
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.NGResetAcknowledgeIEsPresentUEAssociatedLogicalNGConnectionList
+		ie.Value.UEAssociatedLogicalNGConnectionList = partOfNgInterface
+
+		nGResetAcknowledgeIEs.List = append(nGResetAcknowledgeIEs.List, ie)
+	}
+
+	return pdu
+}
+
+func getNgResetAcknowledge(partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList) ([]byte, error) {
+	return ngap.Encoder(buildNgResetAcknowledge(partOfNgInterface))
+}
+
+func buildUeContextReleaseRequest(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, cause ngapType.Cause) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeUEContextReleaseRequest
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentIgnore
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentUEContextReleaseRequest
+	initiatingMessage.Value.UEContextReleaseRequest = new(ngapType.UEContextReleaseRequest)
+
+	uEContextReleaseRequest := initiatingMessage.Value.UEContextReleaseRequest
+	uEContextReleaseRequestIEs := &uEContextReleaseRequest.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.UEContextReleaseRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.UEContextReleaseRequestIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	uEContextReleaseRequestIEs.List = append(uEContextReleaseRequestIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.UEContextReleaseRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.UEContextReleaseRequestIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	uEContextReleaseRequestIEs.List = append(uEContextReleaseRequestIEs.List, ie)
+
+	// PDU Session Resource List, only present when the UE has active pdu sessions
+	if len(pduSessionIdList) > 0 {
+		ie = ngapType.UEContextReleaseRequestIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceListCxtRelReq
+		ie.Criticality.Value = ngapType.CriticalityPresentReject
+		ie.Value.Present = ngapType.UEContextReleaseRequestIEsPresentPDUSessionResourceListCxtRelReq
+		ie.Value.PDUSessionResourceListCxtRelReq = new(ngapType.PDUSessionResourceListCxtRelReq)
+
+		pDUSessionResourceListCxtRelReq := ie.Value.PDUSessionResourceListCxtRelReq
+
+		for _, pduSessionId := range pduSessionIdList {
+			pDUSessionResourceItemCxtRelReq := ngapType.PDUSessionResourceItemCxtRelReq{}
+			pDUSessionResourceItemCxtRelReq.PDUSessionID.Value = pduSessionId
+			pDUSessionResourceListCxtRelReq.List = append(pDUSessionResourceListCxtRelReq.List, pDUSessionResourceItemCxtRelReq)
+		}
+
+		uEContextReleaseRequestIEs.List = append(uEContextReleaseRequestIEs.List, ie)
+	}
+
+	// Cause
+	ie = ngapType.UEContextReleaseRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.UEContextReleaseRequestIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	*ie.Value.Cause = cause
+
+	uEContextReleaseRequestIEs.List = append(uEContextReleaseRequestIEs.List, ie)
+
+	return pdu
+}
+
+func getUeContextReleaseRequest(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, cause ngapType.Cause) ([]byte, error) {
+	return ngap.Encoder(buildUeContextReleaseRequest(amfUeNgapId, ranUeNgapId, pduSessionIdList, cause))
+}
+
+// the handover request transfer carries the UL tunnel and the QoS flows of a pdu session from the source gNB to the target gNB
+func buildHandoverRequestTransfer(ulTeid []byte, upfN3Ip string, qosFlowIdList []int64, fiveQiList []int64) ngapType.PDUSessionResourceSetupRequestTransfer {
+	transferMessage := ngapType.PDUSessionResourceSetupRequestTransfer{}
+	transferMessageIEs := &transferMessage.ProtocolIEs
+
+	// UL NG-U UP TNL Information
+	ie := ngapType.PDUSessionResourceSetupRequestTransferIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDULNGUUPTNLInformation
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.PDUSessionResourceSetupRequestTransferIEsPresentULNGUUPTNLInformation
+	ie.Value.ULNGUUPTNLInformation = new(ngapType.UPTransportLayerInformation)
+
+	uLNGUUPTNLInformation := ie.Value.ULNGUUPTNLInformation
+	uLNGUUPTNLInformation.Present = ngapType.UPTransportLayerInformationPresentGTPTunnel
+	uLNGUUPTNLInformation.GTPTunnel = new(ngapType.GTPTunnel)
+	uLNGUUPTNLInformation.GTPTunnel.GTPTEID.Value = aper.OctetString(ulTeid)
+	uLNGUUPTNLInformation.GTPTunnel.TransportLayerAddress = ngapConvert.IPAddressToNgap(upfN3Ip, "")
+
+	transferMessageIEs.List = append(transferMessageIEs.List, ie)
+
+	// PDU Session Type
+	ie = ngapType.PDUSessionResourceSetupRequestTransferIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDPDUSessionType
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.PDUSessionResourceSetupRequestTransferIEsPresentPDUSessionType
+	ie.Value.PDUSessionType = new(ngapType.PDUSessionType)
+
+	pDUSessionType := ie.Value.PDUSessionType
+	pDUSessionType.Value = ngapType.PDUSessionTypePresentIpv4
+
+	transferMessageIEs.List = append(transferMessageIEs.List, ie)
+
+	// QoS Flow Setup Request List
+	ie = ngapType.PDUSessionResourceSetupRequestTransferIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDQosFlowSetupRequestList
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.PDUSessionResourceSetupRequestTransferIEsPresentQosFlowSetupRequestList
+	ie.Value.QosFlowSetupRequestList = new(ngapType.QosFlowSetupRequestList)
+
+	qosFlowSetupRequestList := ie.Value.QosFlowSetupRequestList
+
+	// QoS Flow Setup Request Item in QoS Flow Setup Request List
+	for i, qosFlowId := range qosFlowIdList {
+		qosFlowSetupRequestItem := ngapType.QosFlowSetupRequestItem{}
+		qosFlowSetupRequestItem.QosFlowIdentifier.Value = qosFlowId
+
+		qosFlowLevelQosParameters := &qosFlowSetupRequestItem.QosFlowLevelQosParameters
+		qosFlowLevelQosParameters.QosCharacteristics.Present = ngapType.QosCharacteristicsPresentNonDynamic5QI
+		qosFlowLevelQosParameters.QosCharacteristics.NonDynamic5QI = new(ngapType.NonDynamic5QIDescriptor)
+		qosFlowLevelQosParameters.QosCharacteristics.NonDynamic5QI.FiveQI.Value = fiveQiList[i]
+		qosFlowLevelQosParameters.AllocationAndRetentionPriority.PriorityLevelARP.Value = 8
+		qosFlowLevelQosParameters.AllocationAndRetentionPriority.PreEmptionCapability.Value = ngapType.PreEmptionCapabilityPresentShallNotTriggerPreEmption
+		qosFlowLevelQosParameters.AllocationAndRetentionPriority.PreEmptionVulnerability.Value = ngapType.PreEmptionVulnerabilityPresentNotPreEmptable
+
+		qosFlowSetupRequestList.List = append(qosFlowSetupRequestList.List, qosFlowSetupRequestItem)
+	}
+
+	transferMessageIEs.List = append(transferMessageIEs.List, ie)
+
+	return transferMessage
+}
+
+func getHandoverRequestTransfer(ulTeid []byte, upfN3Ip string, qosFlowIdList []int64, fiveQiList []int64) ([]byte, error) {
+	transferMessage := buildHandoverRequestTransfer(ulTeid, upfN3Ip, qosFlowIdList, fiveQiList)
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal handover request transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+// the handover request is sent to the target gNB over Xn, it only carries the UE context the target gNB needs for the path switch
+func buildHandoverRequest(amfUeNgapId int64, ueSecurityCapabilities ngapType.UESecurityCapabilities, guami ngapType.GUAMI, pduSessionIdList []int64, snssaiList []ngapType.SNSSAI, handoverRequestTransferMessageList [][]byte) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeHandoverResourceAllocation
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentReject
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentHandoverRequest
+	initiatingMessage.Value.HandoverRequest = new(ngapType.HandoverRequest)
+
+	handoverRequest := initiatingMessage.Value.HandoverRequest
+	handoverRequestIEs := &handoverRequest.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.HandoverRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequestIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+
+	// Handover Type
+	ie = ngapType.HandoverRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDHandoverType
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequestIEsPresentHandoverType
+	ie.Value.HandoverType = new(ngapType.HandoverType)
+
+	handoverType := ie.Value.HandoverType
+	handoverType.Value = ngapType.HandoverTypePresentIntra5gs
+
+	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+
+	// Cause
+	ie = ngapType.HandoverRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverRequestIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	cause := ie.Value.Cause
+	cause.Present = ngapType.CausePresentRadioNetwork
+	cause.RadioNetwork = new(ngapType.CauseRadioNetwork)
+	cause.RadioNetwork.Value = ngapType.CauseRadioNetworkPresentHandoverDesirableForRadioReason
+
+	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+
+	// UE Security Capabilities
+	ie = ngapType.HandoverRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDUESecurityCapabilities
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequestIEsPresentUESecurityCapabilities
+	ie.Value.UESecurityCapabilities = new(ngapType.UESecurityCapabilities)
+
+	*ie.Value.UESecurityCapabilities = ueSecurityCapabilities
+
+	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+
+	// PDU Session Resource Setup List HO Req
+	ie = ngapType.HandoverRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceSetupListHOReq
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequestIEsPresentPDUSessionResourceSetupListHOReq
+	ie.Value.PDUSessionResourceSetupListHOReq = new(ngapType.PDUSessionResourceSetupListHOReq)
+
+	pDUSessionResourceSetupListHOReq := ie.Value.PDUSessionResourceSetupListHOReq
+
+	// PDU Session Resource Setup Item HO Req in PDU Session Resource Setup List HO Req
+	for i, pduSessionId := range pduSessionIdList {
+		pDUSessionResourceSetupItemHOReq := ngapType.PDUSessionResourceSetupItemHOReq{}
+		pDUSessionResourceSetupItemHOReq.PDUSessionID.Value = pduSessionId
+		pDUSessionResourceSetupItemHOReq.SNSSAI = snssaiList[i]
+		pDUSessionResourceSetupItemHOReq.HandoverRequestTransfer = handoverRequestTransferMessageList[i]
+
+		pDUSessionResourceSetupListHOReq.List = append(pDUSessionResourceSetupListHOReq.List, pDUSessionResourceSetupItemHOReq)
+	}
+
+	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+
+	// GUAMI
+	ie = ngapType.HandoverRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDGUAMI
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequestIEsPresentGUAMI
+	ie.Value.GUAMI = new(ngapType.GUAMI)
+
+	*ie.Value.GUAMI = guami
+
+	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+
+	return pdu
+}
+
+func getHandoverRequest(amfUeNgapId int64, ueSecurityCapabilities ngapType.UESecurityCapabilities, guami ngapType.GUAMI, pduSessionIdList []int64, snssaiList []ngapType.SNSSAI, handoverRequestTransferMessageList [][]byte) ([]byte, error) {
+	return ngap.Encoder(buildHandoverRequest(amfUeNgapId, ueSecurityCapabilities, guami, pduSessionIdList, snssaiList, handoverRequestTransferMessageList))
+}
+
+// the target to source transparent container carries the handover command, which the source gNB forwards to the UE as is
+func buildHandoverRequestAcknowledge(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, handoverRequestAcknowledgeTransferMessageList [][]byte, targetToSourceTransparentContainer []byte) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentSuccessfulOutcome
+	pdu.SuccessfulOutcome = new(ngapType.SuccessfulOutcome)
+
+	successfulOutcome := pdu.SuccessfulOutcome
+	successfulOutcome.ProcedureCode.Value = ngapType.ProcedureCodeHandoverResourceAllocation
+	successfulOutcome.Criticality.Value = ngapType.CriticalityPresentReject
+
+	successfulOutcome.Value.Present = ngapType.SuccessfulOutcomePresentHandoverRequestAcknowledge
+	successfulOutcome.Value.HandoverRequestAcknowledge = new(ngapType.HandoverRequestAcknowledge)
+
+	handoverRequestAcknowledge := successfulOutcome.Value.HandoverRequestAcknowledge
+	handoverRequestAcknowledgeIEs := &handoverRequestAcknowledge.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.HandoverRequestAcknowledgeIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverRequestAcknowledgeIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	handoverRequestAcknowledgeIEs.List = append(handoverRequestAcknowledgeIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.HandoverRequestAcknowledgeIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverRequestAcknowledgeIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	handoverRequestAcknowledgeIEs.List = append(handoverRequestAcknowledgeIEs.List, ie)
+
+	// PDU Session Resource Admitted List, the list can not be empty
+	if len(pduSessionIdList) > 0 {
+		ie = ngapType.HandoverRequestAcknowledgeIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceAdmittedList
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.HandoverRequestAcknowledgeIEsPresentPDUSessionResourceAdmittedList
+		ie.Value.PDUSessionResourceAdmittedList = new(ngapType.PDUSessionResourceAdmittedList)
+
+		pDUSessionResourceAdmittedList := ie.Value.PDUSessionResourceAdmittedList
+
+		// PDU Session Resource Admitted Item in PDU Session Resource Admitted List
+		for i, pduSessionId := range pduSessionIdList {
+			pDUSessionResourceAdmittedItem := ngapType.PDUSessionResourceAdmittedItem{}
+			pDUSessionResourceAdmittedItem.PDUSessionID.Value = pduSessionId
+			pDUSessionResourceAdmittedItem.HandoverRequestAcknowledgeTransfer = handoverRequestAcknowledgeTransferMessageList[i]
+
+			pDUSessionResourceAdmittedList.List = append(pDUSessionResourceAdmittedList.List, pDUSessionResourceAdmittedItem)
+		}
+
+		handoverRequestAcknowledgeIEs.List = append(handoverRequestAcknowledgeIEs.List, ie)
+	}
+
+	// Target to Source Transparent Container
+	ie = ngapType.HandoverRequestAcknowledgeIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDTargetToSourceTransparentContainer
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequestAcknowledgeIEsPresentTargetToSourceTransparentContainer
+	ie.Value.TargetToSourceTransparentContainer = new(ngapType.TargetToSourceTransparentContainer)
+
+	targetToSourceTransparentContainerIE := ie.Value.TargetToSourceTransparentContainer
+	targetToSourceTransparentContainerIE.Value = targetToSourceTransparentContainer
+
+	handoverRequestAcknowledgeIEs.List = append(handoverRequestAcknowledgeIEs.List, ie)
+
+	return pdu
+}
+
+func getHandoverRequestAcknowledge(amfUeNgapId, ranUeNgapId int64, pduSessionIdList []int64, handoverRequestAcknowledgeTransferMessageList [][]byte, targetToSourceTransparentContainer []byte) ([]byte, error) {
+	return ngap.Encoder(buildHandoverRequestAcknowledge(amfUeNgapId, ranUeNgapId, pduSessionIdList, handoverRequestAcknowledgeTransferMessageList, targetToSourceTransparentContainer))
+}
+
+func buildPathSwitchRequestTransfer(dlTeid []byte, ranN3Ip string, qosFlowIdList []int64) ngapType.PathSwitchRequestTransfer {
+	transferMessage := ngapType.PathSwitchRequestTransfer{}
+
+	// DL NG-U UP TNL Information
+	dLNGUUPTNLInformation := &transferMessage.DLNGUUPTNLInformation
+	dLNGUUPTNLInformation.Present = ngapType.UPTransportLayerInformationPresentGTPTunnel
+	dLNGUUPTNLInformation.GTPTunnel = new(ngapType.GTPTunnel)
+	dLNGUUPTNLInformation.GTPTunnel.GTPTEID.Value = aper.OctetString(dlTeid)
+	dLNGUUPTNLInformation.GTPTunnel.TransportLayerAddress = ngapConvert.IPAddressToNgap(ranN3Ip, "")
+
+	// QoS Flow Accepted List
+	qosFlowAcceptedList := &transferMessage.QosFlowAcceptedList
+	for _, qosFlowId := range qosFlowIdList {
+		qosFlowAcceptedItem := ngapType.QosFlowAcceptedItem{}
+		qosFlowAcceptedItem.QosFlowIdentifier.Value = qosFlowId
+		qosFlowAcceptedList.List = append(qosFlowAcceptedList.List, qosFlowAcceptedItem)
+	}
+
+	return transferMessage
+}
+
+func getPathSwitchRequestTransfer(dlTeid []byte, ranN3Ip string, qosFlowIdList []int64) ([]byte, error) {
+	transferMessage := buildPathSwitchRequestTransfer(dlTeid, ranN3Ip, qosFlowIdList)
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal path switch request transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+func buildPathSwitchRequest(ranUeNgapId, sourceAmfUeNgapId int64, plmnId ngapType.PLMNIdentity, tai ngapType.TAI, ueSecurityCapabilities ngapType.UESecurityCapabilities, pduSessionIdList []int64, pathSwitchRequestTransferMessageList [][]byte) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodePathSwitchRequest
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentReject
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentPathSwitchRequest
+	initiatingMessage.Value.PathSwitchRequest = new(ngapType.PathSwitchRequest)
+
+	pathSwitchRequest := initiatingMessage.Value.PathSwitchRequest
+	pathSwitchRequestIEs := &pathSwitchRequest.ProtocolIEs
+
+	// RAN UE NGAP ID
+	ie := ngapType.PathSwitchRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.PathSwitchRequestIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	pathSwitchRequestIEs.List = append(pathSwitchRequestIEs.List, ie)
+
+	// Source AMF UE NGAP ID
+	ie = ngapType.PathSwitchRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDSourceAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.PathSwitchRequestIEsPresentSourceAMFUENGAPID
+	ie.Value.SourceAMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	sourceAMFUENGAPID := ie.Value.SourceAMFUENGAPID
+	sourceAMFUENGAPID.Value = sourceAmfUeNgapId
+
+	pathSwitchRequestIEs.List = append(pathSwitchRequestIEs.List, ie)
+
+	// User Location Information
+	ie = ngapType.PathSwitchRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDUserLocationInformation
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PathSwitchRequestIEsPresentUserLocationInformation
+	ie.Value.UserLocationInformation = new(ngapType.UserLocationInformation)
+
+	userLocationInformation := ie.Value.UserLocationInformation
+	userLocationInformation.Present = ngapType.UserLocationInformationPresentUserLocationInformationNR
+	userLocationInformation.UserLocationInformationNR = new(ngapType.UserLocationInformationNR)
+
+	userLocationInformationNR := userLocationInformation.UserLocationInformationNR
+	userLocationInformationNR.NRCGI.PLMNIdentity.Value = plmnId.Value
+	userLocationInformationNR.NRCGI.NRCellIdentity.Value = aper.BitString{
+		Bytes:     []byte{0x00, 0x00, 0x00, 0x00, 0x10},
+		BitLength: 36,
+	}
+
+	userLocationInformationNR.TAI.PLMNIdentity.Value = tai.PLMNIdentity.Value
+	userLocationInformationNR.TAI.TAC.Value = tai.TAC.Value
+
+	pathSwitchRequestIEs.List = append(pathSwitchRequestIEs.List, ie)
+
+	// UE Security Capabilities
+	ie = ngapType.PathSwitchRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDUESecurityCapabilities
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.PathSwitchRequestIEsPresentUESecurityCapabilities
+	ie.Value.UESecurityCapabilities = new(ngapType.UESecurityCapabilities)
+
+	*ie.Value.UESecurityCapabilities = ueSecurityCapabilities
+
+	pathSwitchRequestIEs.List = append(pathSwitchRequestIEs.List, ie)
+
+	// PDU Session Resource to be Switched in Downlink List
+	ie = ngapType.PathSwitchRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceToBeSwitchedDLList
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.PathSwitchRequestIEsPresentPDUSessionResourceToBeSwitchedDLList
+	ie.Value.PDUSessionResourceToBeSwitchedDLList = new(ngapType.PDUSessionResourceToBeSwitchedDLList)
+
+	pDUSessionResourceToBeSwitchedDLList := ie.Value.PDUSessionResourceToBeSwitchedDLList
+
+	// PDU Session Resource to be Switched in Downlink Item in PDU Session Resource to be Switched in Downlink List
+	for i, pduSessionId := range pduSessionIdList {
+		pDUSessionResourceToBeSwitchedDLItem := ngapType.PDUSessionResourceToBeSwitchedDLItem{}
+		pDUSessionResourceToBeSwitchedDLItem.PDUSessionID.Value = pduSessionId
+		pDUSessionResourceToBeSwitchedDLItem.PathSwitchRequestTransfer = pathSwitchRequestTransferMessageList[i]
+
+		pDUSessionResourceToBeSwitchedDLList.List = append(pDUSessionResourceToBeSwitchedDLList.List, pDUSessionResourceToBeSwitchedDLItem)
+	}
+
+	pathSwitchRequestIEs.List = append(pathSwitchRequestIEs.List, ie)
+
+	return pdu
+}
+
+func getPathSwitchRequest(ranUeNgapId, sourceAmfUeNgapId int64, plmnId ngapType.PLMNIdentity, tai ngapType.TAI, ueSecurityCapabilities ngapType.UESecurityCapabilities, pduSessionIdList []int64, pathSwitchRequestTransferMessageList [][]byte) ([]byte, error) {
+	return ngap.Encoder(buildPathSwitchRequest(ranUeNgapId, sourceAmfUeNgapId, plmnId, tai, ueSecurityCapabilities, pduSessionIdList, pathSwitchRequestTransferMessageList))
+}
+
+// the target gNB releases the UE context of the source gNB over Xn once the path is switched, the RAN UE NGAP ID of
+// the source gNB is unknown to the target gNB, so the UE is identified by its AMF UE NGAP ID
+func buildUeContextReleaseCommand(amfUeNgapId int64, cause ngapType.Cause) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeUEContextRelease
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentReject
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentUEContextReleaseCommand
+	initiatingMessage.Value.UEContextReleaseCommand = new(ngapType.UEContextReleaseCommand)
+
+	uEContextReleaseCommand := initiatingMessage.Value.UEContextReleaseCommand
+	uEContextReleaseCommandIEs := &uEContextReleaseCommand.ProtocolIEs
+
+	// UE NGAP IDs
+	ie := ngapType.UEContextReleaseCommandIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDUENGAPIDs
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.UEContextReleaseCommandIEsPresentUENGAPIDs
+	ie.Value.UENGAPIDs = new(ngapType.UENGAPIDs)
+
+	uENGAPIDs := ie.Value.UENGAPIDs
+	uENGAPIDs.Present = ngapType.UENGAPIDsPresentAMFUENGAPID
+	uENGAPIDs.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+	uENGAPIDs.AMFUENGAPID.Value = amfUeNgapId
+
+	uEContextReleaseCommandIEs.List = append(uEContextReleaseCommandIEs.List, ie)
+
+	// Cause
+	ie = ngapType.UEContextReleaseCommandIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.UEContextReleaseCommandIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	*ie.Value.Cause = cause
+
+	uEContextReleaseCommandIEs.List = append(uEContextReleaseCommandIEs.List, ie)
+
+	return pdu
+}
+
+func getUeContextReleaseCommand(amfUeNgapId int64, cause ngapType.Cause) ([]byte, error) {
+	return ngap.Encoder(buildUeContextReleaseCommand(amfUeNgapId, cause))
+}
+
+// no direct forwarding path to the target gNB, the DL data is forwarded indirectly through the UPF
+func buildHandoverRequiredTransfer() ngapType.HandoverRequiredTransfer {
+	return ngapType.HandoverRequiredTransfer{}
+}
+
+func getHandoverRequiredTransfer() ([]byte, error) {
+	transferMessage := buildHandoverRequiredTransfer()
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal handover required transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+// there is no RRC in this RAN, the IMSI of the UE stands in for the RRC handover preparation information
+func buildSourceToTargetTransparentContainer(imsi string, plmnId ngapType.PLMNIdentity, gnbId []byte, targetTai ngapType.TAI, targetGnbId []byte) ngapType.SourceNGRANNodeToTargetNGRANNodeTransparentContainer {
+	container := ngapType.SourceNGRANNodeToTargetNGRANNodeTransparentContainer{}
+
+	// RRC Container
+	container.RRCContainer.Value = aper.OctetString(imsi)
+
+	// Target Cell ID
+	container.TargetCellID.Present = ngapType.NGRANCGIPresentNRCGI
+	container.TargetCellID.NRCGI = new(ngapType.NRCGI)
+	container.TargetCellID.NRCGI.PLMNIdentity.Value = targetTai.PLMNIdentity.Value
+	container.TargetCellID.NRCGI.NRCellIdentity.Value = buildNrCellIdentity(targetGnbId)
+
+	// UE History Information, the source cell is the only one visited
+	lastVisitedCellItem := ngapType.LastVisitedCellItem{}
+	lastVisitedCellItem.LastVisitedCellInformation.Present = ngapType.LastVisitedCellInformationPresentNGRANCell
+	lastVisitedCellItem.LastVisitedCellInformation.NGRANCell = new(ngapType.LastVisitedNGRANCellInformation)
+
+	ngRanCell := lastVisitedCellItem.LastVisitedCellInformation.NGRANCell
+	ngRanCell.GlobalCellID.Present = ngapType.NGRANCGIPresentNRCGI
+	ngRanCell.GlobalCellID.NRCGI = new(ngapType.NRCGI)
+	ngRanCell.GlobalCellID.NRCGI.PLMNIdentity.Value = plmnId.Value
+	ngRanCell.GlobalCellID.NRCGI.NRCellIdentity.Value = buildNrCellIdentity(gnbId)
+	ngRanCell.CellType.CellSize.Value = ngapType.CellSizePresentSmall
+
+	container.UEHistoryInformation.List = append(container.UEHistoryInformation.List, lastVisitedCellItem)
+
+	return container
+}
+
+func getSourceToTargetTransparentContainer(imsi string, plmnId ngapType.PLMNIdentity, gnbId []byte, targetTai ngapType.TAI, targetGnbId []byte) ([]byte, error) {
+	container := buildSourceToTargetTransparentContainer(imsi, plmnId, gnbId, targetTai, targetGnbId)
+	encodedContainer, err := aper.MarshalWithParams(container, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal source to target transparent container: %v", err)
+	}
+	return encodedContainer, nil
+}
+
+// the 36 bits NR cell identity starts with the gNB ID, the gNB has a single cell
+func buildNrCellIdentity(gnbId []byte) aper.BitString {
+	nrCellIdentity := make([]byte, 5)
+	copy(nrCellIdentity, gnbId)
+	return aper.BitString{
+		Bytes:     nrCellIdentity,
+		BitLength: 36,
+	}
+}
+
+// the source to target transparent container is forwarded by the AMF to the target gNB as is
+func buildHandoverRequired(amfUeNgapId, ranUeNgapId int64, targetGnbId []byte, targetTai ngapType.TAI, pduSessionIdList []int64, handoverRequiredTransferMessageList [][]byte, sourceToTargetTransparentContainer []byte) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeHandoverPreparation
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentReject
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentHandoverRequired
+	initiatingMessage.Value.HandoverRequired = new(ngapType.HandoverRequired)
+
+	handoverRequired := initiatingMessage.Value.HandoverRequired
+	handoverRequiredIEs := &handoverRequired.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	// Handover Type
+	ie = ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDHandoverType
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentHandoverType
+	ie.Value.HandoverType = new(ngapType.HandoverType)
+
+	handoverType := ie.Value.HandoverType
+	handoverType.Value = ngapType.HandoverTypePresentIntra5gs
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	// Cause
+	ie = ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	cause := ie.Value.Cause
+	cause.Present = ngapType.CausePresentRadioNetwork
+	cause.RadioNetwork = new(ngapType.CauseRadioNetwork)
+	cause.RadioNetwork.Value = ngapType.CauseRadioNetworkPresentHandoverDesirableForRadioReason
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	// Target ID
+	ie = ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDTargetID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentTargetID
+	ie.Value.TargetID = new(ngapType.TargetID)
+
+	targetID := ie.Value.TargetID
+	targetID.Present = ngapType.TargetIDPresentTargetRANNodeID
+	targetID.TargetRANNodeID = new(ngapType.TargetRANNodeID)
+
+	// Global RAN Node ID and Selected TAI in Target RAN Node ID
+	targetRANNodeID := targetID.TargetRANNodeID
+	targetRANNodeID.GlobalRANNodeID.Present = ngapType.GlobalRANNodeIDPresentGlobalGNBID
+	targetRANNodeID.GlobalRANNodeID.GlobalGNBID = new(ngapType.GlobalGNBID)
+
+	globalGNBID := targetRANNodeID.GlobalRANNodeID.GlobalGNBID
+	globalGNBID.PLMNIdentity.Value = targetTai.PLMNIdentity.Value
+	globalGNBID.GNBID.Present = ngapType.GNBIDPresentGNBID
+	globalGNBID.GNBID.GNBID = new(aper.BitString)
+	*globalGNBID.GNBID.GNBID = aper.BitString{
+		Bytes:     targetGnbId,
+		BitLength: uint64(len(targetGnbId) * 8),
+	}
+
+	targetRANNodeID.SelectedTAI.PLMNIdentity.Value = targetTai.PLMNIdentity.Value
+	targetRANNodeID.SelectedTAI.TAC.Value = targetTai.TAC.Value
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	// PDU Session Resource List HO Rqd
+	ie = ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceListHORqd
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentPDUSessionResourceListHORqd
+	ie.Value.PDUSessionResourceListHORqd = new(ngapType.PDUSessionResourceListHORqd)
+
+	pDUSessionResourceListHORqd := ie.Value.PDUSessionResourceListHORqd
+
+	// PDU Session Resource Item HO Rqd in PDU Session Resource List HO Rqd
+	for i, pduSessionId := range pduSessionIdList {
+		pDUSessionResourceItemHORqd := ngapType.PDUSessionResourceItemHORqd{}
+		pDUSessionResourceItemHORqd.PDUSessionID.Value = pduSessionId
+		pDUSessionResourceItemHORqd.HandoverRequiredTransfer = handoverRequiredTransferMessageList[i]
+
+		pDUSessionResourceListHORqd.List = append(pDUSessionResourceListHORqd.List, pDUSessionResourceItemHORqd)
+	}
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	// Source to Target Transparent Container
+	ie = ngapType.HandoverRequiredIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDSourceToTargetTransparentContainer
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverRequiredIEsPresentSourceToTargetTransparentContainer
+	ie.Value.SourceToTargetTransparentContainer = new(ngapType.SourceToTargetTransparentContainer)
+
+	sourceToTargetTransparentContainerIE := ie.Value.SourceToTargetTransparentContainer
+	sourceToTargetTransparentContainerIE.Value = sourceToTargetTransparentContainer
+
+	handoverRequiredIEs.List = append(handoverRequiredIEs.List, ie)
+
+	return pdu
+}
+
+func getHandoverRequired(amfUeNgapId, ranUeNgapId int64, targetGnbId []byte, targetTai ngapType.TAI, pduSessionIdList []int64, handoverRequiredTransferMessageList [][]byte, sourceToTargetTransparentContainer []byte) ([]byte, error) {
+	return ngap.Encoder(buildHandoverRequired(amfUeNgapId, ranUeNgapId, targetGnbId, targetTai, pduSessionIdList, handoverRequiredTransferMessageList, sourceToTargetTransparentContainer))
+}
+
+// the DL tunnel of the target gNB is also offered as the DL forwarding tunnel, so the data forwarded by the source gNB
+// reaches the UE once it is connected to the target gNB
+func buildHandoverRequestAcknowledgeTransfer(dlTeid []byte, ranN3Ip string, qosFlowIdList []int64) ngapType.HandoverRequestAcknowledgeTransfer {
+	transferMessage := ngapType.HandoverRequestAcknowledgeTransfer{}
+
+	// DL NG-U UP TNL Information
+	dLNGUUPTNLInformation := &transferMessage.DLNGUUPTNLInformation
+	dLNGUUPTNLInformation.Present = ngapType.UPTransportLayerInformationPresentGTPTunnel
+	dLNGUUPTNLInformation.GTPTunnel = new(ngapType.GTPTunnel)
+	dLNGUUPTNLInformation.GTPTunnel.GTPTEID.Value = aper.OctetString(dlTeid)
+	dLNGUUPTNLInformation.GTPTunnel.TransportLayerAddress = ngapConvert.IPAddressToNgap(ranN3Ip, "")
+
+	// DL Forwarding UP TNL Information
+	transferMessage.DLForwardingUPTNLInformation = new(ngapType.UPTransportLayerInformation)
+
+	dLForwardingUPTNLInformation := transferMessage.DLForwardingUPTNLInformation
+	dLForwardingUPTNLInformation.Present = ngapType.UPTransportLayerInformationPresentGTPTunnel
+	dLForwardingUPTNLInformation.GTPTunnel = new(ngapType.GTPTunnel)
+	dLForwardingUPTNLInformation.GTPTunnel.GTPTEID.Value = aper.OctetString(dlTeid)
+	dLForwardingUPTNLInformation.GTPTunnel.TransportLayerAddress = ngapConvert.IPAddressToNgap(ranN3Ip, "")
+
+	// QoS Flow Setup Response List
+	qosFlowSetupResponseList := &transferMessage.QosFlowSetupResponseList
+	for _, qosFlowId := range qosFlowIdList {
+		qosFlowItemWithDataForwarding := ngapType.QosFlowItemWithDataForwarding{}
+		qosFlowItemWithDataForwarding.QosFlowIdentifier.Value = qosFlowId
+		qosFlowItemWithDataForwarding.DataForwardingAccepted = new(ngapType.DataForwardingAccepted)
+		qosFlowItemWithDataForwarding.DataForwardingAccepted.Value = ngapType.DataForwardingAcceptedPresentDataForwardingAccepted
+		qosFlowSetupResponseList.List = append(qosFlowSetupResponseList.List, qosFlowItemWithDataForwarding)
+	}
+
+	return transferMessage
+}
+
+func getHandoverRequestAcknowledgeTransfer(dlTeid []byte, ranN3Ip string, qosFlowIdList []int64) ([]byte, error) {
+	transferMessage := buildHandoverRequestAcknowledgeTransfer(dlTeid, ranN3Ip, qosFlowIdList)
+	encodedTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+	if err != nil {
+		return nil, fmt.Errorf("error marshal handover request acknowledge transfer message: %v", err)
+	}
+	return encodedTransferMessage, nil
+}
+
+func buildHandoverFailure(amfUeNgapId int64, cause ngapType.Cause) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentUnsuccessfulOutcome
+	pdu.UnsuccessfulOutcome = new(ngapType.UnsuccessfulOutcome)
+
+	unsuccessfulOutcome := pdu.UnsuccessfulOutcome
+	unsuccessfulOutcome.ProcedureCode.Value = ngapType.ProcedureCodeHandoverResourceAllocation
+	unsuccessfulOutcome.Criticality.Value = ngapType.CriticalityPresentReject
+
+	unsuccessfulOutcome.Value.Present = ngapType.UnsuccessfulOutcomePresentHandoverFailure
+	unsuccessfulOutcome.Value.HandoverFailure = new(ngapType.HandoverFailure)
+
+	handoverFailure := unsuccessfulOutcome.Value.HandoverFailure
+	handoverFailureIEs := &handoverFailure.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.HandoverFailureIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverFailureIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	handoverFailureIEs.List = append(handoverFailureIEs.List, ie)
+
+	// Cause
+	ie = ngapType.HandoverFailureIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverFailureIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	*ie.Value.Cause = cause
+
+	handoverFailureIEs.List = append(handoverFailureIEs.List, ie)
+
+	return pdu
+}
+
+func getHandoverFailure(amfUeNgapId int64, cause ngapType.Cause) ([]byte, error) {
+	return ngap.Encoder(buildHandoverFailure(amfUeNgapId, cause))
+}
+
+func buildHandoverNotify(amfUeNgapId, ranUeNgapId int64, plmnId ngapType.PLMNIdentity, tai ngapType.TAI) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeHandoverNotification
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentIgnore
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentHandoverNotify
+	initiatingMessage.Value.HandoverNotify = new(ngapType.HandoverNotify)
+
+	handoverNotify := initiatingMessage.Value.HandoverNotify
+	handoverNotifyIEs := &handoverNotify.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.HandoverNotifyIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverNotifyIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	handoverNotifyIEs.List = append(handoverNotifyIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.HandoverNotifyIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverNotifyIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	handoverNotifyIEs.List = append(handoverNotifyIEs.List, ie)
+
+	// User Location Information
+	ie = ngapType.HandoverNotifyIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDUserLocationInformation
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverNotifyIEsPresentUserLocationInformation
+	ie.Value.UserLocationInformation = new(ngapType.UserLocationInformation)
+
+	userLocationInformation := ie.Value.UserLocationInformation
+	userLocationInformation.Present = ngapType.UserLocationInformationPresentUserLocationInformationNR
+	userLocationInformation.UserLocationInformationNR = new(ngapType.UserLocationInformationNR)
+
+	userLocationInformationNR := userLocationInformation.UserLocationInformationNR
+	userLocationInformationNR.NRCGI.PLMNIdentity.Value = plmnId.Value
+	userLocationInformationNR.NRCGI.NRCellIdentity.Value = aper.BitString{
+		Bytes:     []byte{0x00, 0x00, 0x00, 0x00, 0x10},
+		BitLength: 36,
+	}
+
+	userLocationInformationNR.TAI.PLMNIdentity.Value = tai.PLMNIdentity.Value
+	userLocationInformationNR.TAI.TAC.Value = tai.TAC.Value
+
+	handoverNotifyIEs.List = append(handoverNotifyIEs.List, ie)
+
+	return pdu
+}
+
+func getHandoverNotify(amfUeNgapId, ranUeNgapId int64, plmnId ngapType.PLMNIdentity, tai ngapType.TAI) ([]byte, error) {
+	return ngap.Encoder(buildHandoverNotify(amfUeNgapId, ranUeNgapId, plmnId, tai))
+}
+
+func buildHandoverCancel(amfUeNgapId, ranUeNgapId int64, cause ngapType.Cause) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeHandoverCancel
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentReject
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentHandoverCancel
+	initiatingMessage.Value.HandoverCancel = new(ngapType.HandoverCancel)
+
+	handoverCancel := initiatingMessage.Value.HandoverCancel
+	handoverCancelIEs := &handoverCancel.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.HandoverCancelIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverCancelIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	handoverCancelIEs.List = append(handoverCancelIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.HandoverCancelIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.HandoverCancelIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	handoverCancelIEs.List = append(handoverCancelIEs.List, ie)
+
+	// Cause
+	ie = ngapType.HandoverCancelIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDCause
+	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+	ie.Value.Present = ngapType.HandoverCancelIEsPresentCause
+	ie.Value.Cause = new(ngapType.Cause)
+
+	*ie.Value.Cause = cause
+
+	handoverCancelIEs.List = append(handoverCancelIEs.List, ie)
+
+	return pdu
+}
+
+func getHandoverCancel(amfUeNgapId, ranUeNgapId int64, cause ngapType.Cause) ([]byte, error) {
+	return ngap.Encoder(buildHandoverCancel(amfUeNgapId, ranUeNgapId, cause))
+}
+
+// there is no PDCP in this RAN, so every DRB is reported with the initial COUNT values
+func buildUplinkRanStatusTransfer(amfUeNgapId, ranUeNgapId int64, drbIdList []int64) ngapType.NGAPPDU {
+	pdu := ngapType.NGAPPDU{}
+
+	pdu.Present = ngapType.NGAPPDUPresentInitiatingMessage
+	pdu.InitiatingMessage = new(ngapType.InitiatingMessage)
+
+	initiatingMessage := pdu.InitiatingMessage
+	initiatingMessage.ProcedureCode.Value = ngapType.ProcedureCodeUplinkRANStatusTransfer
+	initiatingMessage.Criticality.Value = ngapType.CriticalityPresentIgnore
+
+	initiatingMessage.Value.Present = ngapType.InitiatingMessagePresentUplinkRANStatusTransfer
+	initiatingMessage.Value.UplinkRANStatusTransfer = new(ngapType.UplinkRANStatusTransfer)
+
+	uplinkRANStatusTransfer := initiatingMessage.Value.UplinkRANStatusTransfer
+	uplinkRANStatusTransferIEs := &uplinkRANStatusTransfer.ProtocolIEs
+
+	// AMF UE NGAP ID
+	ie := ngapType.UplinkRANStatusTransferIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.UplinkRANStatusTransferIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = new(ngapType.AMFUENGAPID)
+
+	aMFUENGAPID := ie.Value.AMFUENGAPID
+	aMFUENGAPID.Value = amfUeNgapId
+
+	uplinkRANStatusTransferIEs.List = append(uplinkRANStatusTransferIEs.List, ie)
+
+	// RAN UE NGAP ID
+	ie = ngapType.UplinkRANStatusTransferIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANUENGAPID
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.UplinkRANStatusTransferIEsPresentRANUENGAPID
+	ie.Value.RANUENGAPID = new(ngapType.RANUENGAPID)
+
+	rANUENGAPID := ie.Value.RANUENGAPID
+	rANUENGAPID.Value = ranUeNgapId
+
+	uplinkRANStatusTransferIEs.List = append(uplinkRANStatusTransferIEs.List, ie)
+
+	// RAN Status Transfer Transparent Container
+	ie = ngapType.UplinkRANStatusTransferIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDRANStatusTransferTransparentContainer
+	ie.Criticality.Value = ngapType.CriticalityPresentReject
+	ie.Value.Present = ngapType.UplinkRANStatusTransferIEsPresentRANStatusTransferTransparentContainer
+	ie.Value.RANStatusTransferTransparentContainer = new(ngapType.RANStatusTransferTransparentContainer)
+
+	dRBsSubjectToStatusTransferList := &ie.Value.RANStatusTransferTransparentContainer.DRBsSubjectToStatusTransferList
+
+	// DRBs Subject to Status Transfer Item in DRBs Subject to Status Transfer List
+	for _, drbId := range drbIdList {
+		dRBsSubjectToStatusTransferItem := ngapType.DRBsSubjectToStatusTransferItem{}
+		dRBsSubjectToStatusTransferItem.DRBID.Value = drbId
+
+		dRBsSubjectToStatusTransferItem.DRBStatusUL.Present = ngapType.DRBStatusULPresentDRBStatusUL12
+		dRBsSubjectToStatusTransferItem.DRBStatusUL.DRBStatusUL12 = new(ngapType.DRBStatusUL12)
+
+		dRBsSubjectToStatusTransferItem.DRBStatusDL.Present = ngapType.DRBStatusDLPresentDRBStatusDL12
+		dRBsSubjectToStatusTransferItem.DRBStatusDL.DRBStatusDL12 = new(ngapType.DRBStatusDL12)
+
+		dRBsSubjectToStatusTransferList.List = append(dRBsSubjectToStatusTransferList.List, dRBsSubjectToStatusTransferItem)
+	}
+
+	uplinkRANStatusTransferIEs.List = append(uplinkRANStatusTransferIEs.List, ie)
+
+	return pdu
+}
+
+func getUplinkRanStatusTransfer(amfUeNgapId, ranUeNgapId int64, drbIdList []int64) ([]byte, error) {
+	return ngap.Encoder(buildUplinkRanStatusTransfer(amfUeNgapId, ranUeNgapId, drbIdList))
+}
@@ -11,12 +11,11 @@ import (
 )
 
 var testBuildNgapSetupRequestCases = []struct {
-	name    string
-	gnbId   []byte
-	gnbName string
-	plmnId  ngapType.PLMNIdentity
-	tai     ngapType.TAI
-	snssai  ngapType.SNSSAI
+	name            string
+	gnbId           []byte
+	gnbName         string
+	plmnId          ngapType.PLMNIdentity
+	supportedTaList ngapType.SupportedTAList
 }{
 	{
 		name:    "testBuildNgapSetupRequest",
@@ -25,20 +24,130 @@ var testBuildNgapSetupRequestCases = []struct {
 		plmnId: ngapType.PLMNIdentity{
 			Value: aper.OctetString("\x02\xF8\x39"),
 		},
-		tai: ngapType.TAI{
-			TAC: ngapType.TAC{
-				Value: aper.OctetString("\x00\x00\x01"),
-			},
-			PLMNIdentity: ngapType.PLMNIdentity{
-				Value: aper.OctetString("\x02\xF8\x39"),
+		supportedTaList: ngapType.SupportedTAList{
+			List: []ngapType.SupportedTAItem{
+				{
+					TAC: ngapType.TAC{
+						Value: aper.OctetString("\x00\x00\x01"),
+					},
+					BroadcastPLMNList: ngapType.BroadcastPLMNList{
+						List: []ngapType.BroadcastPLMNItem{
+							{
+								PLMNIdentity: ngapType.PLMNIdentity{
+									Value: aper.OctetString("\x02\xF8\x39"),
+								},
+								TAISliceSupportList: ngapType.SliceSupportList{
+									List: []ngapType.SliceSupportItem{
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{
+													Value: aper.OctetString("\x01"),
+												},
+												SD: &ngapType.SD{
+													Value: aper.OctetString("\x01\x02\x03"),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
 			},
 		},
-		snssai: ngapType.SNSSAI{
-			SST: ngapType.SST{
-				Value: aper.OctetString("\x01"),
-			},
-			SD: &ngapType.SD{
-				Value: aper.OctetString("\x01\x02\x03"),
+	},
+	{
+		name:    "testBuildNgapSetupRequestWithMultipleTaAndSlice",
+		gnbId:   []byte("\x00\x03\x14"),
+		gnbName: "gNB",
+		plmnId: ngapType.PLMNIdentity{
+			Value: aper.OctetString("\x02\xF8\x39"),
+		},
+		supportedTaList: ngapType.SupportedTAList{
+			List: []ngapType.SupportedTAItem{
+				{
+					TAC: ngapType.TAC{
+						Value: aper.OctetString("\x00\x00\x01"),
+					},
+					BroadcastPLMNList: ngapType.BroadcastPLMNList{
+						List: []ngapType.BroadcastPLMNItem{
+							{
+								PLMNIdentity: ngapType.PLMNIdentity{
+									Value: aper.OctetString("\x02\xF8\x39"),
+								},
+								TAISliceSupportList: ngapType.SliceSupportList{
+									List: []ngapType.SliceSupportItem{
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{
+													Value: aper.OctetString("\x01"),
+												},
+												SD: &ngapType.SD{
+													Value: aper.OctetString("\x01\x02\x03"),
+												},
+											},
+										},
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{
+													Value: aper.OctetString("\x01"),
+												},
+												SD: &ngapType.SD{
+													Value: aper.OctetString("\x11\x22\x33"),
+												},
+											},
+										},
+									},
+								},
+							},
+							{
+								PLMNIdentity: ngapType.PLMNIdentity{
+									Value: aper.OctetString("\x64\xF6\x29"),
+								},
+								TAISliceSupportList: ngapType.SliceSupportList{
+									List: []ngapType.SliceSupportItem{
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{
+													Value: aper.OctetString("\x02"),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					TAC: ngapType.TAC{
+						Value: aper.OctetString("\x00\x00\x02"),
+					},
+					BroadcastPLMNList: ngapType.BroadcastPLMNList{
+						List: []ngapType.BroadcastPLMNItem{
+							{
+								PLMNIdentity: ngapType.PLMNIdentity{
+									Value: aper.OctetString("\x02\xF8\x39"),
+								},
+								TAISliceSupportList: ngapType.SliceSupportList{
+									List: []ngapType.SliceSupportItem{
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{
+													Value: aper.OctetString("\x01"),
+												},
+												SD: &ngapType.SD{
+													Value: aper.OctetString("\x01\x02\x03"),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
 			},
 		},
 	},
@@ -47,7 +156,7 @@ var testBuildNgapSetupRequestCases = []struct {
 func TestBuildNgapSetupRequest(t *testing.T) {
 	for _, testCase := range testBuildNgapSetupRequestCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			pdu := buildNgapSetupRequest(testCase.gnbId, testCase.gnbName, testCase.plmnId, testCase.tai, testCase.snssai)
+			pdu := buildNgapSetupRequest(testCase.gnbId, testCase.gnbName, testCase.plmnId, testCase.supportedTaList)
 			encodeData, err := ngap.Encoder(pdu)
 			if err != nil {
 				t.Fatalf("Failed to encode NGAP setup request: %v", err)
@@ -69,6 +178,8 @@ var testBuildIntialUeMessageCases = []struct {
 	ueRegistrationRequest []byte
 	plmnId                ngapType.PLMNIdentity
 	tai                   ngapType.TAI
+	rrcEstablishmentCause aper.Enumerated
+	fiveGSTmsi            *ngapType.FiveGSTMSI
 }{
 	{
 		name:                  "testBuildIntialUeMessage",
@@ -85,13 +196,42 @@ var testBuildIntialUeMessageCases = []struct {
 				Value: aper.OctetString("\x02\xF8\x39"),
 			},
 		},
+		rrcEstablishmentCause: ngapType.RRCEstablishmentCausePresentMtAccess,
+	},
+	{
+		name:                  "testBuildIntialUeMessageWithFiveGSTmsi",
+		ranUeNgapId:           1,
+		ueRegistrationRequest: []byte("\x7e\x00\x4c\x01\x00\x07\xf4\xca\xfe\x00\x00\x00\x01"),
+		plmnId: ngapType.PLMNIdentity{
+			Value: aper.OctetString("\x02\xF8\x39"),
+		},
+		tai: ngapType.TAI{
+			TAC: ngapType.TAC{
+				Value: aper.OctetString("\x00\x00\x01"),
+			},
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: aper.OctetString("\x02\xF8\x39"),
+			},
+		},
+		rrcEstablishmentCause: ngapType.RRCEstablishmentCausePresentMoData,
+		fiveGSTmsi: &ngapType.FiveGSTMSI{
+			AMFSetID: ngapType.AMFSetID{
+				Value: aper.BitString{Bytes: []byte{0xca, 0xc0}, BitLength: 10},
+			},
+			AMFPointer: ngapType.AMFPointer{
+				Value: aper.BitString{Bytes: []byte{0xf8}, BitLength: 6},
+			},
+			FiveGTMSI: ngapType.FiveGTMSI{
+				Value: aper.OctetString("\x00\x00\x00\x01"),
+			},
+		},
 	},
 }
 
 func TestBuildIntialUeMessage(t *testing.T) {
 	for _, testCase := range testBuildIntialUeMessageCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			pdu := buildInitialUeMessage(testCase.ranUeNgapId, testCase.ueRegistrationRequest, testCase.plmnId, testCase.tai)
+			pdu := buildInitialUeMessage(testCase.ranUeNgapId, testCase.ueRegistrationRequest, testCase.plmnId, testCase.tai, testCase.rrcEstablishmentCause, testCase.fiveGSTmsi)
 			encodeData, err := ngap.Encoder(pdu)
 			if err != nil {
 				t.Fatalf("Failed to encode NGAP initial ue message: %v", err)
@@ -154,21 +294,30 @@ func TestBuildUplinkNasTransport(t *testing.T) {
 }
 
 var testBuildNgapInitialContextSetupResponseCases = []struct {
-	name        string
-	amfUeNgapId int64
-	ranUeNgapId int64
+	name                string
+	amfUeNgapId         int64
+	ranUeNgapId         int64
+	pduSessionIdList    []int64
+	transferMessageList [][]byte
 }{
 	{
 		name:        "testBuildNgapInitialContextSetupResponse",
 		amfUeNgapId: 1,
 		ranUeNgapId: 1,
 	},
+	{
+		name:                "testBuildNgapInitialContextSetupResponseWithPduSessions",
+		amfUeNgapId:         1,
+		ranUeNgapId:         1,
+		pduSessionIdList:    []int64{1, 2},
+		transferMessageList: [][]byte{[]byte("\x00\x03\x00\x00"), []byte("\x00\x03\x00\x01")},
+	},
 }
 
 func TestBuildNgapInitialContextSetupResponse(t *testing.T) {
 	for _, testCase := range testBuildNgapInitialContextSetupResponseCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			pdu := buildNgapInitialContextSetupResponse(testCase.amfUeNgapId, testCase.ranUeNgapId)
+			pdu := buildNgapInitialContextSetupResponse(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.pduSessionIdList, testCase.transferMessageList)
 			encodeData, err := ngap.Encoder(pdu)
 			if err != nil {
 				t.Fatalf("Failed to encode NGAP initial context setup response: %v", err)
@@ -274,25 +423,32 @@ func TestBuildPduSessionResourceSetupResponseTransferMessageWithNRDCases(t *test
 }
 
 var testBuildPduSessionResourceSetupResponseCases = []struct {
-	name            string
-	amfUeNgapId     int64
-	ranUeNgapId     int64
-	pduSessionId    int64
-	transferMessage []byte
+	name                string
+	amfUeNgapId         int64
+	ranUeNgapId         int64
+	pduSessionIdList    []int64
+	transferMessageList [][]byte
 }{
 	{
-		name:            "testBuildPduSessionResourceSetupResponse",
-		amfUeNgapId:     1,
-		ranUeNgapId:     1,
-		pduSessionId:    1,
-		transferMessage: []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+		name:                "testBuildPduSessionResourceSetupResponse",
+		amfUeNgapId:         1,
+		ranUeNgapId:         1,
+		pduSessionIdList:    []int64{1},
+		transferMessageList: [][]byte{[]byte("\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")},
+	},
+	{
+		name:                "testBuildPduSessionResourceSetupResponseMultipleSessions",
+		amfUeNgapId:         1,
+		ranUeNgapId:         1,
+		pduSessionIdList:    []int64{1, 2},
+		transferMessageList: [][]byte{[]byte("\x00\x03\x00\x00"), []byte("\x00\x03\x00\x01")},
 	},
 }
 
 func TestBuildPduSessionResourceSetupResponse(t *testing.T) {
 	for _, testCase := range testBuildPduSessionResourceSetupResponseCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			pdu := buildPduSessionResourceSetupResponse(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.pduSessionId, testCase.transferMessage)
+			pdu := buildPduSessionResourceSetupResponse(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.pduSessionIdList, testCase.transferMessageList)
 			encodeData, err := ngap.Encoder(pdu)
 			if err != nil {
 				t.Fatalf("Failed to encode NGAP pdu session resource setup response: %v", err)
@@ -386,3 +542,913 @@ func TestBuildPDUSessionResourceModifyIndicationTransfer(t *testing.T) {
 		})
 	}
 }
+
+var testBuildPduSessionResourceReleaseResponseCases = []struct {
+	name             string
+	amfUeNgapId      int64
+	ranUeNgapId      int64
+	pduSessionIdList []int64
+	transferMessage  []byte
+}{
+	{
+		name:             "testBuildPduSessionResourceReleaseResponse",
+		amfUeNgapId:      1,
+		ranUeNgapId:      1,
+		pduSessionIdList: []int64{1},
+		transferMessage:  []byte("\x00"),
+	},
+	{
+		name:             "testBuildPduSessionResourceReleaseResponseWithMultiplePduSessions",
+		amfUeNgapId:      1,
+		ranUeNgapId:      1,
+		pduSessionIdList: []int64{1, 2},
+		transferMessage:  []byte("\x00"),
+	},
+}
+
+func TestBuildPduSessionResourceReleaseResponse(t *testing.T) {
+	for _, testCase := range testBuildPduSessionResourceReleaseResponseCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildPduSessionResourceReleaseResponse(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.pduSessionIdList, testCase.transferMessage)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP pdu session resource release response: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP pdu session resource release response: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP pdu session resource release response mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildPduSessionResourceModifyResponseTransferCases = []struct {
+	name                string
+	qosFlowIdList       []int64
+	failedQosFlowIdList []int64
+}{
+	{
+		name:                "testBuildPduSessionResourceModifyResponseTransfer",
+		qosFlowIdList:       []int64{1, 2},
+		failedQosFlowIdList: nil,
+	},
+	{
+		name:                "testBuildPduSessionResourceModifyResponseTransferWithFailedQosFlow",
+		qosFlowIdList:       []int64{1},
+		failedQosFlowIdList: []int64{3},
+	},
+}
+
+func TestBuildPduSessionResourceModifyResponseTransfer(t *testing.T) {
+	for _, testCase := range testBuildPduSessionResourceModifyResponseTransferCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferMessage := buildPduSessionResourceModifyResponseTransfer(testCase.qosFlowIdList, testCase.failedQosFlowIdList)
+			encodeTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+			if err != nil {
+				t.Fatalf("Failed to marshal pdu session resource modify response transfer message: %v", err)
+			} else {
+				decodeTransferMessage := &ngapType.PDUSessionResourceModifyResponseTransfer{}
+				if err := aper.UnmarshalWithParams(encodeTransferMessage, decodeTransferMessage, "valueExt"); err != nil {
+					t.Fatalf("Failed to unmarshal pdu session resource modify response transfer message: %v", err)
+				} else if !reflect.DeepEqual(transferMessage, *decodeTransferMessage) {
+					t.Fatalf("PDU session resource modify response transfer message mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildPduSessionResourceModifyResponseCases = []struct {
+	name             string
+	amfUeNgapId      int64
+	ranUeNgapId      int64
+	modifiedItemList []ngapType.PDUSessionResourceModifyItemModRes
+	failedItemList   []ngapType.PDUSessionResourceFailedToModifyItemModRes
+}{
+	{
+		name:        "testBuildPduSessionResourceModifyResponse",
+		amfUeNgapId: 1,
+		ranUeNgapId: 1,
+		modifiedItemList: []ngapType.PDUSessionResourceModifyItemModRes{
+			{
+				PDUSessionID:                             ngapType.PDUSessionID{Value: 1},
+				PDUSessionResourceModifyResponseTransfer: []byte("\x08\x00\x01\x00\x02"),
+			},
+			{
+				PDUSessionID:                             ngapType.PDUSessionID{Value: 2},
+				PDUSessionResourceModifyResponseTransfer: []byte("\x08\x00\x01\x00\x02"),
+			},
+		},
+	},
+	{
+		name:        "testBuildPduSessionResourceModifyResponseWithFailedList",
+		amfUeNgapId: 1,
+		ranUeNgapId: 1,
+		modifiedItemList: []ngapType.PDUSessionResourceModifyItemModRes{
+			{
+				PDUSessionID:                             ngapType.PDUSessionID{Value: 1},
+				PDUSessionResourceModifyResponseTransfer: []byte("\x08\x00\x01\x00\x02"),
+			},
+		},
+		failedItemList: []ngapType.PDUSessionResourceFailedToModifyItemModRes{
+			{
+				PDUSessionID: ngapType.PDUSessionID{Value: 2},
+				PDUSessionResourceModifyUnsuccessfulTransfer: []byte("\x00\x68"),
+			},
+		},
+	},
+}
+
+func TestBuildPduSessionResourceModifyResponse(t *testing.T) {
+	for _, testCase := range testBuildPduSessionResourceModifyResponseCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildPduSessionResourceModifyResponse(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.modifiedItemList, testCase.failedItemList)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP pdu session resource modify response: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP pdu session resource modify response: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP pdu session resource modify response mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildNgResetCases = []struct {
+	name              string
+	cause             ngapType.Cause
+	partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList
+}{
+	{
+		name: "testBuildNgResetNgInterface",
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentMisc,
+			Misc: &ngapType.CauseMisc{
+				Value: ngapType.CauseMiscPresentOmIntervention,
+			},
+		},
+		partOfNgInterface: nil,
+	},
+	{
+		name: "testBuildNgResetPartOfNgInterface",
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentMisc,
+			Misc: &ngapType.CauseMisc{
+				Value: ngapType.CauseMiscPresentOmIntervention,
+			},
+		},
+		partOfNgInterface: &ngapType.UEAssociatedLogicalNGConnectionList{
+			List: []ngapType.UEAssociatedLogicalNGConnectionItem{
+				{
+					AMFUENGAPID: &ngapType.AMFUENGAPID{Value: 1},
+					RANUENGAPID: &ngapType.RANUENGAPID{Value: 1},
+				},
+			},
+		},
+	},
+}
+
+func TestBuildNgReset(t *testing.T) {
+	for _, testCase := range testBuildNgResetCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildNgReset(testCase.cause, testCase.partOfNgInterface)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP ng reset: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP ng reset: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP ng reset mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildNgResetAcknowledgeCases = []struct {
+	name              string
+	partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList
+}{
+	{
+		name:              "testBuildNgResetAcknowledgeNgInterface",
+		partOfNgInterface: nil,
+	},
+	{
+		name: "testBuildNgResetAcknowledgePartOfNgInterface",
+		partOfNgInterface: &ngapType.UEAssociatedLogicalNGConnectionList{
+			List: []ngapType.UEAssociatedLogicalNGConnectionItem{
+				{
+					AMFUENGAPID: &ngapType.AMFUENGAPID{Value: 1},
+					RANUENGAPID: &ngapType.RANUENGAPID{Value: 1},
+				},
+			},
+		},
+	},
+}
+
+func TestBuildNgResetAcknowledge(t *testing.T) {
+	for _, testCase := range testBuildNgResetAcknowledgeCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildNgResetAcknowledge(testCase.partOfNgInterface)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP ng reset acknowledge: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP ng reset acknowledge: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP ng reset acknowledge mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildUeContextReleaseRequestCases = []struct {
+	name             string
+	amfUeNgapId      int64
+	ranUeNgapId      int64
+	pduSessionIdList []int64
+	cause            ngapType.Cause
+}{
+	{
+		name:             "testBuildUeContextReleaseRequestRadioConnectionWithUeLost",
+		amfUeNgapId:      1,
+		ranUeNgapId:      1,
+		pduSessionIdList: []int64{1, 2},
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost,
+			},
+		},
+	},
+	{
+		name:             "testBuildUeContextReleaseRequestUserInactivity",
+		amfUeNgapId:      1,
+		ranUeNgapId:      1,
+		pduSessionIdList: []int64{1},
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: ngapType.CauseRadioNetworkPresentUserInactivity,
+			},
+		},
+	},
+	{
+		name:             "testBuildUeContextReleaseRequestWithoutPduSession",
+		amfUeNgapId:      1,
+		ranUeNgapId:      1,
+		pduSessionIdList: []int64{},
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost,
+			},
+		},
+	},
+}
+
+func TestBuildUeContextReleaseRequest(t *testing.T) {
+	for _, testCase := range testBuildUeContextReleaseRequestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildUeContextReleaseRequest(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.pduSessionIdList, testCase.cause)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP ue context release request: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP ue context release request: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP ue context release request mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverRequestTransferCases = []struct {
+	name          string
+	ulTeid        []byte
+	upfN3Ip       string
+	qosFlowIdList []int64
+	fiveQiList    []int64
+}{
+	{
+		name:          "testBuildHandoverRequestTransfer",
+		ulTeid:        []byte("\x00\x00\x00\x01"),
+		upfN3Ip:       "127.0.0.1",
+		qosFlowIdList: []int64{1},
+		fiveQiList:    []int64{9},
+	},
+	{
+		name:          "testBuildHandoverRequestTransferMultipleQosFlows",
+		ulTeid:        []byte("\x00\x00\x00\x01"),
+		upfN3Ip:       "127.0.0.1",
+		qosFlowIdList: []int64{1, 2},
+		fiveQiList:    []int64{9, 5},
+	},
+}
+
+func TestBuildHandoverRequestTransfer(t *testing.T) {
+	for _, testCase := range testBuildHandoverRequestTransferCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferMessage := buildHandoverRequestTransfer(testCase.ulTeid, testCase.upfN3Ip, testCase.qosFlowIdList, testCase.fiveQiList)
+			encodeTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+			if err != nil {
+				t.Fatalf("Failed to marshal handover request transfer message: %v", err)
+			} else {
+				decodeTransferMessage := &ngapType.PDUSessionResourceSetupRequestTransfer{}
+				if err := aper.UnmarshalWithParams(encodeTransferMessage, decodeTransferMessage, "valueExt"); err != nil {
+					t.Fatalf("Failed to unmarshal handover request transfer message: %v", err)
+				} else if !reflect.DeepEqual(transferMessage, *decodeTransferMessage) {
+					t.Fatalf("Handover request transfer message mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverRequestCases = []struct {
+	name                   string
+	amfUeNgapId            int64
+	ueSecurityCapabilities ngapType.UESecurityCapabilities
+	guami                  ngapType.GUAMI
+	pduSessionIdList       []int64
+	snssaiList             []ngapType.SNSSAI
+	transferMessageList    [][]byte
+}{
+	{
+		name:        "testBuildHandoverRequest",
+		amfUeNgapId: 1,
+		ueSecurityCapabilities: ngapType.UESecurityCapabilities{
+			NRencryptionAlgorithms: ngapType.NRencryptionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0xe0, 0x00}, BitLength: 16},
+			},
+			NRintegrityProtectionAlgorithms: ngapType.NRintegrityProtectionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0xe0, 0x00}, BitLength: 16},
+			},
+			EUTRAencryptionAlgorithms: ngapType.EUTRAencryptionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0x00, 0x00}, BitLength: 16},
+			},
+			EUTRAintegrityProtectionAlgorithms: ngapType.EUTRAintegrityProtectionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0x00, 0x00}, BitLength: 16},
+			},
+		},
+		guami: ngapType.GUAMI{
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: aper.OctetString("\x02\xF8\x39"),
+			},
+			AMFRegionID: ngapType.AMFRegionID{
+				Value: aper.BitString{Bytes: []byte{0xca}, BitLength: 8},
+			},
+			AMFSetID: ngapType.AMFSetID{
+				Value: aper.BitString{Bytes: []byte{0xfe, 0x00}, BitLength: 10},
+			},
+			AMFPointer: ngapType.AMFPointer{
+				Value: aper.BitString{Bytes: []byte{0x00}, BitLength: 6},
+			},
+		},
+		pduSessionIdList: []int64{1, 2},
+		snssaiList: []ngapType.SNSSAI{
+			{
+				SST: ngapType.SST{Value: aper.OctetString("\x01")},
+				SD:  &ngapType.SD{Value: aper.OctetString("\x01\x02\x03")},
+			},
+			{
+				SST: ngapType.SST{Value: aper.OctetString("\x01")},
+			},
+		},
+		transferMessageList: [][]byte{[]byte("\x00\x00\x00\x00"), []byte("\x00\x00\x00\x00")},
+	},
+}
+
+func TestBuildHandoverRequest(t *testing.T) {
+	for _, testCase := range testBuildHandoverRequestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildHandoverRequest(testCase.amfUeNgapId, testCase.ueSecurityCapabilities, testCase.guami, testCase.pduSessionIdList, testCase.snssaiList, testCase.transferMessageList)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP handover request: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP handover request: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP handover request mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverRequestAcknowledgeCases = []struct {
+	name                               string
+	amfUeNgapId                        int64
+	ranUeNgapId                        int64
+	pduSessionIdList                   []int64
+	transferMessageList                [][]byte
+	targetToSourceTransparentContainer []byte
+}{
+	{
+		name:                               "testBuildHandoverRequestAcknowledge",
+		amfUeNgapId:                        1,
+		ranUeNgapId:                        0,
+		targetToSourceTransparentContainer: []byte("handover command 127.0.0.1:31413 127.0.0.1:31414"),
+	},
+	{
+		name:                               "testBuildHandoverRequestAcknowledgeAdmittedPduSessions",
+		amfUeNgapId:                        1,
+		ranUeNgapId:                        2,
+		pduSessionIdList:                   []int64{1, 2},
+		transferMessageList:                [][]byte{[]byte("\x00\x00\x00\x00"), []byte("\x00\x00\x00\x00")},
+		targetToSourceTransparentContainer: []byte("handover command 127.0.0.1:31413 127.0.0.1:31414"),
+	},
+}
+
+func TestBuildHandoverRequestAcknowledge(t *testing.T) {
+	for _, testCase := range testBuildHandoverRequestAcknowledgeCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildHandoverRequestAcknowledge(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.pduSessionIdList, testCase.transferMessageList, testCase.targetToSourceTransparentContainer)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP handover request acknowledge: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP handover request acknowledge: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP handover request acknowledge mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildPathSwitchRequestTransferCases = []struct {
+	name          string
+	dlTeid        []byte
+	ranN3Ip       string
+	qosFlowIdList []int64
+}{
+	{
+		name:          "testBuildPathSwitchRequestTransfer",
+		dlTeid:        []byte("\x00\x00\x00\x01"),
+		ranN3Ip:       "127.0.0.1",
+		qosFlowIdList: []int64{1},
+	},
+	{
+		name:          "testBuildPathSwitchRequestTransferMultipleQosFlows",
+		dlTeid:        []byte("\x00\x00\x00\x01"),
+		ranN3Ip:       "127.0.0.1",
+		qosFlowIdList: []int64{1, 2},
+	},
+}
+
+func TestBuildPathSwitchRequestTransfer(t *testing.T) {
+	for _, testCase := range testBuildPathSwitchRequestTransferCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferMessage := buildPathSwitchRequestTransfer(testCase.dlTeid, testCase.ranN3Ip, testCase.qosFlowIdList)
+			encodeTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+			if err != nil {
+				t.Fatalf("Failed to marshal path switch request transfer message: %v", err)
+			} else {
+				decodeTransferMessage := &ngapType.PathSwitchRequestTransfer{}
+				if err := aper.UnmarshalWithParams(encodeTransferMessage, decodeTransferMessage, "valueExt"); err != nil {
+					t.Fatalf("Failed to unmarshal path switch request transfer message: %v", err)
+				} else if !reflect.DeepEqual(transferMessage, *decodeTransferMessage) {
+					t.Fatalf("Path switch request transfer message mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildPathSwitchRequestCases = []struct {
+	name                   string
+	ranUeNgapId            int64
+	sourceAmfUeNgapId      int64
+	plmnId                 ngapType.PLMNIdentity
+	tai                    ngapType.TAI
+	ueSecurityCapabilities ngapType.UESecurityCapabilities
+	pduSessionIdList       []int64
+	transferMessageList    [][]byte
+}{
+	{
+		name:              "testBuildPathSwitchRequest",
+		ranUeNgapId:       2,
+		sourceAmfUeNgapId: 1,
+		plmnId: ngapType.PLMNIdentity{
+			Value: aper.OctetString("\x02\xF8\x39"),
+		},
+		tai: ngapType.TAI{
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: aper.OctetString("\x02\xF8\x39"),
+			},
+			TAC: ngapType.TAC{
+				Value: aper.OctetString("\x00\x00\x01"),
+			},
+		},
+		ueSecurityCapabilities: ngapType.UESecurityCapabilities{
+			NRencryptionAlgorithms: ngapType.NRencryptionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0xe0, 0x00}, BitLength: 16},
+			},
+			NRintegrityProtectionAlgorithms: ngapType.NRintegrityProtectionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0xe0, 0x00}, BitLength: 16},
+			},
+			EUTRAencryptionAlgorithms: ngapType.EUTRAencryptionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0x00, 0x00}, BitLength: 16},
+			},
+			EUTRAintegrityProtectionAlgorithms: ngapType.EUTRAintegrityProtectionAlgorithms{
+				Value: aper.BitString{Bytes: []byte{0x00, 0x00}, BitLength: 16},
+			},
+		},
+		pduSessionIdList:    []int64{1, 2},
+		transferMessageList: [][]byte{[]byte("\x00\x00\x00\x00"), []byte("\x00\x00\x00\x00")},
+	},
+}
+
+func TestBuildPathSwitchRequest(t *testing.T) {
+	for _, testCase := range testBuildPathSwitchRequestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildPathSwitchRequest(testCase.ranUeNgapId, testCase.sourceAmfUeNgapId, testCase.plmnId, testCase.tai, testCase.ueSecurityCapabilities, testCase.pduSessionIdList, testCase.transferMessageList)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP path switch request: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP path switch request: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP path switch request mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildUeContextReleaseCommandCases = []struct {
+	name        string
+	amfUeNgapId int64
+	cause       ngapType.Cause
+}{
+	{
+		name:        "testBuildUeContextReleaseCommandSuccessfulHandover",
+		amfUeNgapId: 1,
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: ngapType.CauseRadioNetworkPresentSuccessfulHandover,
+			},
+		},
+	},
+}
+
+func TestBuildUeContextReleaseCommand(t *testing.T) {
+	for _, testCase := range testBuildUeContextReleaseCommandCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildUeContextReleaseCommand(testCase.amfUeNgapId, testCase.cause)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP ue context release command: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP ue context release command: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP ue context release command mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverRequiredTransferCases = []struct {
+	name string
+}{
+	{
+		name: "testBuildHandoverRequiredTransfer",
+	},
+}
+
+func TestBuildHandoverRequiredTransfer(t *testing.T) {
+	for _, testCase := range testBuildHandoverRequiredTransferCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferMessage := buildHandoverRequiredTransfer()
+			encodeTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+			if err != nil {
+				t.Fatalf("Failed to marshal handover required transfer message: %v", err)
+			} else {
+				decodeTransferMessage := &ngapType.HandoverRequiredTransfer{}
+				if err := aper.UnmarshalWithParams(encodeTransferMessage, decodeTransferMessage, "valueExt"); err != nil {
+					t.Fatalf("Failed to unmarshal handover required transfer message: %v", err)
+				} else if !reflect.DeepEqual(transferMessage, *decodeTransferMessage) {
+					t.Fatalf("Handover required transfer message mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildSourceToTargetTransparentContainerCases = []struct {
+	name        string
+	imsi        string
+	plmnId      ngapType.PLMNIdentity
+	gnbId       []byte
+	targetTai   ngapType.TAI
+	targetGnbId []byte
+}{
+	{
+		name: "testBuildSourceToTargetTransparentContainer",
+		imsi: "imsi-208930000000001",
+		plmnId: ngapType.PLMNIdentity{
+			Value: aper.OctetString("\x02\xF8\x39"),
+		},
+		gnbId: []byte("\x00\x03\x13"),
+		targetTai: ngapType.TAI{
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: aper.OctetString("\x02\xF8\x39"),
+			},
+			TAC: ngapType.TAC{
+				Value: aper.OctetString("\x00\x00\x01"),
+			},
+		},
+		targetGnbId: []byte("\x00\x03\x14"),
+	},
+}
+
+func TestBuildSourceToTargetTransparentContainer(t *testing.T) {
+	for _, testCase := range testBuildSourceToTargetTransparentContainerCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			container := buildSourceToTargetTransparentContainer(testCase.imsi, testCase.plmnId, testCase.gnbId, testCase.targetTai, testCase.targetGnbId)
+			encodeContainer, err := aper.MarshalWithParams(container, "valueExt")
+			if err != nil {
+				t.Fatalf("Failed to marshal source to target transparent container: %v", err)
+			} else {
+				decodeContainer := &ngapType.SourceNGRANNodeToTargetNGRANNodeTransparentContainer{}
+				if err := aper.UnmarshalWithParams(encodeContainer, decodeContainer, "valueExt"); err != nil {
+					t.Fatalf("Failed to unmarshal source to target transparent container: %v", err)
+				} else if !reflect.DeepEqual(container, *decodeContainer) {
+					t.Fatalf("Source to target transparent container mismatch")
+				} else if string(decodeContainer.RRCContainer.Value) != testCase.imsi {
+					t.Fatalf("RRC container %q, expected %q", decodeContainer.RRCContainer.Value, testCase.imsi)
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverRequiredCases = []struct {
+	name                               string
+	amfUeNgapId                        int64
+	ranUeNgapId                        int64
+	targetGnbId                        []byte
+	targetTai                          ngapType.TAI
+	pduSessionIdList                   []int64
+	transferMessageList                [][]byte
+	sourceToTargetTransparentContainer []byte
+}{
+	{
+		name:        "testBuildHandoverRequired",
+		amfUeNgapId: 1,
+		ranUeNgapId: 2,
+		targetGnbId: []byte("\x00\x03\x14"),
+		targetTai: ngapType.TAI{
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: aper.OctetString("\x02\xF8\x39"),
+			},
+			TAC: ngapType.TAC{
+				Value: aper.OctetString("\x00\x00\x01"),
+			},
+		},
+		pduSessionIdList:                   []int64{1, 2},
+		transferMessageList:                [][]byte{[]byte("\x00"), []byte("\x00")},
+		sourceToTargetTransparentContainer: []byte("imsi-208930000000001"),
+	},
+}
+
+func TestBuildHandoverRequired(t *testing.T) {
+	for _, testCase := range testBuildHandoverRequiredCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildHandoverRequired(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.targetGnbId, testCase.targetTai, testCase.pduSessionIdList, testCase.transferMessageList, testCase.sourceToTargetTransparentContainer)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP handover required: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP handover required: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP handover required mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverRequestAcknowledgeTransferCases = []struct {
+	name          string
+	dlTeid        []byte
+	ranN3Ip       string
+	qosFlowIdList []int64
+}{
+	{
+		name:          "testBuildHandoverRequestAcknowledgeTransfer",
+		dlTeid:        []byte("\x00\x00\x00\x01"),
+		ranN3Ip:       "127.0.0.1",
+		qosFlowIdList: []int64{1},
+	},
+	{
+		name:          "testBuildHandoverRequestAcknowledgeTransferMultipleQosFlows",
+		dlTeid:        []byte("\x00\x00\x00\x01"),
+		ranN3Ip:       "127.0.0.1",
+		qosFlowIdList: []int64{1, 2},
+	},
+}
+
+func TestBuildHandoverRequestAcknowledgeTransfer(t *testing.T) {
+	for _, testCase := range testBuildHandoverRequestAcknowledgeTransferCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferMessage := buildHandoverRequestAcknowledgeTransfer(testCase.dlTeid, testCase.ranN3Ip, testCase.qosFlowIdList)
+			encodeTransferMessage, err := aper.MarshalWithParams(transferMessage, "valueExt")
+			if err != nil {
+				t.Fatalf("Failed to marshal handover request acknowledge transfer message: %v", err)
+			} else {
+				decodeTransferMessage := &ngapType.HandoverRequestAcknowledgeTransfer{}
+				if err := aper.UnmarshalWithParams(encodeTransferMessage, decodeTransferMessage, "valueExt"); err != nil {
+					t.Fatalf("Failed to unmarshal handover request acknowledge transfer message: %v", err)
+				} else if !reflect.DeepEqual(transferMessage, *decodeTransferMessage) {
+					t.Fatalf("Handover request acknowledge transfer message mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverFailureCases = []struct {
+	name        string
+	amfUeNgapId int64
+	cause       ngapType.Cause
+}{
+	{
+		name:        "testBuildHandoverFailure",
+		amfUeNgapId: 1,
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: ngapType.CauseRadioNetworkPresentHoFailureInTarget5GCNgranNodeOrTargetSystem,
+			},
+		},
+	},
+}
+
+func TestBuildHandoverFailure(t *testing.T) {
+	for _, testCase := range testBuildHandoverFailureCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildHandoverFailure(testCase.amfUeNgapId, testCase.cause)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP handover failure: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP handover failure: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP handover failure mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverNotifyCases = []struct {
+	name        string
+	amfUeNgapId int64
+	ranUeNgapId int64
+	plmnId      ngapType.PLMNIdentity
+	tai         ngapType.TAI
+}{
+	{
+		name:        "testBuildHandoverNotify",
+		amfUeNgapId: 1,
+		ranUeNgapId: 2,
+		plmnId: ngapType.PLMNIdentity{
+			Value: aper.OctetString("\x02\xF8\x39"),
+		},
+		tai: ngapType.TAI{
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: aper.OctetString("\x02\xF8\x39"),
+			},
+			TAC: ngapType.TAC{
+				Value: aper.OctetString("\x00\x00\x01"),
+			},
+		},
+	},
+}
+
+func TestBuildHandoverNotify(t *testing.T) {
+	for _, testCase := range testBuildHandoverNotifyCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildHandoverNotify(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.plmnId, testCase.tai)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP handover notify: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP handover notify: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP handover notify mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildHandoverCancelCases = []struct {
+	name        string
+	amfUeNgapId int64
+	ranUeNgapId int64
+	cause       ngapType.Cause
+}{
+	{
+		name:        "testBuildHandoverCancel",
+		amfUeNgapId: 1,
+		ranUeNgapId: 2,
+		cause: ngapType.Cause{
+			Present: ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: ngapType.CauseRadioNetworkPresentTngrelocprepExpiry,
+			},
+		},
+	},
+}
+
+func TestBuildHandoverCancel(t *testing.T) {
+	for _, testCase := range testBuildHandoverCancelCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildHandoverCancel(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.cause)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP handover cancel: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP handover cancel: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP handover cancel mismatch")
+				}
+			}
+		})
+	}
+}
+
+var testBuildUplinkRanStatusTransferCases = []struct {
+	name        string
+	amfUeNgapId int64
+	ranUeNgapId int64
+	drbIdList   []int64
+}{
+	{
+		name:        "testBuildUplinkRanStatusTransfer",
+		amfUeNgapId: 1,
+		ranUeNgapId: 2,
+		drbIdList:   []int64{1},
+	},
+	{
+		name:        "testBuildUplinkRanStatusTransferMultipleDrbs",
+		amfUeNgapId: 1,
+		ranUeNgapId: 2,
+		drbIdList:   []int64{1, 2},
+	},
+}
+
+func TestBuildUplinkRanStatusTransfer(t *testing.T) {
+	for _, testCase := range testBuildUplinkRanStatusTransferCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pdu := buildUplinkRanStatusTransfer(testCase.amfUeNgapId, testCase.ranUeNgapId, testCase.drbIdList)
+			encodeData, err := ngap.Encoder(pdu)
+			if err != nil {
+				t.Fatalf("Failed to encode NGAP uplink ran status transfer: %v", err)
+			} else {
+				decodeData, err := ngap.Decoder(encodeData)
+				if err != nil {
+					t.Fatalf("Failed to decode NGAP uplink ran status transfer: %v", err)
+				} else if !reflect.DeepEqual(pdu, *decodeData) {
+					t.Fatalf("NGAP uplink ran status transfer mismatch")
+				}
+			}
+		})
+	}
+}
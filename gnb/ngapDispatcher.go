@@ -1,27 +1,43 @@
 package gnb
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/Alonza0314/free-ran-ue/util"
 	"github.com/free5gc/aper"
 	"github.com/free5gc/ngap"
+	"github.com/free5gc/ngap/ngapConvert"
 	"github.com/free5gc/ngap/ngapType"
 )
 
 type ngapDispatcher struct{}
 
-func (d *ngapDispatcher) start(g *Gnb) {
-	g.NgapLog.Infoln("NGAP dispatcher started")
-	ngapBuffer := make([]byte, 1024)
+func (d *ngapDispatcher) start(ctx context.Context, g *Gnb, amf *Amf) {
+	g.NgapLog.Infof("NGAP dispatcher for AMF %s:%d started", amf.GetAmfN2Ip(), amf.GetAmfN2Port())
+	ngapBuffer := make([]byte, constant.NGAP_MAX_MESSAGE_LEN)
 	for {
-		n, err := g.n2Conn.Read(ngapBuffer)
+		n, err := amf.GetN2Conn().Read(ngapBuffer)
 		if err != nil {
-			if errors.Is(err, net.ErrClosed) {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.EBADF) {
 				g.NgapLog.Debugln("NGAP dispatcher closed")
 				return
 			}
+			if isN2AssociationLost(err) {
+				g.NgapLog.Warnf("N2 association lost: %v", err)
+				if err := g.reestablishN2(ctx, amf); err != nil {
+					g.NgapLog.Errorf("Error re-establishing N2: %v", err)
+					return
+				}
+				continue
+			}
 			g.NgapLog.Errorf("Error reading NGAP buffer: %v", err)
 			continue
 		}
@@ -30,11 +46,11 @@ func (d *ngapDispatcher) start(g *Gnb) {
 
 		tmp := make([]byte, n)
 		copy(tmp, ngapBuffer[:n])
-		go d.dispatch(g, tmp)
+		go d.dispatch(g, amf, tmp)
 	}
 }
 
-func (d *ngapDispatcher) dispatch(g *Gnb, ngapRaw []byte) {
+func (d *ngapDispatcher) dispatch(g *Gnb, amf *Amf, ngapRaw []byte) {
 	ngapPdu, err := ngap.Decoder(ngapRaw)
 	if err != nil {
 		g.NgapLog.Errorf("Error decoding NGAP PDU: %v", err)
@@ -43,45 +59,87 @@ func (d *ngapDispatcher) dispatch(g *Gnb, ngapRaw []byte) {
 
 	switch ngapPdu.Present {
 	case ngapType.NGAPPDUPresentInitiatingMessage:
-		d.initiatingMessageProcessor(g, ngapPdu, ngapRaw)
+		d.initiatingMessageProcessor(g, amf, ngapPdu, ngapRaw)
 	case ngapType.NGAPPDUPresentSuccessfulOutcome:
-		d.successfulOutcomeProcessor(g, ngapPdu, ngapRaw)
+		d.successfulOutcomeProcessor(g, amf, ngapPdu, ngapRaw)
+	case ngapType.NGAPPDUPresentUnsuccessfulOutcome:
+		d.unsuccessfulOutcomeProcessor(g, ngapPdu)
 	default:
 		g.NgapLog.Warnf("Unknown NGAP PDU Present: %v", ngapPdu.Present)
 		return
 	}
 }
 
-func (d *ngapDispatcher) initiatingMessageProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
+func (d *ngapDispatcher) initiatingMessageProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
 	switch ngapPdu.InitiatingMessage.ProcedureCode.Value {
 	case ngapType.ProcedureCodeDownlinkNASTransport:
 		g.NgapLog.Debugln("Processing NGAP Downlink NAS Transport")
-		d.downLinkNASTransportProcessor(g, ngapPdu)
+		d.downLinkNASTransportProcessor(g, amf, ngapPdu)
 	case ngapType.ProcedureCodeInitialContextSetup:
 		g.NgapLog.Debugln("Processing NGAP Initial Context Setup")
-		d.initialContextSetupProcessor(g, ngapPdu)
+		d.initialContextSetupProcessor(g, amf, ngapPdu)
 	case ngapType.ProcedureCodePDUSessionResourceSetup:
 		g.NgapLog.Debugln("Processing NGAP PDU Session Resource Setup")
-		d.pduSessionResourceSetupProcessor(g, ngapPdu, ngapRaw)
+		d.pduSessionResourceSetupProcessor(g, amf, ngapPdu, ngapRaw)
+	case ngapType.ProcedureCodePDUSessionResourceModify:
+		g.NgapLog.Debugln("Processing NGAP PDU Session Resource Modify")
+		d.pduSessionResourceModifyProcessor(g, amf, ngapPdu)
+	case ngapType.ProcedureCodePDUSessionResourceRelease:
+		g.NgapLog.Debugln("Processing NGAP PDU Session Resource Release")
+		d.pduSessionResourceReleaseProcessor(g, amf, ngapPdu)
 	case ngapType.ProcedureCodeUEContextRelease:
 		g.NgapLog.Debugln("Processing NGAP UE Context Release")
-		d.ueContextReleaseProcessor(g, ngapPdu)
+		d.ueContextReleaseProcessor(g, amf, ngapPdu)
+	case ngapType.ProcedureCodeNGReset:
+		g.NgapLog.Debugln("Processing NGAP NG Reset")
+		d.ngResetProcessor(g, amf, ngapPdu)
+	case ngapType.ProcedureCodePaging:
+		g.NgapLog.Debugln("Processing NGAP Paging")
+		d.pagingProcessor(g, ngapPdu)
+	case ngapType.ProcedureCodeHandoverResourceAllocation:
+		g.NgapLog.Debugln("Processing NGAP Handover Request")
+		d.handoverRequestProcessor(g, amf, ngapPdu)
+	case ngapType.ProcedureCodeDownlinkRANStatusTransfer:
+		g.NgapLog.Debugln("Processing NGAP Downlink RAN Status Transfer")
+		d.downlinkRanStatusTransferProcessor(g, ngapPdu)
 	default:
 		g.NgapLog.Warnf("Unknown NGAP PDU Initiating Message Procedure Code: %v", ngapPdu.InitiatingMessage.ProcedureCode.Value)
 	}
 }
 
-func (d *ngapDispatcher) successfulOutcomeProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
+func (d *ngapDispatcher) successfulOutcomeProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
 	switch ngapPdu.SuccessfulOutcome.ProcedureCode.Value {
 	case ngapType.ProcedureCodePDUSessionResourceModifyIndication:
 		g.NgapLog.Debugln("Processing NGAP PDU Session Resource Modify Indication")
-		d.pduSessionResourceModifyIndicationProcessor(g, ngapPdu, ngapRaw)
+		d.pduSessionResourceModifyIndicationProcessor(g, amf, ngapPdu, ngapRaw)
+	case ngapType.ProcedureCodeNGReset:
+		g.NgapLog.Debugln("Processing NGAP NG Reset Acknowledge")
+		d.ngResetAcknowledgeProcessor(g, amf)
+	case ngapType.ProcedureCodePathSwitchRequest:
+		g.NgapLog.Debugln("Processing NGAP Path Switch Request Acknowledge")
+		d.pathSwitchRequestAcknowledgeProcessor(g, ngapPdu)
+	case ngapType.ProcedureCodeHandoverPreparation:
+		g.NgapLog.Debugln("Processing NGAP Handover Command")
+		d.handoverCommandProcessor(g, amf, ngapPdu)
+	case ngapType.ProcedureCodeHandoverCancel:
+		g.NgapLog.Debugln("Processing NGAP Handover Cancel Acknowledge")
+		d.handoverCancelAcknowledgeProcessor(g, ngapPdu)
 	default:
 		g.NgapLog.Warnf("Unknown NGAP PDU Successful Outcome Procedure Code: %v", ngapPdu.SuccessfulOutcome.ProcedureCode.Value)
 	}
 }
 
-func (d *ngapDispatcher) downLinkNASTransportProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+func (d *ngapDispatcher) unsuccessfulOutcomeProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+	switch ngapPdu.UnsuccessfulOutcome.ProcedureCode.Value {
+	case ngapType.ProcedureCodeHandoverPreparation:
+		g.NgapLog.Debugln("Processing NGAP Handover Preparation Failure")
+		d.handoverPreparationFailureProcessor(g, ngapPdu)
+	default:
+		g.NgapLog.Warnf("Unknown NGAP PDU Unsuccessful Outcome Procedure Code: %v", ngapPdu.UnsuccessfulOutcome.ProcedureCode.Value)
+	}
+}
+
+func (d *ngapDispatcher) downLinkNASTransportProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
 	var (
 		downLinkNASTransportMessage []byte
 		amfUeNgapId                 int64
@@ -116,7 +174,7 @@ func (d *ngapDispatcher) downLinkNASTransportProcessor(g *Gnb, ngapPdu *ngapType
 		ranUe.SetAmfUeId(amfUeNgapId)
 	}
 
-	n, err := ranUe.GetN1Conn().Write(downLinkNASTransportMessage)
+	n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, downLinkNASTransportMessage)
 	if err != nil {
 		g.NgapLog.Errorf("Error send downlink NAS transport message to UE: %v", err)
 		return
@@ -125,11 +183,25 @@ func (d *ngapDispatcher) downLinkNASTransportProcessor(g *Gnb, ngapPdu *ngapType
 	g.NgapLog.Debugf("Send downlink NAS transport message to UE %s", ranUe.GetMobileIdentityIMSI())
 }
 
-func (d *ngapDispatcher) initialContextSetupProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+// the AMF sets up the pdu sessions in the initial context setup when the UE comes back from CM-IDLE with a service request
+func (d *ngapDispatcher) initialContextSetupProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
+	type pduSessionResourceSetupItem struct {
+		pduSessionId int64
+		snssai       ngapType.SNSSAI
+		nasPdu       []byte
+
+		pduSessionResourceSetupRequestTransfer ngapType.PDUSessionResourceSetupRequestTransfer
+	}
+
 	var (
 		nasPdu      []byte
 		amfUeNgapId int64
 		ranUeNgapId int64
+
+		ueSecurityCapabilities *ngapType.UESecurityCapabilities
+		guami                  *ngapType.GUAMI
+
+		pduSessionResourceSetupItemList []pduSessionResourceSetupItem
 	)
 
 	for _, ie := range ngapPdu.InitiatingMessage.Value.InitialContextSetupRequest.ProtocolIEs.List {
@@ -146,6 +218,30 @@ func (d *ngapDispatcher) initialContextSetupProcessor(g *Gnb, ngapPdu *ngapType.
 			nasPdu = make([]byte, len(ie.Value.NASPDU.Value))
 			copy(nasPdu, ie.Value.NASPDU.Value)
 			g.NgapLog.Tracef("Get initial context setup NASPDU: %+v", nasPdu)
+		case ngapType.ProtocolIEIDUESecurityCapabilities:
+			ueSecurityCapabilities = ie.Value.UESecurityCapabilities
+		case ngapType.ProtocolIEIDGUAMI:
+			guami = ie.Value.GUAMI
+		case ngapType.ProtocolIEIDPDUSessionResourceSetupListCxtReq:
+			for _, pduSessionResourceSetupItemCxtReq := range ie.Value.PDUSessionResourceSetupListCxtReq.List {
+				item := pduSessionResourceSetupItem{
+					pduSessionId: pduSessionResourceSetupItemCxtReq.PDUSessionID.Value,
+					snssai:       pduSessionResourceSetupItemCxtReq.SNSSAI,
+				}
+				if pduSessionResourceSetupItemCxtReq.NASPDU != nil {
+					item.nasPdu = make([]byte, len(pduSessionResourceSetupItemCxtReq.NASPDU.Value))
+					copy(item.nasPdu, pduSessionResourceSetupItemCxtReq.NASPDU.Value)
+					g.NgapLog.Tracef("Get initial context setup PDU session NASPDU: %+v", item.nasPdu)
+				}
+
+				if err := aper.UnmarshalWithParams(pduSessionResourceSetupItemCxtReq.PDUSessionResourceSetupRequestTransfer, &item.pduSessionResourceSetupRequestTransfer, "valueExt"); err != nil {
+					g.NgapLog.Errorf("Error unmarshal pdu session resource setup request transfer: %v", err)
+					return
+				}
+				g.NgapLog.Tracef("Get PDU Session Resource Setup Request Transfer: %+v", item.pduSessionResourceSetupRequestTransfer)
+
+				pduSessionResourceSetupItemList = append(pduSessionResourceSetupItemList, item)
+			}
 		}
 	}
 
@@ -156,19 +252,60 @@ func (d *ngapDispatcher) initialContextSetupProcessor(g *Gnb, ngapPdu *ngapType.
 	}
 	ranUe := ueValue.(*RanUe)
 
+	if ranUe.GetAmfUeId() == -1 {
+		ranUe.SetAmfUeId(amfUeNgapId)
+	}
+
 	if ranUe.GetAmfUeId() != amfUeNgapId {
 		g.NgapLog.Errorf("Error initial context setup: Ran UE with ranUeNgapId %d has amfUeNgapId %d, expected %d", ranUeNgapId, ranUe.GetAmfUeId(), amfUeNgapId)
 		return
 	}
 
-	initialContextSetupResponse, err := getNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId)
+	if ueSecurityCapabilities != nil {
+		ranUe.SetUeSecurityCapabilities(*ueSecurityCapabilities)
+	}
+	if guami != nil {
+		ranUe.SetGuami(*guami)
+	}
+
+	var (
+		pduSessionIdList                            []int64
+		pduSessionResourceSetupResponseTransferList [][]byte
+	)
+	for _, item := range pduSessionResourceSetupItemList {
+		if _, exists := ranUe.GetPduSession(item.pduSessionId); exists {
+			g.NgapLog.Warnf("Error initial context setup: PDU session %d already exists for UE %s", item.pduSessionId, ranUe.GetMobileIdentityIMSI())
+			continue
+		}
+
+		// the pdu sessions set up here are not dual connected, so no raw NGAP message is forwarded to the secondary gNB
+		ngapPduSessionResourceSetupResponseTransfer, err := d.setupPduSessionResource(g, ranUe, item.pduSessionId, item.snssai, &item.pduSessionResourceSetupRequestTransfer, nil)
+		if err != nil {
+			g.NgapLog.Errorf("Error setup pdu session resource: %v", err)
+			return
+		}
+
+		if len(item.nasPdu) > 0 {
+			n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, item.nasPdu)
+			if err != nil {
+				g.NgapLog.Errorf("Error send initial context setup PDU session NASPDU to UE: %v", err)
+				return
+			}
+			g.NgapLog.Tracef("Sent %d bytes of initial context setup PDU session NASPDU to UE", n)
+		}
+
+		pduSessionIdList = append(pduSessionIdList, item.pduSessionId)
+		pduSessionResourceSetupResponseTransferList = append(pduSessionResourceSetupResponseTransferList, ngapPduSessionResourceSetupResponseTransfer)
+	}
+
+	initialContextSetupResponse, err := getNgapInitialContextSetupResponse(amfUeNgapId, ranUeNgapId, pduSessionIdList, pduSessionResourceSetupResponseTransferList)
 	if err != nil {
 		g.NgapLog.Errorf("Error get initial context setup response: %v", err)
 		return
 	}
 	g.NgapLog.Tracef("Get initial context setup response: %+v", initialContextSetupResponse)
 
-	n, err := g.n2Conn.Write(initialContextSetupResponse)
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), initialContextSetupResponse)
 	if err != nil {
 		g.NgapLog.Errorf("Error send initial context setup response to AMF: %v", err)
 		return
@@ -176,7 +313,15 @@ func (d *ngapDispatcher) initialContextSetupProcessor(g *Gnb, ngapPdu *ngapType.
 	g.NgapLog.Tracef("Sent %d bytes of initial context setup response to AMF", n)
 	g.NgapLog.Debugln("Send initial context setup response to AMF")
 
-	n, err = ranUe.GetN1Conn().Write(nasPdu)
+	if len(pduSessionIdList) > 0 {
+		g.NgapLog.Infof("UE %s PDU session %v user plane activated", ranUe.GetMobileIdentityIMSI(), pduSessionIdList)
+	}
+
+	if len(nasPdu) == 0 {
+		return
+	}
+
+	n, err = ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, nasPdu)
 	if err != nil {
 		g.NgapLog.Errorf("Error send initial context setup NASPDU to UE: %v", err)
 		return
@@ -185,14 +330,20 @@ func (d *ngapDispatcher) initialContextSetupProcessor(g *Gnb, ngapPdu *ngapType.
 	g.NgapLog.Debugln("Send initial context setup NASPDU to UE %s", ranUe.GetMobileIdentityIMSI())
 }
 
-func (d *ngapDispatcher) pduSessionResourceSetupProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
+func (d *ngapDispatcher) pduSessionResourceSetupProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
+	type pduSessionResourceSetupItem struct {
+		pduSessionId int64
+		snssai       ngapType.SNSSAI
+		nasPdu       []byte
+
+		pduSessionResourceSetupRequestTransfer ngapType.PDUSessionResourceSetupRequestTransfer
+	}
+
 	var (
-		nasPdu      []byte
 		amfUeNgapId int64
 		ranUeNgapId int64
-		err         error
 
-		pduSessionResourceSetupRequestTransfer *ngapType.PDUSessionResourceSetupRequestTransfer
+		pduSessionResourceSetupItemList []pduSessionResourceSetupItem
 	)
 
 	for _, ie := range ngapPdu.InitiatingMessage.Value.PDUSessionResourceSetupRequest.ProtocolIEs.List {
@@ -202,16 +353,22 @@ func (d *ngapDispatcher) pduSessionResourceSetupProcessor(g *Gnb, ngapPdu *ngapT
 		case ngapType.ProtocolIEIDRANUENGAPID:
 			ranUeNgapId = ie.Value.RANUENGAPID.Value
 		case ngapType.ProtocolIEIDPDUSessionResourceSetupListSUReq:
-			for _, pduSessionResourceSetupItem := range ie.Value.PDUSessionResourceSetupListSUReq.List {
-				nasPdu = make([]byte, len(pduSessionResourceSetupItem.PDUSessionNASPDU.Value))
-				copy(nasPdu, pduSessionResourceSetupItem.PDUSessionNASPDU.Value)
-				g.NgapLog.Tracef("Get PDU Session Resource Setup NASPDU: %+v", nasPdu)
+			for _, pduSessionResourceSetupItemSUReq := range ie.Value.PDUSessionResourceSetupListSUReq.List {
+				item := pduSessionResourceSetupItem{
+					pduSessionId: pduSessionResourceSetupItemSUReq.PDUSessionID.Value,
+					snssai:       pduSessionResourceSetupItemSUReq.SNSSAI,
+					nasPdu:       make([]byte, len(pduSessionResourceSetupItemSUReq.PDUSessionNASPDU.Value)),
+				}
+				copy(item.nasPdu, pduSessionResourceSetupItemSUReq.PDUSessionNASPDU.Value)
+				g.NgapLog.Tracef("Get PDU Session Resource Setup NASPDU: %+v", item.nasPdu)
 
-				if err := aper.UnmarshalWithParams(pduSessionResourceSetupItem.PDUSessionResourceSetupRequestTransfer, &pduSessionResourceSetupRequestTransfer, "valueExt"); err != nil {
+				if err := aper.UnmarshalWithParams(pduSessionResourceSetupItemSUReq.PDUSessionResourceSetupRequestTransfer, &item.pduSessionResourceSetupRequestTransfer, "valueExt"); err != nil {
 					g.NgapLog.Errorf("Error unmarshal pdu session resource setup request transfer: %v", err)
 					return
 				}
-				g.NgapLog.Tracef("Get PDU Session Resource Setup Request Transfer: %+v", pduSessionResourceSetupRequestTransfer)
+				g.NgapLog.Tracef("Get PDU Session Resource Setup Request Transfer: %+v", item.pduSessionResourceSetupRequestTransfer)
+
+				pduSessionResourceSetupItemList = append(pduSessionResourceSetupItemList, item)
 			}
 		case ngapType.ProtocolIEIDUEAggregateMaximumBitRate:
 		}
@@ -229,61 +386,338 @@ func (d *ngapDispatcher) pduSessionResourceSetupProcessor(g *Gnb, ngapPdu *ngapT
 		return
 	}
 
-	for _, item := range pduSessionResourceSetupRequestTransfer.ProtocolIEs.List {
-		switch item.Id.Value {
-		case ngapType.ProtocolIEIDPDUSessionAggregateMaximumBitRate:
-		case ngapType.ProtocolIEIDULNGUUPTNLInformation:
-			ranUe.SetUlTeid(item.Value.ULNGUUPTNLInformation.GTPTunnel.GTPTEID.Value)
-		case ngapType.ProtocolIEIDAdditionalULNGUUPTNLInformation:
-		case ngapType.ProtocolIEIDPDUSessionType:
-		case ngapType.ProtocolIEIDQosFlowSetupRequestList:
+	var (
+		pduSessionIdList                            []int64
+		pduSessionResourceSetupResponseTransferList [][]byte
+	)
+	for _, item := range pduSessionResourceSetupItemList {
+		if _, exists := ranUe.GetPduSession(item.pduSessionId); exists {
+			g.NgapLog.Warnf("Error pdu session resource setup: PDU session %d already exists for UE %s", item.pduSessionId, ranUe.GetMobileIdentityIMSI())
+			continue
 		}
+
+		ngapPduSessionResourceSetupResponseTransfer, err := d.setupPduSessionResource(g, ranUe, item.pduSessionId, item.snssai, &item.pduSessionResourceSetupRequestTransfer, ngapRaw)
+		if err != nil {
+			g.NgapLog.Errorf("Error setup pdu session resource: %v", err)
+			return
+		}
+
+		n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, item.nasPdu)
+		if err != nil {
+			g.NgapLog.Errorf("Error send pdu session resource setup NASPDU to UE: %v", err)
+			return
+		}
+		g.NgapLog.Tracef("Sent %d bytes of pdu session resource setup NASPDU to UE", n)
+		g.NgapLog.Debugf("Send pdu session %d resource setup NASPDU to UE", item.pduSessionId)
+
+		pduSessionIdList = append(pduSessionIdList, item.pduSessionId)
+		pduSessionResourceSetupResponseTransferList = append(pduSessionResourceSetupResponseTransferList, ngapPduSessionResourceSetupResponseTransfer)
+	}
+
+	if len(pduSessionIdList) == 0 {
+		g.NgapLog.Errorf("Error pdu session resource setup: no PDU session setup for UE %s", ranUe.GetMobileIdentityIMSI())
+		return
+	}
+
+	ngapPduSessionResourceSetupResponse, err := getPduSessionResourceSetupResponse(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), pduSessionIdList, pduSessionResourceSetupResponseTransferList)
+	if err != nil {
+		g.NgapLog.Errorf("Error get pdu session resource setup response: %v", err)
+		return
 	}
+	g.NgapLog.Tracef("Get pdu session resource setup response: %+v", ngapPduSessionResourceSetupResponse)
+
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), ngapPduSessionResourceSetupResponse)
+	if err != nil {
+		g.NgapLog.Errorf("Error send pdu session resource setup response to AMF: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of pdu session resource setup response to AMF", n)
+	g.NgapLog.Debugln("Send pdu session resource setup response to AMF")
+
+	g.NgapLog.Infof("UE %s PDU session %v establishment completed", ranUe.GetMobileIdentityIMSI(), pduSessionIdList)
+}
+
+// the pdu session is stored for the user plane and its setup response transfer is returned, the setup request is
+// forwarded to the secondary gNB only for the default pdu session and only when the raw NGAP message is given
+func (d *ngapDispatcher) setupPduSessionResource(g *Gnb, ranUe *RanUe, pduSessionId int64, snssai ngapType.SNSSAI, pduSessionResourceSetupRequestTransfer *ngapType.PDUSessionResourceSetupRequestTransfer, ngapRaw []byte) ([]byte, error) {
+	pduSession := g.addUePduSessionResource(ranUe, pduSessionId, snssai, pduSessionResourceSetupRequestTransfer)
+
+	// only the default pdu session is dual connected
+	isDualConnected := ngapRaw != nil && ranUe.GetDefaultPduSession() == pduSession
 
 	var qosFlowPerTNLInformationItem ngapType.QosFlowPerTNLInformationItem
-	if ranUe.IsNrdcActivated() {
+	if isDualConnected && ranUe.IsNrdcActivated() {
+		var err error
 		if qosFlowPerTNLInformationItem, err = g.xnPduSessionResourceSetupRequestTransfer(ranUe.GetMobileIdentityIMSI(), ngapRaw); err != nil {
 			g.XnLog.Warnf("Error xn pdu session resource setup request transfer: %v", err)
 		}
 	}
 
-	n, err := ranUe.GetN1Conn().Write(nasPdu)
+	ngapPduSessionResourceSetupResponseTransfer, err := getPduSessionResourceSetupResponseTransfer(pduSession.GetDlTeid(), g.ranN3Ip, 1, isDualConnected && g.staticNrdc, qosFlowPerTNLInformationItem)
 	if err != nil {
-		g.NgapLog.Errorf("Error send pdu session resource setup NASPDU to UE: %v", err)
+		return nil, fmt.Errorf("error get pdu session resource setup response transfer: %v", err)
+	}
+	g.NgapLog.Tracef("Get pdu session resource setup response transfer: %+v", ngapPduSessionResourceSetupResponseTransfer)
+
+	return ngapPduSessionResourceSetupResponseTransfer, nil
+}
+
+func (d *ngapDispatcher) pduSessionResourceModifyProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		amfUeNgapId int64
+		ranUeNgapId int64
+
+		pduSessionResourceModifyItemList []ngapType.PDUSessionResourceModifyItemModReq
+	)
+
+	for _, ie := range ngapPdu.InitiatingMessage.Value.PDUSessionResourceModifyRequest.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+			amfUeNgapId = ie.Value.AMFUENGAPID.Value
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDRANPagingPriority:
+		case ngapType.ProtocolIEIDPDUSessionResourceModifyListModReq:
+			pduSessionResourceModifyItemList = ie.Value.PDUSessionResourceModifyListModReq.List
+		}
+	}
+
+	ueValue, exist := g.ranUeConns.Load(ranUeNgapId)
+	if !exist {
+		g.NgapLog.Errorf("Error pdu session resource modify: Ran UE with ranUeNgapId %d not found", ranUeNgapId)
+		return
+	}
+	ranUe := ueValue.(*RanUe)
+
+	if ranUe.GetAmfUeId() != amfUeNgapId {
+		g.NgapLog.Errorf("Error pdu session resource modify: Ran UE with ranUeNgapId %d has amfUeNgapId %d, expected %d", ranUeNgapId, ranUe.GetAmfUeId(), amfUeNgapId)
 		return
 	}
-	g.NgapLog.Tracef("Sent %d bytes of pdu session resource setup NASPDU to UE", n)
-	g.NgapLog.Debugln("Send pdu session resource setup NASPDU to UE")
 
-	ngapPduSessionResourceSetupResponseTransfer, err := getPduSessionResourceSetupResponseTransfer(ranUe.GetDlTeid(), g.ranN3Ip, 1, g.staticNrdc, qosFlowPerTNLInformationItem)
+	// each pdu session is modified on its own, a failed one is reported in the failed list without affecting the others
+	var (
+		modifiedItemList []ngapType.PDUSessionResourceModifyItemModRes
+		failedItemList   []ngapType.PDUSessionResourceFailedToModifyItemModRes
+	)
+	for _, pduSessionResourceModifyItem := range pduSessionResourceModifyItemList {
+		pduSessionId := pduSessionResourceModifyItem.PDUSessionID.Value
+
+		pduSessionResourceModifyResponseTransfer, cause, err := d.pduSessionResourceModifyItemProcessor(g, ranUe, pduSessionResourceModifyItem)
+		if err != nil {
+			g.NgapLog.Errorf("Error pdu session resource modify: PDU session %d of UE %s: %v", pduSessionId, ranUe.GetMobileIdentityIMSI(), err)
+
+			pduSessionResourceModifyUnsuccessfulTransfer, err := getPduSessionResourceModifyUnsuccessfulTransfer(cause)
+			if err != nil {
+				g.NgapLog.Errorf("Error get pdu session resource modify unsuccessful transfer: %v", err)
+				continue
+			}
+			failedItemList = append(failedItemList, ngapType.PDUSessionResourceFailedToModifyItemModRes{
+				PDUSessionID: ngapType.PDUSessionID{Value: pduSessionId},
+				PDUSessionResourceModifyUnsuccessfulTransfer: pduSessionResourceModifyUnsuccessfulTransfer,
+			})
+			continue
+		}
+
+		modifiedItemList = append(modifiedItemList, ngapType.PDUSessionResourceModifyItemModRes{
+			PDUSessionID:                             ngapType.PDUSessionID{Value: pduSessionId},
+			PDUSessionResourceModifyResponseTransfer: pduSessionResourceModifyResponseTransfer,
+		})
+	}
+
+	pduSessionResourceModifyResponse, err := getPduSessionResourceModifyResponse(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), modifiedItemList, failedItemList)
 	if err != nil {
-		g.NgapLog.Errorf("Error get pdu session resource setup response transfer: %v", err)
+		g.NgapLog.Errorf("Error get pdu session resource modify response: %v", err)
 		return
 	}
-	g.NgapLog.Tracef("Get pdu session resource setup response transfer: %+v", ngapPduSessionResourceSetupResponseTransfer)
+	g.NgapLog.Tracef("Get pdu session resource modify response: %+v", pduSessionResourceModifyResponse)
 
-	ngapPduSessionResourceSetupResponse, err := getPduSessionResourceSetupResponse(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), constant.PDU_SESSION_ID, ngapPduSessionResourceSetupResponseTransfer)
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), pduSessionResourceModifyResponse)
 	if err != nil {
-		g.NgapLog.Errorf("Error get pdu session resource setup response: %v", err)
+		g.NgapLog.Errorf("Error send pdu session resource modify response to AMF: %v", err)
 		return
 	}
-	g.NgapLog.Tracef("Get pdu session resource setup response: %+v", ngapPduSessionResourceSetupResponse)
+	g.NgapLog.Tracef("Sent %d bytes of pdu session resource modify response to AMF", n)
+	g.NgapLog.Debugf("Send pdu session resource modify response to AMF, %d modified, %d failed", len(modifiedItemList), len(failedItemList))
+}
+
+// the QoS flows are updated and the NAS PDU is forwarded to the UE, the cause is reported to the AMF on error
+func (d *ngapDispatcher) pduSessionResourceModifyItemProcessor(g *Gnb, ranUe *RanUe, pduSessionResourceModifyItem ngapType.PDUSessionResourceModifyItemModReq) ([]byte, ngapType.Cause, error) {
+	pduSessionId := pduSessionResourceModifyItem.PDUSessionID.Value
+
+	pduSession, exists := ranUe.GetPduSession(pduSessionId)
+	if !exists {
+		return nil, newRadioNetworkCause(ngapType.CauseRadioNetworkPresentUnknownPDUSessionID), fmt.Errorf("PDU session %d not found", pduSessionId)
+	}
 
-	n, err = g.n2Conn.Write(ngapPduSessionResourceSetupResponse)
+	var pduSessionResourceModifyRequestTransfer ngapType.PDUSessionResourceModifyRequestTransfer
+	if err := aper.UnmarshalWithParams(pduSessionResourceModifyItem.PDUSessionResourceModifyRequestTransfer, &pduSessionResourceModifyRequestTransfer, "valueExt"); err != nil {
+		return nil, newRadioNetworkCause(ngapType.CauseRadioNetworkPresentUnspecified), fmt.Errorf("error unmarshal pdu session resource modify request transfer: %v", err)
+	}
+	g.NgapLog.Tracef("Get PDU Session Resource Modify Request Transfer: %+v", pduSessionResourceModifyRequestTransfer)
+
+	var qosFlowIdList, failedQosFlowIdList []int64
+	for _, item := range pduSessionResourceModifyRequestTransfer.ProtocolIEs.List {
+		switch item.Id.Value {
+		case ngapType.ProtocolIEIDPDUSessionAggregateMaximumBitRate:
+		case ngapType.ProtocolIEIDULNGUUPTNLModifyList:
+		case ngapType.ProtocolIEIDNetworkInstance:
+		case ngapType.ProtocolIEIDQosFlowAddOrModifyRequestList:
+			for _, qosFlowAddOrModifyRequestItem := range item.Value.QosFlowAddOrModifyRequestList.List {
+				qfi := qosFlowAddOrModifyRequestItem.QosFlowIdentifier.Value
+				if qosFlowAddOrModifyRequestItem.QosFlowLevelQosParameters == nil {
+					// a new QoS flow can not be added without its QoS parameters
+					if _, exists := pduSession.GetQosFlow(qfi); !exists {
+						g.NgapLog.Warnf("Error pdu session resource modify: QoS flow %d not found and no QoS parameters given", qfi)
+						failedQosFlowIdList = append(failedQosFlowIdList, qfi)
+						continue
+					}
+				} else {
+					fiveQi, _ := util.QosCharacteristicsToFiveQi(qosFlowAddOrModifyRequestItem.QosFlowLevelQosParameters.QosCharacteristics)
+					pduSession.SetQosFlow(qfi, fiveQi)
+					g.NgapLog.Debugf("Add or modify QoS flow %d with 5QI %d", qfi, fiveQi)
+				}
+				qosFlowIdList = append(qosFlowIdList, qfi)
+			}
+		case ngapType.ProtocolIEIDQosFlowToReleaseList:
+			for _, qosFlowWithCauseItem := range item.Value.QosFlowToReleaseList.List {
+				if !pduSession.DeleteQosFlow(qosFlowWithCauseItem.QosFlowIdentifier.Value) {
+					g.NgapLog.Warnf("Error pdu session resource modify: QoS flow %d to release not found", qosFlowWithCauseItem.QosFlowIdentifier.Value)
+					continue
+				}
+				g.NgapLog.Debugf("Release QoS flow %d", qosFlowWithCauseItem.QosFlowIdentifier.Value)
+			}
+		case ngapType.ProtocolIEIDAdditionalULNGUUPTNLInformation:
+		}
+	}
+
+	if pduSessionResourceModifyItem.NASPDU != nil {
+		nasPdu := pduSessionResourceModifyItem.NASPDU.Value
+		g.NgapLog.Tracef("Get PDU Session Resource Modify NASPDU: %+v", nasPdu)
+
+		n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, nasPdu)
+		if err != nil {
+			return nil, newRadioNetworkCause(ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost), fmt.Errorf("error send pdu session resource modify NASPDU to UE: %v", err)
+		}
+		g.NgapLog.Tracef("Sent %d bytes of pdu session resource modify NASPDU to UE", n)
+		g.NgapLog.Debugf("Send pdu session resource modify NASPDU to UE %s", ranUe.GetMobileIdentityIMSI())
+	}
+
+	pduSessionResourceModifyResponseTransfer, err := getPduSessionResourceModifyResponseTransfer(qosFlowIdList, failedQosFlowIdList)
 	if err != nil {
-		g.NgapLog.Errorf("Error send pdu session resource setup response to AMF: %v", err)
+		return nil, newRadioNetworkCause(ngapType.CauseRadioNetworkPresentUnspecified), fmt.Errorf("error get pdu session resource modify response transfer: %v", err)
+	}
+	g.NgapLog.Tracef("Get pdu session resource modify response transfer: %+v", pduSessionResourceModifyResponseTransfer)
+	return pduSessionResourceModifyResponseTransfer, ngapType.Cause{}, nil
+}
+
+func (d *ngapDispatcher) pduSessionResourceReleaseProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		nasPdu           []byte
+		amfUeNgapId      int64
+		ranUeNgapId      int64
+		pduSessionIdList []int64
+	)
+
+	for _, ie := range ngapPdu.InitiatingMessage.Value.PDUSessionResourceReleaseCommand.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+			amfUeNgapId = ie.Value.AMFUENGAPID.Value
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDRANPagingPriority:
+		case ngapType.ProtocolIEIDNASPDU:
+			if ie.Value.NASPDU == nil {
+				g.NgapLog.Errorf("Error pdu session resource release: NASPDU is nil")
+				return
+			}
+			nasPdu = make([]byte, len(ie.Value.NASPDU.Value))
+			copy(nasPdu, ie.Value.NASPDU.Value)
+			g.NgapLog.Tracef("Get PDU Session Resource Release NASPDU: %+v", nasPdu)
+		case ngapType.ProtocolIEIDPDUSessionResourceToReleaseListRelCmd:
+			for _, pduSessionResourceToReleaseItem := range ie.Value.PDUSessionResourceToReleaseListRelCmd.List {
+				var pduSessionResourceReleaseCommandTransfer ngapType.PDUSessionResourceReleaseCommandTransfer
+				if err := aper.UnmarshalWithParams(pduSessionResourceToReleaseItem.PDUSessionResourceReleaseCommandTransfer, &pduSessionResourceReleaseCommandTransfer, "valueExt"); err != nil {
+					g.NgapLog.Warnf("Error unmarshal pdu session resource release command transfer: %v", err)
+				} else {
+					g.NgapLog.Debugf("Release PDU session %d with cause: %+v", pduSessionResourceToReleaseItem.PDUSessionID.Value, pduSessionResourceReleaseCommandTransfer.Cause)
+				}
+				pduSessionIdList = append(pduSessionIdList, pduSessionResourceToReleaseItem.PDUSessionID.Value)
+			}
+		}
+	}
+
+	ueValue, exist := g.ranUeConns.Load(ranUeNgapId)
+	if !exist {
+		g.NgapLog.Errorf("Error pdu session resource release: Ran UE with ranUeNgapId %d not found", ranUeNgapId)
+		return
+	}
+	ranUe := ueValue.(*RanUe)
+
+	if ranUe.GetAmfUeId() != amfUeNgapId {
+		g.NgapLog.Errorf("Error pdu session resource release: Ran UE with ranUeNgapId %d has amfUeNgapId %d, expected %d", ranUeNgapId, ranUe.GetAmfUeId(), amfUeNgapId)
+		return
+	}
+
+	releasedPduSessionIdList := []int64{}
+	for _, pduSessionId := range pduSessionIdList {
+		pduSession, exists := ranUe.GetPduSession(pduSessionId)
+		if !exists {
+			g.NgapLog.Warnf("Error pdu session resource release: PDU session %d not found for UE %s", pduSessionId, ranUe.GetMobileIdentityIMSI())
+			continue
+		}
+		g.releaseUePduSessionResource(ranUe, pduSession)
+		releasedPduSessionIdList = append(releasedPduSessionIdList, pduSessionId)
+	}
+
+	// the released list of the response must not be empty
+	if len(releasedPduSessionIdList) == 0 {
+		g.NgapLog.Warnf("No PDU session released for UE %s, skip pdu session resource release response", ranUe.GetMobileIdentityIMSI())
+	} else {
+		d.sendPduSessionResourceReleaseResponse(g, amf, ranUe, releasedPduSessionIdList)
+	}
+
+	if nasPdu == nil {
 		return
 	}
-	g.NgapLog.Tracef("Sent %d bytes of pdu session resource setup response to AMF", n)
-	g.NgapLog.Debugln("Send pdu session resource setup response to AMF")
 
-	ranUe.GetPduSessionEstablishmentCompleteChan() <- struct{}{}
+	n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, nasPdu)
+	if err != nil {
+		g.NgapLog.Errorf("Error send pdu session resource release NASPDU to UE: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of pdu session resource release NASPDU to UE", n)
+	g.NgapLog.Debugf("Send pdu session resource release NASPDU to UE %s", ranUe.GetMobileIdentityIMSI())
+}
+
+func (d *ngapDispatcher) sendPduSessionResourceReleaseResponse(g *Gnb, amf *Amf, ranUe *RanUe, pduSessionIdList []int64) {
+	pduSessionResourceReleaseResponseTransfer, err := getPduSessionResourceReleaseResponseTransfer()
+	if err != nil {
+		g.NgapLog.Errorf("Error get pdu session resource release response transfer: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Get pdu session resource release response transfer: %+v", pduSessionResourceReleaseResponseTransfer)
+
+	pduSessionResourceReleaseResponse, err := getPduSessionResourceReleaseResponse(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), pduSessionIdList, pduSessionResourceReleaseResponseTransfer)
+	if err != nil {
+		g.NgapLog.Errorf("Error get pdu session resource release response: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Get pdu session resource release response: %+v", pduSessionResourceReleaseResponse)
+
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), pduSessionResourceReleaseResponse)
+	if err != nil {
+		g.NgapLog.Errorf("Error send pdu session resource release response to AMF: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of pdu session resource release response to AMF", n)
+	g.NgapLog.Debugln("Send pdu session resource release response to AMF")
 }
 
-func (d *ngapDispatcher) ueContextReleaseProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+func (d *ngapDispatcher) ueContextReleaseProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
 	var (
 		amfUeNgapId int64
 		ranUeNgapId int64
+		cause       *ngapType.Cause
 	)
 
 	for _, ie := range ngapPdu.InitiatingMessage.Value.UEContextReleaseCommand.ProtocolIEs.List {
@@ -291,11 +725,24 @@ func (d *ngapDispatcher) ueContextReleaseProcessor(g *Gnb, ngapPdu *ngapType.NGA
 		case ngapType.ProtocolIEIDUENGAPIDs:
 			amfUeNgapId, ranUeNgapId = ie.Value.UENGAPIDs.UENGAPIDPair.AMFUENGAPID.Value, ie.Value.UENGAPIDs.UENGAPIDPair.RANUENGAPID.Value
 		case ngapType.ProtocolIEIDCause:
+			cause = ie.Value.Cause
 		}
 	}
 
+	// the IEs may come in any order, so the cause is logged once the ranUeNgapId is known
+	if cause == nil {
+		g.NgapLog.Warnf("UE context release command with ranUeNgapId %d without cause", ranUeNgapId)
+	} else {
+		g.NgapLog.Debugf("UE context release command with ranUeNgapId %d, cause: %s", ranUeNgapId, ngapCauseToString(*cause))
+	}
+
 	ueValue, exist := g.ranUeConns.Load(ranUeNgapId)
 	if !exist {
+		// the AMF releases the UE context prepared by the handover request when the handover is cancelled
+		if ranUe := g.findN2HandoverRanUe(ranUeNgapId); ranUe != nil && ranUe.GetAmfUeId() == amfUeNgapId {
+			d.n2HandoverRanUeContextReleaseProcessor(g, amf, ranUe)
+			return
+		}
 		g.NgapLog.Errorf("Error ue context release: Ran UE with ranUeNgapId %d not found", ranUeNgapId)
 		return
 	}
@@ -306,14 +753,19 @@ func (d *ngapDispatcher) ueContextReleaseProcessor(g *Gnb, ngapPdu *ngapType.NGA
 		return
 	}
 
-	ngapUeContextReleaseCompleteMessage, err := getNgapUeContextReleaseCompleteMessage(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), []int64{constant.PDU_SESSION_ID}, g.plmnId, g.tai)
+	pduSessionIdList := []int64{}
+	for _, pduSession := range ranUe.GetPduSessionList() {
+		pduSessionIdList = append(pduSessionIdList, pduSession.GetPduSessionId())
+	}
+
+	ngapUeContextReleaseCompleteMessage, err := getNgapUeContextReleaseCompleteMessage(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), pduSessionIdList, g.plmnId, g.tai)
 	if err != nil {
 		g.NgapLog.Errorf("Error get ngap ue context release complete message: %v", err)
 		return
 	}
 	g.NgapLog.Tracef("Get ngap ue context release complete message: %+v", ngapUeContextReleaseCompleteMessage)
 
-	n, err := g.n2Conn.Write(ngapUeContextReleaseCompleteMessage)
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), ngapUeContextReleaseCompleteMessage)
 	if err != nil {
 		g.NgapLog.Errorf("Error send ngap ue context release complete message to AMF: %v", err)
 		return
@@ -321,10 +773,35 @@ func (d *ngapDispatcher) ueContextReleaseProcessor(g *Gnb, ngapPdu *ngapType.NGA
 	g.NgapLog.Tracef("Sent %d bytes of ngap ue context release complete message to AMF", n)
 	g.NgapLog.Debugln("Send ngap ue context release complete message to AMF")
 
-	ranUe.GetUeContextReleaseCompleteChan() <- struct{}{}
+	// nobody waits for the release any more once UE_CONTEXT_RELEASE_TIMEOUT has passed, the dispatcher must not block
+	select {
+	case ranUe.GetUeContextReleaseCompleteChan() <- struct{}{}:
+	default:
+		g.NgapLog.Warnf("UE %s context release complete not awaited", ranUe.GetMobileIdentityIMSI())
+	}
 }
 
-func (d *ngapDispatcher) pduSessionResourceModifyIndicationProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
+func (d *ngapDispatcher) n2HandoverRanUeContextReleaseProcessor(g *Gnb, amf *Amf, ranUe *RanUe) {
+	if !g.n2HandoverRanUes.CompareAndDelete(ranUe.GetMobileIdentityIMSI(), ranUe) {
+		g.NgapLog.Warnf("Prepared UE context of UE %s already taken over", ranUe.GetMobileIdentityIMSI())
+		return
+	}
+
+	ngapUeContextReleaseCompleteMessage, err := getNgapUeContextReleaseCompleteMessage(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), []int64{}, g.plmnId, g.tai)
+	if err != nil {
+		g.NgapLog.Errorf("Error get ngap ue context release complete message: %v", err)
+	} else if n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), ngapUeContextReleaseCompleteMessage); err != nil {
+		g.NgapLog.Errorf("Error send ngap ue context release complete message to AMF: %v", err)
+	} else {
+		g.NgapLog.Tracef("Sent %d bytes of ngap ue context release complete message to AMF", n)
+		g.NgapLog.Debugln("Send ngap ue context release complete message to AMF")
+	}
+
+	g.releaseN2HandoverRanUe(ranUe)
+	g.NgapLog.Infof("Prepared UE context of UE %s released", ranUe.GetMobileIdentityIMSI())
+}
+
+func (d *ngapDispatcher) pduSessionResourceModifyIndicationProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU, ngapRaw []byte) {
 	var (
 		amfUeNgapId int64
 		ranUeNgapId int64
@@ -366,9 +843,7 @@ func (d *ngapDispatcher) pduSessionResourceModifyIndicationProcessor(g *Gnb, nga
 	}
 
 	// send modify message to UE
-	modifyMessage := []byte(constant.UE_TUNNEL_UPDATE)
-
-	n, err := ranUe.GetN1Conn().Write(modifyMessage)
+	n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_TUNNEL_UPDATE, nil)
 	if err != nil {
 		g.NgapLog.Errorf("Error send modify message to UE: %v", err)
 		return
@@ -387,3 +862,507 @@ func (d *ngapDispatcher) pduSessionResourceModifyIndicationProcessor(g *Gnb, nga
 
 	ranUe.GetPduSessionModifyIndicationCompleteChan() <- struct{}{}
 }
+
+func (d *ngapDispatcher) ngResetProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
+	var resetType *ngapType.ResetType
+
+	for _, ie := range ngapPdu.InitiatingMessage.Value.NGReset.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDCause:
+			if ie.Value.Cause != nil {
+				g.NgapLog.Infof("NG reset from AMF, cause: %s", ngapCauseToString(*ie.Value.Cause))
+			}
+		case ngapType.ProtocolIEIDResetType:
+			resetType = ie.Value.ResetType
+		}
+	}
+
+	if resetType == nil {
+		g.NgapLog.Errorf("Error ng reset: ResetType is nil")
+		return
+	}
+
+	var partOfNgInterface *ngapType.UEAssociatedLogicalNGConnectionList
+	switch resetType.Present {
+	case ngapType.ResetTypePresentNGInterface:
+		g.ranUeConns.Range(func(key, value any) bool {
+			if ranUe := value.(*RanUe); ranUe.GetAmf() == amf {
+				g.releaseRanUe(ranUe)
+			}
+			return true
+		})
+		g.NgapLog.Infoln("NG reset all UE-associated connections")
+	case ngapType.ResetTypePresentPartOfNGInterface:
+		partOfNgInterface = resetType.PartOfNGInterface
+		for _, item := range partOfNgInterface.List {
+			var ranUe *RanUe
+			g.ranUeConns.Range(func(key, value any) bool {
+				candidate := value.(*RanUe)
+				if candidate.GetAmf() != amf {
+					return true
+				}
+				if (item.RANUENGAPID != nil && candidate.GetRanUeId() == item.RANUENGAPID.Value) ||
+					(item.RANUENGAPID == nil && item.AMFUENGAPID != nil && candidate.GetAmfUeId() == item.AMFUENGAPID.Value) {
+					ranUe = candidate
+					return false
+				}
+				return true
+			})
+			if ranUe == nil {
+				g.NgapLog.Warnf("Error ng reset: Ran UE in UE-associated logical NG-connection item %+v not found", item)
+				continue
+			}
+			g.releaseRanUe(ranUe)
+		}
+		g.NgapLog.Infof("NG reset %d UE-associated connection(s)", len(partOfNgInterface.List))
+	default:
+		g.NgapLog.Errorf("Error ng reset: unknown ResetType present %d", resetType.Present)
+		return
+	}
+
+	ngResetAcknowledge, err := getNgResetAcknowledge(partOfNgInterface)
+	if err != nil {
+		g.NgapLog.Errorf("Error get ng reset acknowledge: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Get ng reset acknowledge: %+v", ngResetAcknowledge)
+
+	n, err := amf.WriteNonUeAssociated(ngResetAcknowledge)
+	if err != nil {
+		g.NgapLog.Errorf("Error send ng reset acknowledge to AMF: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of ng reset acknowledge to AMF", n)
+	g.NgapLog.Debugln("Send ng reset acknowledge to AMF")
+}
+
+func (d *ngapDispatcher) ngResetAcknowledgeProcessor(g *Gnb, amf *Amf) {
+	select {
+	case amf.GetNgResetAcknowledgeChan() <- struct{}{}:
+	default:
+		g.NgapLog.Warnln("Unexpected NG reset acknowledge from AMF")
+	}
+}
+
+// the AMF keeps the UE NGAP association of the source gNB, the UL tunnel is only given when the UPF changes it
+func (d *ngapDispatcher) pathSwitchRequestAcknowledgeProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		amfUeNgapId           int64
+		ranUeNgapId           int64
+		switchedList          []ngapType.PDUSessionResourceSwitchedItem
+		releasedPduSessionIds []int64
+	)
+
+	for _, ie := range ngapPdu.SuccessfulOutcome.Value.PathSwitchRequestAcknowledge.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+			amfUeNgapId = ie.Value.AMFUENGAPID.Value
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDUESecurityCapabilities:
+		case ngapType.ProtocolIEIDSecurityContext:
+		case ngapType.ProtocolIEIDPDUSessionResourceSwitchedList:
+			switchedList = ie.Value.PDUSessionResourceSwitchedList.List
+		case ngapType.ProtocolIEIDPDUSessionResourceReleasedListPSAck:
+			for _, releasedItem := range ie.Value.PDUSessionResourceReleasedListPSAck.List {
+				releasedPduSessionIds = append(releasedPduSessionIds, releasedItem.PDUSessionID.Value)
+			}
+		case ngapType.ProtocolIEIDAllowedNSSAI:
+		}
+	}
+
+	ueValue, exist := g.ranUeConns.Load(ranUeNgapId)
+	if !exist {
+		g.NgapLog.Errorf("Error path switch request acknowledge: Ran UE with ranUeNgapId %d not found", ranUeNgapId)
+		return
+	}
+	ranUe := ueValue.(*RanUe)
+	ranUe.SetAmfUeId(amfUeNgapId)
+
+	for _, switchedItem := range switchedList {
+		pduSession, exists := ranUe.GetPduSession(switchedItem.PDUSessionID.Value)
+		if !exists {
+			g.NgapLog.Warnf("Error path switch request acknowledge: PDU session %d of UE %s not found", switchedItem.PDUSessionID.Value, ranUe.GetMobileIdentityIMSI())
+			continue
+		}
+
+		var pathSwitchRequestAcknowledgeTransfer ngapType.PathSwitchRequestAcknowledgeTransfer
+		if err := aper.UnmarshalWithParams(switchedItem.PathSwitchRequestAcknowledgeTransfer, &pathSwitchRequestAcknowledgeTransfer, "valueExt"); err != nil {
+			g.NgapLog.Warnf("Error unmarshal path switch request acknowledge transfer: %v", err)
+			continue
+		}
+
+		if ulNguUpTnlInformation := pathSwitchRequestAcknowledgeTransfer.ULNGUUPTNLInformation; ulNguUpTnlInformation != nil && ulNguUpTnlInformation.GTPTunnel != nil {
+			pduSession.SetUlTeid(ulNguUpTnlInformation.GTPTunnel.GTPTEID.Value)
+			g.GtpLog.Debugf("UE %s PDU session %d UL TEID switched to %s", ranUe.GetMobileIdentityIMSI(), pduSession.GetPduSessionId(), hex.EncodeToString(pduSession.GetUlTeid()))
+		}
+	}
+
+	for _, pduSessionId := range releasedPduSessionIds {
+		if pduSession, exists := ranUe.GetPduSession(pduSessionId); exists {
+			g.NgapLog.Warnf("UE %s PDU session %d not switched by the AMF", ranUe.GetMobileIdentityIMSI(), pduSessionId)
+			g.releaseUePduSessionResource(ranUe, pduSession)
+		}
+	}
+
+	select {
+	case ranUe.GetPathSwitchRequestAcknowledgeChan() <- struct{}{}:
+	default:
+		g.NgapLog.Warnf("Unexpected path switch request acknowledge for UE %s", ranUe.GetMobileIdentityIMSI())
+	}
+}
+
+func (d *ngapDispatcher) pagingProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+	var fiveGSTmsi *ngapType.FiveGSTMSI
+
+	for _, ie := range ngapPdu.InitiatingMessage.Value.Paging.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDUEPagingIdentity:
+			if ie.Value.UEPagingIdentity == nil || ie.Value.UEPagingIdentity.Present != ngapType.UEPagingIdentityPresentFiveGSTMSI {
+				g.NgapLog.Errorf("Error paging: UE paging identity is not a 5G-S-TMSI")
+				return
+			}
+			fiveGSTmsi = ie.Value.UEPagingIdentity.FiveGSTMSI
+		case ngapType.ProtocolIEIDPagingDRX:
+		case ngapType.ProtocolIEIDTAIListForPaging:
+		case ngapType.ProtocolIEIDPagingPriority:
+		}
+	}
+
+	if fiveGSTmsi == nil {
+		g.NgapLog.Errorf("Error paging: UE paging identity not found")
+		return
+	}
+
+	// the AMF pages every gNB of the registration area, the UE camps on only one of them
+	if err := g.pageUe(util.FiveGSTmsiToString(*fiveGSTmsi)); err != nil {
+		g.NgapLog.Debugf("Paging not delivered: %v", err)
+		return
+	}
+	g.NgapLog.Debugf("Paging of UE with 5G-S-TMSI %s completed", util.FiveGSTmsiToString(*fiveGSTmsi))
+}
+
+// the target gNB prepares the UE context and the DL tunnels before the UE arrives, the UE is recognized by the IMSI
+// which the source gNB puts in the source to target transparent container
+func (d *ngapDispatcher) handoverRequestProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		amfUeNgapId                        int64
+		ueSecurityCapabilities             *ngapType.UESecurityCapabilities
+		guami                              *ngapType.GUAMI
+		pduSessionResourceSetupListHOReq   []ngapType.PDUSessionResourceSetupItemHOReq
+		sourceToTargetTransparentContainer []byte
+	)
+
+	for _, ie := range ngapPdu.InitiatingMessage.Value.HandoverRequest.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+			amfUeNgapId = ie.Value.AMFUENGAPID.Value
+		case ngapType.ProtocolIEIDHandoverType:
+		case ngapType.ProtocolIEIDCause:
+		case ngapType.ProtocolIEIDUEAggregateMaximumBitRate:
+		case ngapType.ProtocolIEIDUESecurityCapabilities:
+			ueSecurityCapabilities = ie.Value.UESecurityCapabilities
+		case ngapType.ProtocolIEIDSecurityContext:
+		case ngapType.ProtocolIEIDPDUSessionResourceSetupListHOReq:
+			pduSessionResourceSetupListHOReq = ie.Value.PDUSessionResourceSetupListHOReq.List
+		case ngapType.ProtocolIEIDAllowedNSSAI:
+		case ngapType.ProtocolIEIDSourceToTargetTransparentContainer:
+			sourceToTargetTransparentContainer = ie.Value.SourceToTargetTransparentContainer.Value
+		case ngapType.ProtocolIEIDGUAMI:
+			guami = ie.Value.GUAMI
+		}
+	}
+
+	container := ngapType.SourceNGRANNodeToTargetNGRANNodeTransparentContainer{}
+	if err := aper.UnmarshalWithParams(sourceToTargetTransparentContainer, &container, "valueExt"); err != nil {
+		g.NgapLog.Errorf("Error handover request: decode source to target transparent container: %v", err)
+		d.sendHandoverFailure(g, amf, amfUeNgapId, ngapType.CauseRadioNetworkPresentUnspecified)
+		return
+	}
+
+	imsi := string(container.RRCContainer.Value)
+	if !strings.HasPrefix(imsi, constant.UE_IMSI_PREFIX) {
+		g.NgapLog.Errorf("Error handover request: unexpected RRC container %q", container.RRCContainer.Value)
+		d.sendHandoverFailure(g, amf, amfUeNgapId, ngapType.CauseRadioNetworkPresentUnspecified)
+		return
+	}
+
+	ranUe := NewRanUe(nil, g.ranUeNgapIdGenerator)
+	ranUe.SetIMSI(imsi)
+	ranUe.SetAmf(amf)
+	ranUe.SetAmfUeId(amfUeNgapId)
+	if ueSecurityCapabilities != nil {
+		ranUe.SetUeSecurityCapabilities(*ueSecurityCapabilities)
+	}
+	if guami != nil {
+		ranUe.SetGuami(*guami)
+	}
+
+	pduSessionIdList, handoverRequestAcknowledgeTransferList := []int64{}, [][]byte{}
+	for _, pduSessionItem := range pduSessionResourceSetupListHOReq {
+		var handoverRequestTransfer ngapType.PDUSessionResourceSetupRequestTransfer
+		if err := aper.UnmarshalWithParams(pduSessionItem.HandoverRequestTransfer, &handoverRequestTransfer, "valueExt"); err != nil {
+			g.NgapLog.Errorf("Error unmarshal handover request transfer: %v", err)
+			continue
+		}
+		g.NgapLog.Tracef("Get handover request transfer of PDU session %d: %+v", pduSessionItem.PDUSessionID.Value, handoverRequestTransfer)
+
+		pduSession := g.addUePduSessionResource(ranUe, pduSessionItem.PDUSessionID.Value, pduSessionItem.SNSSAI, &handoverRequestTransfer)
+
+		handoverRequestAcknowledgeTransfer, err := getHandoverRequestAcknowledgeTransfer(pduSession.GetDlTeid(), g.ranN3Ip, pduSession.GetQosFlowIdList())
+		if err != nil {
+			g.NgapLog.Errorf("Error get handover request acknowledge transfer: %v", err)
+			g.releaseUePduSessionResource(ranUe, pduSession)
+			continue
+		}
+		g.NgapLog.Tracef("Get handover request acknowledge transfer of PDU session %d: %+v", pduSession.GetPduSessionId(), handoverRequestAcknowledgeTransfer)
+
+		pduSessionIdList = append(pduSessionIdList, pduSession.GetPduSessionId())
+		handoverRequestAcknowledgeTransferList = append(handoverRequestAcknowledgeTransferList, handoverRequestAcknowledgeTransfer)
+	}
+
+	if len(pduSessionIdList) == 0 {
+		g.NgapLog.Errorf("Error handover request: no PDU session of UE %s admitted", imsi)
+		g.releaseN2HandoverRanUe(ranUe)
+		d.sendHandoverFailure(g, amf, amfUeNgapId, ngapType.CauseRadioNetworkPresentHoFailureInTarget5GCNgranNodeOrTargetSystem)
+		return
+	}
+
+	handoverRequestAcknowledge, err := getHandoverRequestAcknowledge(amfUeNgapId, ranUe.GetRanUeId(), pduSessionIdList, handoverRequestAcknowledgeTransferList, g.getUeHandoverCommand())
+	if err != nil {
+		g.NgapLog.Errorf("Error get handover request acknowledge: %v", err)
+		g.releaseN2HandoverRanUe(ranUe)
+		return
+	}
+	g.NgapLog.Tracef("Get handover request acknowledge: %+v", handoverRequestAcknowledge)
+
+	// a handover of the same UE prepared before is superseded
+	if previousValue, exists := g.n2HandoverRanUes.Swap(imsi, ranUe); exists {
+		g.releaseN2HandoverRanUe(previousValue.(*RanUe))
+	}
+
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), handoverRequestAcknowledge)
+	if err != nil {
+		g.NgapLog.Errorf("Error send handover request acknowledge to AMF: %v", err)
+		if g.n2HandoverRanUes.CompareAndDelete(imsi, ranUe) {
+			g.releaseN2HandoverRanUe(ranUe)
+		}
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of handover request acknowledge to AMF", n)
+	g.NgapLog.Debugln("Send Handover Request Acknowledge to AMF")
+
+	// the prepared UE context is dropped if the UE never arrives
+	time.AfterFunc(constant.HANDOVER_TIMEOUT, func() {
+		if g.n2HandoverRanUes.CompareAndDelete(imsi, ranUe) {
+			g.NgapLog.Warnf("UE %s did not arrive within %v after the handover request", imsi, constant.HANDOVER_TIMEOUT)
+			g.releaseN2HandoverRanUe(ranUe)
+		}
+	})
+
+	g.NgapLog.Infof("UE %s handover prepared with ranUeNgapId %d", imsi, ranUe.GetRanUeId())
+}
+
+func (d *ngapDispatcher) sendHandoverFailure(g *Gnb, amf *Amf, amfUeNgapId int64, radioNetworkCause aper.Enumerated) {
+	handoverFailure, err := getHandoverFailure(amfUeNgapId, ngapType.Cause{
+		Present:      ngapType.CausePresentRadioNetwork,
+		RadioNetwork: &ngapType.CauseRadioNetwork{Value: radioNetworkCause},
+	})
+	if err != nil {
+		g.NgapLog.Errorf("Error get handover failure: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Get handover failure: %+v", handoverFailure)
+
+	n, err := amf.WriteNonUeAssociated(handoverFailure)
+	if err != nil {
+		g.NgapLog.Errorf("Error send handover failure to AMF: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of handover failure to AMF", n)
+	g.NgapLog.Debugln("Send Handover Failure to AMF")
+}
+
+// there is no PDCP in this RAN, the COUNT values are only logged
+func (d *ngapDispatcher) downlinkRanStatusTransferProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		ranUeNgapId                     int64
+		dRBsSubjectToStatusTransferList []ngapType.DRBsSubjectToStatusTransferItem
+	)
+
+	for _, ie := range ngapPdu.InitiatingMessage.Value.DownlinkRANStatusTransfer.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDRANStatusTransferTransparentContainer:
+			dRBsSubjectToStatusTransferList = ie.Value.RANStatusTransferTransparentContainer.DRBsSubjectToStatusTransferList.List
+		}
+	}
+
+	ranUe := g.findN2HandoverRanUe(ranUeNgapId)
+	if ranUe == nil {
+		g.NgapLog.Warnf("Error downlink ran status transfer: prepared Ran UE with ranUeNgapId %d not found", ranUeNgapId)
+		return
+	}
+
+	for _, dRBsSubjectToStatusTransferItem := range dRBsSubjectToStatusTransferList {
+		g.NgapLog.Debugf("UE %s DRB %d status transferred", ranUe.GetMobileIdentityIMSI(), dRBsSubjectToStatusTransferItem.DRBID.Value)
+	}
+	g.NgapLog.Infof("UE %s RAN status of %d DRBs transferred", ranUe.GetMobileIdentityIMSI(), len(dRBsSubjectToStatusTransferList))
+}
+
+// the source gNB forwards the handover command to the UE, the DL data arriving afterwards is forwarded through the UPF
+func (d *ngapDispatcher) handoverCommandProcessor(g *Gnb, amf *Amf, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		amfUeNgapId                        int64
+		ranUeNgapId                        int64
+		pduSessionResourceHandoverList     []ngapType.PDUSessionResourceHandoverItem
+		targetToSourceTransparentContainer []byte
+	)
+
+	for _, ie := range ngapPdu.SuccessfulOutcome.Value.HandoverCommand.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+			amfUeNgapId = ie.Value.AMFUENGAPID.Value
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDHandoverType:
+		case ngapType.ProtocolIEIDPDUSessionResourceHandoverList:
+			pduSessionResourceHandoverList = ie.Value.PDUSessionResourceHandoverList.List
+		case ngapType.ProtocolIEIDPDUSessionResourceToReleaseListHOCmd:
+		case ngapType.ProtocolIEIDTargetToSourceTransparentContainer:
+			targetToSourceTransparentContainer = ie.Value.TargetToSourceTransparentContainer.Value
+		}
+	}
+
+	ueValue, exist := g.ranUeConns.Load(ranUeNgapId)
+	if !exist {
+		g.NgapLog.Errorf("Error handover command: Ran UE with ranUeNgapId %d not found", ranUeNgapId)
+		return
+	}
+	ranUe := ueValue.(*RanUe)
+
+	if ranUe.GetAmfUeId() != amfUeNgapId {
+		g.NgapLog.Errorf("Error handover command: Ran UE with ranUeNgapId %d has amfUeNgapId %d, expected %d", ranUeNgapId, ranUe.GetAmfUeId(), amfUeNgapId)
+		return
+	}
+
+	for _, pduSessionItem := range pduSessionResourceHandoverList {
+		pduSession, exists := ranUe.GetPduSession(pduSessionItem.PDUSessionID.Value)
+		if !exists {
+			g.NgapLog.Warnf("Error handover command: PDU session %d of UE %s not found", pduSessionItem.PDUSessionID.Value, ranUe.GetMobileIdentityIMSI())
+			continue
+		}
+
+		var handoverCommandTransfer ngapType.HandoverCommandTransfer
+		if err := aper.UnmarshalWithParams(pduSessionItem.HandoverCommandTransfer, &handoverCommandTransfer, "valueExt"); err != nil {
+			g.NgapLog.Warnf("Error unmarshal handover command transfer: %v", err)
+			continue
+		}
+
+		dlForwardingUpTnlInformation := handoverCommandTransfer.DLForwardingUPTNLInformation
+		if dlForwardingUpTnlInformation == nil || dlForwardingUpTnlInformation.GTPTunnel == nil {
+			continue
+		}
+		// the forwarded data is sent on the N3 connection, which only reaches the UPF of the UL tunnel
+		if forwardingIp, _ := ngapConvert.IPAddressToString(dlForwardingUpTnlInformation.GTPTunnel.TransportLayerAddress); forwardingIp != g.upfN3Ip {
+			g.GtpLog.Warnf("UE %s PDU session %d DL forwarding tunnel at %s is not reachable, DL data is not forwarded", ranUe.GetMobileIdentityIMSI(), pduSession.GetPduSessionId(), forwardingIp)
+			continue
+		}
+		pduSession.SetDlForwardingTeid(dlForwardingUpTnlInformation.GTPTunnel.GTPTEID.Value)
+		g.GtpLog.Debugf("UE %s PDU session %d DL forwarding TEID: %s", ranUe.GetMobileIdentityIMSI(), pduSession.GetPduSessionId(), hex.EncodeToString(pduSession.GetDlForwardingTeid()))
+	}
+
+	// the N1 connection lost afterwards is expected, wait for the AMF to release the UE context
+	ranUe.SetHandoverOngoing(true)
+
+	var handoverErr error
+	if n, err := ranUe.GetN1Conn().WriteMessage(constant.RAN_UE_MESSAGE_TYPE_HANDOVER_COMMAND, targetToSourceTransparentContainer); err != nil {
+		handoverErr = fmt.Errorf("error send handover command to UE: %v", err)
+	} else {
+		g.RanLog.Tracef("Sent %d bytes of handover command to UE", n)
+		d.sendUplinkRanStatusTransfer(g, amf, ranUe)
+	}
+
+	select {
+	case ranUe.GetHandoverPreparationResultChan() <- handoverErr:
+	default:
+		g.NgapLog.Warnf("Unexpected handover command for UE %s", ranUe.GetMobileIdentityIMSI())
+	}
+}
+
+// every pdu session is carried by one DRB in this RAN, the DRB ID follows the pdu session id
+func (d *ngapDispatcher) sendUplinkRanStatusTransfer(g *Gnb, amf *Amf, ranUe *RanUe) {
+	drbIdList := []int64{}
+	for _, pduSession := range ranUe.GetPduSessionList() {
+		drbIdList = append(drbIdList, pduSession.GetPduSessionId())
+	}
+	if len(drbIdList) == 0 {
+		return
+	}
+
+	uplinkRanStatusTransfer, err := getUplinkRanStatusTransfer(ranUe.GetAmfUeId(), ranUe.GetRanUeId(), drbIdList)
+	if err != nil {
+		g.NgapLog.Errorf("Error get uplink ran status transfer: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Get uplink ran status transfer: %+v", uplinkRanStatusTransfer)
+
+	n, err := amf.WriteUeAssociated(ranUe.GetRanUeId(), uplinkRanStatusTransfer)
+	if err != nil {
+		g.NgapLog.Errorf("Error send uplink ran status transfer to AMF: %v", err)
+		return
+	}
+	g.NgapLog.Tracef("Sent %d bytes of uplink ran status transfer to AMF", n)
+	g.NgapLog.Debugln("Send Uplink RAN Status Transfer to AMF")
+}
+
+func (d *ngapDispatcher) handoverPreparationFailureProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+	var (
+		ranUeNgapId int64
+		cause       ngapType.Cause
+	)
+
+	for _, ie := range ngapPdu.UnsuccessfulOutcome.Value.HandoverPreparationFailure.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDCause:
+			// a missing cause is reported as unknown
+			if ie.Value.Cause != nil {
+				cause = *ie.Value.Cause
+			}
+		case ngapType.ProtocolIEIDCriticalityDiagnostics:
+		}
+	}
+
+	ueValue, exist := g.ranUeConns.Load(ranUeNgapId)
+	if !exist {
+		g.NgapLog.Errorf("Error handover preparation failure: Ran UE with ranUeNgapId %d not found", ranUeNgapId)
+		return
+	}
+	ranUe := ueValue.(*RanUe)
+
+	select {
+	case ranUe.GetHandoverPreparationResultChan() <- fmt.Errorf("handover preparation failure with cause %s", ngapCauseToString(cause)):
+	default:
+		g.NgapLog.Warnf("Unexpected handover preparation failure for UE %s", ranUe.GetMobileIdentityIMSI())
+	}
+}
+
+func (d *ngapDispatcher) handoverCancelAcknowledgeProcessor(g *Gnb, ngapPdu *ngapType.NGAPPDU) {
+	var ranUeNgapId int64
+
+	for _, ie := range ngapPdu.SuccessfulOutcome.Value.HandoverCancelAcknowledge.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+		case ngapType.ProtocolIEIDRANUENGAPID:
+			ranUeNgapId = ie.Value.RANUENGAPID.Value
+		case ngapType.ProtocolIEIDCriticalityDiagnostics:
+		}
+	}
+
+	g.NgapLog.Infof("Handover of Ran UE with ranUeNgapId %d cancelled", ranUeNgapId)
+}
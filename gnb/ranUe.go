@@ -3,11 +3,15 @@ package gnb
 import (
 	"fmt"
 	"net"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/Alonza0314/free-ran-ue/util"
 	"github.com/free5gc/aper"
 	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/ngap/ngapType"
 )
 
 type RanUeNgapIdGenerator struct {
@@ -47,23 +51,37 @@ type RanUe struct {
 	amfUeNgapId int64
 	ranUeNgapId int64
 
+	imsi              string // announced by the UE in the connection setup, the SUCI may be concealed or replaced by a 5G-S-TMSI
 	mobileIdentity5GS nasType.MobileIdentity5GS
 
-	ulTeid aper.OctetString
-	dlTeid aper.OctetString
+	amf *Amf
 
-	n1Conn           net.Conn
-	dataPlaneAddress *net.UDPAddr
+	// kept from the initial context setup, the UE context is handed over to the target gNB with them
+	ueSecurityCapabilities ngapType.UESecurityCapabilities
+	guami                  ngapType.GUAMI
+
+	n1Conn        *util.RanUeConn
+	connectedTime time.Time
+
+	// the first established pdu session is the default one, only it is dual connected by NR-DC
+	pduSessions         map[int64]*RanUePduSession // pdu session id -> pdu session
+	defaultPduSessionId int64
+	pduSessionsMtx      sync.Mutex
 
-	pduSessionEstablishmentCompleteChan    chan struct{}
 	ueContextReleaseCompleteChan           chan struct{}
 	pduSessionModifyIndicationCompleteChan chan struct{}
+	pathSwitchRequestAcknowledgeChan       chan struct{}
+	handoverPreparationResultChan          chan error // nil for the handover command, the cause for the handover preparation failure
 
 	nrdcIndicator    bool
 	nrdcIndicatorMtx sync.Mutex
+
+	// set on the source gNB once the handover command is sent, the UE context is then released by the target gNB
+	// over Xn, or by the AMF after an N2 handover
+	handoverOngoing atomic.Bool
 }
 
-func NewRanUe(n1Conn net.Conn, ranUeNgapIdGenerator *RanUeNgapIdGenerator) *RanUe {
+func NewRanUe(n1Conn *util.RanUeConn, ranUeNgapIdGenerator *RanUeNgapIdGenerator) *RanUe {
 	ranUeId := ranUeNgapIdGenerator.AllocateRanUeId()
 	if ranUeId == -1 {
 		panic("Failed to allocate ranUeId")
@@ -75,23 +93,29 @@ func NewRanUe(n1Conn net.Conn, ranUeNgapIdGenerator *RanUeNgapIdGenerator) *RanU
 
 		mobileIdentity5GS: nasType.MobileIdentity5GS{},
 
-		n1Conn: n1Conn,
+		n1Conn:        n1Conn,
+		connectedTime: time.Now(),
 
-		pduSessionEstablishmentCompleteChan:    make(chan struct{}),
-		ueContextReleaseCompleteChan:           make(chan struct{}),
+		pduSessions:         make(map[int64]*RanUePduSession),
+		defaultPduSessionId: 0,
+		pduSessionsMtx:      sync.Mutex{},
+
+		ueContextReleaseCompleteChan:           make(chan struct{}, 1),
 		pduSessionModifyIndicationCompleteChan: make(chan struct{}),
+		pathSwitchRequestAcknowledgeChan:       make(chan struct{}),
+		handoverPreparationResultChan:          make(chan error),
 
 		nrdcIndicator:    false,
 		nrdcIndicatorMtx: sync.Mutex{},
 	}
 }
 
-func (r *RanUe) Release(ranUeNgapIdGenerator *RanUeNgapIdGenerator, teidGenerator *TeidGenerator) {
+func (r *RanUe) Release(ranUeNgapIdGenerator *RanUeNgapIdGenerator) {
 	ranUeNgapIdGenerator.ReleaseRanUeId(r.ranUeNgapId)
-	teidGenerator.ReleaseTeid(r.dlTeid)
-	close(r.pduSessionEstablishmentCompleteChan)
-	close(r.ueContextReleaseCompleteChan)
+	// the ue context release complete channel is left open, a late ue context release command may still signal it
 	close(r.pduSessionModifyIndicationCompleteChan)
+	close(r.pathSwitchRequestAcknowledgeChan)
+	close(r.handoverPreparationResultChan)
 }
 
 func (r *RanUe) GetAmfUeId() int64 {
@@ -103,24 +127,23 @@ func (r *RanUe) GetRanUeId() int64 {
 }
 
 func (r *RanUe) GetMobileIdentityIMSI() string {
-	suci := r.mobileIdentity5GS.GetSUCI()
-	return fmt.Sprintf("%s%s%s%s", constant.UE_IMSI_PREFIX, suci[7:10], suci[11:13], suci[20:])
+	return r.imsi
 }
 
-func (r *RanUe) GetUlTeid() aper.OctetString {
-	return r.ulTeid
+func (r *RanUe) GetAmf() *Amf {
+	return r.amf
 }
 
-func (r *RanUe) GetDlTeid() aper.OctetString {
-	return r.dlTeid
+func (r *RanUe) GetUeSecurityCapabilities() ngapType.UESecurityCapabilities {
+	return r.ueSecurityCapabilities
 }
 
-func (r *RanUe) GetN1Conn() net.Conn {
-	return r.n1Conn
+func (r *RanUe) GetGuami() ngapType.GUAMI {
+	return r.guami
 }
 
-func (r *RanUe) GetDataPlaneAddress() *net.UDPAddr {
-	return r.dataPlaneAddress
+func (r *RanUe) GetN1Conn() *util.RanUeConn {
+	return r.n1Conn
 }
 
 func (r *RanUe) SetAmfUeId(amfUeId int64) {
@@ -131,24 +154,24 @@ func (r *RanUe) SetRanUeId(ranUeId int64) {
 	r.ranUeNgapId = ranUeId
 }
 
-func (r *RanUe) SetMobileIdentity5GS(mobileIdentity5GS nasType.MobileIdentity5GS) {
-	r.mobileIdentity5GS = mobileIdentity5GS
+func (r *RanUe) SetIMSI(imsi string) {
+	r.imsi = imsi
 }
 
-func (r *RanUe) SetUlTeid(ulTeid aper.OctetString) {
-	r.ulTeid = ulTeid
+func (r *RanUe) SetMobileIdentity5GS(mobileIdentity5GS nasType.MobileIdentity5GS) {
+	r.mobileIdentity5GS = mobileIdentity5GS
 }
 
-func (r *RanUe) SetDlTeid(dlTeid aper.OctetString) {
-	r.dlTeid = dlTeid
+func (r *RanUe) SetAmf(amf *Amf) {
+	r.amf = amf
 }
 
-func (r *RanUe) SetDataPlaneAddress(dataPlaneAddress *net.UDPAddr) {
-	r.dataPlaneAddress = dataPlaneAddress
+func (r *RanUe) SetUeSecurityCapabilities(ueSecurityCapabilities ngapType.UESecurityCapabilities) {
+	r.ueSecurityCapabilities = ueSecurityCapabilities
 }
 
-func (r *RanUe) GetPduSessionEstablishmentCompleteChan() chan struct{} {
-	return r.pduSessionEstablishmentCompleteChan
+func (r *RanUe) SetGuami(guami ngapType.GUAMI) {
+	r.guami = guami
 }
 
 func (r *RanUe) GetUeContextReleaseCompleteChan() chan struct{} {
@@ -159,6 +182,22 @@ func (r *RanUe) GetPduSessionModifyIndicationCompleteChan() chan struct{} {
 	return r.pduSessionModifyIndicationCompleteChan
 }
 
+func (r *RanUe) GetPathSwitchRequestAcknowledgeChan() chan struct{} {
+	return r.pathSwitchRequestAcknowledgeChan
+}
+
+func (r *RanUe) GetHandoverPreparationResultChan() chan error {
+	return r.handoverPreparationResultChan
+}
+
+func (r *RanUe) IsHandoverOngoing() bool {
+	return r.handoverOngoing.Load()
+}
+
+func (r *RanUe) SetHandoverOngoing(handoverOngoing bool) {
+	r.handoverOngoing.Store(handoverOngoing)
+}
+
 func (r *RanUe) IsNrdcActivated() bool {
 	r.nrdcIndicatorMtx.Lock()
 	defer r.nrdcIndicatorMtx.Unlock()
@@ -176,3 +215,185 @@ func (r *RanUe) DeactivateNrdc() {
 	defer r.nrdcIndicatorMtx.Unlock()
 	r.nrdcIndicator = false
 }
+
+func (r *RanUe) AddPduSession(pduSession *RanUePduSession) {
+	r.pduSessionsMtx.Lock()
+	defer r.pduSessionsMtx.Unlock()
+	r.pduSessions[pduSession.GetPduSessionId()] = pduSession
+	if r.defaultPduSessionId == 0 {
+		r.defaultPduSessionId = pduSession.GetPduSessionId()
+	}
+}
+
+func (r *RanUe) DeletePduSession(pduSessionId int64) {
+	r.pduSessionsMtx.Lock()
+	defer r.pduSessionsMtx.Unlock()
+	delete(r.pduSessions, pduSessionId)
+	if r.defaultPduSessionId == pduSessionId {
+		r.defaultPduSessionId = 0
+	}
+}
+
+func (r *RanUe) GetPduSession(pduSessionId int64) (*RanUePduSession, bool) {
+	r.pduSessionsMtx.Lock()
+	defer r.pduSessionsMtx.Unlock()
+	pduSession, exists := r.pduSessions[pduSessionId]
+	return pduSession, exists
+}
+
+// nil if the default pdu session is not established or has been released
+func (r *RanUe) GetDefaultPduSession() *RanUePduSession {
+	r.pduSessionsMtx.Lock()
+	defer r.pduSessionsMtx.Unlock()
+	return r.pduSessions[r.defaultPduSessionId]
+}
+
+// sorted by pdu session id
+func (r *RanUe) GetPduSessionList() []*RanUePduSession {
+	r.pduSessionsMtx.Lock()
+	defer r.pduSessionsMtx.Unlock()
+	pduSessionList := make([]*RanUePduSession, 0, len(r.pduSessions))
+	for _, pduSession := range r.pduSessions {
+		pduSessionList = append(pduSessionList, pduSession)
+	}
+	slices.SortFunc(pduSessionList, func(a, b *RanUePduSession) int {
+		return int(a.GetPduSessionId() - b.GetPduSessionId())
+	})
+	return pduSessionList
+}
+
+// the latest user plane activity among the pdu sessions, the N1 connection time if there is no pdu session
+func (r *RanUe) GetLastActivityTime() time.Time {
+	r.pduSessionsMtx.Lock()
+	defer r.pduSessionsMtx.Unlock()
+	lastActivityTime := r.connectedTime
+	for _, pduSession := range r.pduSessions {
+		if pduSessionLastActivityTime := pduSession.GetLastActivityTime(); pduSessionLastActivityTime.After(lastActivityTime) {
+			lastActivityTime = pduSessionLastActivityTime
+		}
+	}
+	return lastActivityTime
+}
+
+type RanUePduSession struct {
+	imsi         string
+	pduSessionId int64
+	snssai       ngapType.SNSSAI
+
+	ulTeid aper.OctetString
+	dlTeid aper.OctetString
+
+	dlForwardingTeid aper.OctetString // indirect forwarding tunnel at the UPF during an N2 handover
+
+	dataPlaneAddress *net.UDPAddr
+
+	qosFlows    map[int64]int64 // qfi -> 5qi
+	qosFlowsMtx sync.Mutex
+
+	lastActivityTime atomic.Int64 // unix nano of the latest uplink or downlink packet
+}
+
+func NewRanUePduSession(imsi string, pduSessionId int64, snssai ngapType.SNSSAI, dlTeid aper.OctetString) *RanUePduSession {
+	pduSession := &RanUePduSession{
+		imsi:         imsi,
+		pduSessionId: pduSessionId,
+		snssai:       snssai,
+
+		ulTeid: aper.OctetString{},
+		dlTeid: dlTeid,
+
+		qosFlows:    make(map[int64]int64),
+		qosFlowsMtx: sync.Mutex{},
+	}
+	pduSession.UpdateLastActivityTime()
+
+	return pduSession
+}
+
+func (s *RanUePduSession) GetIMSI() string {
+	return s.imsi
+}
+
+func (s *RanUePduSession) GetPduSessionId() int64 {
+	return s.pduSessionId
+}
+
+func (s *RanUePduSession) GetSnssai() ngapType.SNSSAI {
+	return s.snssai
+}
+
+// matches the initial packet sent by the UE on the data plane of this pdu session
+func (s *RanUePduSession) GetDataPlaneKey() string {
+	return fmt.Sprintf("%s %d", s.imsi, s.pduSessionId)
+}
+
+func (s *RanUePduSession) GetUlTeid() aper.OctetString {
+	return s.ulTeid
+}
+
+func (s *RanUePduSession) GetDlTeid() aper.OctetString {
+	return s.dlTeid
+}
+
+func (s *RanUePduSession) GetDlForwardingTeid() aper.OctetString {
+	return s.dlForwardingTeid
+}
+
+func (s *RanUePduSession) GetDataPlaneAddress() *net.UDPAddr {
+	return s.dataPlaneAddress
+}
+
+func (s *RanUePduSession) SetUlTeid(ulTeid aper.OctetString) {
+	s.ulTeid = ulTeid
+}
+
+func (s *RanUePduSession) SetDlForwardingTeid(dlForwardingTeid aper.OctetString) {
+	s.dlForwardingTeid = dlForwardingTeid
+}
+
+func (s *RanUePduSession) SetDataPlaneAddress(dataPlaneAddress *net.UDPAddr) {
+	s.dataPlaneAddress = dataPlaneAddress
+}
+
+func (s *RanUePduSession) SetQosFlow(qfi, fiveQi int64) {
+	s.qosFlowsMtx.Lock()
+	defer s.qosFlowsMtx.Unlock()
+	s.qosFlows[qfi] = fiveQi
+}
+
+func (s *RanUePduSession) DeleteQosFlow(qfi int64) bool {
+	s.qosFlowsMtx.Lock()
+	defer s.qosFlowsMtx.Unlock()
+	if _, exists := s.qosFlows[qfi]; !exists {
+		return false
+	}
+	delete(s.qosFlows, qfi)
+	return true
+}
+
+func (s *RanUePduSession) GetQosFlow(qfi int64) (int64, bool) {
+	s.qosFlowsMtx.Lock()
+	defer s.qosFlowsMtx.Unlock()
+	fiveQi, exists := s.qosFlows[qfi]
+	return fiveQi, exists
+}
+
+// sorted by qfi
+func (s *RanUePduSession) GetQosFlowIdList() []int64 {
+	s.qosFlowsMtx.Lock()
+	defer s.qosFlowsMtx.Unlock()
+	qosFlowIdList := make([]int64, 0, len(s.qosFlows))
+	for qfi := range s.qosFlows {
+		qosFlowIdList = append(qosFlowIdList, qfi)
+	}
+	slices.Sort(qosFlowIdList)
+	return qosFlowIdList
+}
+
+func (s *RanUePduSession) UpdateLastActivityTime() {
+	s.lastActivityTime.Store(time.Now().UnixNano())
+}
+
+func (s *RanUePduSession) GetLastActivityTime() time.Time {
+	return time.Unix(0, s.lastActivityTime.Load())
+}
@@ -1,30 +1,65 @@
 package gnb
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
+	"unsafe"
 
+	"github.com/Alonza0314/free-ran-ue/model"
 	"github.com/free5gc/sctp"
 )
 
-func getAmfAndGnbSctpN2Addr(amfN2Ip, gnbN2Ip string, amfN2Port, gnbN2Port int) (*sctp.SCTPAddr, *sctp.SCTPAddr, error) {
-	amfIps := make([]net.IPAddr, 0)
-	gnbIps := make([]net.IPAddr, 0)
+const (
+	// size of the packed struct sctp_paddrparams in linux/sctp.h
+	sctpPeerAddrParamsSize = 156
+	// offset of spp_hbinterval in struct sctp_paddrparams
+	sctpPeerAddrParamsHbIntervalOffset = 132
+	// offset of spp_flags in struct sctp_paddrparams
+	sctpPeerAddrParamsFlagsOffset = 146
+	// SPP_HB_ENABLE in linux/sctp.h
+	sctpSppHbEnable = 1
+)
+
+// struct sctp_status in linux/sctp.h, the primary path info is not used
+type sctpStatus struct {
+	AssocId            int32
+	State              int32
+	Rwnd               uint32
+	Unackdata          uint16
+	Penddata           uint16
+	Instrms            uint16
+	Outstrms           uint16
+	FragmentationPoint uint32
+	Primary            [152]byte
+}
+
+func resolveSctpIpAddrs(ipList []string) ([]net.IPAddr, error) {
+	ipAddrs := make([]net.IPAddr, 0, len(ipList))
+	for _, ip := range ipList {
+		ipAddr, err := net.ResolveIPAddr("ip", ip)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': '%v'", ip, err)
+		}
+		ipAddrs = append(ipAddrs, *ipAddr)
+	}
+	return ipAddrs, nil
+}
 
-	if ip, err := net.ResolveIPAddr("ip", amfN2Ip); err != nil {
-		return nil, nil, fmt.Errorf("error resolving AMF N2 IP address '%s': '%v'", amfN2Ip, err)
-	} else {
-		amfIps = append(amfIps, *ip)
+// every address in the lists is bound / connected to the same association, the first one is the primary path
+func getAmfAndGnbSctpN2Addr(amfN2IpList, gnbN2IpList []string, amfN2Port, gnbN2Port int) (*sctp.SCTPAddr, *sctp.SCTPAddr, error) {
+	amfIps, err := resolveSctpIpAddrs(amfN2IpList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error resolving AMF N2 IP address %v", err)
 	}
 	amfAddr := &sctp.SCTPAddr{
 		IPAddrs: amfIps,
 		Port:    amfN2Port,
 	}
 
-	if ip, err := net.ResolveIPAddr("ip", gnbN2Ip); err != nil {
-		return nil, nil, fmt.Errorf("error resolving GNB N2 IP address '%s': '%v'", gnbN2Ip, err)
-	} else {
-		gnbIps = append(gnbIps, *ip)
+	gnbIps, err := resolveSctpIpAddrs(gnbN2IpList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error resolving GNB N2 IP address %v", err)
 	}
 	gnbAddr := &sctp.SCTPAddr{
 		IPAddrs: gnbIps,
@@ -33,3 +68,58 @@ func getAmfAndGnbSctpN2Addr(amfN2Ip, gnbN2Ip string, amfN2Port, gnbN2Port int) (
 
 	return amfAddr, gnbAddr, nil
 }
+
+// zero values are left to the kernel defaults
+func getSctpInitMsg(sctpIe model.SctpIE) sctp.InitMsg {
+	return sctp.InitMsg{
+		NumOstreams:  uint16(sctpIe.NumOutStreams),
+		MaxInstreams: uint16(sctpIe.MaxInStreams),
+		MaxAttempts:  uint16(sctpIe.MaxInitRetries),
+	}
+}
+
+// zero values are left unchanged by the kernel, nil if none of them is configured
+func getSctpRtoInfo(sctpIe model.SctpIE) *sctp.RtoInfo {
+	if sctpIe.RtoInitial == 0 && sctpIe.RtoMin == 0 && sctpIe.RtoMax == 0 {
+		return nil
+	}
+	return &sctp.RtoInfo{
+		SrtoInitial: uint32(sctpIe.RtoInitial),
+		SrtoMax:     uint32(sctpIe.RtoMax),
+		StroMin:     uint32(sctpIe.RtoMin),
+	}
+}
+
+// the heartbeat interval applies to every peer address of the association
+func getSctpPeerAddrParams(heartbeatInterval int) []byte {
+	peerAddrParams := make([]byte, sctpPeerAddrParamsSize)
+	binary.NativeEndian.PutUint32(peerAddrParams[sctpPeerAddrParamsHbIntervalOffset:], uint32(heartbeatInterval))
+	binary.NativeEndian.PutUint32(peerAddrParams[sctpPeerAddrParamsFlagsOffset:], sctpSppHbEnable)
+	return peerAddrParams
+}
+
+func setSctpHeartbeatInterval(conn *sctp.SCTPConn, heartbeatInterval int) error {
+	peerAddrParams := getSctpPeerAddrParams(heartbeatInterval)
+	if _, _, err := conn.Setsockopt(sctp.SCTP_PEER_ADDR_PARAMS, uintptr(unsafe.Pointer(&peerAddrParams[0])), uintptr(len(peerAddrParams))); err != nil {
+		return fmt.Errorf("error setting SCTP heartbeat interval: %v", err)
+	}
+	return nil
+}
+
+// the number of outbound streams negotiated with the peer during association setup
+func getSctpOutStreams(conn *sctp.SCTPConn) (uint16, error) {
+	status := sctpStatus{}
+	statusLen := uint32(unsafe.Sizeof(status))
+	if _, _, err := conn.Getsockopt(sctp.SCTP_STATUS, uintptr(unsafe.Pointer(&status)), uintptr(unsafe.Pointer(&statusLen))); err != nil {
+		return 0, fmt.Errorf("error getting SCTP status: %v", err)
+	}
+	return status.Outstrms, nil
+}
+
+// stream 0 is reserved for non UE-associated signalling, TS 38.412 7
+func getUeAssociatedStream(ranUeNgapId int64, numOutStreams uint16) uint16 {
+	if numOutStreams <= 1 {
+		return 0
+	}
+	return uint16(1 + uint64(ranUeNgapId)%uint64(numOutStreams-1))
+}
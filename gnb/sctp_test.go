@@ -1,36 +1,137 @@
 package gnb
 
 import (
+	"encoding/binary"
 	"testing"
 
+	"github.com/Alonza0314/free-ran-ue/model"
+	"github.com/free5gc/sctp"
 	"github.com/go-playground/assert"
 )
 
 var testGetAmfAndGnbSctpN2AddrCases = []struct {
-	name      string
-	amfN2Ip   string
-	gnbN2Ip   string
-	amfN2Port int
-	gnbN2Port int
+	name        string
+	amfN2IpList []string
+	gnbN2IpList []string
+	amfN2Port   int
+	gnbN2Port   int
 }{
 	{
-		name:      "testGetAmfAndGnbSctpN2Addr",
-		amfN2Ip:   "127.0.0.18",
-		gnbN2Ip:   "127.0.0.1",
-		amfN2Port: 38412,
-		gnbN2Port: 38413,
+		name:        "testGetAmfAndGnbSctpN2Addr",
+		amfN2IpList: []string{"127.0.0.18"},
+		gnbN2IpList: []string{"127.0.0.1"},
+		amfN2Port:   38412,
+		gnbN2Port:   38413,
+	},
+	{
+		name:        "testGetAmfAndGnbSctpN2AddrWithMultiHoming",
+		amfN2IpList: []string{"127.0.0.18", "127.0.1.18"},
+		gnbN2IpList: []string{"127.0.0.1", "127.0.1.1"},
+		amfN2Port:   38412,
+		gnbN2Port:   38413,
 	},
 }
 
 func TestGetAmfAndGnbSctpN2Addr(t *testing.T) {
 	for _, testCase := range testGetAmfAndGnbSctpN2AddrCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			amfAddr, gnbAddr, err := getAmfAndGnbSctpN2Addr(testCase.amfN2Ip, testCase.gnbN2Ip, testCase.amfN2Port, testCase.gnbN2Port)
+			amfAddr, gnbAddr, err := getAmfAndGnbSctpN2Addr(testCase.amfN2IpList, testCase.gnbN2IpList, testCase.amfN2Port, testCase.gnbN2Port)
 			assert.Equal(t, nil, err)
-			assert.Equal(t, testCase.amfN2Ip, amfAddr.IPAddrs[0].String())
-			assert.Equal(t, testCase.gnbN2Ip, gnbAddr.IPAddrs[0].String())
+			assert.Equal(t, len(testCase.amfN2IpList), len(amfAddr.IPAddrs))
+			assert.Equal(t, len(testCase.gnbN2IpList), len(gnbAddr.IPAddrs))
+			for i, ip := range testCase.amfN2IpList {
+				assert.Equal(t, ip, amfAddr.IPAddrs[i].String())
+			}
+			for i, ip := range testCase.gnbN2IpList {
+				assert.Equal(t, ip, gnbAddr.IPAddrs[i].String())
+			}
 			assert.Equal(t, testCase.amfN2Port, amfAddr.Port)
 			assert.Equal(t, testCase.gnbN2Port, gnbAddr.Port)
 		})
 	}
 }
+
+var testGetSctpParamCases = []struct {
+	name            string
+	sctpIe          model.SctpIE
+	expectedInitMsg sctp.InitMsg
+	expectedRtoInfo *sctp.RtoInfo
+}{
+	{
+		name:            "testGetSctpParamWithDefault",
+		sctpIe:          model.SctpIE{},
+		expectedInitMsg: sctp.InitMsg{},
+		expectedRtoInfo: nil,
+	},
+	{
+		name: "testGetSctpParam",
+		sctpIe: model.SctpIE{
+			NumOutStreams:  4,
+			MaxInStreams:   8,
+			MaxInitRetries: 5,
+			RtoInitial:     500,
+			RtoMin:         100,
+			RtoMax:         1000,
+		},
+		expectedInitMsg: sctp.InitMsg{
+			NumOstreams:  4,
+			MaxInstreams: 8,
+			MaxAttempts:  5,
+		},
+		expectedRtoInfo: &sctp.RtoInfo{
+			SrtoInitial: 500,
+			SrtoMax:     1000,
+			StroMin:     100,
+		},
+	},
+}
+
+func TestGetSctpParam(t *testing.T) {
+	for _, testCase := range testGetSctpParamCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedInitMsg, getSctpInitMsg(testCase.sctpIe))
+			assert.Equal(t, testCase.expectedRtoInfo, getSctpRtoInfo(testCase.sctpIe))
+		})
+	}
+}
+
+func TestGetSctpPeerAddrParams(t *testing.T) {
+	peerAddrParams := getSctpPeerAddrParams(1000)
+	assert.Equal(t, sctpPeerAddrParamsSize, len(peerAddrParams))
+	assert.Equal(t, uint32(1000), binary.NativeEndian.Uint32(peerAddrParams[sctpPeerAddrParamsHbIntervalOffset:]))
+	assert.Equal(t, uint32(sctpSppHbEnable), binary.NativeEndian.Uint32(peerAddrParams[sctpPeerAddrParamsFlagsOffset:]))
+}
+
+var testGetUeAssociatedStreamCases = []struct {
+	name           string
+	ranUeNgapId    int64
+	numOutStreams  uint16
+	expectedStream uint16
+}{
+	{
+		name:           "testGetUeAssociatedStreamWithSingleStream",
+		ranUeNgapId:    1,
+		numOutStreams:  1,
+		expectedStream: 0,
+	},
+	{
+		name:           "testGetUeAssociatedStream",
+		ranUeNgapId:    1,
+		numOutStreams:  4,
+		expectedStream: 2,
+	},
+	{
+		name:           "testGetUeAssociatedStreamWrapAround",
+		ranUeNgapId:    3,
+		numOutStreams:  4,
+		expectedStream: 1,
+	},
+}
+
+func TestGetUeAssociatedStream(t *testing.T) {
+	for _, testCase := range testGetUeAssociatedStreamCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedStream, getUeAssociatedStream(testCase.ranUeNgapId, testCase.numOutStreams))
+		})
+	}
+}
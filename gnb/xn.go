@@ -5,8 +5,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/Alonza0314/free-ran-ue/util"
 	"github.com/free5gc/aper"
 	"github.com/free5gc/ngap"
 	"github.com/free5gc/ngap/ngapConvert"
@@ -102,6 +104,12 @@ func xnPduPresentInitiatingMessageDispatcher(g *Gnb, conn net.Conn, imsi string,
 	case ngapType.ProcedureCodePDUSessionResourceModifyIndication:
 		g.XnLog.Infoln("Processing NGAP PDU Session Resource Modify Indication")
 		xnPduSessionResourceModifyIndicationProcessor(g, conn, imsi, ngapPdu)
+	case ngapType.ProcedureCodeHandoverResourceAllocation:
+		g.XnLog.Infoln("Processing NGAP Handover Request")
+		xnHandoverRequestProcessor(g, conn, imsi, ngapPdu)
+	case ngapType.ProcedureCodeUEContextRelease:
+		g.XnLog.Infoln("Processing NGAP UE Context Release Command")
+		xnUeContextReleaseCommandProcessor(g, imsi, ngapPdu)
 	default:
 		g.XnLog.Warnf("Unknown NGAP PDU Procedure Code: %v", ngapPdu.InitiatingMessage.ProcedureCode.Value)
 		return
@@ -244,14 +252,12 @@ func xnPduSessionResourceModifyIndicationProcessor(g *Gnb, conn net.Conn, imsi s
 		}
 	}
 
-	var pduSessionResourceModifyIndicationTransferMessageRaw []byte
-
-	for _, pduSessionResourceModifyItem := range pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List {
-		switch pduSessionResourceModifyItem.PDUSessionID.Value {
-		case 4:
-			pduSessionResourceModifyIndicationTransferMessageRaw = pduSessionResourceModifyItem.PDUSessionResourceModifyIndicationTransfer
-		}
+	// the master node only puts its default pdu session into the indication
+	if len(pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List) == 0 {
+		g.XnLog.Warnf("Error pdu session resource modify indication: no pdu session for imsi: %s", imsi)
+		return
 	}
+	pduSessionResourceModifyIndicationTransferMessageRaw := pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List[0].PDUSessionResourceModifyIndicationTransfer
 
 	pduSessionResourceModifyIndicationTransfer := ngapType.PDUSessionResourceModifyIndicationTransfer{}
 	if err := aper.UnmarshalWithParams(pduSessionResourceModifyIndicationTransferMessageRaw, &pduSessionResourceModifyIndicationTransfer, "valueExt"); err != nil {
@@ -291,12 +297,7 @@ func xnPduSessionResourceModifyIndicationProcessor(g *Gnb, conn net.Conn, imsi s
 		return
 	}
 
-	for i := range pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List {
-		switch pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List[i].PDUSessionID.Value {
-		case 4:
-			pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List[i].PDUSessionResourceModifyIndicationTransfer = pduSessionResourceModifyIndicationTransferMarshal
-		}
-	}
+	pduSessionResourceModifyIndicationIE.Value.PDUSessionResourceModifyListModInd.List[0].PDUSessionResourceModifyIndicationTransfer = pduSessionResourceModifyIndicationTransferMarshal
 	g.XnLog.Tracef("Get PDUSessionResourceModifyIndicationTransfer: %+v", pduSessionResourceModifyIndicationTransfer)
 
 	ngapPdu, err := ngap.Encoder(*ngapPduSessionResourceModifyIndication)
@@ -334,12 +335,12 @@ func xnPduSessionResourceModifyConfirmProcessor(g *Gnb, conn net.Conn, imsi stri
 		}
 	}
 
-	for _, pduSessionResourceModifyItem := range pduSessionResourceModifyListModCfm.List {
-		switch pduSessionResourceModifyItem.PDUSessionID.Value {
-		case 4:
-			pduSessionResourceModifyConfirmtransferRaw = pduSessionResourceModifyItem.PDUSessionResourceModifyConfirmTransfer
-		}
+	// the confirm answers the indication of the default pdu session only
+	if pduSessionResourceModifyListModCfm == nil || len(pduSessionResourceModifyListModCfm.List) == 0 {
+		g.XnLog.Warnf("Error pdu session resource modify confirm: no pdu session for imsi: %s", imsi)
+		return
 	}
+	pduSessionResourceModifyConfirmtransferRaw = pduSessionResourceModifyListModCfm.List[0].PDUSessionResourceModifyConfirmTransfer
 
 	pduSessionResourceModifyConfirmtransfer := ngapType.PDUSessionResourceModifyConfirmTransfer{}
 	if err := aper.UnmarshalWithParams(pduSessionResourceModifyConfirmtransferRaw, &pduSessionResourceModifyConfirmtransfer, "valueExt"); err != nil {
@@ -419,3 +420,115 @@ func xnReleaseUeProcessor(g *Gnb, conn net.Conn, imsi string, ngapPduSessionReso
 
 	return true
 }
+
+// the UE context handed over by the source gNB, kept until the UE connects to this gNB with the handover complete
+type xnHandoverContext struct {
+	amf                    *Amf
+	sourceAmfUeNgapId      int64
+	ueSecurityCapabilities ngapType.UESecurityCapabilities
+	guami                  ngapType.GUAMI
+	pduSessionList         []ngapType.PDUSessionResourceSetupItemHOReq
+}
+
+func xnHandoverRequestProcessor(g *Gnb, conn net.Conn, imsi string, ngapHandoverRequest *ngapType.NGAPPDU) {
+	handoverContext := &xnHandoverContext{
+		sourceAmfUeNgapId: -1,
+	}
+
+	for _, ie := range ngapHandoverRequest.InitiatingMessage.Value.HandoverRequest.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDAMFUENGAPID:
+			handoverContext.sourceAmfUeNgapId = ie.Value.AMFUENGAPID.Value
+		case ngapType.ProtocolIEIDHandoverType:
+		case ngapType.ProtocolIEIDCause:
+		case ngapType.ProtocolIEIDUESecurityCapabilities:
+			handoverContext.ueSecurityCapabilities = *ie.Value.UESecurityCapabilities
+		case ngapType.ProtocolIEIDPDUSessionResourceSetupListHOReq:
+			handoverContext.pduSessionList = ie.Value.PDUSessionResourceSetupListHOReq.List
+		case ngapType.ProtocolIEIDGUAMI:
+			handoverContext.guami = *ie.Value.GUAMI
+		}
+	}
+
+	if handoverContext.sourceAmfUeNgapId == -1 {
+		g.XnLog.Warnf("Error handover request of UE %s: AMF UE NGAP ID not found", imsi)
+		return
+	}
+
+	readyAmfList := make([]*Amf, 0, len(g.amfList))
+	for _, amf := range g.amfList {
+		if amf.IsReady() {
+			readyAmfList = append(readyAmfList, amf)
+		}
+	}
+	handoverContext.amf = selectAmfByGuami(readyAmfList, util.GuamiToModels(handoverContext.guami))
+	if handoverContext.amf == nil {
+		g.XnLog.Warnf("Error handover request of UE %s: no AMF serving the GUAMI", imsi)
+		return
+	}
+	g.XnLog.Debugf("Selected AMF %s:%d for UE %s", handoverContext.amf.GetAmfN2Ip(), handoverContext.amf.GetAmfN2Port(), imsi)
+
+	// the RAN UE NGAP ID is only allocated when the UE connects, the source gNB does not use it
+	handoverRequestAcknowledge, err := getHandoverRequestAcknowledge(handoverContext.sourceAmfUeNgapId, 0, nil, nil, g.getUeHandoverCommand())
+	if err != nil {
+		g.XnLog.Warnf("Error get handover request acknowledge: %v", err)
+		return
+	}
+	g.XnLog.Tracef("Get handover request acknowledge: %+v", handoverRequestAcknowledge)
+
+	g.xnHandoverContexts.Store(imsi, handoverContext)
+	g.XnLog.Debugf("Stored handover context of UE %s to xnHandoverContexts", imsi)
+
+	xnPdu := NewXnPdu(imsi, handoverRequestAcknowledge)
+	xnPduBytes, err := xnPdu.Marshal()
+	if err != nil {
+		g.xnHandoverContexts.Delete(imsi)
+		g.XnLog.Warnf("Error marshal xn pdu: %v", err)
+		return
+	}
+
+	n, err := conn.Write(xnPduBytes)
+	if err != nil {
+		g.xnHandoverContexts.Delete(imsi)
+		g.XnLog.Warnf("Error write handover request acknowledge: %v", err)
+		return
+	}
+	g.XnLog.Tracef("Sent %d bytes of Handover Request Acknowledge to XN", n)
+	g.XnLog.Debugln("Send Handover Request Acknowledge to XN")
+}
+
+// the target gNB has switched the path of the UE, so the source gNB releases the RAN UE without involving the AMF
+func xnUeContextReleaseCommandProcessor(g *Gnb, imsi string, ngapUeContextReleaseCommand *ngapType.NGAPPDU) {
+	amfUeNgapId := int64(-1)
+
+	for _, ie := range ngapUeContextReleaseCommand.InitiatingMessage.Value.UEContextReleaseCommand.ProtocolIEs.List {
+		switch ie.Id.Value {
+		case ngapType.ProtocolIEIDUENGAPIDs:
+			if ie.Value.UENGAPIDs.Present == ngapType.UENGAPIDsPresentAMFUENGAPID {
+				amfUeNgapId = ie.Value.UENGAPIDs.AMFUENGAPID.Value
+			}
+		case ngapType.ProtocolIEIDCause:
+		}
+	}
+
+	var ranUe *RanUe
+	g.ranUeConns.Range(func(key, value interface{}) bool {
+		candidate := value.(*RanUe)
+		if candidate.GetMobileIdentityIMSI() == imsi && candidate.GetAmfUeId() == amfUeNgapId && candidate.IsHandoverOngoing() {
+			ranUe = candidate
+			return false
+		}
+		return true
+	})
+	if ranUe == nil {
+		g.XnLog.Warnf("Error ue context release command: UE %s with AMF UE NGAP ID %d not handed over", imsi, amfUeNgapId)
+		return
+	}
+
+	select {
+	case ranUe.GetUeContextReleaseCompleteChan() <- struct{}{}:
+		g.XnLog.Infof("UE %s released after Xn handover", imsi)
+	case <-time.After(constant.HANDOVER_TIMEOUT):
+		g.XnLog.Warnf("Error release UE %s after Xn handover: timeout after %v", imsi, constant.HANDOVER_TIMEOUT)
+	}
+}
@@ -6,15 +6,15 @@ type GnbConfig struct {
 }
 
 type GnbIE struct {
-	AmfN2Ip string `yaml:"amfN2Ip" valid:"required"`
-	RanN2Ip string `yaml:"ranN2Ip" valid:"required"`
-	UpfN3Ip string `yaml:"upfN3Ip" valid:"required"`
-	RanN3Ip string `yaml:"ranN3Ip" valid:"required"`
+	AmfN2List []AmfN2IE `yaml:"amfN2List" valid:"required"`
+
+	RanN2IpList []string `yaml:"ranN2IpList" valid:"required"`
+	UpfN3Ip     string   `yaml:"upfN3Ip" valid:"required"`
+	RanN3Ip     string   `yaml:"ranN3Ip" valid:"required"`
 
 	RanControlPlaneIp string `yaml:"ranControlPlaneIp" valid:"required"`
 	RanDataPlaneIp    string `yaml:"ranDataPlaneIp" valid:"required"`
 
-	AmfN2Port int `yaml:"amfN2Port" valid:"required"`
 	RanN2Port int `yaml:"ranN2Port" valid:"required"`
 	UpfN3Port int `yaml:"upfN3Port" valid:"required"`
 	RanN3Port int `yaml:"ranN3Port" valid:"required"`
@@ -22,13 +22,16 @@ type GnbIE struct {
 	RanControlPlanePort int `yaml:"ranControlPlanePort" valid:"required"`
 	RanDataPlanePort    int `yaml:"ranDataPlanePort" valid:"required"`
 
+	Sctp SctpIE `yaml:"sctp"`
+
 	GnbId   string `yaml:"gnbId" valid:"required"`
 	GnbName string `yaml:"gnbName" valid:"required"`
 
 	PlmnId PlmnIdIE `yaml:"plmnId" valid:"required"`
 
-	Tai    TaiIE    `yaml:"tai" valid:"required"`
-	Snssai SnssaiIE `yaml:"snssai" valid:"required"`
+	SupportedTaList []SupportedTaIE `yaml:"supportedTaList" valid:"required"`
+
+	UeInactivityTimer int `yaml:"ueInactivityTimer"`
 
 	StaticNrdc bool `yaml:"staticNrdc"`
 
@@ -37,6 +40,23 @@ type GnbIE struct {
 	Api ApiIE `yaml:"api" valid:"required"`
 }
 
+type AmfN2IE struct {
+	IpList []string `yaml:"ipList" valid:"required"`
+	Port   int      `yaml:"port" valid:"required"`
+}
+
+type SctpIE struct {
+	NumOutStreams  int `yaml:"numOutStreams"`
+	MaxInStreams   int `yaml:"maxInStreams"`
+	MaxInitRetries int `yaml:"maxInitRetries"`
+
+	HeartbeatInterval int `yaml:"heartbeatInterval"`
+
+	RtoInitial int `yaml:"rtoInitial"`
+	RtoMin     int `yaml:"rtoMin"`
+	RtoMax     int `yaml:"rtoMax"`
+}
+
 type XnInterfaceIE struct {
 	Enable bool `yaml:"enable" valid:"required"`
 
@@ -1,8 +1,13 @@
 package model
 
-type TaiIE struct {
-	Tac             string   `yaml:"tac" valid:"required"`
-	BroadcastPlmnId PlmnIdIE `yaml:"broadcastPlmnId" valid:"required"`
+type SupportedTaIE struct {
+	Tac               string            `yaml:"tac" valid:"required"`
+	BroadcastPlmnList []BroadcastPlmnIE `yaml:"broadcastPlmnList" valid:"required"`
+}
+
+type BroadcastPlmnIE struct {
+	PlmnId     PlmnIdIE   `yaml:"plmnId" valid:"required"`
+	SnssaiList []SnssaiIE `yaml:"snssaiList" valid:"required"`
 }
 
 type PlmnIdIE struct {
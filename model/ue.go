@@ -17,6 +17,9 @@ type UeIE struct {
 
 	PlmnId PlmnIdIE `yaml:"plmnId" valid:"required"`
 	Msin   string   `yaml:"msin" valid:"required"`
+	Suci   SuciIE   `yaml:"suci"`
+	Imei   string   `yaml:"imei"`
+	Imeisv string   `yaml:"imeisv"`
 
 	AccessType                 models.AccessType            `yaml:"accessType" valid:"required"`
 	AuthenticationSubscription AuthenticationSubscriptionIE `yaml:"authenticationSubscription" valid:"required"`
@@ -24,18 +27,25 @@ type UeIE struct {
 	CipheringAlgorithm CipheringAlgorithmIE `yaml:"cipheringAlgorithm" valid:"required"`
 	IntegrityAlgorithm IntegrityAlgorithmIE `yaml:"integrityAlgorithm" valid:"required"`
 
-	PduSession PduSessionIE `yaml:"pduSession" valid:"required"`
+	RequestedNssai []RequestedSnssaiIE `yaml:"requestedNssai"`
+	PduSessionList []PduSessionIE      `yaml:"pduSessionList" valid:"required"`
 
 	Nrdc NrdcIE `yaml:"nrdc"`
+}
 
-	UeTunnelDevice string `yaml:"ueTunnelDevice" valid:"required"`
+type SuciIE struct {
+	ProtectionScheme       string `yaml:"protectionScheme"`
+	HomeNetworkPublicKey   string `yaml:"homeNetworkPublicKey"`
+	HomeNetworkPublicKeyId int    `yaml:"homeNetworkPublicKeyId"`
 }
 
 type AuthenticationSubscriptionIE struct {
+	AuthenticationMethod          string `yaml:"authenticationMethod"`
 	EncPermanentKey               string `yaml:"encPermanentKey" valid:"required"`
 	EncOpcKey                     string `yaml:"encOpcKey" valid:"required"`
 	AuthenticationManagementField string `yaml:"authenticationManagementField" valid:"required"`
 	SequenceNumber                string `yaml:"sequenceNumber" valid:"required"`
+	SequenceNumberFile            string `yaml:"sequenceNumberFile"`
 }
 
 type IntegrityAlgorithmIE struct {
@@ -53,8 +63,17 @@ type CipheringAlgorithmIE struct {
 }
 
 type PduSessionIE struct {
-	Dnn    string   `yaml:"dnn" valid:"required"`
-	Snssai SnssaiIE `yaml:"snssai" valid:"required"`
+	PduSessionId   int      `yaml:"pduSessionId" valid:"required"`
+	PduSessionType string   `yaml:"pduSessionType" valid:"required"`
+	Dnn            string   `yaml:"dnn" valid:"required"`
+	Snssai         SnssaiIE `yaml:"snssai" valid:"required"`
+	UeTunnelDevice string   `yaml:"ueTunnelDevice" valid:"required"`
+}
+
+type RequestedSnssaiIE struct {
+	Sst               string    `yaml:"sst" valid:"required"`
+	Sd                string    `yaml:"sd"`
+	MappedHplmnSnssai *SnssaiIE `yaml:"mappedHplmnSnssai"`
 }
 
 type NrdcIE struct {
@@ -0,0 +1,138 @@
+package ue
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+)
+
+type eapAkaPrimeChallenge struct {
+	identifier uint8
+	rand       []byte
+	autn       []byte
+	kdf        uint16
+	// the serving network name CK' and IK' are bound to
+	networkName string
+	mac         []byte
+
+	// kept to verify the AT_MAC once K_aut is derived
+	eapPacket []byte
+}
+
+// EAP packet header: code, identifier, length, then type, subtype and two reserved bytes for EAP-AKA'
+func parseEapAkaPrimeChallenge(eapPacket []byte) (*eapAkaPrimeChallenge, error) {
+	if len(eapPacket) < 8 {
+		return nil, fmt.Errorf("too short eap packet: %d bytes", len(eapPacket))
+	}
+	if eapPacket[0] != constant.EAP_CODE_REQUEST {
+		return nil, fmt.Errorf("unexpected eap code: %d, expected request", eapPacket[0])
+	}
+	if length := int(binary.BigEndian.Uint16(eapPacket[2:4])); length != len(eapPacket) {
+		return nil, fmt.Errorf("eap length %d mismatches the packet of %d bytes", length, len(eapPacket))
+	}
+	if eapPacket[4] != constant.EAP_TYPE_AKA_PRIME || eapPacket[5] != constant.EAP_AKA_SUBTYPE_CHALLENGE {
+		return nil, fmt.Errorf("unexpected eap type %d subtype %d, expected AKA'-Challenge", eapPacket[4], eapPacket[5])
+	}
+
+	challenge := &eapAkaPrimeChallenge{
+		identifier: eapPacket[1],
+		eapPacket:  eapPacket,
+	}
+
+	// each attribute is type, length in 4 bytes, then the value
+	for attributes := eapPacket[8:]; len(attributes) > 0; {
+		if len(attributes) < 4 || int(attributes[1])*4 > len(attributes) || attributes[1] == 0 {
+			return nil, fmt.Errorf("malformed eap attribute: %x", attributes)
+		}
+		attributeType, value := attributes[0], attributes[2:int(attributes[1])*4]
+		attributes = attributes[int(attributes[1])*4:]
+
+		switch attributeType {
+		case constant.EAP_AKA_ATTRIBUTE_RAND:
+			challenge.rand = value[2:]
+		case constant.EAP_AKA_ATTRIBUTE_AUTN:
+			challenge.autn = value[2:]
+		case constant.EAP_AKA_ATTRIBUTE_MAC:
+			challenge.mac = value[2:]
+		case constant.EAP_AKA_ATTRIBUTE_KDF:
+			challenge.kdf = binary.BigEndian.Uint16(value[:2])
+		case constant.EAP_AKA_ATTRIBUTE_KDF_INPUT:
+			networkNameLength := int(binary.BigEndian.Uint16(value[:2]))
+			if networkNameLength > len(value)-2 {
+				return nil, fmt.Errorf("malformed eap kdf input: %x", value)
+			}
+			challenge.networkName = string(value[2 : 2+networkNameLength])
+		}
+	}
+
+	if len(challenge.rand) != 16 || len(challenge.autn) != 16 || len(challenge.mac) != constant.EAP_AKA_MAC_LEN || challenge.networkName == "" {
+		return nil, fmt.Errorf("missing attribute in AKA'-Challenge: %+v", challenge)
+	}
+	return challenge, nil
+}
+
+// the AT_MAC is calculated over the whole packet with the MAC value zeroed
+func verifyEapAkaPrimeChallengeMac(challenge *eapAkaPrimeChallenge, kAut []byte) bool {
+	eapPacket := make([]byte, len(challenge.eapPacket))
+	copy(eapPacket, challenge.eapPacket)
+
+	macOffset := len(eapPacket) - len(challenge.mac)
+	for attributes := 8; attributes < len(eapPacket); attributes += int(eapPacket[attributes+1]) * 4 {
+		if eapPacket[attributes] == constant.EAP_AKA_ATTRIBUTE_MAC {
+			macOffset = attributes + 4
+			break
+		}
+	}
+	clear(eapPacket[macOffset : macOffset+constant.EAP_AKA_MAC_LEN])
+
+	return hmac.Equal(challenge.mac, eapAkaPrimeMac(kAut, eapPacket))
+}
+
+func eapAkaPrimeMac(kAut, eapPacket []byte) []byte {
+	mac := hmac.New(sha256.New, kAut)
+	mac.Write(eapPacket)
+	return mac.Sum(nil)[:constant.EAP_AKA_MAC_LEN]
+}
+
+func buildEapAkaPrimeResponse(identifier, subtype uint8, attributes []byte) []byte {
+	eapPacket := make([]byte, 8, 8+len(attributes))
+	eapPacket[0] = constant.EAP_CODE_RESPONSE
+	eapPacket[1] = identifier
+	binary.BigEndian.PutUint16(eapPacket[2:4], uint16(8+len(attributes)))
+	eapPacket[4] = constant.EAP_TYPE_AKA_PRIME
+	eapPacket[5] = subtype
+	return append(eapPacket, attributes...)
+}
+
+// the AT_RES carries the RES length in bits, the AT_MAC is calculated by K_aut over the packet with the MAC value zeroed
+func buildEapAkaPrimeChallengeResponse(identifier uint8, res, kAut []byte) []byte {
+	attributes := []byte{constant.EAP_AKA_ATTRIBUTE_RES, uint8((4 + len(res) + 3) / 4)}
+	attributes = binary.BigEndian.AppendUint16(attributes, uint16(len(res)*8))
+	attributes = append(attributes, res...)
+	attributes = append(attributes, make([]byte, int(attributes[1])*4-len(attributes))...)
+
+	attributes = append(attributes, constant.EAP_AKA_ATTRIBUTE_MAC, 5, 0x00, 0x00)
+	attributes = append(attributes, make([]byte, constant.EAP_AKA_MAC_LEN)...)
+
+	eapPacket := buildEapAkaPrimeResponse(identifier, constant.EAP_AKA_SUBTYPE_CHALLENGE, attributes)
+	copy(eapPacket[len(eapPacket)-constant.EAP_AKA_MAC_LEN:], eapAkaPrimeMac(kAut, eapPacket))
+	return eapPacket
+}
+
+func buildEapAkaPrimeSynchronizationFailure(identifier uint8, auts []byte) []byte {
+	attributes := append([]byte{constant.EAP_AKA_ATTRIBUTE_AUTS, uint8((2 + len(auts)) / 4)}, auts...)
+	return buildEapAkaPrimeResponse(identifier, constant.EAP_AKA_SUBTYPE_SYNCHRONIZATION_FAILURE, attributes)
+}
+
+func buildEapAkaPrimeAuthenticationReject(identifier uint8) []byte {
+	return buildEapAkaPrimeResponse(identifier, constant.EAP_AKA_SUBTYPE_AUTHENTICATION_REJECT, nil)
+}
+
+// client error code 0, unable to process packet
+func buildEapAkaPrimeClientError(identifier uint8) []byte {
+	attributes := []byte{constant.EAP_AKA_ATTRIBUTE_CLIENT_ERROR_CODE, 1, 0x00, 0x00}
+	return buildEapAkaPrimeResponse(identifier, constant.EAP_AKA_SUBTYPE_CLIENT_ERROR, attributes)
+}
@@ -0,0 +1,76 @@
+package ue
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/go-playground/assert"
+)
+
+// the AKA'-Challenge as built by the AUSF, the AT_MAC is calculated by K_aut at last
+func buildTestEapAkaPrimeChallenge(identifier uint8, rand, autn []byte, networkName string, kAut []byte) []byte {
+	attributes := append([]byte{constant.EAP_AKA_ATTRIBUTE_RAND, 5, 0x00, 0x00}, rand...)
+	attributes = append(attributes, constant.EAP_AKA_ATTRIBUTE_AUTN, 5, 0x00, 0x00)
+	attributes = append(attributes, autn...)
+
+	kdfInput := binary.BigEndian.AppendUint16(nil, uint16(len(networkName)))
+	kdfInput = append(kdfInput, networkName...)
+	kdfInput = append(kdfInput, make([]byte, (4-(len(kdfInput)+2)%4)%4)...)
+	attributes = append(attributes, constant.EAP_AKA_ATTRIBUTE_KDF_INPUT, uint8((len(kdfInput)+2)/4))
+	attributes = append(attributes, kdfInput...)
+	attributes = append(attributes, constant.EAP_AKA_ATTRIBUTE_KDF, 1, 0x00, constant.EAP_AKA_PRIME_KDF)
+
+	attributes = append(attributes, constant.EAP_AKA_ATTRIBUTE_MAC, 5, 0x00, 0x00)
+	attributes = append(attributes, make([]byte, constant.EAP_AKA_MAC_LEN)...)
+
+	eapPacket := []byte{constant.EAP_CODE_REQUEST, identifier, 0x00, 0x00, constant.EAP_TYPE_AKA_PRIME, constant.EAP_AKA_SUBTYPE_CHALLENGE, 0x00, 0x00}
+	eapPacket = append(eapPacket, attributes...)
+	binary.BigEndian.PutUint16(eapPacket[2:4], uint16(len(eapPacket)))
+	copy(eapPacket[len(eapPacket)-constant.EAP_AKA_MAC_LEN:], eapAkaPrimeMac(kAut, eapPacket))
+	return eapPacket
+}
+
+func TestParseEapAkaPrimeChallenge(t *testing.T) {
+	rand := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	autn := []byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20}
+	kAut := make([]byte, 32)
+	eapPacket := buildTestEapAkaPrimeChallenge(7, rand, autn, "5G:mnc093.mcc208.3gppnetwork.org", kAut)
+
+	challenge, err := parseEapAkaPrimeChallenge(eapPacket)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint8(7), challenge.identifier)
+	assert.Equal(t, rand, challenge.rand)
+	assert.Equal(t, autn, challenge.autn)
+	assert.Equal(t, uint16(constant.EAP_AKA_PRIME_KDF), challenge.kdf)
+	assert.Equal(t, "5G:mnc093.mcc208.3gppnetwork.org", challenge.networkName)
+	assert.Equal(t, true, verifyEapAkaPrimeChallengeMac(challenge, kAut))
+
+	anotherKAut := make([]byte, 32)
+	anotherKAut[0] = 0x01
+	assert.Equal(t, false, verifyEapAkaPrimeChallengeMac(challenge, anotherKAut))
+
+	_, err = parseEapAkaPrimeChallenge(eapPacket[:len(eapPacket)-4])
+	assert.NotEqual(t, nil, err)
+}
+
+func TestBuildEapAkaPrimeChallengeResponse(t *testing.T) {
+	res := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	kAut := make([]byte, 32)
+
+	eapPacket := buildEapAkaPrimeChallengeResponse(7, res, kAut)
+	assert.Equal(t, 40, len(eapPacket))
+	assert.Equal(t, []byte{constant.EAP_CODE_RESPONSE, 7, 0x00, 40, constant.EAP_TYPE_AKA_PRIME, constant.EAP_AKA_SUBTYPE_CHALLENGE, 0x00, 0x00}, eapPacket[:8])
+	assert.Equal(t, append([]byte{constant.EAP_AKA_ATTRIBUTE_RES, 3, 0x00, 0x40}, res...), eapPacket[8:20])
+
+	mac := make([]byte, constant.EAP_AKA_MAC_LEN)
+	copy(mac, eapPacket[24:])
+	clear(eapPacket[24:])
+	assert.Equal(t, mac, eapAkaPrimeMac(kAut, eapPacket))
+}
+
+func TestBuildEapAkaPrimeSynchronizationFailure(t *testing.T) {
+	auts := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e}
+	expected := append([]byte{constant.EAP_CODE_RESPONSE, 7, 0x00, 24, constant.EAP_TYPE_AKA_PRIME, constant.EAP_AKA_SUBTYPE_SYNCHRONIZATION_FAILURE, 0x00, 0x00, constant.EAP_AKA_ATTRIBUTE_AUTS, 4}, auts...)
+	assert.Equal(t, expected, buildEapAkaPrimeSynchronizationFailure(7, auts))
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/Alonza0314/free-ran-ue/util"
 	"github.com/free5gc/nas"
@@ -124,6 +125,20 @@ func buildUeMobileIdentity5GS(supi string) nasType.MobileIdentity5GS {
 	}
 }
 
+// the routing indicator of the null scheme SUCI is kept, the protection scheme output replaces the MSIN
+func buildSuciMobileIdentity5GS(supi string, protectionScheme, homeNetworkPublicKeyId uint8, schemeOutput []byte) nasType.MobileIdentity5GS {
+	supiBytes := util.SupiToBytes(supi)
+
+	buffer := make([]byte, 0, 8+len(schemeOutput))
+	buffer = append(buffer, supiBytes[:6]...)
+	buffer = append(buffer, protectionScheme, homeNetworkPublicKeyId)
+	buffer = append(buffer, schemeOutput...)
+	return nasType.MobileIdentity5GS{
+		Len:    uint16(len(buffer)),
+		Buffer: buffer,
+	}
+}
+
 func buildUeSecurityCapability(cipheringAlgorithm uint8, integrityAlgorithm uint8) nasType.UESecurityCapability {
 	ueSecurityCapability := nasType.UESecurityCapability{
 		Iei:    nasMessage.RegistrationRequestUESecurityCapabilityType,
@@ -156,7 +171,9 @@ func buildUeSecurityCapability(cipheringAlgorithm uint8, integrityAlgorithm uint
 	return ueSecurityCapability
 }
 
-func buildUeRegistrationRequest(registrationType uint8, mobileIdentity5GS *nasType.MobileIdentity5GS, requestedNSSAI *nasType.RequestedNSSAI, ueSecurityCapability *nasType.UESecurityCapability, capability5GMM *nasType.Capability5GMM, nasMessageContainer []uint8, uplinkDataStatus *nasType.UplinkDataStatus) ([]byte, error) {
+// the ngKSI of the current security context and the 5G-GUTI are given for the mobility and periodic registration updates,
+// whose follow-on request is off so the network releases the connection afterwards
+func buildUeRegistrationRequest(registrationType, ngKsi uint8, followOnRequest bool, mobileIdentity5GS *nasType.MobileIdentity5GS, requestedNSSAI *nasType.RequestedNSSAI, ueSecurityCapability *nasType.UESecurityCapability, capability5GMM *nasType.Capability5GMM, nasMessageContainer []uint8, uplinkDataStatus *nasType.UplinkDataStatus, pduSessionStatus *nasType.PDUSessionStatus) ([]byte, error) {
 	m := nas.NewMessage()
 	m.GmmMessage = nas.NewGmmMessage()
 	m.GmmHeader.SetMessageType(nas.MsgTypeRegistrationRequest)
@@ -167,8 +184,11 @@ func buildUeRegistrationRequest(registrationType uint8, mobileIdentity5GS *nasTy
 	registrationRequest.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0x00)
 	registrationRequest.RegistrationRequestMessageIdentity.SetMessageType(nas.MsgTypeRegistrationRequest)
 	registrationRequest.NgksiAndRegistrationType5GS.SetTSC(nasMessage.TypeOfSecurityContextFlagNative)
-	registrationRequest.NgksiAndRegistrationType5GS.SetNasKeySetIdentifiler(0x7)
-	registrationRequest.NgksiAndRegistrationType5GS.SetFOR(1)
+	registrationRequest.NgksiAndRegistrationType5GS.SetNasKeySetIdentifiler(ngKsi)
+	registrationRequest.NgksiAndRegistrationType5GS.SetFOR(nasMessage.FollowOnRequestNoPending)
+	if followOnRequest {
+		registrationRequest.NgksiAndRegistrationType5GS.SetFOR(nasMessage.FollowOnRequestPending)
+	}
 	registrationRequest.NgksiAndRegistrationType5GS.SetRegistrationType5GS(registrationType)
 	registrationRequest.MobileIdentity5GS = *mobileIdentity5GS
 
@@ -176,6 +196,7 @@ func buildUeRegistrationRequest(registrationType uint8, mobileIdentity5GS *nasTy
 	registrationRequest.Capability5GMM = capability5GMM
 	registrationRequest.RequestedNSSAI = requestedNSSAI
 	registrationRequest.UplinkDataStatus = uplinkDataStatus
+	registrationRequest.PDUSessionStatus = pduSessionStatus
 
 	if nasMessageContainer != nil {
 		registrationRequest.NASMessageContainer = nasType.NewNASMessageContainer(
@@ -194,11 +215,12 @@ func buildUeRegistrationRequest(registrationType uint8, mobileIdentity5GS *nasTy
 	return request.Bytes(), nil
 }
 
-func getUeRegistrationRequest(registrationType uint8, mobileIdentity5GS *nasType.MobileIdentity5GS, requestedNSSAI *nasType.RequestedNSSAI, ueSecurityCapability *nasType.UESecurityCapability, capability5GMM *nasType.Capability5GMM, nasMessageContainer []uint8, uplinkDataStatus *nasType.UplinkDataStatus) ([]byte, error) {
-	return buildUeRegistrationRequest(registrationType, mobileIdentity5GS, requestedNSSAI, ueSecurityCapability, capability5GMM, nasMessageContainer, uplinkDataStatus)
+func getUeRegistrationRequest(registrationType, ngKsi uint8, followOnRequest bool, mobileIdentity5GS *nasType.MobileIdentity5GS, requestedNSSAI *nasType.RequestedNSSAI, ueSecurityCapability *nasType.UESecurityCapability, capability5GMM *nasType.Capability5GMM, nasMessageContainer []uint8, uplinkDataStatus *nasType.UplinkDataStatus, pduSessionStatus *nasType.PDUSessionStatus) ([]byte, error) {
+	return buildUeRegistrationRequest(registrationType, ngKsi, followOnRequest, mobileIdentity5GS, requestedNSSAI, ueSecurityCapability, capability5GMM, nasMessageContainer, uplinkDataStatus, pduSessionStatus)
 }
 
-func buildAuthenticationResponse(authenticationResponseParam []byte) ([]byte, error) {
+// the RES* answers the 5G-AKA challenge, the EAP message answers the EAP-AKA' challenge
+func buildAuthenticationResponse(authenticationResponseParam []byte, eapMessage []byte) ([]byte, error) {
 	m := nas.NewMessage()
 	m.GmmMessage = nas.NewGmmMessage()
 	m.GmmHeader.SetMessageType(nas.MsgTypeAuthenticationResponse)
@@ -217,6 +239,12 @@ func buildAuthenticationResponse(authenticationResponseParam []byte) ([]byte, er
 		copy(authenticationResponse.AuthenticationResponseParameter.Octet[:], authenticationResponseParam[0:16])
 	}
 
+	if len(eapMessage) > 0 {
+		authenticationResponse.EAPMessage = nasType.NewEAPMessage(nasMessage.AuthenticationResponseEAPMessageType)
+		authenticationResponse.EAPMessage.SetLen(uint16(len(eapMessage)))
+		authenticationResponse.EAPMessage.SetEAPMessage(eapMessage)
+	}
+
 	m.GmmMessage.AuthenticationResponse = authenticationResponse
 
 	response := new(bytes.Buffer)
@@ -227,11 +255,47 @@ func buildAuthenticationResponse(authenticationResponseParam []byte) ([]byte, er
 	return response.Bytes(), nil
 }
 
-func getAuthenticationResponse(authenticationResponseParam []byte) ([]byte, error) {
-	return buildAuthenticationResponse(authenticationResponseParam)
+func getAuthenticationResponse(authenticationResponseParam []byte, eapMessage []byte) ([]byte, error) {
+	return buildAuthenticationResponse(authenticationResponseParam, eapMessage)
+}
+
+// the AUTS is only given for the synch failure
+func buildAuthenticationFailure(cause uint8, auts []byte) ([]byte, error) {
+	m := nas.NewMessage()
+	m.GmmMessage = nas.NewGmmMessage()
+	m.GmmHeader.SetMessageType(nas.MsgTypeAuthenticationFailure)
+
+	authenticationFailure := nasMessage.NewAuthenticationFailure(0)
+	authenticationFailure.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(
+		nasMessage.Epd5GSMobilityManagementMessage)
+	authenticationFailure.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
+	authenticationFailure.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
+	authenticationFailure.AuthenticationFailureMessageIdentity.SetMessageType(nas.MsgTypeAuthenticationFailure)
+	authenticationFailure.Cause5GMM.SetCauseValue(cause)
+
+	if len(auts) > 0 {
+		authenticationFailure.AuthenticationFailureParameter = nasType.NewAuthenticationFailureParameter(
+			nasMessage.AuthenticationFailureAuthenticationFailureParameterType)
+		authenticationFailure.AuthenticationFailureParameter.SetLen(uint8(len(authenticationFailure.AuthenticationFailureParameter.Octet)))
+		copy(authenticationFailure.AuthenticationFailureParameter.Octet[:], auts)
+	}
+
+	m.GmmMessage.AuthenticationFailure = authenticationFailure
+
+	failure := new(bytes.Buffer)
+	if err := m.GmmMessageEncode(failure); err != nil {
+		return nil, err
+	}
+
+	return failure.Bytes(), nil
+}
+
+func getAuthenticationFailure(cause uint8, auts []byte) ([]byte, error) {
+	return buildAuthenticationFailure(cause, auts)
 }
 
-func buildNasSecurityModeCompleteMessage(nasMessageContainer []byte) ([]byte, error) {
+// the IMEISV is only included when the security mode command requests it
+func buildNasSecurityModeCompleteMessage(nasMessageContainer []byte, imeisv *nasType.MobileIdentity5GS) ([]byte, error) {
 	m := nas.NewMessage()
 
 	m.GmmMessage = nas.NewGmmMessage()
@@ -244,13 +308,11 @@ func buildNasSecurityModeCompleteMessage(nasMessageContainer []byte) ([]byte, er
 	securityModeComplete.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
 	securityModeComplete.SecurityModeCompleteMessageIdentity.SetMessageType(nas.MsgTypeSecurityModeComplete)
 
-	securityModeComplete.IMEISV = nasType.NewIMEISV(nasMessage.SecurityModeCompleteIMEISVType)
-	securityModeComplete.IMEISV.SetLen(9)
-	securityModeComplete.SetOddEvenIdic(0)
-	securityModeComplete.SetTypeOfIdentity(nasMessage.MobileIdentity5GSTypeImeisv)
-	securityModeComplete.SetIdentityDigit1(1)
-	securityModeComplete.SetIdentityDigitP_1(1)
-	securityModeComplete.SetIdentityDigitP(1)
+	if imeisv != nil {
+		securityModeComplete.IMEISV = nasType.NewIMEISV(nasMessage.SecurityModeCompleteIMEISVType)
+		securityModeComplete.IMEISV.SetLen(imeisv.GetLen())
+		copy(securityModeComplete.IMEISV.Octet[:], imeisv.Buffer)
+	}
 
 	if nasMessageContainer != nil {
 		securityModeComplete.NASMessageContainer = nasType.NewNASMessageContainer(nasMessage.SecurityModeCompleteNASMessageContainerType)
@@ -268,8 +330,8 @@ func buildNasSecurityModeCompleteMessage(nasMessageContainer []byte) ([]byte, er
 	return completeMessage.Bytes(), nil
 }
 
-func getNasSecurityModeCompleteMessage(nasMessageContainer []byte) ([]byte, error) {
-	return buildNasSecurityModeCompleteMessage(nasMessageContainer)
+func getNasSecurityModeCompleteMessage(nasMessageContainer []byte, imeisv *nasType.MobileIdentity5GS) ([]byte, error) {
+	return buildNasSecurityModeCompleteMessage(nasMessageContainer, imeisv)
 }
 
 func buildNasRegistrationCompleteMessage(sorTransparentContainer []byte) ([]byte, error) {
@@ -305,7 +367,59 @@ func getNasRegistrationCompleteMessage(nasMessageContainer []byte) ([]byte, erro
 	return buildNasRegistrationCompleteMessage(nasMessageContainer)
 }
 
-func buildPduSessionEstablishmentRequest(pduSessionId uint8) ([]byte, error) {
+func buildConfigurationUpdateComplete() ([]byte, error) {
+	m := nas.NewMessage()
+	m.GmmMessage = nas.NewGmmMessage()
+	m.GmmHeader.SetMessageType(nas.MsgTypeConfigurationUpdateComplete)
+
+	configurationUpdateComplete := nasMessage.NewConfigurationUpdateComplete(0)
+	configurationUpdateComplete.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSMobilityManagementMessage)
+	configurationUpdateComplete.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
+	configurationUpdateComplete.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
+	configurationUpdateComplete.ConfigurationUpdateCompleteMessageIdentity.SetMessageType(nas.MsgTypeConfigurationUpdateComplete)
+
+	m.GmmMessage.ConfigurationUpdateComplete = configurationUpdateComplete
+
+	complete := new(bytes.Buffer)
+	if err := m.GmmMessageEncode(complete); err != nil {
+		return nil, err
+	}
+
+	return complete.Bytes(), nil
+}
+
+func getConfigurationUpdateComplete() ([]byte, error) {
+	return buildConfigurationUpdateComplete()
+}
+
+func buildIdentityResponse(mobileIdentity5GS nasType.MobileIdentity5GS) ([]byte, error) {
+	m := nas.NewMessage()
+	m.GmmMessage = nas.NewGmmMessage()
+	m.GmmHeader.SetMessageType(nas.MsgTypeIdentityResponse)
+
+	identityResponse := nasMessage.NewIdentityResponse(0)
+	identityResponse.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSMobilityManagementMessage)
+	identityResponse.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
+	identityResponse.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
+	identityResponse.IdentityResponseMessageIdentity.SetMessageType(nas.MsgTypeIdentityResponse)
+	identityResponse.MobileIdentity.SetLen(mobileIdentity5GS.GetLen())
+	identityResponse.MobileIdentity.SetMobileIdentityContents(mobileIdentity5GS.GetMobileIdentity5GSContents())
+
+	m.GmmMessage.IdentityResponse = identityResponse
+
+	response := new(bytes.Buffer)
+	if err := m.GmmMessageEncode(response); err != nil {
+		return nil, err
+	}
+
+	return response.Bytes(), nil
+}
+
+func getIdentityResponse(mobileIdentity5GS nasType.MobileIdentity5GS) ([]byte, error) {
+	return buildIdentityResponse(mobileIdentity5GS)
+}
+
+func buildPduSessionEstablishmentRequest(pduSessionId, pduSessionType uint8) ([]byte, error) {
 	m := nas.NewMessage()
 	m.GsmMessage = nas.NewGsmMessage()
 	m.GsmHeader.SetMessageType(nas.MsgTypePDUSessionEstablishmentRequest)
@@ -319,7 +433,7 @@ func buildPduSessionEstablishmentRequest(pduSessionId uint8) ([]byte, error) {
 	pduSessionEstablishmentRequest.IntegrityProtectionMaximumDataRate.SetMaximumDataRatePerUEForUserPlaneIntegrityProtectionForUpLink(0xff)
 
 	pduSessionEstablishmentRequest.PDUSessionType = nasType.NewPDUSessionType(nasMessage.PDUSessionEstablishmentRequestPDUSessionTypeType)
-	pduSessionEstablishmentRequest.PDUSessionType.SetPDUSessionTypeValue(uint8(0x01)) //IPv4 type
+	pduSessionEstablishmentRequest.PDUSessionType.SetPDUSessionTypeValue(pduSessionType)
 
 	pduSessionEstablishmentRequest.SSCMode = nasType.NewSSCMode(nasMessage.PDUSessionEstablishmentRequestSSCModeType)
 	pduSessionEstablishmentRequest.SSCMode.SetSSCMode(uint8(0x01)) //SSC Mode 1
@@ -344,8 +458,58 @@ func buildPduSessionEstablishmentRequest(pduSessionId uint8) ([]byte, error) {
 	return request.Bytes(), nil
 }
 
-func getPduSessionEstablishmentRequest(pduSessionId uint8) ([]byte, error) {
-	return buildPduSessionEstablishmentRequest(pduSessionId)
+func getPduSessionEstablishmentRequest(pduSessionId, pduSessionType uint8) ([]byte, error) {
+	return buildPduSessionEstablishmentRequest(pduSessionId, pduSessionType)
+}
+
+func buildPduSessionReleaseComplete(pduSessionId uint8, pti uint8) ([]byte, error) {
+	m := nas.NewMessage()
+	m.GsmMessage = nas.NewGsmMessage()
+	m.GsmHeader.SetMessageType(nas.MsgTypePDUSessionReleaseComplete)
+
+	pduSessionReleaseComplete := nasMessage.NewPDUSessionReleaseComplete(0)
+	pduSessionReleaseComplete.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSSessionManagementMessage)
+	pduSessionReleaseComplete.SetMessageType(nas.MsgTypePDUSessionReleaseComplete)
+	pduSessionReleaseComplete.PDUSessionID.SetPDUSessionID(pduSessionId)
+	pduSessionReleaseComplete.PTI.SetPTI(pti)
+
+	m.GsmMessage.PDUSessionReleaseComplete = pduSessionReleaseComplete
+
+	complete := new(bytes.Buffer)
+	if err := m.GsmMessageEncode(complete); err != nil {
+		return nil, err
+	}
+
+	return complete.Bytes(), nil
+}
+
+func getPduSessionReleaseComplete(pduSessionId uint8, pti uint8) ([]byte, error) {
+	return buildPduSessionReleaseComplete(pduSessionId, pti)
+}
+
+func buildPduSessionModificationComplete(pduSessionId uint8, pti uint8) ([]byte, error) {
+	m := nas.NewMessage()
+	m.GsmMessage = nas.NewGsmMessage()
+	m.GsmHeader.SetMessageType(nas.MsgTypePDUSessionModificationComplete)
+
+	pduSessionModificationComplete := nasMessage.NewPDUSessionModificationComplete(0)
+	pduSessionModificationComplete.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSSessionManagementMessage)
+	pduSessionModificationComplete.SetMessageType(nas.MsgTypePDUSessionModificationComplete)
+	pduSessionModificationComplete.PDUSessionID.SetPDUSessionID(pduSessionId)
+	pduSessionModificationComplete.PTI.SetPTI(pti)
+
+	m.GsmMessage.PDUSessionModificationComplete = pduSessionModificationComplete
+
+	complete := new(bytes.Buffer)
+	if err := m.GsmMessageEncode(complete); err != nil {
+		return nil, err
+	}
+
+	return complete.Bytes(), nil
+}
+
+func getPduSessionModificationComplete(pduSessionId uint8, pti uint8) ([]byte, error) {
+	return buildPduSessionModificationComplete(pduSessionId, pti)
 }
 
 func buildUlNasTransportMessage(nasMessageContainer []byte, pduSessionId uint8, requestType uint8, dnn string, sNssai *models.Snssai) ([]byte, error) {
@@ -360,9 +524,12 @@ func buildUlNasTransportMessage(nasMessageContainer []byte, pduSessionId uint8,
 	ulNasTransport.PduSessionID2Value = new(nasType.PduSessionID2Value)
 	ulNasTransport.PduSessionID2Value.SetIei(nasMessage.ULNASTransportPduSessionID2ValueType)
 	ulNasTransport.PduSessionID2Value.SetPduSessionID2Value(pduSessionId)
-	ulNasTransport.RequestType = new(nasType.RequestType)
-	ulNasTransport.RequestType.SetIei(nasMessage.ULNASTransportRequestTypeType)
-	ulNasTransport.RequestType.SetRequestTypeValue(requestType)
+	// request type is only included for the pdu session establishment, 0 leaves it out
+	if requestType != 0 {
+		ulNasTransport.RequestType = new(nasType.RequestType)
+		ulNasTransport.RequestType.SetIei(nasMessage.ULNASTransportRequestTypeType)
+		ulNasTransport.RequestType.SetRequestTypeValue(requestType)
+	}
 
 	if dnn != "" {
 		ulNasTransport.DNN = new(nasType.DNN)
@@ -435,6 +602,90 @@ func getUeDeRegistrationRequest(accessType uint8, switchOff uint8, ngKsi uint8,
 	return buildUeDeRegistrationRequest(accessType, switchOff, ngKsi, mobileIdentity5GS)
 }
 
+// PSI(0) is spare, PSI(1) to PSI(7) are in the first octet and PSI(8) to PSI(15) in the second, TS 24.501 9.11.3.44
+func buildUeTerminatedDeregistrationAccept() ([]byte, error) {
+	m := nas.NewMessage()
+	m.GmmMessage = nas.NewGmmMessage()
+	m.GmmHeader.SetMessageType(nas.MsgTypeDeregistrationAcceptUETerminatedDeregistration)
+
+	deregistrationAccept := nasMessage.NewDeregistrationAcceptUETerminatedDeregistration(0)
+	deregistrationAccept.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSMobilityManagementMessage)
+	deregistrationAccept.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
+	deregistrationAccept.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
+	deregistrationAccept.DeregistrationAcceptMessageIdentity.SetMessageType(nas.MsgTypeDeregistrationAcceptUETerminatedDeregistration)
+
+	m.GmmMessage.DeregistrationAcceptUETerminatedDeregistration = deregistrationAccept
+
+	accept := new(bytes.Buffer)
+	if err := m.GmmMessageEncode(accept); err != nil {
+		return nil, err
+	}
+
+	return accept.Bytes(), nil
+}
+
+func getUeTerminatedDeregistrationAccept() ([]byte, error) {
+	return buildUeTerminatedDeregistrationAccept()
+}
+
+func buildPduSessionStatusBitmap(pduSessionIdList []uint8) []uint8 {
+	bitmap := make([]uint8, 2)
+	for _, pduSessionId := range pduSessionIdList {
+		if pduSessionId == 0 || pduSessionId > 15 {
+			continue
+		}
+		bitmap[pduSessionId/8] |= 1 << (pduSessionId % 8)
+	}
+	return bitmap
+}
+
+// the uplink data status is only included for a mobile originated service request with pending uplink data
+func buildServiceRequest(serviceType uint8, ngKsi uint8, guti5G nasType.GUTI5G, pduSessionIdList []uint8) ([]byte, error) {
+	m := nas.NewMessage()
+	m.GmmMessage = nas.NewGmmMessage()
+	m.GmmHeader.SetMessageType(nas.MsgTypeServiceRequest)
+
+	serviceRequest := nasMessage.NewServiceRequest(0)
+	serviceRequest.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSMobilityManagementMessage)
+	serviceRequest.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
+	serviceRequest.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
+	serviceRequest.ServiceRequestMessageIdentity.SetMessageType(nas.MsgTypeServiceRequest)
+
+	serviceRequest.ServiceTypeAndNgksi.SetServiceTypeValue(serviceType)
+	serviceRequest.ServiceTypeAndNgksi.SetTSC(nasMessage.TypeOfSecurityContextFlagNative)
+	serviceRequest.ServiceTypeAndNgksi.SetNasKeySetIdentifiler(ngKsi)
+
+	// 5G-S-TMSI is the AMF Set ID, AMF Pointer and 5G-TMSI of the 5G-GUTI
+	serviceRequest.TMSI5GS.SetLen(7)
+	serviceRequest.TMSI5GS.SetTypeOfIdentity(nasMessage.MobileIdentity5GSType5gSTmsi)
+	serviceRequest.TMSI5GS.SetAMFSetID(guti5G.GetAMFSetID())
+	serviceRequest.TMSI5GS.SetAMFPointer(guti5G.GetAMFPointer())
+	serviceRequest.TMSI5GS.SetTMSI5G(guti5G.GetTMSI5G())
+
+	if serviceType == nasMessage.ServiceTypeData {
+		serviceRequest.UplinkDataStatus = nasType.NewUplinkDataStatus(nasMessage.ServiceRequestUplinkDataStatusType)
+		serviceRequest.UplinkDataStatus.SetLen(2)
+		serviceRequest.UplinkDataStatus.Buffer = buildPduSessionStatusBitmap(pduSessionIdList)
+	}
+
+	serviceRequest.PDUSessionStatus = nasType.NewPDUSessionStatus(nasMessage.ServiceRequestPDUSessionStatusType)
+	serviceRequest.PDUSessionStatus.SetLen(2)
+	serviceRequest.PDUSessionStatus.Buffer = buildPduSessionStatusBitmap(pduSessionIdList)
+
+	m.GmmMessage.ServiceRequest = serviceRequest
+
+	request := new(bytes.Buffer)
+	if err := m.GmmMessageEncode(request); err != nil {
+		return nil, err
+	}
+
+	return request.Bytes(), nil
+}
+
+func getServiceRequest(serviceType uint8, ngKsi uint8, guti5G nasType.GUTI5G, pduSessionIdList []uint8) ([]byte, error) {
+	return buildServiceRequest(serviceType, ngKsi, guti5G, pduSessionIdList)
+}
+
 func getNasPduFromNasPduSessionEstablishmentAccept(nasPduSessionEstablishmentAccept *nas.Message) (*nas.Message, error) {
 	content := nasPduSessionEstablishmentAccept.DLNASTransport.GetPayloadContainerContents()
 
@@ -445,3 +696,217 @@ func getNasPduFromNasPduSessionEstablishmentAccept(nasPduSessionEstablishmentAcc
 
 	return nasMessage, nil
 }
+
+// the 5G-GUTI is sent as the mobile identity of the registration updates
+func buildGutiMobileIdentity5GS(guti5G nasType.GUTI5G) nasType.MobileIdentity5GS {
+	return nasType.MobileIdentity5GS{
+		Len:    guti5G.GetLen(),
+		Buffer: guti5G.Octet[:guti5G.GetLen()],
+	}
+}
+
+// the 5G-S-TMSI is the AMF set ID, the AMF pointer and the 5G-TMSI of the 5G-GUTI
+func build5gSTmsiMobileIdentity5GS(guti5G nasType.GUTI5G) nasType.MobileIdentity5GS {
+	buffer := append([]byte{0xf0 | nasMessage.MobileIdentity5GSType5gSTmsi}, guti5G.Octet[5:11]...)
+	return nasType.MobileIdentity5GS{
+		Len:    uint16(len(buffer)),
+		Buffer: buffer,
+	}
+}
+
+// the IMEI and the IMEISV are BCD coded after the first digit, the odd/even indication and the type of identity,
+// an even number of digits is padded by 0xf
+func buildPeiMobileIdentity5GS(typeOfIdentity uint8, digits string) nasType.MobileIdentity5GS {
+	oddIndication := uint8(len(digits)%2) << 3
+	buffer := []byte{(digits[0]-'0')<<4 | oddIndication | typeOfIdentity}
+	for i := 1; i < len(digits); i += 2 {
+		octet := digits[i] - '0'
+		if i+1 < len(digits) {
+			octet |= (digits[i+1] - '0') << 4
+		} else {
+			octet |= 0xf0
+		}
+		buffer = append(buffer, octet)
+	}
+	return nasType.MobileIdentity5GS{
+		Len:    uint16(len(buffer)),
+		Buffer: buffer,
+	}
+}
+
+// TACs of the 5GS tracking area identity list in hex, the PLMN of each partial list is not kept since
+// the UE only moves between RANs of its own PLMN
+func parseTaiList(partialTrackingAreaIdentityList []uint8) ([]string, error) {
+	tacList := []string{}
+	for len(partialTrackingAreaIdentityList) > 0 {
+		typeOfList := (partialTrackingAreaIdentityList[0] >> 5) & 0x03
+		numberOfElements := int(partialTrackingAreaIdentityList[0]&0x1f) + 1
+		partialTrackingAreaIdentityList = partialTrackingAreaIdentityList[1:]
+
+		switch typeOfList {
+		case 0x00:
+			// one PLMN followed by the non-consecutive TACs
+			if len(partialTrackingAreaIdentityList) < 3+3*numberOfElements {
+				return nil, fmt.Errorf("partial tracking area identity list too short: %d bytes", len(partialTrackingAreaIdentityList))
+			}
+			for i := 0; i < numberOfElements; i++ {
+				tacList = append(tacList, hex.EncodeToString(partialTrackingAreaIdentityList[3+3*i:6+3*i]))
+			}
+			partialTrackingAreaIdentityList = partialTrackingAreaIdentityList[3+3*numberOfElements:]
+		case 0x01:
+			// one PLMN followed by the first of the consecutive TACs
+			if len(partialTrackingAreaIdentityList) < 6 {
+				return nil, fmt.Errorf("partial tracking area identity list too short: %d bytes", len(partialTrackingAreaIdentityList))
+			}
+			tac := uint32(partialTrackingAreaIdentityList[3])<<16 | uint32(partialTrackingAreaIdentityList[4])<<8 | uint32(partialTrackingAreaIdentityList[5])
+			for i := 0; i < numberOfElements; i++ {
+				tacList = append(tacList, fmt.Sprintf("%06x", tac+uint32(i)))
+			}
+			partialTrackingAreaIdentityList = partialTrackingAreaIdentityList[6:]
+		case 0x02:
+			// PLMN and TAC pairs
+			if len(partialTrackingAreaIdentityList) < 6*numberOfElements {
+				return nil, fmt.Errorf("partial tracking area identity list too short: %d bytes", len(partialTrackingAreaIdentityList))
+			}
+			for i := 0; i < numberOfElements; i++ {
+				tacList = append(tacList, hex.EncodeToString(partialTrackingAreaIdentityList[6*i+3:6*i+6]))
+			}
+			partialTrackingAreaIdentityList = partialTrackingAreaIdentityList[6*numberOfElements:]
+		default:
+			return nil, fmt.Errorf("unknown type of partial tracking area identity list: %d", typeOfList)
+		}
+	}
+	return tacList, nil
+}
+
+// GPRS timer 3, zero when the timer is deactivated
+func gprsTimer3ToDuration(unit, timerValue uint8) time.Duration {
+	var unitDuration time.Duration
+	switch unit {
+	case 0x00:
+		unitDuration = 10 * time.Minute
+	case 0x01:
+		unitDuration = time.Hour
+	case 0x02:
+		unitDuration = 10 * time.Hour
+	case 0x03:
+		unitDuration = 2 * time.Second
+	case 0x04:
+		unitDuration = 30 * time.Second
+	case 0x05:
+		unitDuration = time.Minute
+	case 0x06:
+		unitDuration = 320 * time.Hour
+	default:
+		return 0
+	}
+	return time.Duration(timerValue) * unitDuration
+}
+
+// GPRS timer 2, zero when the timer is deactivated
+func gprsTimer2ToDuration(timerValue uint8) time.Duration {
+	value := time.Duration(timerValue & 0x1f)
+	switch timerValue >> 5 {
+	case 0x00:
+		return value * 2 * time.Second
+	case 0x01:
+		return value * time.Minute
+	case 0x02:
+		return value * 6 * time.Minute
+	default:
+		return 0
+	}
+}
+
+// TS 24.501 9.11.3.37, each S-NSSAI is encoded with the length of its contents, the mapped HPLMN S-NSSAI follows the
+// S-NSSAI of the serving PLMN
+func buildRequestedNssai(requestedNssai []models.MappingOfSnssai) (*nasType.RequestedNSSAI, error) {
+	if len(requestedNssai) == 0 {
+		return nil, nil
+	}
+
+	buffer := make([]uint8, 0, len(requestedNssai)*9)
+	for _, mappingOfSnssai := range requestedNssai {
+		contents, err := buildSnssaiContents(*mappingOfSnssai.ServingSnssai)
+		if err != nil {
+			return nil, fmt.Errorf("S-NSSAI %+v: %v", *mappingOfSnssai.ServingSnssai, err)
+		}
+		if mappingOfSnssai.HomeSnssai != nil {
+			homeContents, err := buildSnssaiContents(*mappingOfSnssai.HomeSnssai)
+			if err != nil {
+				return nil, fmt.Errorf("mapped HPLMN S-NSSAI %+v: %v", *mappingOfSnssai.HomeSnssai, err)
+			}
+			if len(homeContents) > len(contents) {
+				return nil, fmt.Errorf("mapped HPLMN S-NSSAI %+v has an SD without the SD of the S-NSSAI", *mappingOfSnssai.HomeSnssai)
+			}
+			contents = append(contents, homeContents...)
+		}
+		buffer = append(buffer, uint8(len(contents)))
+		buffer = append(buffer, contents...)
+	}
+
+	requestedNSSAI := nasType.NewRequestedNSSAI(nasMessage.RegistrationRequestRequestedNSSAIType)
+	requestedNSSAI.SetLen(uint8(len(buffer)))
+	requestedNSSAI.SetSNSSAIValue(buffer)
+	return requestedNSSAI, nil
+}
+
+func buildSnssaiContents(snssai models.Snssai) ([]uint8, error) {
+	contents := []uint8{uint8(snssai.Sst)}
+	if snssai.Sd == "" {
+		return contents, nil
+	}
+
+	sd, err := hex.DecodeString(snssai.Sd)
+	if err != nil {
+		return nil, fmt.Errorf("sd decode error: %v", err)
+	}
+	if len(sd) != 3 {
+		return nil, fmt.Errorf("sd should be 3 octets, got %d", len(sd))
+	}
+	return append(contents, sd...), nil
+}
+
+// the S-NSSAI values of the allowed, configured or requested NSSAI, only the S-NSSAIs of the serving PLMN are kept
+func parseNssai(sNssaiValue []uint8) ([]models.Snssai, error) {
+	mappingOfSnssaiList, err := nasConvert.RequestedNssaiToModels(&nasType.RequestedNSSAI{
+		Len:    uint8(len(sNssaiValue)),
+		Buffer: sNssaiValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nssai := make([]models.Snssai, 0, len(mappingOfSnssaiList))
+	for _, mappingOfSnssai := range mappingOfSnssaiList {
+		nssai = append(nssai, *mappingOfSnssai.ServingSnssai)
+	}
+	return nssai, nil
+}
+
+// TS 24.501 9.11.3.46, the length of each rejected S-NSSAI shares its first octet with the reject cause
+func parseRejectedNssai(rejectedNssaiContents []uint8) ([]rejectedSnssai, error) {
+	rejectedNssai := make([]rejectedSnssai, 0)
+	for offset := 0; offset < len(rejectedNssaiContents); {
+		length, cause := int(rejectedNssaiContents[offset]>>4), rejectedNssaiContents[offset]&0x0f
+		if length != 1 && length != 4 {
+			return nil, fmt.Errorf("invalid length of rejected S-NSSAI contents: %d", length)
+		}
+		if offset+1+length > len(rejectedNssaiContents) {
+			return nil, fmt.Errorf("rejected S-NSSAI contents is too short: expected = %d, actual = %d", length, len(rejectedNssaiContents)-offset-1)
+		}
+
+		snssai := models.Snssai{
+			Sst: int32(rejectedNssaiContents[offset+1]),
+		}
+		if length == 4 {
+			snssai.Sd = hex.EncodeToString(rejectedNssaiContents[offset+2 : offset+5])
+		}
+		rejectedNssai = append(rejectedNssai, rejectedSnssai{
+			snssai: snssai,
+			cause:  cause,
+		})
+		offset += 1 + length
+	}
+	return rejectedNssai, nil
+}
@@ -1,8 +1,12 @@
 package ue
 
 import (
+	"encoding/hex"
 	"testing"
+	"time"
 
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasConvert"
 	"github.com/free5gc/nas/nasMessage"
 	"github.com/free5gc/nas/nasType"
 	"github.com/free5gc/openapi/models"
@@ -44,12 +48,19 @@ func TestBuildUeMobileIdentity5GS(t *testing.T) {
 
 var testBuildUeRegistrationRequestCases = []struct {
 	name              string
+	registrationType  uint8
+	ngKsi             uint8
+	followOnRequest   bool
 	mobileIdentity5GS nasType.MobileIdentity5GS
+	pduSessionStatus  *nasType.PDUSessionStatus
 	expectedError     error
 	expected          []byte
 }{
 	{
-		name: "imsi-208930000007487",
+		name:             "imsi-208930000007487",
+		registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+		ngKsi:            uint8(nasMessage.NasKeySetIdentifierNoKeyIsAvailable),
+		followOnRequest:  true,
 		mobileIdentity5GS: nasType.MobileIdentity5GS{
 			Len:    12,
 			Buffer: []byte{0x01, 0x02, 0xf8, 0x39, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x47, 0x78},
@@ -57,12 +68,29 @@ var testBuildUeRegistrationRequestCases = []struct {
 		expectedError: nil,
 		expected:      []byte{0x7e, 0x00, 0x41, 0x79, 0x00, 0x0c, 0x01, 0x02, 0xf8, 0x39, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x47, 0x78},
 	},
+	{
+		name:             "periodicRegistrationUpdatingWithGuti",
+		registrationType: nasMessage.RegistrationType5GSPeriodicRegistrationUpdating,
+		ngKsi:            1,
+		followOnRequest:  false,
+		mobileIdentity5GS: nasType.MobileIdentity5GS{
+			Len:    11,
+			Buffer: []byte{0xf2, 0x02, 0xf8, 0x39, 0xca, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x01},
+		},
+		pduSessionStatus: &nasType.PDUSessionStatus{
+			Iei:    nasMessage.RegistrationRequestPDUSessionStatusType,
+			Len:    2,
+			Buffer: []byte{0x02, 0x00},
+		},
+		expectedError: nil,
+		expected:      []byte{0x7e, 0x00, 0x41, 0x13, 0x00, 0x0b, 0xf2, 0x02, 0xf8, 0x39, 0xca, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x01, 0x50, 0x02, 0x02, 0x00},
+	},
 }
 
 func TestBuildUeRegistrationRequest(t *testing.T) {
 	for _, testCase := range testBuildUeRegistrationRequestCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			result, err := buildUeRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, &testCase.mobileIdentity5GS, nil, nil, nil, nil, nil)
+			result, err := buildUeRegistrationRequest(testCase.registrationType, testCase.ngKsi, testCase.followOnRequest, &testCase.mobileIdentity5GS, nil, nil, nil, nil, nil, testCase.pduSessionStatus)
 			assert.Equal(t, testCase.expectedError, err)
 			assert.Equal(t, testCase.expected, result)
 		})
@@ -72,6 +100,7 @@ func TestBuildUeRegistrationRequest(t *testing.T) {
 var testBuildAuthenticationResponseCases = []struct {
 	name          string
 	param         []byte
+	eapMessage    []byte
 	expectedError error
 }{
 	{
@@ -79,34 +108,145 @@ var testBuildAuthenticationResponseCases = []struct {
 		param:         []byte{0x7e, 0x00, 0x41, 0x79, 0x00, 0x0c, 0x01, 0x02, 0xf8, 0x39, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x47, 0x78},
 		expectedError: nil,
 	},
+	{
+		name:          "testBuildAuthenticationResponseWithEapMessage",
+		eapMessage:    []byte{0x02, 0x01, 0x00, 0x08, 0x32, 0x02, 0x00, 0x00},
+		expectedError: nil,
+	},
 }
 
 func TestBuildAuthenticationResponse(t *testing.T) {
 	for _, testCase := range testBuildAuthenticationResponseCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			_, err := buildAuthenticationResponse(testCase.param)
+			_, err := buildAuthenticationResponse(testCase.param, testCase.eapMessage)
 			assert.Equal(t, testCase.expectedError, err)
 		})
 	}
 }
 
+var testBuildAuthenticationFailureCases = []struct {
+	name     string
+	cause    uint8
+	auts     []byte
+	expected []byte
+}{
+	{
+		name:     "macFailure",
+		cause:    nasMessage.Cause5GMMMACFailure,
+		expected: []byte{0x7e, 0x00, 0x59, 0x14},
+	},
+	{
+		name:     "synchFailure",
+		cause:    nasMessage.Cause5GMMSynchFailure,
+		auts:     []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e},
+		expected: []byte{0x7e, 0x00, 0x59, 0x15, 0x30, 0x0e, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e},
+	},
+}
+
+func TestBuildAuthenticationFailure(t *testing.T) {
+	for _, testCase := range testBuildAuthenticationFailureCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := buildAuthenticationFailure(testCase.cause, testCase.auts)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+}
+
 var testBuildNasSecurityModeCompleteMessageCases = []struct {
-	name          string
-	param         []byte
-	expectedError error
+	name           string
+	param          []byte
+	imeisv         string
+	expectedImeisv string
+	expectedError  error
 }{
 	{
-		name:          "testBuildNasSecurityModeCompleteMessage",
-		param:         []byte{0x7e, 0x00, 0x41, 0x79, 0x00, 0x0c, 0x01, 0x02, 0xf8, 0x39, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x47, 0x78},
-		expectedError: nil,
+		name:           "testBuildNasSecurityModeCompleteMessage",
+		param:          []byte{0x7e, 0x00, 0x41, 0x79, 0x00, 0x0c, 0x01, 0x02, 0xf8, 0x39, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x47, 0x78},
+		imeisv:         "",
+		expectedImeisv: "",
+		expectedError:  nil,
+	},
+	{
+		name:           "testBuildNasSecurityModeCompleteMessageWithImeisv",
+		param:          []byte{0x7e, 0x00, 0x41, 0x79, 0x00, 0x0c, 0x01, 0x02, 0xf8, 0x39, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x47, 0x78},
+		imeisv:         "4370816125816151",
+		expectedImeisv: "imeisv-4370816125816151",
+		expectedError:  nil,
 	},
 }
 
 func TestBuildNasSecurityModeCompleteMessage(t *testing.T) {
 	for _, testCase := range testBuildNasSecurityModeCompleteMessageCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			_, err := buildNasSecurityModeCompleteMessage(testCase.param)
+			var imeisv *nasType.MobileIdentity5GS
+			if testCase.imeisv != "" {
+				imeisvMobileIdentity5GS := buildPeiMobileIdentity5GS(nasMessage.MobileIdentity5GSTypeImeisv, testCase.imeisv)
+				imeisv = &imeisvMobileIdentity5GS
+			}
+
+			result, err := buildNasSecurityModeCompleteMessage(testCase.param, imeisv)
 			assert.Equal(t, testCase.expectedError, err)
+
+			m := nas.NewMessage()
+			assert.Equal(t, nil, m.GmmMessageDecode(&result))
+			if testCase.expectedImeisv == "" {
+				assert.Equal(t, (*nasType.IMEISV)(nil), m.SecurityModeComplete.IMEISV)
+				return
+			}
+			pei, err := nasConvert.PeiToStringWithError(m.SecurityModeComplete.IMEISV.Octet[:m.SecurityModeComplete.IMEISV.GetLen()])
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.expectedImeisv, pei)
+		})
+	}
+}
+
+var testBuildIdentityResponseCases = []struct {
+	name                   string
+	mobileIdentity5GS      nasType.MobileIdentity5GS
+	expectedTypeOfIdentity uint8
+	expectedIdentity       string
+}{
+	{
+		name:                   "testBuildIdentityResponseImei",
+		mobileIdentity5GS:      buildPeiMobileIdentity5GS(nasMessage.MobileIdentity5GSTypeImei, "356938035643809"),
+		expectedTypeOfIdentity: nasMessage.MobileIdentity5GSTypeImei,
+		expectedIdentity:       "imei-356938035643809",
+	},
+	{
+		name:                   "testBuildIdentityResponseImeisv",
+		mobileIdentity5GS:      buildPeiMobileIdentity5GS(nasMessage.MobileIdentity5GSTypeImeisv, "4370816125816151"),
+		expectedTypeOfIdentity: nasMessage.MobileIdentity5GSTypeImeisv,
+		expectedIdentity:       "imeisv-4370816125816151",
+	},
+	{
+		name:                   "testBuildIdentityResponse5gSTmsi",
+		mobileIdentity5GS:      build5gSTmsiMobileIdentity5GS(nasType.GUTI5G{Len: 11, Octet: [11]uint8{0xf2, 0x02, 0xf8, 0x39, 0xca, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x01}}),
+		expectedTypeOfIdentity: nasMessage.MobileIdentity5GSType5gSTmsi,
+		expectedIdentity:       "fe0000000001",
+	},
+}
+
+func TestBuildIdentityResponse(t *testing.T) {
+	for _, testCase := range testBuildIdentityResponseCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := buildIdentityResponse(testCase.mobileIdentity5GS)
+			assert.Equal(t, nil, err)
+
+			m := nas.NewMessage()
+			assert.Equal(t, nil, m.GmmMessageDecode(&result))
+			assert.Equal(t, uint8(nas.MsgTypeIdentityResponse), m.GmmHeader.GetMessageType())
+
+			mobileIdentity := m.IdentityResponse.MobileIdentity.GetMobileIdentityContents()
+			assert.Equal(t, testCase.expectedTypeOfIdentity, mobileIdentity[0]&0x07)
+			switch testCase.expectedTypeOfIdentity {
+			case nasMessage.MobileIdentity5GSType5gSTmsi:
+				assert.Equal(t, testCase.expectedIdentity, hex.EncodeToString(mobileIdentity[1:]))
+			default:
+				pei, err := nasConvert.PeiToStringWithError(mobileIdentity)
+				assert.Equal(t, nil, err)
+				assert.Equal(t, testCase.expectedIdentity, pei)
+			}
 		})
 	}
 }
@@ -132,22 +272,82 @@ func TestBuildNasRegistrationCompleteMessage(t *testing.T) {
 	}
 }
 
+func TestBuildConfigurationUpdateComplete(t *testing.T) {
+	result, err := buildConfigurationUpdateComplete()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []byte{0x7e, 0x00, 0x55}, result)
+}
+
 var testBuildPduSessionEstablishmentRequestCases = []struct {
+	name           string
+	pduSessionId   uint8
+	pduSessionType uint8
+	expectedError  error
+}{
+	{
+		name:           "testBuildPduSessionEstablishmentRequest",
+		pduSessionId:   4,
+		pduSessionType: nasMessage.PDUSessionTypeIPv4,
+		expectedError:  nil,
+	},
+	{
+		name:           "testBuildPduSessionEstablishmentRequestIPv4v6",
+		pduSessionId:   5,
+		pduSessionType: nasMessage.PDUSessionTypeIPv4IPv6,
+		expectedError:  nil,
+	},
+}
+
+func TestBuildPduSessionEstablishmentRequest(t *testing.T) {
+	for _, testCase := range testBuildPduSessionEstablishmentRequestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := buildPduSessionEstablishmentRequest(testCase.pduSessionId, testCase.pduSessionType)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
+var testBuildPduSessionReleaseCompleteCases = []struct {
 	name          string
 	pduSessionId  uint8
+	pti           uint8
 	expectedError error
 }{
 	{
-		name:          "testBuildPduSessionEstablishmentRequest",
+		name:          "testBuildPduSessionReleaseComplete",
 		pduSessionId:  4,
+		pti:           0,
 		expectedError: nil,
 	},
 }
 
-func TestBuildPduSessionEstablishmentRequest(t *testing.T) {
-	for _, testCase := range testBuildPduSessionEstablishmentRequestCases {
+func TestBuildPduSessionReleaseComplete(t *testing.T) {
+	for _, testCase := range testBuildPduSessionReleaseCompleteCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			_, err := buildPduSessionEstablishmentRequest(testCase.pduSessionId)
+			_, err := buildPduSessionReleaseComplete(testCase.pduSessionId, testCase.pti)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
+var testBuildPduSessionModificationCompleteCases = []struct {
+	name          string
+	pduSessionId  uint8
+	pti           uint8
+	expectedError error
+}{
+	{
+		name:          "testBuildPduSessionModificationComplete",
+		pduSessionId:  4,
+		pti:           0,
+		expectedError: nil,
+	},
+}
+
+func TestBuildPduSessionModificationComplete(t *testing.T) {
+	for _, testCase := range testBuildPduSessionModificationCompleteCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := buildPduSessionModificationComplete(testCase.pduSessionId, testCase.pti)
 			assert.Equal(t, testCase.expectedError, err)
 		})
 	}
@@ -214,3 +414,352 @@ func TestBuildUeDeRegistrationRequest(t *testing.T) {
 		})
 	}
 }
+
+var testBuildServiceRequestCases = []struct {
+	name             string
+	serviceType      uint8
+	ngKsi            uint8
+	guti5G           nasType.GUTI5G
+	pduSessionIdList []uint8
+	expectedError    error
+}{
+	{
+		name:        "testBuildServiceRequestData",
+		serviceType: nasMessage.ServiceTypeData,
+		ngKsi:       1,
+		guti5G: nasType.GUTI5G{
+			Iei:   nasMessage.RegistrationAcceptGUTI5GType,
+			Len:   11,
+			Octet: [11]uint8{0xf2, 0x02, 0xf8, 0x39, 0xca, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x01},
+		},
+		pduSessionIdList: []uint8{1, 2},
+		expectedError:    nil,
+	},
+	{
+		name:        "testBuildServiceRequestMobileTerminatedServices",
+		serviceType: nasMessage.ServiceTypeMobileTerminatedServices,
+		ngKsi:       1,
+		guti5G: nasType.GUTI5G{
+			Iei:   nasMessage.RegistrationAcceptGUTI5GType,
+			Len:   11,
+			Octet: [11]uint8{0xf2, 0x02, 0xf8, 0x39, 0xca, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x01},
+		},
+		pduSessionIdList: []uint8{1},
+		expectedError:    nil,
+	},
+}
+
+func TestBuildServiceRequest(t *testing.T) {
+	for _, testCase := range testBuildServiceRequestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			serviceRequest, err := buildServiceRequest(testCase.serviceType, testCase.ngKsi, testCase.guti5G, testCase.pduSessionIdList)
+			assert.Equal(t, testCase.expectedError, err)
+
+			m := nas.NewMessage()
+			assert.Equal(t, nil, m.GmmMessageDecode(&serviceRequest))
+			assert.Equal(t, testCase.serviceType, m.ServiceRequest.GetServiceTypeValue())
+			assert.Equal(t, testCase.guti5G.GetAMFSetID(), m.ServiceRequest.TMSI5GS.GetAMFSetID())
+			assert.Equal(t, testCase.guti5G.GetAMFPointer(), m.ServiceRequest.TMSI5GS.GetAMFPointer())
+			assert.Equal(t, testCase.guti5G.GetTMSI5G(), m.ServiceRequest.TMSI5GS.GetTMSI5G())
+			assert.Equal(t, buildPduSessionStatusBitmap(testCase.pduSessionIdList), m.ServiceRequest.PDUSessionStatus.Buffer)
+			assert.Equal(t, testCase.serviceType == nasMessage.ServiceTypeData, m.ServiceRequest.UplinkDataStatus != nil)
+		})
+	}
+}
+
+var testParseTaiListCases = []struct {
+	name                            string
+	partialTrackingAreaIdentityList []uint8
+	expectedError                   bool
+	expected                        []string
+}{
+	{
+		name:                            "nonConsecutiveTacs",
+		partialTrackingAreaIdentityList: []uint8{0x01, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x01, 0x00, 0x00, 0x05},
+		expected:                        []string{"000001", "000005"},
+	},
+	{
+		name:                            "consecutiveTacs",
+		partialTrackingAreaIdentityList: []uint8{0x22, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x01},
+		expected:                        []string{"000001", "000002", "000003"},
+	},
+	{
+		name:                            "taisWithDifferentPlmns",
+		partialTrackingAreaIdentityList: []uint8{0x41, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x01, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x02},
+		expected:                        []string{"000001", "000002"},
+	},
+	{
+		name:                            "multiplePartialLists",
+		partialTrackingAreaIdentityList: []uint8{0x00, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x01, 0x20, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x07},
+		expected:                        []string{"000001", "000007"},
+	},
+	{
+		name:                            "truncated",
+		partialTrackingAreaIdentityList: []uint8{0x01, 0x02, 0xf8, 0x39, 0x00, 0x00, 0x01},
+		expectedError:                   true,
+	},
+}
+
+func TestParseTaiList(t *testing.T) {
+	for _, testCase := range testParseTaiListCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := parseTaiList(testCase.partialTrackingAreaIdentityList)
+			assert.Equal(t, testCase.expectedError, err != nil)
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+}
+
+var testGprsTimer3ToDurationCases = []struct {
+	name       string
+	unit       uint8
+	timerValue uint8
+	expected   time.Duration
+}{
+	{
+		name:       "tenMinutes",
+		unit:       0x00,
+		timerValue: 5,
+		expected:   50 * time.Minute,
+	},
+	{
+		name:       "twoSeconds",
+		unit:       0x03,
+		timerValue: 10,
+		expected:   20 * time.Second,
+	},
+	{
+		name:       "oneMinute",
+		unit:       0x05,
+		timerValue: 54,
+		expected:   54 * time.Minute,
+	},
+	{
+		name:       "deactivated",
+		unit:       0x07,
+		timerValue: 1,
+		expected:   0,
+	},
+}
+
+func TestGprsTimer3ToDuration(t *testing.T) {
+	for _, testCase := range testGprsTimer3ToDurationCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, gprsTimer3ToDuration(testCase.unit, testCase.timerValue))
+		})
+	}
+}
+
+var testGprsTimer2ToDurationCases = []struct {
+	name       string
+	timerValue uint8
+	expected   time.Duration
+}{
+	{
+		name:       "twoSeconds",
+		timerValue: 0x0c,
+		expected:   24 * time.Second,
+	},
+	{
+		name:       "oneMinute",
+		timerValue: 0x2c,
+		expected:   12 * time.Minute,
+	},
+	{
+		name:       "decihours",
+		timerValue: 0x42,
+		expected:   12 * time.Minute,
+	},
+	{
+		name:       "deactivated",
+		timerValue: 0xe1,
+		expected:   0,
+	},
+}
+
+func TestGprsTimer2ToDuration(t *testing.T) {
+	for _, testCase := range testGprsTimer2ToDurationCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, gprsTimer2ToDuration(testCase.timerValue))
+		})
+	}
+}
+
+var testParseNssaiCases = []struct {
+	name          string
+	sNssaiValue   []uint8
+	expected      []models.Snssai
+	expectedError bool
+}{
+	{
+		name:        "sstOnly",
+		sNssaiValue: []uint8{0x01, 0x01},
+		expected: []models.Snssai{
+			{Sst: 1},
+		},
+	},
+	{
+		name:        "sstAndSd",
+		sNssaiValue: []uint8{0x04, 0x01, 0x01, 0x02, 0x03, 0x01, 0x02},
+		expected: []models.Snssai{
+			{Sst: 1, Sd: "010203"},
+			{Sst: 2},
+		},
+	},
+}
+
+func TestParseNssai(t *testing.T) {
+	for _, testCase := range testParseNssaiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := parseNssai(testCase.sNssaiValue)
+			assert.Equal(t, testCase.expectedError, err != nil)
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+}
+
+var testBuildRequestedNssaiCases = []struct {
+	name           string
+	requestedNssai []models.MappingOfSnssai
+	expected       []uint8
+	expectedError  bool
+}{
+	{
+		name:           "empty",
+		requestedNssai: nil,
+		expected:       nil,
+	},
+	{
+		name: "sstAndMappedHplmnSnssai",
+		requestedNssai: []models.MappingOfSnssai{
+			{
+				ServingSnssai: &models.Snssai{Sst: 1, Sd: "010203"},
+			},
+			{
+				ServingSnssai: &models.Snssai{Sst: 2},
+				HomeSnssai:    &models.Snssai{Sst: 3},
+			},
+			{
+				ServingSnssai: &models.Snssai{Sst: 1, Sd: "010203"},
+				HomeSnssai:    &models.Snssai{Sst: 1, Sd: "112233"},
+			},
+		},
+		expected: []uint8{0x04, 0x01, 0x01, 0x02, 0x03, 0x02, 0x02, 0x03, 0x08, 0x01, 0x01, 0x02, 0x03, 0x01, 0x11, 0x22, 0x33},
+	},
+	{
+		name: "mappedHplmnSdWithoutSd",
+		requestedNssai: []models.MappingOfSnssai{
+			{
+				ServingSnssai: &models.Snssai{Sst: 1},
+				HomeSnssai:    &models.Snssai{Sst: 1, Sd: "112233"},
+			},
+		},
+		expectedError: true,
+	},
+}
+
+func TestBuildRequestedNssai(t *testing.T) {
+	for _, testCase := range testBuildRequestedNssaiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requestedNSSAI, err := buildRequestedNssai(testCase.requestedNssai)
+			assert.Equal(t, testCase.expectedError, err != nil)
+			if requestedNSSAI == nil {
+				assert.Equal(t, testCase.expected, []uint8(nil))
+				return
+			}
+
+			assert.Equal(t, nasMessage.RegistrationRequestRequestedNSSAIType, requestedNSSAI.GetIei())
+			assert.Equal(t, testCase.expected, requestedNSSAI.GetSNSSAIValue())
+
+			decoded, err := nasConvert.RequestedNssaiToModels(requestedNSSAI)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.requestedNssai, decoded)
+		})
+	}
+}
+
+var testParseRejectedNssaiCases = []struct {
+	name                  string
+	rejectedNssaiContents []uint8
+	expected              []rejectedSnssai
+	expectedError         bool
+}{
+	{
+		name:                  "sstOnlyAndSstAndSd",
+		rejectedNssaiContents: []uint8{0x10, 0x01, 0x41, 0x02, 0x01, 0x02, 0x03},
+		expected: []rejectedSnssai{
+			{snssai: models.Snssai{Sst: 1}, cause: nasMessage.RejectedSnssaiCauseNotAvailableInCurrentPlmn},
+			{snssai: models.Snssai{Sst: 2, Sd: "010203"}, cause: nasMessage.RejectedSnssaiCauseNotAvailableInCurrentRegistrationArea},
+		},
+	},
+	{
+		name:                  "invalidLength",
+		rejectedNssaiContents: []uint8{0x20, 0x01, 0x02},
+		expectedError:         true,
+	},
+	{
+		name:                  "tooShort",
+		rejectedNssaiContents: []uint8{0x40, 0x01, 0x02},
+		expectedError:         true,
+	},
+}
+
+func TestParseRejectedNssai(t *testing.T) {
+	for _, testCase := range testParseRejectedNssaiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := parseRejectedNssai(testCase.rejectedNssaiContents)
+			assert.Equal(t, testCase.expectedError, err != nil)
+			if !testCase.expectedError {
+				assert.Equal(t, testCase.expected, result)
+			}
+		})
+	}
+}
+
+var testBuildSuciMobileIdentity5GSCases = []struct {
+	name                   string
+	supi                   string
+	protectionScheme       uint8
+	homeNetworkPublicKeyId uint8
+	schemeOutput           []byte
+	expected               nasType.MobileIdentity5GS
+}{
+	{
+		name:                   "profileA",
+		supi:                   "208930000000001",
+		protectionScheme:       uint8(nasMessage.ProtectionSchemeECIESProfileA),
+		homeNetworkPublicKeyId: 1,
+		schemeOutput:           []byte{0xaa, 0xbb, 0xcc},
+		expected: nasType.MobileIdentity5GS{
+			Len:    11,
+			Buffer: []byte{0x01, 0x02, 0xf8, 0x39, 0xf0, 0xff, 0x01, 0x01, 0xaa, 0xbb, 0xcc},
+		},
+	},
+	{
+		name:                   "profileB",
+		supi:                   "208930000000001",
+		protectionScheme:       uint8(nasMessage.ProtectionSchemeECIESProfileB),
+		homeNetworkPublicKeyId: 2,
+		schemeOutput:           []byte{0x01},
+		expected: nasType.MobileIdentity5GS{
+			Len:    9,
+			Buffer: []byte{0x01, 0x02, 0xf8, 0x39, 0xf0, 0xff, 0x02, 0x02, 0x01},
+		},
+	},
+}
+
+func TestBuildSuciMobileIdentity5GS(t *testing.T) {
+	for _, testCase := range testBuildSuciMobileIdentity5GSCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := buildSuciMobileIdentity5GS(testCase.supi, testCase.protectionScheme, testCase.homeNetworkPublicKeyId, testCase.schemeOutput)
+			assert.Equal(t, testCase.expected.Len, result.Len)
+			assert.Equal(t, testCase.expected.Buffer, result.Buffer)
+		})
+	}
+}
+
+func TestBuildUeTerminatedDeregistrationAccept(t *testing.T) {
+	result, err := buildUeTerminatedDeregistrationAccept()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []byte{0x7e, 0x00, 0x48}, result)
+}
@@ -0,0 +1,163 @@
+package ue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+)
+
+// RejectError is returned when the network rejects the registration, the authentication, the service request or the
+// pdu session establishment, so the cause and the back-off timer can be asserted by the caller through errors.As
+type RejectError struct {
+	// nas.MsgTypeRegistrationReject, nas.MsgTypeServiceReject, nas.MsgTypePDUSessionEstablishmentReject or
+	// nas.MsgTypeAuthenticationReject, which has no cause
+	MessageType uint8
+	// 5GMM cause for the registration and the service reject, 5GSM cause for the pdu session establishment reject
+	Cause uint8
+	// T3346 for the registration and the service reject, T3396 for the pdu session establishment reject,
+	// zero when not given or deactivated by the network
+	BackOff time.Duration
+	// only set for the pdu session establishment reject
+	PduSessionId uint8
+
+	retry bool
+}
+
+func (e *RejectError) Error() string {
+	var rejectMessage string
+	switch e.MessageType {
+	case nas.MsgTypeRegistrationReject:
+		rejectMessage = fmt.Sprintf("registration rejected, 5GMM cause: %s", cause5GmmToString(e.Cause))
+	case nas.MsgTypeServiceReject:
+		rejectMessage = fmt.Sprintf("service request rejected, 5GMM cause: %s", cause5GmmToString(e.Cause))
+	case nas.MsgTypePDUSessionEstablishmentReject:
+		rejectMessage = fmt.Sprintf("pdu session %d establishment rejected, 5GSM cause: %d", e.PduSessionId, e.Cause)
+	case nas.MsgTypeAuthenticationReject:
+		rejectMessage = "authentication rejected by the network"
+	default:
+		rejectMessage = fmt.Sprintf("message %d rejected, cause: %d", e.MessageType, e.Cause)
+	}
+	if e.BackOff == 0 {
+		return rejectMessage
+	}
+	return fmt.Sprintf("%s, back-off: %v", rejectMessage, e.BackOff)
+}
+
+func cause5GmmToString(cause uint8) string {
+	if causeString := nasMessage.Cause5GMMToString(cause); causeString != "" {
+		return causeString
+	}
+	return fmt.Sprintf("Unknown (%d)", cause)
+}
+
+// TS 24.501 9.11.3.46 table 9.11.3.46.1
+func rejectedSnssaiCauseToString(cause uint8) string {
+	switch cause {
+	case nasMessage.RejectedSnssaiCauseNotAvailableInCurrentPlmn:
+		return "S-NSSAI not available in the current PLMN"
+	case nasMessage.RejectedSnssaiCauseNotAvailableInCurrentRegistrationArea:
+		return "S-NSSAI not available in the current registration area"
+	case 0x02:
+		return "S-NSSAI not available due to the failed or revoked network slice-specific authentication and authorization"
+	default:
+		return fmt.Sprintf("Unknown (%d)", cause)
+	}
+}
+
+func getRegistrationRejectError(registrationReject *nasMessage.RegistrationReject) *RejectError {
+	rejectErr := &RejectError{
+		MessageType: nas.MsgTypeRegistrationReject,
+		Cause:       registrationReject.GetCauseValue(),
+	}
+	if registrationReject.T3346Value != nil {
+		rejectErr.BackOff = gprsTimer2ToDuration(registrationReject.T3346Value.GetGPRSTimer2Value())
+	}
+
+	// TS 24.501 5.5.1.2.5, the registration is attempted again with the SUCI when the network cannot identify the UE,
+	// or once T3346 expires when the network is congested
+	switch rejectErr.Cause {
+	case nasMessage.Cause5GMMUEIdentityCannotBeDerivedByTheNetwork, nasMessage.Cause5GMMImplicitlyDeregistered:
+		rejectErr.retry = true
+	case nasMessage.Cause5GMMCongestion:
+		rejectErr.retry = rejectErr.BackOff > 0
+	}
+	return rejectErr
+}
+
+func getServiceRejectError(serviceReject *nasMessage.ServiceReject) *RejectError {
+	rejectErr := &RejectError{
+		MessageType: nas.MsgTypeServiceReject,
+		Cause:       serviceReject.GetCauseValue(),
+	}
+	if serviceReject.T3346Value != nil {
+		rejectErr.BackOff = gprsTimer2ToDuration(serviceReject.T3346Value.GetGPRSTimer2Value())
+	}
+	return rejectErr
+}
+
+func getPduSessionEstablishmentRejectError(pduSessionEstablishmentReject *nasMessage.PDUSessionEstablishmentReject) *RejectError {
+	rejectErr := &RejectError{
+		MessageType:  nas.MsgTypePDUSessionEstablishmentReject,
+		Cause:        pduSessionEstablishmentReject.GetCauseValue(),
+		PduSessionId: pduSessionEstablishmentReject.GetPDUSessionID(),
+	}
+	if pduSessionEstablishmentReject.BackoffTimerValue != nil {
+		rejectErr.BackOff = gprsTimer3ToDuration(pduSessionEstablishmentReject.BackoffTimerValue.GetUnitTimerValue(), pduSessionEstablishmentReject.BackoffTimerValue.GetTimerValue())
+	}
+
+	// TS 24.501 6.4.1.4.3, the same request is only sent again after T3396 when the network lacks the resources,
+	// the other causes are not solved by sending it again
+	switch rejectErr.Cause {
+	case nasMessage.Cause5GSMInsufficientResources,
+		nasMessage.Cause5GSMInsufficientResourcesForSpecificSliceAndDNN,
+		nasMessage.Cause5GSMInsufficientResourcesForSpecificSlice:
+		rejectErr.retry = rejectErr.BackOff > 0
+	}
+	return rejectErr
+}
+
+// the back-off before the failed procedure is attempted again, false when another attempt does not help
+func getRetryBackOff(err error) (time.Duration, bool) {
+	var rejectErr *RejectError
+	if errors.As(err, &rejectErr) {
+		return rejectErr.BackOff, rejectErr.retry
+	}
+	var nasTimerExpiredErr *nasTimerExpiredError
+	if errors.As(err, &nasTimerExpiredErr) {
+		return nasTimerExpiredErr.retryAfter, nasTimerExpiredErr.retryAfter > 0
+	}
+	return 0, false
+}
+
+// the procedure is attempted again after the back-off of a retriable reject or after T3511 when the network does not
+// answer, bounded like the registration attempt counter, prepareRetry sets up what the failure tore down
+func (u *Ue) retryNasProcedure(ctx context.Context, procedure func() error, prepareRetry func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := procedure()
+
+		backOff, retry := getRetryBackOff(err)
+		if err == nil || !retry || attempt >= constant.UE_PROCEDURE_MAX_ATTEMPTS {
+			return err
+		}
+		u.NasLog.Warnf("Attempt %d failed: %v, retry after %v", attempt, err, backOff)
+
+		backOffTimer := time.NewTimer(backOff)
+		select {
+		case <-ctx.Done():
+			backOffTimer.Stop()
+			return err
+		case <-backOffTimer.C:
+		}
+
+		if prepareRetry != nil {
+			if err := prepareRetry(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/go-playground/assert"
+)
+
+func newTestRegistrationReject(cause uint8, t3346 *uint8) *nasMessage.RegistrationReject {
+	registrationReject := nasMessage.NewRegistrationReject(0)
+	registrationReject.SetCauseValue(cause)
+	if t3346 != nil {
+		registrationReject.T3346Value = nasType.NewT3346Value(nasMessage.RegistrationRejectT3346ValueType)
+		registrationReject.T3346Value.SetLen(1)
+		registrationReject.T3346Value.SetGPRSTimer2Value(*t3346)
+	}
+	return registrationReject
+}
+
+func newTestPduSessionEstablishmentReject(pduSessionId, cause uint8, t3396 *uint8) *nasMessage.PDUSessionEstablishmentReject {
+	pduSessionEstablishmentReject := nasMessage.NewPDUSessionEstablishmentReject(0)
+	pduSessionEstablishmentReject.SetPDUSessionID(pduSessionId)
+	pduSessionEstablishmentReject.SetCauseValue(cause)
+	if t3396 != nil {
+		pduSessionEstablishmentReject.BackoffTimerValue = nasType.NewBackoffTimerValue(nasMessage.PDUSessionEstablishmentRejectBackoffTimerValueType)
+		pduSessionEstablishmentReject.BackoffTimerValue.SetLen(1)
+		pduSessionEstablishmentReject.BackoffTimerValue.Octet = *t3396
+	}
+	return pduSessionEstablishmentReject
+}
+
+func timerValue(value uint8) *uint8 {
+	return &value
+}
+
+var testGetRegistrationRejectErrorCases = []struct {
+	name               string
+	registrationReject *nasMessage.RegistrationReject
+	expectedBackOff    time.Duration
+	expectedRetry      bool
+	expectedError      string
+}{
+	{
+		name:               "congestion with T3346",
+		registrationReject: newTestRegistrationReject(nasMessage.Cause5GMMCongestion, timerValue(0x21)),
+		expectedBackOff:    time.Minute,
+		expectedRetry:      true,
+		expectedError:      "registration rejected, 5GMM cause: Congestion (22), back-off: 1m0s",
+	},
+	{
+		name:               "congestion without T3346",
+		registrationReject: newTestRegistrationReject(nasMessage.Cause5GMMCongestion, nil),
+		expectedBackOff:    0,
+		expectedRetry:      false,
+		expectedError:      "registration rejected, 5GMM cause: Congestion (22)",
+	},
+	{
+		name:               "congestion with deactivated T3346",
+		registrationReject: newTestRegistrationReject(nasMessage.Cause5GMMCongestion, timerValue(0xe0)),
+		expectedBackOff:    0,
+		expectedRetry:      false,
+		expectedError:      "registration rejected, 5GMM cause: Congestion (22)",
+	},
+	{
+		name:               "ue identity cannot be derived by the network",
+		registrationReject: newTestRegistrationReject(nasMessage.Cause5GMMUEIdentityCannotBeDerivedByTheNetwork, nil),
+		expectedBackOff:    0,
+		expectedRetry:      true,
+		expectedError:      "registration rejected, 5GMM cause: UE identity cannot be derived by the network (9)",
+	},
+	{
+		name:               "illegal ue",
+		registrationReject: newTestRegistrationReject(nasMessage.Cause5GMMIllegalUE, nil),
+		expectedBackOff:    0,
+		expectedRetry:      false,
+		expectedError:      "registration rejected, 5GMM cause: Illegal UE (3)",
+	},
+	{
+		name:               "unknown cause",
+		registrationReject: newTestRegistrationReject(0xff, nil),
+		expectedBackOff:    0,
+		expectedRetry:      false,
+		expectedError:      "registration rejected, 5GMM cause: Unknown (255)",
+	},
+}
+
+func TestGetRegistrationRejectError(t *testing.T) {
+	for _, testCase := range testGetRegistrationRejectErrorCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rejectErr := getRegistrationRejectError(testCase.registrationReject)
+			assert.Equal(t, uint8(nas.MsgTypeRegistrationReject), rejectErr.MessageType)
+			assert.Equal(t, testCase.registrationReject.GetCauseValue(), rejectErr.Cause)
+			assert.Equal(t, testCase.expectedBackOff, rejectErr.BackOff)
+			assert.Equal(t, testCase.expectedRetry, rejectErr.retry)
+			assert.Equal(t, testCase.expectedError, rejectErr.Error())
+		})
+	}
+}
+
+var testGetPduSessionEstablishmentRejectErrorCases = []struct {
+	name                          string
+	pduSessionEstablishmentReject *nasMessage.PDUSessionEstablishmentReject
+	expectedBackOff               time.Duration
+	expectedRetry                 bool
+	expectedError                 string
+}{
+	{
+		name:                          "insufficient resources with T3396",
+		pduSessionEstablishmentReject: newTestPduSessionEstablishmentReject(1, nasMessage.Cause5GSMInsufficientResources, timerValue(0x65)),
+		expectedBackOff:               10 * time.Second,
+		expectedRetry:                 true,
+		expectedError:                 "pdu session 1 establishment rejected, 5GSM cause: 26, back-off: 10s",
+	},
+	{
+		name:                          "insufficient resources without T3396",
+		pduSessionEstablishmentReject: newTestPduSessionEstablishmentReject(1, nasMessage.Cause5GSMInsufficientResources, nil),
+		expectedBackOff:               0,
+		expectedRetry:                 false,
+		expectedError:                 "pdu session 1 establishment rejected, 5GSM cause: 26",
+	},
+	{
+		name:                          "missing or unknown dnn with T3396",
+		pduSessionEstablishmentReject: newTestPduSessionEstablishmentReject(2, nasMessage.Cause5GSMMissingOrUnknownDNN, timerValue(0x65)),
+		expectedBackOff:               10 * time.Second,
+		expectedRetry:                 false,
+		expectedError:                 "pdu session 2 establishment rejected, 5GSM cause: 27, back-off: 10s",
+	},
+}
+
+func TestGetPduSessionEstablishmentRejectError(t *testing.T) {
+	for _, testCase := range testGetPduSessionEstablishmentRejectErrorCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rejectErr := getPduSessionEstablishmentRejectError(testCase.pduSessionEstablishmentReject)
+			assert.Equal(t, uint8(nas.MsgTypePDUSessionEstablishmentReject), rejectErr.MessageType)
+			assert.Equal(t, testCase.pduSessionEstablishmentReject.GetPDUSessionID(), rejectErr.PduSessionId)
+			assert.Equal(t, testCase.pduSessionEstablishmentReject.GetCauseValue(), rejectErr.Cause)
+			assert.Equal(t, testCase.expectedBackOff, rejectErr.BackOff)
+			assert.Equal(t, testCase.expectedRetry, rejectErr.retry)
+			assert.Equal(t, testCase.expectedError, rejectErr.Error())
+		})
+	}
+}
+
+var testGetRetryBackOffCases = []struct {
+	name            string
+	err             error
+	expectedBackOff time.Duration
+	expectedRetry   bool
+}{
+	{
+		name:            "retriable reject",
+		err:             &RejectError{MessageType: nas.MsgTypeRegistrationReject, BackOff: time.Minute, retry: true},
+		expectedBackOff: time.Minute,
+		expectedRetry:   true,
+	},
+	{
+		name:            "authentication reject",
+		err:             &RejectError{MessageType: nas.MsgTypeAuthenticationReject},
+		expectedBackOff: 0,
+		expectedRetry:   false,
+	},
+	{
+		name:            "T3510 expired",
+		err:             &nasTimerExpiredError{timer: "T3510", duration: 15 * time.Second, retryAfter: 10 * time.Second},
+		expectedBackOff: 10 * time.Second,
+		expectedRetry:   true,
+	},
+	{
+		name:            "T3580 expired",
+		err:             &nasTimerExpiredError{timer: "T3580", duration: 16 * time.Second},
+		expectedBackOff: 0,
+		expectedRetry:   false,
+	},
+	{
+		name:            "other error",
+		err:             errors.New("error read nas message"),
+		expectedBackOff: 0,
+		expectedRetry:   false,
+	},
+}
+
+func TestGetRetryBackOff(t *testing.T) {
+	for _, testCase := range testGetRetryBackOffCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			backOff, retry := getRetryBackOff(testCase.err)
+			assert.Equal(t, testCase.expectedBackOff, backOff)
+			assert.Equal(t, testCase.expectedRetry, retry)
+		})
+	}
+}
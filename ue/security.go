@@ -2,13 +2,26 @@ package ue
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
 	"regexp"
+	"slices"
 
+	"github.com/Alonza0314/free-ran-ue/constant"
 	"github.com/free5gc/nas"
 	"github.com/free5gc/nas/nasMessage"
 	"github.com/free5gc/nas/security"
+	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/milenage"
 	"github.com/free5gc/util/ueauth"
 )
@@ -103,7 +116,12 @@ func deriveKAmf(supi string, key []byte, snName string, SQN, AK []byte) ([]byte,
 	if err != nil {
 		return nil, fmt.Errorf("GetKDFValue error: %+v", err)
 	}
-	P0 = []byte(snName)
+	return deriveKAmfFromKAusf(supi, Kausf, snName)
+}
+
+// KSEAF and KAMF follow the KAUSF the same way for 5G-AKA and EAP-AKA'
+func deriveKAmfFromKAusf(supi string, Kausf []byte, snName string) ([]byte, error) {
+	P0 := []byte(snName)
 	Kseaf, err := ueauth.GetKDFValue(Kausf, ueauth.FC_FOR_KSEAF_DERIVATION, P0, ueauth.KDFLen(P0))
 	if err != nil {
 		return nil, fmt.Errorf("GetKDFValue error: %+v", err)
@@ -117,7 +135,7 @@ func deriveKAmf(supi string, key []byte, snName string, SQN, AK []byte) ([]byte,
 
 	P0 = []byte(groups[1])
 	L0 := ueauth.KDFLen(P0)
-	P1 = []byte{0x00, 0x00}
+	P1 := []byte{0x00, 0x00}
 	L1 := ueauth.KDFLen(P1)
 
 	return ueauth.GetKDFValue(Kseaf, ueauth.FC_FOR_KAMF_DERIVATION, P0, L0, P1, L1)
@@ -149,6 +167,11 @@ func deriveAlgorithmKey(kAmf []byte, cipheringAlgorithm, integrityAlgorithm uint
 	return kenc, kint, nil
 }
 
+// TS 24.501 9.12.1, the MNC of two digits is padded with a leading zero
+func buildServingNetworkName(plmnId models.PlmnId) string {
+	return fmt.Sprintf("5G:mnc%03s.mcc%s.3gppnetwork.org", plmnId.Mnc, plmnId.Mcc)
+}
+
 func deriveSequenceNumber(autn []byte, ak []uint8) []byte {
 	sqn := make([]byte, 6)
 
@@ -161,6 +184,44 @@ func deriveSequenceNumber(autn []byte, ak []uint8) []byte {
 	return sqn
 }
 
+// the AUTN is accepted when its MAC-A is verified and its SQN is fresh against the highest accepted SQN of the UE,
+// otherwise the 5GMM cause of the authentication failure is returned, with the AUTS to resynchronise on a synch failure
+func verifyAutn(sqnMs, encPermanentKey, encOpcKey string, rand, autn []byte) (uint8, []byte, error) {
+	sqnMsHex, err := hex.DecodeString(sqnMs)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error decode sqn: %v", err)
+	}
+	kHex, err := hex.DecodeString(encPermanentKey)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error decode encPermanentKey: %v", err)
+	}
+	opcHex, err := hex.DecodeString(encOpcKey)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error decode encOpcKey: %v", err)
+	}
+
+	sqnHe, _, _, _, _, err := milenage.GenerateKeysWithAUTN(opcHex, kHex, rand, autn)
+	if err != nil {
+		var macFailureError *milenage.MACFailureError
+		if errors.As(err, &macFailureError) {
+			return nasMessage.Cause5GMMMACFailure, nil, nil
+		}
+		return 0, nil, fmt.Errorf("error verify autn: %v", err)
+	}
+
+	// TS 33.102 annex C.2.1, the SQN is fresh when it is greater than SQNms but not by more than delta
+	sqnHeValue, sqnMsValue := new(big.Int).SetBytes(sqnHe), new(big.Int).SetBytes(sqnMsHex)
+	if difference := new(big.Int).Sub(sqnHeValue, sqnMsValue); difference.Sign() > 0 && difference.Cmp(big.NewInt(constant.UE_SQN_DELTA)) <= 0 {
+		return 0, nil, nil
+	}
+
+	auts, err := milenage.GenerateAUTS(opcHex, kHex, rand, sqnMsHex)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error generate auts: %v", err)
+	}
+	return nasMessage.Cause5GMMSynchFailure, auts, nil
+}
+
 func deriveResStarAndSetKey(supi string, cipheringAlgorithm, integrityAlgorithm uint8, sqn, amf, encPermanentKey, encOpcKey string, rand []byte, autn []byte, snName string) ([]byte, []byte, []byte, []byte, string, error) {
 	sqnHex, err := hex.DecodeString(sqn)
 	if err != nil {
@@ -224,6 +285,56 @@ func deriveResStarAndSetKey(supi string, cipheringAlgorithm, integrityAlgorithm
 	return kAmf, kenc, kint, kdfVal_for_resStar[len(kdfVal_for_resStar)/2:], hex.EncodeToString(sqnHex), nil
 }
 
+// EAP-AKA' keys of RFC 9048, the AUTN is verified by verifyAutn beforehand, CK' and IK' are bound to the network
+// name of the AT_KDF_INPUT and the KAUSF is taken from the EMSK
+func deriveEapAkaPrimeKeys(identity, encPermanentKey, encOpcKey string, rand, autn []byte, networkName string) ([]byte, []byte, []byte, string, error) {
+	kHex, err := hex.DecodeString(encPermanentKey)
+	if err != nil {
+		return nil, nil, nil, "", fmt.Errorf("error decode encPermanentKey: %v", err)
+	}
+	opcHex, err := hex.DecodeString(encOpcKey)
+	if err != nil {
+		return nil, nil, nil, "", fmt.Errorf("error decode encOpcKey: %v", err)
+	}
+
+	sqnHe, ak, ik, ck, res, err := milenage.GenerateKeysWithAUTN(opcHex, kHex, rand, autn)
+	if err != nil {
+		return nil, nil, nil, "", fmt.Errorf("error generate keys with autn: %v", err)
+	}
+
+	sqnXorAk := make([]byte, len(sqnHe))
+	for i := range sqnHe {
+		sqnXorAk[i] = sqnHe[i] ^ ak[i]
+	}
+	P0 := []byte(networkName)
+	P1 := sqnXorAk
+	ckPrimeIkPrime, err := ueauth.GetKDFValue(append(ck, ik...), ueauth.FC_FOR_CK_PRIME_IK_PRIME_DERIVATION, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
+	if err != nil {
+		return nil, nil, nil, "", fmt.Errorf("GetKDFValue error: %+v", err)
+	}
+	ckPrime, ikPrime := ckPrimeIkPrime[:len(ckPrimeIkPrime)/2], ckPrimeIkPrime[len(ckPrimeIkPrime)/2:]
+
+	// MK = PRF'(IK'|CK', "EAP-AKA'"|Identity) = K_encr | K_aut | K_re | MSK | EMSK
+	mk := eapAkaPrimePrf(append(slices.Clone(ikPrime), ckPrime...), []byte("EAP-AKA'"+identity), 208)
+	kAut, emsk := mk[16:48], mk[144:208]
+
+	return res, kAut, emsk[:32], hex.EncodeToString(sqnHe), nil
+}
+
+// PRF' of RFC 9048 section 3.4, T1 = HMAC-SHA-256(K, S | 0x01), Tn = HMAC-SHA-256(K, Tn-1 | S | n)
+func eapAkaPrimePrf(key, s []byte, length int) []byte {
+	output, previous := make([]byte, 0, length+sha256.Size), []byte{}
+	for n := byte(1); len(output) < length; n++ {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(previous)
+		mac.Write(s)
+		mac.Write([]byte{n})
+		previous = mac.Sum(nil)
+		output = append(output, previous...)
+	}
+	return output[:length]
+}
+
 func encodeNasPduWithSecurity(nasPdu []byte, securityHeaderType uint8, ue *Ue, securityContextAvailable bool, newSecurityContext bool) ([]byte, error) {
 	m := nas.NewMessage()
 	if err := m.PlainNasDecode(&nasPdu); err != nil {
@@ -237,3 +348,98 @@ func encodeNasPduWithSecurity(nasPdu []byte, securityHeaderType uint8, ue *Ue, s
 
 	return nasEncode(m, securityContextAvailable, newSecurityContext, ue)
 }
+
+// ECIES scheme output of TS 33.501 annex C.3, the ephemeral public key, the ciphertext of the MSIN and the MAC tag
+func concealMsin(protectionScheme uint8, homeNetworkPublicKey, msin []byte) ([]byte, error) {
+	var curve ecdh.Curve
+	switch protectionScheme {
+	case uint8(nasMessage.ProtectionSchemeECIESProfileA):
+		curve = ecdh.X25519()
+	case uint8(nasMessage.ProtectionSchemeECIESProfileB):
+		curve = ecdh.P256()
+		uncompressedPublicKey, err := decompressP256PublicKey(homeNetworkPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("error decompress home network public key: %v", err)
+		}
+		homeNetworkPublicKey = uncompressedPublicKey
+	default:
+		return nil, fmt.Errorf("unsupported protection scheme: %d", protectionScheme)
+	}
+
+	hnPublicKey, err := curve.NewPublicKey(homeNetworkPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error parse home network public key: %v", err)
+	}
+	ephemeralPrivateKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generate ephemeral key: %v", err)
+	}
+	sharedKey, err := ephemeralPrivateKey.ECDH(hnPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error derive shared key: %v", err)
+	}
+
+	// profile B sends the ephemeral public key with point compression
+	ephemeralPublicKey := ephemeralPrivateKey.PublicKey().Bytes()
+	if protectionScheme == uint8(nasMessage.ProtectionSchemeECIESProfileB) {
+		ephemeralPublicKey = compressP256PublicKey(ephemeralPublicKey)
+	}
+
+	kdfKey := ansiX963Kdf(sharedKey, ephemeralPublicKey, constant.SUCI_ENC_KEY_LEN+constant.SUCI_ICB_LEN+constant.SUCI_MAC_KEY_LEN)
+	encKey, icb, macKey := kdfKey[:constant.SUCI_ENC_KEY_LEN], kdfKey[constant.SUCI_ENC_KEY_LEN:constant.SUCI_ENC_KEY_LEN+constant.SUCI_ICB_LEN], kdfKey[constant.SUCI_ENC_KEY_LEN+constant.SUCI_ICB_LEN:]
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, fmt.Errorf("error new aes cipher: %v", err)
+	}
+	cipherText := make([]byte, len(msin))
+	cipher.NewCTR(block, icb).XORKeyStream(cipherText, msin)
+
+	schemeOutput := make([]byte, 0, len(ephemeralPublicKey)+len(cipherText)+constant.SUCI_MAC_TAG_LEN)
+	schemeOutput = append(schemeOutput, ephemeralPublicKey...)
+	schemeOutput = append(schemeOutput, cipherText...)
+	schemeOutput = append(schemeOutput, suciMacTag(macKey, cipherText)...)
+	return schemeOutput, nil
+}
+
+// ANSI-X9.63-KDF with SHA-256, the ephemeral public key is the shared info
+func ansiX963Kdf(sharedKey, sharedInfo []byte, length int) []byte {
+	kdfKey := make([]byte, 0, length+sha256.Size)
+	for counter := uint32(1); len(kdfKey) < length; counter++ {
+		hash := sha256.New()
+		hash.Write(sharedKey)
+		hash.Write(binary.BigEndian.AppendUint32(nil, counter))
+		hash.Write(sharedInfo)
+		kdfKey = hash.Sum(kdfKey)
+	}
+	return kdfKey[:length]
+}
+
+func suciMacTag(macKey, cipherText []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(cipherText)
+	return mac.Sum(nil)[:constant.SUCI_MAC_TAG_LEN]
+}
+
+func compressP256PublicKey(uncompressedPublicKey []byte) []byte {
+	coordinateLen := (len(uncompressedPublicKey) - 1) / 2
+	compressedPublicKey := make([]byte, 0, 1+coordinateLen)
+	compressedPublicKey = append(compressedPublicKey, 0x02|uncompressedPublicKey[len(uncompressedPublicKey)-1]&0x01)
+	return append(compressedPublicKey, uncompressedPublicKey[1:1+coordinateLen]...)
+}
+
+// the home network public key may be configured compressed, while the ecdh package only takes the uncompressed form
+func decompressP256PublicKey(publicKey []byte) ([]byte, error) {
+	if len(publicKey) == 0 || publicKey[0] == 0x04 {
+		return publicKey, nil
+	}
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), publicKey)
+	if x == nil {
+		return nil, fmt.Errorf("invalid compressed point: %x", publicKey)
+	}
+	uncompressedPublicKey := make([]byte, 65)
+	uncompressedPublicKey[0] = 0x04
+	x.FillBytes(uncompressedPublicKey[1:33])
+	y.FillBytes(uncompressedPublicKey[33:])
+	return uncompressedPublicKey, nil
+}
@@ -0,0 +1,205 @@
+package ue
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/milenage"
+	"github.com/go-playground/assert"
+)
+
+// the home network side of the concealment, as done by the SIDF of the UDM
+func deconcealMsin(t *testing.T, protectionScheme uint8, homeNetworkPrivateKey *ecdh.PrivateKey, schemeOutput []byte) []byte {
+	ephemeralPublicKeyLen := 32
+	if protectionScheme == uint8(nasMessage.ProtectionSchemeECIESProfileB) {
+		ephemeralPublicKeyLen = 33
+	}
+	ephemeralPublicKey := schemeOutput[:ephemeralPublicKeyLen]
+	cipherText := schemeOutput[ephemeralPublicKeyLen : len(schemeOutput)-constant.SUCI_MAC_TAG_LEN]
+	macTag := schemeOutput[len(schemeOutput)-constant.SUCI_MAC_TAG_LEN:]
+
+	uncompressedPublicKey := ephemeralPublicKey
+	if protectionScheme == uint8(nasMessage.ProtectionSchemeECIESProfileB) {
+		var err error
+		uncompressedPublicKey, err = decompressP256PublicKey(ephemeralPublicKey)
+		assert.Equal(t, nil, err)
+	}
+	publicKey, err := homeNetworkPrivateKey.Curve().NewPublicKey(uncompressedPublicKey)
+	assert.Equal(t, nil, err)
+	sharedKey, err := homeNetworkPrivateKey.ECDH(publicKey)
+	assert.Equal(t, nil, err)
+
+	kdfKey := ansiX963Kdf(sharedKey, ephemeralPublicKey, constant.SUCI_ENC_KEY_LEN+constant.SUCI_ICB_LEN+constant.SUCI_MAC_KEY_LEN)
+	encKey, icb, macKey := kdfKey[:constant.SUCI_ENC_KEY_LEN], kdfKey[constant.SUCI_ENC_KEY_LEN:constant.SUCI_ENC_KEY_LEN+constant.SUCI_ICB_LEN], kdfKey[constant.SUCI_ENC_KEY_LEN+constant.SUCI_ICB_LEN:]
+	assert.Equal(t, macTag, suciMacTag(macKey, cipherText))
+
+	block, err := aes.NewCipher(encKey)
+	assert.Equal(t, nil, err)
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, icb).XORKeyStream(plainText, cipherText)
+	return plainText
+}
+
+var testConcealMsinCases = []struct {
+	name             string
+	protectionScheme uint8
+	curve            ecdh.Curve
+	compressed       bool
+	msin             []byte
+}{
+	{
+		name:             "profileA",
+		protectionScheme: uint8(nasMessage.ProtectionSchemeECIESProfileA),
+		curve:            ecdh.X25519(),
+		msin:             []byte{0x00, 0x00, 0x00, 0x00, 0x10},
+	},
+	{
+		name:             "profileBUncompressedKey",
+		protectionScheme: uint8(nasMessage.ProtectionSchemeECIESProfileB),
+		curve:            ecdh.P256(),
+		msin:             []byte{0x00, 0x00, 0x00, 0x47, 0x78},
+	},
+	{
+		name:             "profileBCompressedKey",
+		protectionScheme: uint8(nasMessage.ProtectionSchemeECIESProfileB),
+		curve:            ecdh.P256(),
+		compressed:       true,
+		msin:             []byte{0x00, 0x01, 0x20, 0x80, 0xf6},
+	},
+}
+
+func TestConcealMsin(t *testing.T) {
+	for _, testCase := range testConcealMsinCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			homeNetworkPrivateKey, err := testCase.curve.GenerateKey(rand.Reader)
+			assert.Equal(t, nil, err)
+			homeNetworkPublicKey := homeNetworkPrivateKey.PublicKey().Bytes()
+			if testCase.compressed {
+				homeNetworkPublicKey = compressP256PublicKey(homeNetworkPublicKey)
+			}
+
+			schemeOutput, err := concealMsin(testCase.protectionScheme, homeNetworkPublicKey, testCase.msin)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.msin, deconcealMsin(t, testCase.protectionScheme, homeNetworkPrivateKey, schemeOutput))
+
+			// a fresh ephemeral key is taken for every concealment
+			anotherSchemeOutput, err := concealMsin(testCase.protectionScheme, homeNetworkPublicKey, testCase.msin)
+			assert.Equal(t, nil, err)
+			assert.NotEqual(t, schemeOutput, anotherSchemeOutput)
+		})
+	}
+}
+
+func TestConcealMsinUnsupportedProtectionScheme(t *testing.T) {
+	_, err := concealMsin(uint8(nasMessage.ProtectionSchemeNullScheme), nil, []byte{0x00})
+	assert.NotEqual(t, nil, err)
+}
+
+var testVerifyAutnCases = []struct {
+	name          string
+	sqnMs         string
+	sqnHe         string
+	corruptMac    bool
+	expectedCause uint8
+}{
+	{
+		name:          "freshSqn",
+		sqnMs:         "000000000023",
+		sqnHe:         "000000000024",
+		expectedCause: 0,
+	},
+	{
+		name:          "replayedSqn",
+		sqnMs:         "000000000023",
+		sqnHe:         "000000000023",
+		expectedCause: nasMessage.Cause5GMMSynchFailure,
+	},
+	{
+		name:          "staleSqnMs",
+		sqnMs:         "000000000023",
+		sqnHe:         "000010000024",
+		expectedCause: nasMessage.Cause5GMMSynchFailure,
+	},
+	{
+		name:          "macFailure",
+		sqnMs:         "000000000023",
+		sqnHe:         "000000000024",
+		corruptMac:    true,
+		expectedCause: nasMessage.Cause5GMMMACFailure,
+	},
+}
+
+func TestVerifyAutn(t *testing.T) {
+	encPermanentKey, encOpcKey := "8baf473f2f8fd09487cccbd7097c6862", "8e27b6af0e692e750f32667a3b14605d"
+	k, _ := hex.DecodeString(encPermanentKey)
+	opc, _ := hex.DecodeString(encOpcKey)
+	randValue := make([]byte, 16)
+	_, err := rand.Read(randValue)
+	assert.Equal(t, nil, err)
+
+	for _, testCase := range testVerifyAutnCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sqnHe, _ := hex.DecodeString(testCase.sqnHe)
+			_, _, _, autn, err := milenage.GenerateAKAParameters(opc, k, randValue, sqnHe, []byte{0x80, 0x00})
+			assert.Equal(t, nil, err)
+			if testCase.corruptMac {
+				autn[len(autn)-1] ^= 0xff
+			}
+
+			cause, auts, err := verifyAutn(testCase.sqnMs, encPermanentKey, encOpcKey, randValue, autn)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.expectedCause, cause)
+
+			// the home network recovers SQNms from the AUTS to resynchronise
+			if cause == nasMessage.Cause5GMMSynchFailure {
+				sqnMs, err := milenage.ValidateAUTS(opc, k, randValue, auts)
+				assert.Equal(t, nil, err)
+				assert.Equal(t, testCase.sqnMs, hex.EncodeToString(sqnMs))
+			} else {
+				assert.Equal(t, 0, len(auts))
+			}
+		})
+	}
+}
+
+// RFC 5448 appendix C, test case 1
+func TestEapAkaPrimePrf(t *testing.T) {
+	ikPrimeCkPrime, _ := hex.DecodeString("ccfc230ca74fcc96c0a5d61164f5a76c" + "0093962d0dd84aa5684b045c9edffa04")
+
+	mk := eapAkaPrimePrf(ikPrimeCkPrime, []byte("EAP-AKA'0555444333222111"), 208)
+	assert.Equal(t, 208, len(mk))
+	assert.Equal(t, "766fa0a6c317174b812d52fbcd11a179", hex.EncodeToString(mk[:16]))
+	assert.Equal(t, "0842ea722ff6835bfa2032499fc3ec23c2f0e388b4f07543ffc677f1696d71ea", hex.EncodeToString(mk[16:48]))
+}
+
+var testBuildServingNetworkNameCases = []struct {
+	name     string
+	plmnId   models.PlmnId
+	expected string
+}{
+	{
+		name:     "twoDigitMnc",
+		plmnId:   models.PlmnId{Mcc: "208", Mnc: "93"},
+		expected: "5G:mnc093.mcc208.3gppnetwork.org",
+	},
+	{
+		name:     "threeDigitMnc",
+		plmnId:   models.PlmnId{Mcc: "310", Mnc: "410"},
+		expected: "5G:mnc410.mcc310.3gppnetwork.org",
+	},
+}
+
+func TestBuildServingNetworkName(t *testing.T) {
+	for _, testCase := range testBuildServingNetworkNameCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, buildServingNetworkName(testCase.plmnId))
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package ue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/Alonza0314/free-ran-ue/util"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+)
+
+// a NAS procedure not completed by the network before its timer expires is aborted, the registration is attempted
+// again after T3511
+type nasTimerExpiredError struct {
+	timer      string
+	duration   time.Duration
+	retryAfter time.Duration
+}
+
+func (e *nasTimerExpiredError) Error() string {
+	return fmt.Sprintf("%s expired after %v", e.timer, e.duration)
+}
+
+func (u *Ue) getGmmState() constant.GmmState {
+	u.gmmStateLock.RLock()
+	defer u.gmmStateLock.RUnlock()
+	return u.gmmState
+}
+
+func (u *Ue) setGmmState(gmmState constant.GmmState) {
+	u.gmmStateLock.Lock()
+	defer u.gmmStateLock.Unlock()
+	if u.gmmState != gmmState {
+		u.NasLog.Debugf("5GMM state: %s -> %s", u.gmmState, gmmState)
+		u.gmmState = gmmState
+	}
+}
+
+func (u *Ue) getGsmState(pduSession *pduSession) constant.GsmState {
+	pduSession.gsmStateLock.RLock()
+	defer pduSession.gsmStateLock.RUnlock()
+	return pduSession.gsmState
+}
+
+func (u *Ue) setGsmState(pduSession *pduSession, gsmState constant.GsmState) {
+	pduSession.gsmStateLock.Lock()
+	defer pduSession.gsmStateLock.Unlock()
+	if pduSession.gsmState != gsmState {
+		u.NasLog.Debugf("PDU session %d 5GSM state: %s -> %s", pduSession.pduSessionId, pduSession.gsmState, gsmState)
+		pduSession.gsmState = gsmState
+	}
+}
+
+// the messages of an N1 connection, tagged with the connection they are read from so the ones of a replaced
+// connection are ignored, the read error is the last message of a connection
+type ranMessage struct {
+	conn        *util.RanUeConn
+	messageType constant.RanUeMessageType
+	payload     []byte
+	err         error
+}
+
+// the only reader of an N1 connection, every message is handled by the receive loop or by the ongoing NAS procedure
+func (u *Ue) readFromRanControlPlane(ranControlPlaneConn *util.RanUeConn) {
+	for {
+		messageType, payload, err := ranControlPlaneConn.ReadMessage()
+		select {
+		case u.ranMessageChan <- ranMessage{conn: ranControlPlaneConn, messageType: messageType, payload: payload, err: err}:
+		case <-u.stoppedChan:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// the NAS procedure handles the NAS messages of its connection until it leaves its initiated state, a reject ends
+// the procedure at once, the other messages are left to the receive loop
+func (u *Ue) runNasProcedure(timer string, duration time.Duration, retryAfter time.Duration, isOngoing func() bool) error {
+	expiry := time.NewTimer(duration)
+	defer expiry.Stop()
+
+	for isOngoing() {
+		select {
+		case message := <-u.ranMessageChan:
+			if message.conn != u.ranControlPlaneConn {
+				u.RanLog.Tracef("Ignore message type %d of a previous RAN connection, error: %v", message.messageType, message.err)
+				continue
+			}
+
+			// the loss of the connection is also left to the receive loop
+			if message.err != nil {
+				u.pendingRanMessageList = append(u.pendingRanMessageList, message)
+				return fmt.Errorf("error read nas message: %+v", message.err)
+			}
+
+			if message.messageType != constant.RAN_UE_MESSAGE_TYPE_NAS {
+				u.pendingRanMessageList = append(u.pendingRanMessageList, message)
+				continue
+			}
+
+			if err := u.handleNasMessage(message.payload); err != nil {
+				var rejectErr *RejectError
+				if errors.As(err, &rejectErr) {
+					return rejectErr
+				}
+				u.NasLog.Errorf("Error handling NAS message: %+v", err)
+			}
+		case <-expiry.C:
+			return &nasTimerExpiredError{timer: timer, duration: duration, retryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+// every NAS message from the network goes through here, whichever procedure is ongoing, so the authentication, the
+// security mode control and the network-initiated procedures may come at any time
+func (u *Ue) handleNasMessage(nasPduRaw []byte) error {
+	u.NasLog.Tracef("Received %d bytes of NAS message from RAN", len(nasPduRaw))
+
+	nasPdu, err := nasDecode(u, nas.GetSecurityHeaderType(nasPduRaw), nasPduRaw)
+	if err != nil {
+		return fmt.Errorf("error decode nas message: %+v", err)
+	}
+	u.NasLog.Tracef("NAS message: %+v", nasPdu)
+
+	gmmState := u.getGmmState()
+	switch nasPdu.GmmHeader.GetMessageType() {
+	case nas.MsgTypeAuthenticationRequest:
+		u.NasLog.Debugln("Receive NAS Authentication Request from RAN")
+		return u.processAuthentication(nasPdu.AuthenticationRequest)
+	case nas.MsgTypeAuthenticationReject:
+		u.NasLog.Debugln("Receive NAS Authentication Reject from RAN")
+		u.processAuthenticationReject()
+		u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED)
+		return &RejectError{MessageType: nas.MsgTypeAuthenticationReject}
+	case nas.MsgTypeIdentityRequest:
+		u.NasLog.Debugln("Receive NAS Identity Request from RAN")
+		return u.processIdentityRequest(nasPdu.IdentityRequest, nas.GetSecurityHeaderType(nasPduRaw) != nas.SecurityHeaderTypePlainNas)
+	case nas.MsgTypeSecurityModeCommand:
+		u.NasLog.Debugln("Receive NAS Security Mode Command from RAN")
+		// EAP-AKA' completes by the EAP-Success carried in the security mode command
+		if eapMessage := nasPdu.SecurityModeCommand.EAPMessage; eapMessage != nil {
+			if eapPacket := eapMessage.GetEAPMessage(); len(eapPacket) == 0 || eapPacket[0] != constant.EAP_CODE_SUCCESS {
+				return fmt.Errorf("unexpected EAP message in security mode command: %x, expected success", eapPacket)
+			}
+			u.NasLog.Debugln("Receive EAP-Success in NAS Security Mode Command")
+		}
+		imeisvRequested := nasPdu.SecurityModeCommand.IMEISVRequest != nil && nasPdu.SecurityModeCommand.GetIMEISVRequestValue() == nasMessage.IMEISVRequested
+		return u.processSecurityModeComplete(u.initialNasMessage, imeisvRequested)
+	case nas.MsgTypeRegistrationAccept:
+		u.NasLog.Debugln("Receive NAS Registration Accept from RAN")
+		if gmmState != constant.UE_GMM_STATE_REGISTERED_INITIATED {
+			return fmt.Errorf("unexpected registration accept in %s", gmmState)
+		}
+		return u.processRegistrationAccept(nasPdu.RegistrationAccept)
+	case nas.MsgTypeRegistrationReject:
+		u.NasLog.Debugln("Receive NAS Registration Reject from RAN")
+		u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED)
+		return u.processRegistrationReject(nasPdu.RegistrationReject)
+	case nas.MsgTypeConfigurationUpdateCommand:
+		u.NasLog.Debugln("Receive NAS Configuration Update Command from RAN")
+		return u.processConfigurationUpdateCommand(nasPdu.ConfigurationUpdateCommand)
+	case nas.MsgTypeServiceAccept:
+		u.NasLog.Debugln("Receive NAS Service Accept from RAN")
+		if gmmState != constant.UE_GMM_STATE_SERVICE_REQUEST_INITIATED {
+			return fmt.Errorf("unexpected service accept in %s", gmmState)
+		}
+		u.setGmmState(constant.UE_GMM_STATE_REGISTERED)
+		return nil
+	case nas.MsgTypeServiceReject:
+		u.NasLog.Debugln("Receive NAS Service Reject from RAN")
+		u.setGmmState(constant.UE_GMM_STATE_REGISTERED)
+		rejectErr := getServiceRejectError(nasPdu.ServiceReject)
+		u.startT3346(rejectErr.BackOff)
+		return rejectErr
+	case nas.MsgTypeDeregistrationAcceptUEOriginatingDeregistration:
+		u.NasLog.Debugln("Receive NAS UE deregistration accept from RAN")
+		if gmmState != constant.UE_GMM_STATE_DEREGISTERED_INITIATED {
+			return fmt.Errorf("unexpected deregistration accept in %s", gmmState)
+		}
+		u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED)
+		return nil
+	case nas.MsgTypeDeregistrationRequestUETerminatedDeregistration:
+		u.NasLog.Debugln("Receive NAS UE terminated deregistration request from RAN")
+		return u.processNetworkDeregistration(nasPdu.DeregistrationRequestUETerminatedDeregistration)
+	case nas.MsgTypeDLNASTransport:
+		return u.handleGsmMessage(nasPdu)
+	default:
+		return fmt.Errorf("not implemented: %+v", nasPdu.GmmHeader.GetMessageType())
+	}
+}
+
+// the 5GSM messages are carried by the DL NAS transport, each moves the state of its own pdu session
+func (u *Ue) handleGsmMessage(dlNasTransport *nas.Message) error {
+	gsmPdu, err := getNasPduFromNasPduSessionEstablishmentAccept(dlNasTransport)
+	if err != nil {
+		return fmt.Errorf("error get nas pdu from dl nas transport: %+v", err)
+	}
+	u.NasLog.Tracef("DL NAS transport payload: %+v", gsmPdu)
+
+	switch gsmPdu.GsmHeader.GetMessageType() {
+	case nas.MsgTypePDUSessionEstablishmentAccept:
+		u.NasLog.Debugln("Receive NAS PDU Session Establishment Accept from RAN")
+		pduSessionEstablishmentAccept := gsmPdu.PDUSessionEstablishmentAccept
+		pduSession := u.getPduSession(pduSessionEstablishmentAccept.GetPDUSessionID())
+		if pduSession == nil {
+			return fmt.Errorf("error pdu session %d not found", pduSessionEstablishmentAccept.GetPDUSessionID())
+		}
+		if gsmState := u.getGsmState(pduSession); gsmState != constant.UE_GSM_STATE_PDU_SESSION_ACTIVE_PENDING {
+			return fmt.Errorf("unexpected pdu session %d establishment accept in %s", pduSession.pduSessionId, gsmState)
+		}
+		u.storePduSessionEstablishmentAccept(pduSession, pduSessionEstablishmentAccept)
+		u.setGsmState(pduSession, constant.UE_GSM_STATE_PDU_SESSION_ACTIVE)
+		return nil
+	case nas.MsgTypePDUSessionEstablishmentReject:
+		rejectErr := getPduSessionEstablishmentRejectError(gsmPdu.PDUSessionEstablishmentReject)
+		u.PduLog.Warnf("PDU session %d establishment rejected, 5GSM cause: %d, T3396: %v", rejectErr.PduSessionId, rejectErr.Cause, rejectErr.BackOff)
+		if pduSession := u.getPduSession(rejectErr.PduSessionId); pduSession != nil {
+			u.setGsmState(pduSession, constant.UE_GSM_STATE_PDU_SESSION_INACTIVE)
+		}
+		return rejectErr
+	case nas.MsgTypePDUSessionReleaseCommand:
+		u.NasLog.Debugln("Receive PDU Session Release Command from RAN")
+		return u.processPduSessionRelease(gsmPdu.PDUSessionReleaseCommand)
+	case nas.MsgTypePDUSessionModificationCommand:
+		u.NasLog.Debugln("Receive PDU Session Modification Command from RAN")
+		return u.processPduSessionModification(gsmPdu.PDUSessionModificationCommand)
+	default:
+		return fmt.Errorf("not implemented: %+v", gsmPdu.GsmHeader.GetMessageType())
+	}
+}
@@ -18,6 +18,15 @@ func bringUpUeTunnelDevice(ueTunnelDeviceName string, ip string) (*water.Interfa
 		return nil, fmt.Errorf("error creating tunnel device: %v", err)
 	}
 
+	if err := configureUeTunnelDevice(ueTunnelDeviceName, ip); err != nil {
+		return nil, err
+	}
+
+	return tun, nil
+}
+
+// the tunnel device brought down is kept open, it is configured again with the address of the new pdu session
+func configureUeTunnelDevice(ueTunnelDeviceName string, ip string) error {
 	cmds := [][]string{
 		{"ip", "addr", "add", fmt.Sprintf("%s/32", ip), "dev", ueTunnelDeviceName},
 		{"ip", "link", "set", "dev", ueTunnelDeviceName, "up"},
@@ -25,11 +34,11 @@ func bringUpUeTunnelDevice(ueTunnelDeviceName string, ip string) (*water.Interfa
 
 	for _, cmd := range cmds {
 		if err := exec.Command(cmd[0], cmd[1:]...).Run(); err != nil {
-			return nil, fmt.Errorf("error bringing up tunnel device: %v", err)
+			return fmt.Errorf("error bringing up tunnel device: %v", err)
 		}
 	}
 
-	return tun, nil
+	return nil
 }
 
 func bringDownUeTunnelDevice(ueTunnelDeviceName string) error {
@@ -2,12 +2,16 @@ package ue
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Alonza0314/free-ran-ue/constant"
@@ -24,6 +28,9 @@ import (
 
 type authentication struct {
 	supi string
+	// only sent when asked for by the network
+	imei   string
+	imeisv string
 
 	cipheringAlgorithm uint8
 	integrityAlgorithm uint8
@@ -31,9 +38,19 @@ type authentication struct {
 	kNasEnc [16]byte
 	kNasInt [16]byte
 	kAmf    []uint8
+	ngKsi   uint8
 
 	ulCount security.Count
 	dlCount security.Count
+
+	suciProtection
+}
+
+// the null scheme sends the MSIN in clear
+type suciProtection struct {
+	protectionScheme       uint8
+	homeNetworkPublicKey   []byte
+	homeNetworkPublicKeyId uint8
 }
 
 type authenticationSubscription struct {
@@ -42,11 +59,28 @@ type authenticationSubscription struct {
 	encOpcKey                     string
 	authenticationManagementField string
 	sequenceNumber                string
+	// the latest accepted SQN is kept per SUPI when set
+	sequenceNumberFile string
 }
 
 type pduSession struct {
-	dnn    string
-	sNssai *models.Snssai
+	pduSessionId   uint8
+	pduSessionType uint8
+	dnn            string
+	sNssai         *models.Snssai
+
+	ranDataPlaneConn net.Conn
+
+	ueTunnelDeviceName string
+	ueTunnelDevice     *water.Interface
+
+	readFromTun chan []byte
+	readFromRan chan []byte
+
+	gsmState     constant.GsmState
+	gsmStateLock sync.RWMutex
+
+	pduSessionEstablishmentAccept
 }
 
 type pduSessionEstablishmentAccept struct {
@@ -57,6 +91,12 @@ type pduSessionEstablishmentAccept struct {
 	sd      [3]uint8
 }
 
+// the cause tells where the S-NSSAI is not available, TS 24.501 9.11.3.46
+type rejectedSnssai struct {
+	snssai models.Snssai
+	cause  uint8
+}
+
 type dcRanDataPlane struct {
 	ip   string
 	port int
@@ -78,8 +118,7 @@ type Ue struct {
 	ranControlPlanePort int
 	ranDataPlanePort    int
 
-	ranControlPlaneConn net.Conn
-	ranDataPlaneConn    net.Conn
+	ranControlPlaneConn *util.RanUeConn
 	dcRanDataPlaneConn  net.Conn
 
 	mcc  string
@@ -88,20 +127,48 @@ type Ue struct {
 
 	authentication
 
+	// its 5G-S-TMSI identifies the UE in CM-IDLE
+	guti5G        *nasType.GUTI5G
+	taiList       []string
+	servingTac    string
+	servingPlmnId models.PlmnId
+	// the AMF falls back to the default S-NSSAIs when empty
+	requestedNssai  []models.MappingOfSnssai
+	allowedNssai    []models.Snssai
+	rejectedNssai   []rejectedSnssai
+	configuredNssai []models.Snssai
+	// T3512, zero when deactivated by the network
+	t3512 time.Duration
+	// T3502, zero when not given by the network
+	t3502 time.Duration
+	// T3346 expiry after a reject for congestion
+	t3346Expiry time.Time
+
 	accessType models.AccessType
 	authenticationSubscription
 
-	pduSession
+	gmmState     constant.GmmState
+	gmmStateLock sync.RWMutex
+	// sent in full by the security mode complete
+	initialNasMessage      []byte
+	reRegistrationRequired bool
 
-	nrdc
+	// messages left by a NAS procedure are handled first by the receive loop
+	ranMessageChan        chan ranMessage
+	pendingRanMessageList []ranMessage
+	stoppedChan           chan struct{}
 
-	ueTunnelDeviceName string
-	ueTunnelDevice     *water.Interface
+	// the first pdu session is the default one
+	pduSessionList []*pduSession
 
-	readFromTun chan []byte
-	readFromRan chan []byte
+	nrdc
 
-	pduSessionEstablishmentAccept
+	// set on a release for user inactivity
+	cmIdle atomic.Bool
+	// the paging or the uplink data asks for a service request in CM-IDLE
+	serviceRequestChan chan uint8
+	// the UE is paged on this connection in CM-IDLE
+	idleCampConn *util.RanUeConn
 
 	*logger.UeLogger
 }
@@ -131,9 +198,84 @@ func NewUe(config *model.UeConfig, logger *logger.UeLogger) *Ue {
 		cipheringAlgorithm = security.AlgCiphering128NEA3
 	}
 
-	sstInt, err := strconv.Atoi(config.Ue.PduSession.Snssai.Sst)
+	authenticationMethod := models.AuthMethod__5_G_AKA
+	if models.AuthMethod(config.Ue.AuthenticationSubscription.AuthenticationMethod) == models.AuthMethod_EAP_AKA_PRIME {
+		authenticationMethod = models.AuthMethod_EAP_AKA_PRIME
+	}
+
+	sequenceNumber := config.Ue.AuthenticationSubscription.SequenceNumber
+	if sequenceNumberFile := config.Ue.AuthenticationSubscription.SequenceNumberFile; sequenceNumberFile != "" {
+		if storedSequenceNumber, exists, err := util.LoadSequenceNumber(sequenceNumberFile, constant.UE_IMSI_PREFIX+supi); err != nil {
+			logger.CfgLog.Errorf("Error loading sequence number from %s: %v", sequenceNumberFile, err)
+		} else if exists {
+			sequenceNumber = storedSequenceNumber
+			logger.CfgLog.Debugf("Loaded sequence number %s from %s", sequenceNumber, sequenceNumberFile)
+		}
+	}
+
+	protectionScheme := uint8(nasMessage.ProtectionSchemeNullScheme)
+	switch config.Ue.Suci.ProtectionScheme {
+	case constant.SUCI_PROTECTION_SCHEME_PROFILE_A:
+		protectionScheme = uint8(nasMessage.ProtectionSchemeECIESProfileA)
+	case constant.SUCI_PROTECTION_SCHEME_PROFILE_B:
+		protectionScheme = uint8(nasMessage.ProtectionSchemeECIESProfileB)
+	}
+	homeNetworkPublicKey, err := hex.DecodeString(config.Ue.Suci.HomeNetworkPublicKey)
 	if err != nil {
-		logger.CfgLog.Errorf("Error converting sst to int: %v", err)
+		logger.CfgLog.Errorf("Error decoding home network public key: %v", err)
+	}
+
+	pduSessionList := make([]*pduSession, 0, len(config.Ue.PduSessionList))
+	for _, pduSessionIe := range config.Ue.PduSessionList {
+		sstInt, err := strconv.Atoi(pduSessionIe.Snssai.Sst)
+		if err != nil {
+			logger.CfgLog.Errorf("Error converting sst to int: %v", err)
+		}
+
+		pduSessionType := nasMessage.PDUSessionTypeIPv4
+		if pduSessionIe.PduSessionType == constant.PDU_SESSION_TYPE_IPV4V6 {
+			pduSessionType = nasMessage.PDUSessionTypeIPv4IPv6
+		}
+
+		pduSessionList = append(pduSessionList, &pduSession{
+			pduSessionId:   uint8(pduSessionIe.PduSessionId),
+			pduSessionType: pduSessionType,
+			dnn:            pduSessionIe.Dnn,
+			sNssai: &models.Snssai{
+				Sst: int32(sstInt),
+				Sd:  pduSessionIe.Snssai.Sd,
+			},
+
+			ueTunnelDeviceName: pduSessionIe.UeTunnelDevice,
+
+			gsmState: constant.UE_GSM_STATE_PDU_SESSION_INACTIVE,
+		})
+	}
+
+	requestedNssai := make([]models.MappingOfSnssai, 0, len(config.Ue.RequestedNssai))
+	for _, requestedSnssaiIe := range config.Ue.RequestedNssai {
+		sstInt, err := strconv.Atoi(requestedSnssaiIe.Sst)
+		if err != nil {
+			logger.CfgLog.Errorf("Error converting sst to int: %v", err)
+		}
+
+		mappingOfSnssai := models.MappingOfSnssai{
+			ServingSnssai: &models.Snssai{
+				Sst: int32(sstInt),
+				Sd:  requestedSnssaiIe.Sd,
+			},
+		}
+		if mappedHplmnSnssai := requestedSnssaiIe.MappedHplmnSnssai; mappedHplmnSnssai != nil {
+			mappedSstInt, err := strconv.Atoi(mappedHplmnSnssai.Sst)
+			if err != nil {
+				logger.CfgLog.Errorf("Error converting mapped hplmn sst to int: %v", err)
+			}
+			mappingOfSnssai.HomeSnssai = &models.Snssai{
+				Sst: int32(mappedSstInt),
+				Sd:  mappedHplmnSnssai.Sd,
+			}
+		}
+		requestedNssai = append(requestedNssai, mappingOfSnssai)
 	}
 
 	return &Ue{
@@ -148,32 +290,49 @@ func NewUe(config *model.UeConfig, logger *logger.UeLogger) *Ue {
 		mnc:  config.Ue.PlmnId.Mnc,
 		msin: config.Ue.Msin,
 
+		servingPlmnId: models.PlmnId{
+			Mcc: config.Ue.PlmnId.Mcc,
+			Mnc: config.Ue.PlmnId.Mnc,
+		},
+
 		authentication: authentication{
-			supi: supi,
+			supi:   supi,
+			imei:   config.Ue.Imei,
+			imeisv: config.Ue.Imeisv,
 
 			cipheringAlgorithm: cipheringAlgorithm,
 			integrityAlgorithm: integrityAlgorithm,
 
 			ulCount: security.Count{},
 			dlCount: security.Count{},
+
+			suciProtection: suciProtection{
+				protectionScheme:       protectionScheme,
+				homeNetworkPublicKey:   homeNetworkPublicKey,
+				homeNetworkPublicKeyId: uint8(config.Ue.Suci.HomeNetworkPublicKeyId),
+			},
 		},
 
+		requestedNssai: requestedNssai,
+
 		accessType: models.AccessType(config.Ue.AccessType),
 		authenticationSubscription: authenticationSubscription{
-			authenticationMethod:          models.AuthMethod__5_G_AKA,
+			authenticationMethod:          authenticationMethod,
 			encPermanentKey:               config.Ue.AuthenticationSubscription.EncPermanentKey,
 			encOpcKey:                     config.Ue.AuthenticationSubscription.EncOpcKey,
 			authenticationManagementField: config.Ue.AuthenticationSubscription.AuthenticationManagementField,
-			sequenceNumber:                config.Ue.AuthenticationSubscription.SequenceNumber,
+			sequenceNumber:                sequenceNumber,
+			sequenceNumberFile:            config.Ue.AuthenticationSubscription.SequenceNumberFile,
 		},
 
-		pduSession: pduSession{
-			dnn: config.Ue.PduSession.Dnn,
-			sNssai: &models.Snssai{
-				Sst: int32(sstInt),
-				Sd:  config.Ue.PduSession.Snssai.Sd,
-			},
-		},
+		gmmState: constant.UE_GMM_STATE_DEREGISTERED,
+
+		ranMessageChan: make(chan ranMessage, 16),
+		stoppedChan:    make(chan struct{}),
+
+		pduSessionList: pduSessionList,
+
+		serviceRequestChan: make(chan uint8, 1),
 
 		nrdc: nrdc{
 			enable: config.Ue.Nrdc.Enable,
@@ -186,8 +345,6 @@ func NewUe(config *model.UeConfig, logger *logger.UeLogger) *Ue {
 			rwLock:             sync.RWMutex{},
 		},
 
-		ueTunnelDeviceName: config.Ue.UeTunnelDevice,
-
 		UeLogger: logger,
 	}
 }
@@ -200,46 +357,47 @@ func (u *Ue) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		return err
 	}
 
-	if err := u.processUeRegistration(); err != nil {
+	// the RAN releases the N1 connection after the reject
+	if err := u.retryNasProcedure(ctx, u.processUeRegistration, func() error {
+		u.closeRanControlPlaneConn()
+		return u.connectToRanControlPlane()
+	}); err != nil {
 		u.UeLog.Errorf("Error processing UE registration: %v", err)
-		if err := u.ranControlPlaneConn.Close(); err != nil {
+		if err := u.ranControlPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			u.UeLog.Errorf("Error closing RAN connection: %v", err)
 		}
 		return err
 	}
 
-	if err := u.processPduSessionEstablishment(); err != nil {
-		u.UeLog.Errorf("Error processing PDU session establishment: %v", err)
-		if err := u.ranControlPlaneConn.Close(); err != nil {
-			u.UeLog.Errorf("Error closing RAN connection: %v", err)
+	for _, pduSession := range u.pduSessionList {
+		if err := u.retryNasProcedure(ctx, func() error {
+			return u.processPduSessionEstablishment(pduSession)
+		}, nil); err != nil {
+			u.UeLog.Errorf("Error processing PDU session %d establishment: %v", pduSession.pduSessionId, err)
+			u.closeRanConnections()
+			return err
 		}
-		return err
-	}
 
-	if err := u.connectToRanDataPlane(); err != nil {
-		u.UeLog.Errorf("Error connecting to RAN data plane: %v", err)
-		if err := u.ranControlPlaneConn.Close(); err != nil {
-			u.UeLog.Errorf("Error closing RAN connection: %v", err)
+		if err := u.connectToRanDataPlane(pduSession); err != nil {
+			u.UeLog.Errorf("Error connecting to RAN data plane: %v", err)
+			u.closeRanConnections()
+			return err
 		}
-		return err
-	}
 
-	if err := u.setupTunnelDevice(); err != nil {
-		u.UeLog.Errorf("Error setting up tunnel device: %v", err)
-		if err := u.ranDataPlaneConn.Close(); err != nil {
-			u.UeLog.Errorf("Error closing RAN connection: %v", err)
-		}
-		if err := u.ranControlPlaneConn.Close(); err != nil {
-			u.UeLog.Errorf("Error closing RAN connection: %v", err)
+		if err := u.setupTunnelDevice(pduSession); err != nil {
+			u.UeLog.Errorf("Error setting up tunnel device: %v", err)
+			u.closeRanConnections()
+			return err
 		}
-		return err
 	}
 
 	// wait for RAN message
 	go u.waitForRanMessage(ctx, wg)
 
 	// handle data plane
-	go u.handleDataPlane(ctx, wg)
+	for _, pduSession := range u.pduSessionList {
+		go u.handleDataPlane(ctx, wg, pduSession)
+	}
 
 	u.UeLog.Infoln("UE started")
 	return nil
@@ -248,34 +406,59 @@ func (u *Ue) Start(ctx context.Context, wg *sync.WaitGroup) error {
 func (u *Ue) Stop() {
 	u.UeLog.Infof("Stopping UE: imsi-%s", u.supi)
 
-	if err := u.processUeDeregistration(); err != nil {
+	if u.cmIdle.Load() {
+		u.UeLog.Infoln("UE is in CM-IDLE, skip deregistration")
+	} else if u.getGmmState() == constant.UE_GMM_STATE_DEREGISTERED {
+		u.UeLog.Infoln("UE is deregistered, skip deregistration")
+	} else if err := u.processUeDeregistration(); err != nil {
 		u.UeLog.Errorf("Error processing UE deregistration: %v", err)
 	}
 
-	close(u.readFromTun)
-	close(u.readFromRan)
+	for _, pduSession := range u.pduSessionList {
+		close(pduSession.readFromTun)
+		close(pduSession.readFromRan)
 
-	if err := u.cleanUpTunnelDevice(); err != nil {
-		u.UeLog.Errorf("Error cleaning up tunnel device: %v", err)
-	}
+		if err := u.cleanUpTunnelDevice(pduSession); err != nil {
+			u.UeLog.Errorf("Error cleaning up tunnel device: %v", err)
+		}
 
-	if err := u.ranDataPlaneConn.Close(); err != nil {
-		u.UeLog.Errorf("Error closing RAN connection: %v", err)
+		// already closed by the deregistration of the network
+		if err := pduSession.ranDataPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			u.UeLog.Errorf("Error closing RAN connection: %v", err)
+		}
 	}
 
 	if u.isNrdcEnabled() {
-		if err := u.dcRanDataPlaneConn.Close(); err != nil {
+		if err := u.dcRanDataPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			u.UeLog.Errorf("Error closing DC RAN connection: %v", err)
 		}
 	}
 
-	if err := u.ranControlPlaneConn.Close(); err != nil {
+	// may already be closed by a failed service request
+	if err := u.ranControlPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 		u.UeLog.Errorf("Error closing RAN connection: %v", err)
 	}
+	u.closeIdleCampConn()
+	close(u.stoppedChan)
 
 	u.UeLog.Infoln("UE stopped")
 }
 
+// only used when the UE fails to start
+func (u *Ue) closeRanConnections() {
+	for _, pduSession := range u.pduSessionList {
+		if pduSession.ranDataPlaneConn == nil {
+			continue
+		}
+		if err := pduSession.ranDataPlaneConn.Close(); err != nil {
+			u.UeLog.Errorf("Error closing RAN connection: %v", err)
+		}
+	}
+	if err := u.ranControlPlaneConn.Close(); err != nil {
+		u.UeLog.Errorf("Error closing RAN connection: %v", err)
+	}
+}
+
 func (u *Ue) connectToRanControlPlane() error {
 	u.RanLog.Infoln("Connecting to RAN control plane")
 
@@ -288,14 +471,40 @@ func (u *Ue) connectToRanControlPlane() error {
 
 	u.RanLog.Debugln("Dial TCP to RAN control plane success")
 
-	u.ranControlPlaneConn = conn
+	// the mobile identity of the initial NAS message may be a SUCI or a 5G-S-TMSI
+	ranUeConn := util.NewRanUeConn(conn)
+	if _, err := ranUeConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_CONNECTION_SETUP_REQUEST, []byte(constant.UE_IMSI_PREFIX+u.supi)); err != nil {
+		if closeErr := conn.Close(); closeErr != nil {
+			u.RanLog.Errorf("Error closing RAN connection: %v", closeErr)
+		}
+		return fmt.Errorf("error send connection setup request: %+v", err)
+	}
+
+	messageType, connectionSetup, err := ranUeConn.ReadMessage()
+	if err != nil || messageType != constant.RAN_UE_MESSAGE_TYPE_CONNECTION_SETUP {
+		if closeErr := conn.Close(); closeErr != nil {
+			u.RanLog.Errorf("Error closing RAN connection: %v", closeErr)
+		}
+		return fmt.Errorf("error receive connection setup: %+v, message type: %d", err, messageType)
+	}
+	// the home PLMN is kept without a PLMN from the RAN
+	if systemInformation := strings.Fields(string(connectionSetup)); len(systemInformation) > 0 {
+		u.servingTac = systemInformation[0]
+		if len(systemInformation) >= 3 {
+			u.servingPlmnId = models.PlmnId{Mcc: systemInformation[1], Mnc: systemInformation[2]}
+		}
+	}
+	u.RanLog.Debugf("Receive connection setup from RAN, TAC: %s, PLMN: %s%s", u.servingTac, u.servingPlmnId.Mcc, u.servingPlmnId.Mnc)
+
+	u.ranControlPlaneConn = ranUeConn
+	go u.readFromRanControlPlane(ranUeConn)
 
 	u.RanLog.Infof("Connected to RAN control plane: %s:%d", u.ranControlPlaneIp, u.ranControlPlanePort)
 	return nil
 }
 
-func (u *Ue) connectToRanDataPlane() error {
-	u.RanLog.Infoln("Connecting to RAN data plane")
+func (u *Ue) connectToRanDataPlane(pduSession *pduSession) error {
+	u.RanLog.Infof("Connecting to RAN data plane for PDU session %d", pduSession.pduSessionId)
 
 	u.RanLog.Tracef("RAN data plane address: %s:%d", u.ranDataPlaneIp, u.ranDataPlanePort)
 
@@ -303,16 +512,14 @@ func (u *Ue) connectToRanDataPlane() error {
 	if err != nil {
 		return err
 	}
-	u.ranDataPlaneConn = conn
+	pduSession.ranDataPlaneConn = conn
 	u.RanLog.Debugln("Dial UDP to RAN data plane success")
 
-	_, err = u.ranDataPlaneConn.Write([]byte(constant.UE_DATA_PLANE_INITIAL_PACKET + " " + constant.UE_IMSI_PREFIX + u.supi))
-	if err != nil {
-		return fmt.Errorf("error send initial packet: %+v", err)
+	if err := u.sendDataPlaneInitialPacket(pduSession); err != nil {
+		return err
 	}
-	u.RanLog.Debugln("Sent initial packet to RAN data plane UDP server")
 
-	if u.isNrdcEnabled() {
+	if u.isDefaultPduSession(pduSession) && u.isNrdcEnabled() {
 		conn, err := util.UdpDialWithOptionalLocalAddress(u.nrdc.dcRanDataPlane.ip, u.nrdc.dcRanDataPlane.port, u.nrdc.dcLocalDataPlaneIp)
 		if err != nil {
 			return err
@@ -331,57 +538,145 @@ func (u *Ue) connectToRanDataPlane() error {
 	return nil
 }
 
+func (u *Ue) sendDataPlaneInitialPacket(pduSession *pduSession) error {
+	_, err := pduSession.ranDataPlaneConn.Write([]byte(constant.UE_DATA_PLANE_INITIAL_PACKET + " " + constant.UE_IMSI_PREFIX + u.supi + " " + strconv.Itoa(int(pduSession.pduSessionId))))
+	if err != nil {
+		return fmt.Errorf("error send initial packet: %+v", err)
+	}
+	u.RanLog.Debugln("Sent initial packet to RAN data plane UDP server")
+	return nil
+}
+
 func (u *Ue) processUeRegistration() error {
 	u.RanLog.Infoln("Processing UE Registration")
 
-	mobileIdentity5GS := buildUeMobileIdentity5GS(u.supi)
+	// the SUCI is only sent without a 5G-GUTI
+	var mobileIdentity5GS nasType.MobileIdentity5GS
+	if u.guti5G != nil {
+		mobileIdentity5GS = buildGutiMobileIdentity5GS(*u.guti5G)
+	} else {
+		suci, err := u.getSuciMobileIdentity5GS()
+		if err != nil {
+			return fmt.Errorf("error get suci mobile identity: %+v", err)
+		}
+		mobileIdentity5GS = suci
+	}
 	u.NasLog.Tracef("Mobile identity 5GS: %+v", mobileIdentity5GS)
 
 	ueSecurityCapability := buildUeSecurityCapability(u.cipheringAlgorithm, u.integrityAlgorithm)
 	u.NasLog.Tracef("UE security capability: %+v", ueSecurityCapability)
 
+	// protected by the native security context of the previous registration
+	securityContextAvailable := u.isSecurityContextAvailable()
+	ngKsi := uint8(nasMessage.NasKeySetIdentifierNoKeyIsAvailable)
+	if securityContextAvailable {
+		ngKsi = u.ngKsi
+	}
+
+	// the requested NSSAI is not a cleartext IE, it is only sent in the complete registration request
+	requestedNSSAI, err := buildRequestedNssai(u.requestedNssai)
+	if err != nil {
+		return fmt.Errorf("error build requested NSSAI: %+v", err)
+	}
+	u.NasLog.Tracef("Requested NSSAI: %+v", requestedNSSAI)
+
 	// send ue registration request
-	registrationRequest, err := getUeRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, &mobileIdentity5GS, nil, &ueSecurityCapability, nil, nil, nil)
+	registrationRequest, err := getUeRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, ngKsi, true, &mobileIdentity5GS, nil, &ueSecurityCapability, nil, nil, nil, nil)
 	if err != nil {
 		return fmt.Errorf("error get ue registration request: %+v", err)
 	}
 	u.NasLog.Tracef("Get UE %s registration request: %+v", u.supi, registrationRequest)
 
-	n, err := u.ranControlPlaneConn.Write(registrationRequest)
+	// the security mode complete carries the complete registration request
+	registrationRequestWith5Gmm, err := getUeRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, ngKsi, true, &mobileIdentity5GS, requestedNSSAI, &ueSecurityCapability, u.get5GmmCapability(), nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error get ue registration request with 5GMM: %+v", err)
+	}
+	u.NasLog.Tracef("Registration request with 5GMM: %+v", registrationRequestWith5Gmm)
+	u.initialNasMessage = registrationRequestWith5Gmm
+
+	encodedRegistrationRequest := registrationRequest
+	if securityContextAvailable {
+		encodedRegistrationRequest, err = encodeNasPduWithSecurity(registrationRequest, nas.SecurityHeaderTypeIntegrityProtected, u, true, false)
+		if err != nil {
+			return fmt.Errorf("error encode ue registration request: %+v", err)
+		}
+		u.NasLog.Tracef("Encoded UE registration request: %+v", encodedRegistrationRequest)
+	}
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedRegistrationRequest)
 	if err != nil {
 		return fmt.Errorf("error send ue registration request: %+v", err)
 	}
 	u.NasLog.Tracef("Sent %d bytes of UE %s registration request", n, u.supi)
 	u.NasLog.Debugln("Send UE registration request")
+	u.setGmmState(constant.UE_GMM_STATE_REGISTERED_INITIATED)
+
+	// the AMF may skip the authentication and the security mode control
+	if err := u.runNasProcedure("T3510", constant.UE_T3510, constant.UE_T3511, func() bool {
+		return u.getGmmState() == constant.UE_GMM_STATE_REGISTERED_INITIATED
+	}); err != nil {
+		u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED)
+		return err
+	}
+	if gmmState := u.getGmmState(); gmmState != constant.UE_GMM_STATE_REGISTERED {
+		return fmt.Errorf("error registration not accepted, 5GMM state: %s", gmmState)
+	}
+
+	u.RanLog.Infoln("UE Registration finished")
+	return nil
+}
+
+// a fresh ephemeral key for every concealment
+func (u *Ue) getSuciMobileIdentity5GS() (nasType.MobileIdentity5GS, error) {
+	if u.protectionScheme == uint8(nasMessage.ProtectionSchemeNullScheme) {
+		return buildUeMobileIdentity5GS(u.supi), nil
+	}
 
-	// receive nas authentication request
-	nasAuthenticationRequestRaw := make([]byte, 1024)
-	n, err = u.ranControlPlaneConn.Read(nasAuthenticationRequestRaw)
+	schemeOutput, err := concealMsin(u.protectionScheme, u.homeNetworkPublicKey, util.SupiToBytes(u.supi)[8:])
 	if err != nil {
-		return fmt.Errorf("error read nas authentication request: %+v", err)
+		return nasType.MobileIdentity5GS{}, fmt.Errorf("error conceal msin: %+v", err)
 	}
-	u.NasLog.Tracef("Received %d bytes of NAS Authentication Request from RAN", n)
+	u.NasLog.Debugf("MSIN concealed with protection scheme %d and home network public key %d", u.protectionScheme, u.homeNetworkPublicKeyId)
 
-	nasPdu, err := nasDecode(u, nas.GetSecurityHeaderType(nasAuthenticationRequestRaw[:n]), nasAuthenticationRequestRaw[:n])
+	return buildSuciMobileIdentity5GS(u.supi, u.protectionScheme, u.homeNetworkPublicKeyId, schemeOutput), nil
+}
+
+// kept from the previous registration until the next authentication
+func (u *Ue) isSecurityContextAvailable() bool {
+	return u.kAmf != nil && u.ngKsi != uint8(nasMessage.NasKeySetIdentifierNoKeyIsAvailable)
+}
+
+// an AUTN failing the MAC or SQN check is answered by the authentication failure
+func (u *Ue) processAuthentication(authenticationRequest *nasMessage.AuthenticationRequest) error {
+	if u.authenticationMethod == models.AuthMethod_EAP_AKA_PRIME {
+		return u.processEapAkaPrimeAuthentication(authenticationRequest)
+	}
+	if authenticationRequest.AuthenticationParameterRAND == nil || authenticationRequest.AuthenticationParameterAUTN == nil {
+		return fmt.Errorf("5G-AKA authentication request without RAND and AUTN, the subscription may use another method")
+	}
+
+	rand, autn := authenticationRequest.GetRANDValue(), authenticationRequest.GetAUTN()
+
+	cause, auts, err := verifyAutn(u.authenticationSubscription.sequenceNumber, u.authenticationSubscription.encPermanentKey, u.authenticationSubscription.encOpcKey, rand[:], autn[:])
 	if err != nil {
-		return fmt.Errorf("error decode nas authentication request: %+v", err)
+		return fmt.Errorf("error verify autn: %+v", err)
 	}
-	if nasPdu.GmmHeader.GetMessageType() != nas.MsgTypeAuthenticationRequest {
-		return fmt.Errorf("error nas pdu message type: %+v, expected authenticatoin request", nasPdu)
+	if cause != 0 {
+		u.NasLog.Warnf("Authentication failure: %s", nasMessage.Cause5GMMToString(cause))
+		return u.sendAuthenticationFailure(cause, auts)
 	}
-	u.NasLog.Tracef("NAS authentication request: %+v", nasPdu)
-	u.NasLog.Debugln("Receive NAS Authentication Request from RAN")
 
-	// calculate for RES* and send nas authentication response
-	rand, autn := nasPdu.AuthenticationRequest.GetRANDValue(), nasPdu.AuthenticationRequest.GetAUTN()
-	kAmf, kenc, kint, resStar, newSqn, err := deriveResStarAndSetKey(fmt.Sprintf("supi-%s", u.supi), u.cipheringAlgorithm, u.integrityAlgorithm, u.authenticationSubscription.sequenceNumber, u.authenticationSubscription.authenticationManagementField, u.authenticationSubscription.encPermanentKey, u.authenticationSubscription.encOpcKey, rand[:], autn[:], "5G:mnc093.mcc208.3gppnetwork.org")
+	u.ngKsi = authenticationRequest.GetNasKeySetIdentifiler()
+
+	kAmf, kenc, kint, resStar, newSqn, err := deriveResStarAndSetKey(fmt.Sprintf("supi-%s", u.supi), u.cipheringAlgorithm, u.integrityAlgorithm, u.authenticationSubscription.sequenceNumber, u.authenticationSubscription.authenticationManagementField, u.authenticationSubscription.encPermanentKey, u.authenticationSubscription.encOpcKey, rand[:], autn[:], buildServingNetworkName(u.servingPlmnId))
 	if err != nil {
 		return fmt.Errorf("error derive res star and set key: %+v", err)
 	} else {
 		u.kAmf = kAmf
 		copy(u.kNasEnc[:], kenc[16:32])
 		copy(u.kNasInt[:], kint[16:32])
-		u.authenticationSubscription.sequenceNumber = newSqn
+		u.updateSequenceNumber(newSqn)
 
 		u.NasLog.Tracef("RES*: %+v", resStar)
 		u.NasLog.Tracef("kAMF: %+v", kAmf)
@@ -390,184 +685,606 @@ func (u *Ue) processUeRegistration() error {
 		u.NasLog.Tracef("New SQN: %s", newSqn)
 	}
 
-	authenticationResponse, err := getAuthenticationResponse(resStar)
+	authenticationResponse, err := getAuthenticationResponse(resStar, nil)
 	if err != nil {
 		return fmt.Errorf("error get authentication response: %+v", err)
 	}
 	u.NasLog.Tracef("Authentication response: %+v", authenticationResponse)
 
-	n, err = u.ranControlPlaneConn.Write(authenticationResponse)
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, authenticationResponse)
 	if err != nil {
 		return fmt.Errorf("error send authentication response: %+v", err)
 	}
 	u.NasLog.Tracef("Sent %d bytes of Authentication Response to RAN", n)
 	u.NasLog.Debugln("Send Authentication Response to RAN")
+	return nil
+}
 
-	// receive nas security mode command message
-	nasSecurityCommandRaw := make([]byte, 1024)
-	n, err = u.ranControlPlaneConn.Read(nasSecurityCommandRaw)
-	if err != nil {
-		return fmt.Errorf("error read nas security command: %+v", err)
+// the AUTN failures are answered inside EAP
+func (u *Ue) processEapAkaPrimeAuthentication(authenticationRequest *nasMessage.AuthenticationRequest) error {
+	if authenticationRequest.EAPMessage == nil {
+		return fmt.Errorf("EAP-AKA' authentication request without EAP message, the subscription may use another method")
 	}
-	u.NasLog.Tracef("Received %d bytes of NAS Security Mode Command from RAN", n)
 
-	nasPdu, err = nasDecode(u, nas.GetSecurityHeaderType(nasSecurityCommandRaw[:n]), nasSecurityCommandRaw[:n])
+	challenge, err := parseEapAkaPrimeChallenge(authenticationRequest.EAPMessage.GetEAPMessage())
 	if err != nil {
-		return fmt.Errorf("error get nas pdu: %+v", err)
+		return fmt.Errorf("error parse EAP-AKA' challenge: %+v", err)
 	}
-	if nasPdu.GmmHeader.GetMessageType() != nas.MsgTypeSecurityModeCommand {
-		return fmt.Errorf("error nas pdu message type: %+v, expected security mode command", nasPdu)
+	u.NasLog.Tracef("EAP-AKA' challenge: %+v", challenge)
+	u.NasLog.Debugf("Receive EAP-AKA' challenge, network name: %s", challenge.networkName)
+	if servingNetworkName := buildServingNetworkName(u.servingPlmnId); challenge.networkName != servingNetworkName {
+		u.NasLog.Warnf("EAP-AKA' network name %s differs from the serving network name %s", challenge.networkName, servingNetworkName)
 	}
-	u.NasLog.Tracef("NAS security mode command: %+v", nasPdu)
-	u.NasLog.Debugln("Receive NAS Security Mode Command from RAN")
 
-	// send nas security mode complete message
-	registrationRequestWith5Gmm, err := getUeRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, &mobileIdentity5GS, nil, &ueSecurityCapability, u.get5GmmCapability(), nil, nil)
+	var eapResponse []byte
+	cause, auts, err := verifyAutn(u.authenticationSubscription.sequenceNumber, u.authenticationSubscription.encPermanentKey, u.authenticationSubscription.encOpcKey, challenge.rand, challenge.autn)
 	if err != nil {
-		return fmt.Errorf("error get ue registration request with 5GMM: %+v", err)
+		return fmt.Errorf("error verify autn: %+v", err)
+	}
+	switch {
+	case cause == nasMessage.Cause5GMMSynchFailure:
+		u.NasLog.Warnf("EAP-AKA' synchronization failure")
+		eapResponse = buildEapAkaPrimeSynchronizationFailure(challenge.identifier, auts)
+	case cause != 0:
+		u.NasLog.Warnf("EAP-AKA' authentication reject: %s", nasMessage.Cause5GMMToString(cause))
+		eapResponse = buildEapAkaPrimeAuthenticationReject(challenge.identifier)
+	case challenge.kdf != constant.EAP_AKA_PRIME_KDF:
+		u.NasLog.Warnf("EAP-AKA' unsupported KDF: %d", challenge.kdf)
+		eapResponse = buildEapAkaPrimeClientError(challenge.identifier)
+	default:
+		res, kAut, kAusf, newSqn, err := deriveEapAkaPrimeKeys(constant.UE_IMSI_PREFIX+u.supi, u.authenticationSubscription.encPermanentKey, u.authenticationSubscription.encOpcKey, challenge.rand, challenge.autn, challenge.networkName)
+		if err != nil {
+			return fmt.Errorf("error derive EAP-AKA' keys: %+v", err)
+		}
+		if !verifyEapAkaPrimeChallengeMac(challenge, kAut) {
+			u.NasLog.Warnln("EAP-AKA' challenge with invalid AT_MAC")
+			eapResponse = buildEapAkaPrimeClientError(challenge.identifier)
+			break
+		}
+
+		kAmf, err := deriveKAmfFromKAusf(fmt.Sprintf("supi-%s", u.supi), kAusf, challenge.networkName)
+		if err != nil {
+			return fmt.Errorf("error derive kAmf: %+v", err)
+		}
+		kenc, kint, err := deriveAlgorithmKey(kAmf, u.cipheringAlgorithm, u.integrityAlgorithm)
+		if err != nil {
+			return fmt.Errorf("error derive algorithm key: %+v", err)
+		}
+		u.ngKsi = authenticationRequest.GetNasKeySetIdentifiler()
+		u.kAmf = kAmf
+		copy(u.kNasEnc[:], kenc[16:32])
+		copy(u.kNasInt[:], kint[16:32])
+		u.updateSequenceNumber(newSqn)
+
+		u.NasLog.Tracef("kAUSF: %+v", kAusf)
+		u.NasLog.Tracef("kAMF: %+v", kAmf)
+		u.NasLog.Tracef("New SQN: %s", newSqn)
+
+		eapResponse = buildEapAkaPrimeChallengeResponse(challenge.identifier, res, kAut)
 	}
-	u.NasLog.Tracef("Registration request with 5GMM: %+v", registrationRequestWith5Gmm)
 
-	nasSecurityModeCompleteMessage, err := getNasSecurityModeCompleteMessage(registrationRequestWith5Gmm)
+	authenticationResponse, err := getAuthenticationResponse(nil, eapResponse)
 	if err != nil {
-		return fmt.Errorf("error get nas security mode complete message: %+v", err)
+		return fmt.Errorf("error get authentication response: %+v", err)
 	}
-	u.NasLog.Tracef("NAS security mode complete message: %+v", nasSecurityModeCompleteMessage)
+	u.NasLog.Tracef("Authentication response: %+v", authenticationResponse)
 
-	encodedNasSecurityModeCompleteMessage, err := encodeNasPduWithSecurity(nasSecurityModeCompleteMessage, nas.SecurityHeaderTypeIntegrityProtectedAndCipheredWithNew5gNasSecurityContext, u, true, true)
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, authenticationResponse)
 	if err != nil {
-		return fmt.Errorf("error encode nas security mode complete message: %+v", err)
+		return fmt.Errorf("error send authentication response: %+v", err)
 	}
-	u.NasLog.Tracef("Encoded NAS security mode complete message: %+v", encodedNasSecurityModeCompleteMessage)
+	u.NasLog.Tracef("Sent %d bytes of Authentication Response to RAN", n)
+	u.NasLog.Debugln("Send Authentication Response with EAP-AKA' response to RAN")
+	return nil
+}
 
-	n, err = u.ranControlPlaneConn.Write(encodedNasSecurityModeCompleteMessage)
-	if err != nil {
-		return fmt.Errorf("error send nas security mode complete message: %+v", err)
+func (u *Ue) updateSequenceNumber(sequenceNumber string) {
+	u.authenticationSubscription.sequenceNumber = sequenceNumber
+	if u.sequenceNumberFile == "" {
+		return
 	}
-	u.NasLog.Tracef("Sent %d bytes of NAS Security Mode Complete Message to RAN", n)
-	u.NasLog.Debugln("Send NAS Security Mode Complete Message to RAN")
+	// go on with the SQN in memory
+	if err := util.SaveSequenceNumber(u.sequenceNumberFile, constant.UE_IMSI_PREFIX+u.supi, sequenceNumber); err != nil {
+		u.NasLog.Warnf("Error save sequence number to %s: %+v", u.sequenceNumberFile, err)
+	}
+}
 
-	// receive nas registration accept
-	nasRegistrationAcceptRaw := make([]byte, 1024)
-	n, err = u.ranControlPlaneConn.Read(nasRegistrationAcceptRaw)
+func (u *Ue) sendAuthenticationFailure(cause uint8, auts []byte) error {
+	authenticationFailure, err := getAuthenticationFailure(cause, auts)
 	if err != nil {
-		return fmt.Errorf("error read nas registration accept: %+v", err)
+		return fmt.Errorf("error get authentication failure: %+v", err)
 	}
-	u.NasLog.Tracef("Received %d bytes of NAS Registration Accept from RAN", n)
+	u.NasLog.Tracef("Authentication failure: %+v", authenticationFailure)
 
-	nasPdu, err = nasDecode(u, nas.GetSecurityHeaderType(nasRegistrationAcceptRaw[:n]), nasRegistrationAcceptRaw[:n])
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, authenticationFailure)
 	if err != nil {
-		return fmt.Errorf("error decode nas registration accept: %+v", err)
+		return fmt.Errorf("error send authentication failure: %+v", err)
 	}
-	if nasPdu.GmmHeader.GetMessageType() != nas.MsgTypeRegistrationAccept {
-		return fmt.Errorf("error nas pdu message type: %+v, expected registration accept", nasPdu)
+	u.NasLog.Tracef("Sent %d bytes of Authentication Failure to RAN", n)
+	u.NasLog.Debugln("Send Authentication Failure to RAN")
+	return nil
+}
+
+// TS 24.501 5.4.1.3.5
+func (u *Ue) processAuthenticationReject() {
+	u.guti5G = nil
+	u.taiList = nil
+	u.kAmf = nil
+	u.ngKsi = uint8(nasMessage.NasKeySetIdentifierNoKeyIsAvailable)
+}
+
+// TS 24.501 5.5.1.2.5, the cause and T3346 are kept in the returned error
+func (u *Ue) processRegistrationReject(registrationReject *nasMessage.RegistrationReject) error {
+	rejectErr := getRegistrationRejectError(registrationReject)
+	u.NasLog.Warnf("Registration rejected, 5GMM cause: %s, T3346: %v", cause5GmmToString(rejectErr.Cause), rejectErr.BackOff)
+
+	switch rejectErr.Cause {
+	case nasMessage.Cause5GMMIllegalUE,
+		nasMessage.Cause5GMMIllegalME,
+		nasMessage.Cause5GMM5GSServicesNotAllowed,
+		nasMessage.Cause5GMMUEIdentityCannotBeDerivedByTheNetwork,
+		nasMessage.Cause5GMMImplicitlyDeregistered,
+		nasMessage.Cause5GMMPLMNNotAllowed:
+		u.processAuthenticationReject()
 	}
-	u.NasLog.Tracef("NAS registration accept: %+v", nasPdu)
-	u.NasLog.Debugln("Receive NAS Registration Accept from RAN")
+	u.startT3346(rejectErr.BackOff)
 
-	// send nas registration complete message to RAN
-	nasRegistrationCompleteMessage, err := getNasRegistrationCompleteMessage(nil)
-	if err != nil {
-		return fmt.Errorf("error get nas registration complete message: %+v", err)
+	return rejectErr
+}
+
+func (u *Ue) startT3346(t3346 time.Duration) {
+	if t3346 > 0 {
+		u.t3346Expiry = time.Now().Add(t3346)
 	}
-	u.NasLog.Tracef("NAS registration complete message: %+v", nasRegistrationCompleteMessage)
+}
 
-	encodedNasRegistrationCompleteMessage, err := encodeNasPduWithSecurity(nasRegistrationCompleteMessage, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
-	if err != nil {
-		return fmt.Errorf("error encode nas registration complete message: %+v", err)
+func (u *Ue) processSecurityModeComplete(registrationRequest []byte, imeisvRequested bool) error {
+	var imeisv *nasType.MobileIdentity5GS
+	if imeisvRequested {
+		if u.imeisv == "" {
+			u.NasLog.Warnln("IMEISV requested by the network but not configured")
+		} else {
+			imeisvMobileIdentity5GS := buildPeiMobileIdentity5GS(nasMessage.MobileIdentity5GSTypeImeisv, u.imeisv)
+			imeisv = &imeisvMobileIdentity5GS
+		}
 	}
-	u.NasLog.Tracef("Encoded NAS registration complete message: %+v", encodedNasRegistrationCompleteMessage)
 
-	n, err = u.ranControlPlaneConn.Write(encodedNasRegistrationCompleteMessage)
+	nasSecurityModeCompleteMessage, err := getNasSecurityModeCompleteMessage(registrationRequest, imeisv)
 	if err != nil {
-		return fmt.Errorf("error send nas registration complete message: %+v", err)
+		return fmt.Errorf("error get nas security mode complete message: %+v", err)
 	}
-	u.NasLog.Tracef("Sent %d bytes of NAS Registration Complete Message to RAN", n)
-	u.NasLog.Debugln("Send NAS Registration Complete Message to RAN")
+	u.NasLog.Tracef("NAS security mode complete message: %+v", nasSecurityModeCompleteMessage)
 
-	// receive nas configuration update command
-	nasConfigurationUpdateCommandRaw := make([]byte, 1024)
-	n, err = u.ranControlPlaneConn.Read(nasConfigurationUpdateCommandRaw)
+	encodedNasSecurityModeCompleteMessage, err := encodeNasPduWithSecurity(nasSecurityModeCompleteMessage, nas.SecurityHeaderTypeIntegrityProtectedAndCipheredWithNew5gNasSecurityContext, u, true, true)
 	if err != nil {
-		return fmt.Errorf("error read nas configuration update command: %+v", err)
+		return fmt.Errorf("error encode nas security mode complete message: %+v", err)
 	}
-	u.NasLog.Tracef("Received %d bytes of NAS Configuration Update Command from RAN", n)
+	u.NasLog.Tracef("Encoded NAS security mode complete message: %+v", encodedNasSecurityModeCompleteMessage)
 
-	nasPdu, err = nasDecode(u, nas.GetSecurityHeaderType(nasConfigurationUpdateCommandRaw[:n]), nasConfigurationUpdateCommandRaw[:n])
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedNasSecurityModeCompleteMessage)
 	if err != nil {
-		return fmt.Errorf("error decode nas configuration update command: %+v", err)
-	}
-	if nasPdu.GmmHeader.GetMessageType() != nas.MsgTypeConfigurationUpdateCommand {
-		return fmt.Errorf("error nas pdu message type: %+v, expected configuration update command", nasPdu)
+		return fmt.Errorf("error send nas security mode complete message: %+v", err)
 	}
-	u.NasLog.Tracef("NAS configuration update command: %+v", nasPdu)
-	u.NasLog.Debugln("Receive NAS Configuration Update Command from RAN")
-
-	u.RanLog.Infoln("UE Registration finished")
+	u.NasLog.Tracef("Sent %d bytes of NAS Security Mode Complete Message to RAN", n)
+	u.NasLog.Debugln("Send NAS Security Mode Complete Message to RAN")
 	return nil
 }
 
-func (u *Ue) processPduSessionEstablishment() error {
-	u.PduLog.Infoln("Processing PDU session establishment")
+// an identity the UE does not have is answered by no identity
+func (u *Ue) processIdentityRequest(identityRequest *nasMessage.IdentityRequest, protected bool) error {
+	typeOfIdentity := identityRequest.SpareHalfOctetAndIdentityType.GetTypeOfIdentity()
+	u.NasLog.Debugf("Identity type %d requested by the network", typeOfIdentity)
 
-	// send pdu session establishment request
-	pduSessionEstablishmentRequest, err := getPduSessionEstablishmentRequest(constant.PDU_SESSION_ID)
-	if err != nil {
-		return fmt.Errorf("error get pdu session establishment request: %+v", err)
+	mobileIdentity5GS := nasType.MobileIdentity5GS{Len: 1, Buffer: []byte{nasMessage.MobileIdentity5GSTypeNoIdentity}}
+	switch typeOfIdentity {
+	case nasMessage.MobileIdentity5GSTypeSuci:
+		suci, err := u.getSuciMobileIdentity5GS()
+		if err != nil {
+			return fmt.Errorf("error get suci mobile identity: %+v", err)
+		}
+		mobileIdentity5GS = suci
+	case nasMessage.MobileIdentity5GSTypeImei:
+		if u.imei != "" {
+			mobileIdentity5GS = buildPeiMobileIdentity5GS(nasMessage.MobileIdentity5GSTypeImei, u.imei)
+		}
+	case nasMessage.MobileIdentity5GSTypeImeisv:
+		if u.imeisv != "" {
+			mobileIdentity5GS = buildPeiMobileIdentity5GS(nasMessage.MobileIdentity5GSTypeImeisv, u.imeisv)
+		}
+	case nasMessage.MobileIdentity5GSType5gSTmsi:
+		if u.guti5G != nil {
+			mobileIdentity5GS = build5gSTmsiMobileIdentity5GS(*u.guti5G)
+		}
 	}
-	u.NasLog.Tracef("PDU session establishment request: %+v", pduSessionEstablishmentRequest)
-
-	ulNasTransportPduSessionEstablishmentRequest, err := getUlNasTransportMessage(pduSessionEstablishmentRequest, constant.PDU_SESSION_ID, nasMessage.ULNASTransportRequestTypeInitialRequest, u.pduSession.dnn, u.pduSession.sNssai)
-	if err != nil {
-		return fmt.Errorf("error get ul nas transport pdu session establishment request: %+v", err)
+	if mobileIdentity5GS.Buffer[0]&0x07 == nasMessage.MobileIdentity5GSTypeNoIdentity {
+		u.NasLog.Warnf("Identity type %d not available, answer with no identity", typeOfIdentity)
 	}
-	u.NasLog.Tracef("UL NAS transport pdu session establishment request: %+v", ulNasTransportPduSessionEstablishmentRequest)
 
-	encodedUlNasTransportPduSessionEstablishmentRequest, err := encodeNasPduWithSecurity(ulNasTransportPduSessionEstablishmentRequest, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	identityResponse, err := getIdentityResponse(mobileIdentity5GS)
 	if err != nil {
-		return fmt.Errorf("error encode ul nas transport pdu session establishment request: %+v", err)
+		return fmt.Errorf("error get identity response: %+v", err)
 	}
-	u.NasLog.Tracef("Encoded UL NAS transport pdu session establishment request: %+v", encodedUlNasTransportPduSessionEstablishmentRequest)
+	u.NasLog.Tracef("Identity response: %+v", identityResponse)
 
-	n, err := u.ranControlPlaneConn.Write(encodedUlNasTransportPduSessionEstablishmentRequest)
-	if err != nil {
-		return fmt.Errorf("error send ul nas transport pdu session establishment request: %+v", err)
+	if protected {
+		identityResponse, err = encodeNasPduWithSecurity(identityResponse, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+		if err != nil {
+			return fmt.Errorf("error encode identity response: %+v", err)
+		}
+		u.NasLog.Tracef("Encoded identity response: %+v", identityResponse)
 	}
-	u.NasLog.Tracef("Sent %d bytes of UL NAS transport pdu session establishment request to RAN", n)
-	u.NasLog.Debugln("Send UL NAS transport pdu session establishment request to RAN")
 
-	// receive pdu session establishment accept
-	nasPduSessionEstablishmentAcceptRaw := make([]byte, 1024)
-	n, err = u.ranControlPlaneConn.Read(nasPduSessionEstablishmentAcceptRaw)
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, identityResponse)
 	if err != nil {
-		return fmt.Errorf("error read nas pdu session establishment accept: %+v", err)
+		return fmt.Errorf("error send identity response: %+v", err)
 	}
-	u.NasLog.Tracef("Received %d bytes of NAS PDU Session Establishment Accept from RAN", n)
+	u.NasLog.Tracef("Sent %d bytes of Identity Response to RAN", n)
+	u.NasLog.Debugln("Send Identity Response to RAN")
+	return nil
+}
 
-	nasPduSessionEstablishmentAccept, err := nasDecode(u, nas.GetSecurityHeaderType(nasPduSessionEstablishmentAcceptRaw[:n]), nasPduSessionEstablishmentAcceptRaw[:n])
-	if err != nil {
-		return fmt.Errorf("error decode nas pdu session establishment accept: %+v", err)
-	}
-	if nasPduSessionEstablishmentAccept.GmmHeader.GetMessageType() != nas.MsgTypeDLNASTransport {
-		return fmt.Errorf("error nas pdu message type: %+v, expected pdu session establishment accept", nasPduSessionEstablishmentAccept.GmmHeader.GetMessageType())
-	}
-	u.NasLog.Tracef("NAS PDU Session Establishment Accept: %+v", nasPduSessionEstablishmentAccept)
-	u.NasLog.Debugln("Receive NAS PDU Session Establishment Accept from RAN")
+// a new 5G-GUTI is acknowledged by the registration complete
+func (u *Ue) processRegistrationAccept(registrationAccept *nasMessage.RegistrationAccept) error {
+	u.storeRegistrationAccept(registrationAccept)
 
-	// store ue information
-	if err := u.extractUeInformationFromNasPduSessionEstablishmentAccept(nasPduSessionEstablishmentAccept); err != nil {
-		return fmt.Errorf("error extract ue information from nas pdu session establishment accept: %+v", err)
+	if registrationAccept.GUTI5G != nil {
+		if err := u.sendRegistrationComplete(); err != nil {
+			return err
+		}
 	}
 
-	u.PduLog.Infof("UE %s PDU session establishment complete", u.supi)
+	u.setGmmState(constant.UE_GMM_STATE_REGISTERED)
 	return nil
 }
 
-func (u *Ue) processUeDeregistration() error {
-	u.RanLog.Infoln("Processing UE deregistration")
+// fall back to the TAC of the RAN without a TAI list
+func (u *Ue) storeRegistrationAccept(registrationAccept *nasMessage.RegistrationAccept) {
+	if registrationAccept.GUTI5G != nil {
+		u.guti5G = registrationAccept.GUTI5G
+		u.NasLog.Debugf("Assigned 5G-S-TMSI: %s", u.get5GSTmsi())
+	}
 
-	mobileIdentity5GS := buildUeMobileIdentity5GS(u.supi)
-	u.NasLog.Tracef("Mobile identity 5GS: %+v", mobileIdentity5GS)
+	if registrationAccept.TAIList != nil {
+		taiList, err := parseTaiList(registrationAccept.TAIList.GetPartialTrackingAreaIdentityList())
+		if err != nil {
+			u.NasLog.Warnf("Error parse TAI list: %+v", err)
+		} else {
+			u.taiList = taiList
+		}
+	} else if u.servingTac != "" && len(u.taiList) == 0 {
+		u.taiList = []string{u.servingTac}
+	}
+	u.NasLog.Debugf("Registration area TAC list: %v", u.taiList)
+
+	u.storeNssai(registrationAccept.AllowedNSSAI, registrationAccept.RejectedNSSAI, registrationAccept.ConfiguredNSSAI)
+
+	if registrationAccept.T3512Value != nil {
+		u.t3512 = gprsTimer3ToDuration(registrationAccept.T3512Value.GetUnit(), registrationAccept.T3512Value.GetTimerValue())
+		u.NasLog.Debugf("T3512: %v", u.t3512)
+	}
+	if registrationAccept.T3502Value != nil {
+		u.t3502 = gprsTimer2ToDuration(registrationAccept.T3502Value.GetGPRSTimer2Value())
+		u.NasLog.Debugf("T3502: %v", u.t3502)
+	}
+}
+
+// an absent NSSAI leaves the stored one unchanged
+func (u *Ue) storeNssai(allowedNSSAI *nasType.AllowedNSSAI, rejectedNSSAI *nasType.RejectedNSSAI, configuredNSSAI *nasType.ConfiguredNSSAI) {
+	if allowedNSSAI != nil {
+		allowedNssai, err := parseNssai(allowedNSSAI.GetSNSSAIValue())
+		if err != nil {
+			u.NasLog.Warnf("Error parse allowed NSSAI: %+v", err)
+		} else {
+			u.allowedNssai = allowedNssai
+			u.NasLog.Debugf("Allowed NSSAI: %+v", u.allowedNssai)
+		}
+	}
+
+	if rejectedNSSAI != nil {
+		rejectedNssai, err := parseRejectedNssai(rejectedNSSAI.GetRejectedNSSAIContents())
+		if err != nil {
+			u.NasLog.Warnf("Error parse rejected NSSAI: %+v", err)
+		} else {
+			u.rejectedNssai = rejectedNssai
+			for _, rejected := range u.rejectedNssai {
+				u.NasLog.Warnf("Rejected S-NSSAI: %+v, cause: %s", rejected.snssai, rejectedSnssaiCauseToString(rejected.cause))
+			}
+		}
+	}
+
+	if configuredNSSAI != nil {
+		configuredNssai, err := parseNssai(configuredNSSAI.GetSNSSAIValue())
+		if err != nil {
+			u.NasLog.Warnf("Error parse configured NSSAI: %+v", err)
+		} else {
+			u.configuredNssai = configuredNssai
+			u.NasLog.Debugf("Configured NSSAI: %+v", u.configuredNssai)
+		}
+	}
+}
+
+// TS 24.501 6.4.1.2, skipped without an allowed NSSAI
+func (u *Ue) checkAllowedSnssai(sNssai *models.Snssai) error {
+	if sNssai == nil {
+		return nil
+	}
+	if slices.ContainsFunc(u.allowedNssai, func(allowed models.Snssai) bool {
+		return isSameSnssai(allowed, *sNssai)
+	}) {
+		return nil
+	}
+
+	for _, rejected := range u.rejectedNssai {
+		if isSameSnssai(rejected.snssai, *sNssai) {
+			return fmt.Errorf("S-NSSAI %+v is rejected by the network: %s", *sNssai, rejectedSnssaiCauseToString(rejected.cause))
+		}
+	}
+	if u.allowedNssai != nil {
+		return fmt.Errorf("S-NSSAI %+v is not in the allowed NSSAI %+v", *sNssai, u.allowedNssai)
+	}
+	return nil
+}
+
+func isSameSnssai(a, b models.Snssai) bool {
+	return a.Sst == b.Sst && strings.EqualFold(a.Sd, b.Sd)
+}
+
+func (u *Ue) sendRegistrationComplete() error {
+	nasRegistrationCompleteMessage, err := getNasRegistrationCompleteMessage(nil)
+	if err != nil {
+		return fmt.Errorf("error get nas registration complete message: %+v", err)
+	}
+	u.NasLog.Tracef("NAS registration complete message: %+v", nasRegistrationCompleteMessage)
+
+	encodedNasRegistrationCompleteMessage, err := encodeNasPduWithSecurity(nasRegistrationCompleteMessage, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode nas registration complete message: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded NAS registration complete message: %+v", encodedNasRegistrationCompleteMessage)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedNasRegistrationCompleteMessage)
+	if err != nil {
+		return fmt.Errorf("error send nas registration complete message: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of NAS Registration Complete Message to RAN", n)
+	u.NasLog.Debugln("Send NAS Registration Complete Message to RAN")
+	return nil
+}
+
+// TS 24.501 5.4.4, completed only when the network asks for the acknowledgement
+func (u *Ue) processConfigurationUpdateCommand(configurationUpdateCommand *nasMessage.ConfigurationUpdateCommand) error {
+	if configurationUpdateCommand.GUTI5G != nil {
+		u.guti5G = configurationUpdateCommand.GUTI5G
+		u.NasLog.Debugf("Assigned 5G-S-TMSI: %s", u.get5GSTmsi())
+	}
+
+	if configurationUpdateCommand.TAIList != nil {
+		taiList, err := parseTaiList(configurationUpdateCommand.TAIList.GetPartialTrackingAreaIdentityList())
+		if err != nil {
+			u.NasLog.Warnf("Error parse TAI list: %+v", err)
+		} else {
+			u.taiList = taiList
+			u.NasLog.Debugf("Registration area TAC list: %v", u.taiList)
+		}
+	}
+
+	u.storeNssai(configurationUpdateCommand.AllowedNSSAI, configurationUpdateCommand.RejectedNSSAI, configurationUpdateCommand.ConfiguredNSSAI)
+
+	configurationUpdateIndication := configurationUpdateCommand.ConfigurationUpdateIndication
+	if configurationUpdateIndication != nil && configurationUpdateIndication.GetRED() == 1 {
+		u.NasLog.Warnln("Registration requested by the configuration update command, not supported")
+	}
+	if configurationUpdateIndication == nil || configurationUpdateIndication.GetACK() == 0 {
+		return nil
+	}
+
+	configurationUpdateComplete, err := getConfigurationUpdateComplete()
+	if err != nil {
+		return fmt.Errorf("error get configuration update complete: %+v", err)
+	}
+	u.NasLog.Tracef("Configuration update complete: %+v", configurationUpdateComplete)
+
+	encodedConfigurationUpdateComplete, err := encodeNasPduWithSecurity(configurationUpdateComplete, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode configuration update complete: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded configuration update complete: %+v", encodedConfigurationUpdateComplete)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedConfigurationUpdateComplete)
+	if err != nil {
+		return fmt.Errorf("error send configuration update complete: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of Configuration Update Complete to RAN", n)
+	u.NasLog.Debugln("Send Configuration Update Complete to RAN")
+	return nil
+}
+
+// a RAN without TAC is regarded as in the registration area
+func (u *Ue) isInRegistrationArea() bool {
+	return u.servingTac == "" || len(u.taiList) == 0 || slices.Contains(u.taiList, u.servingTac)
+}
+
+// reuse the 5G-GUTI and the current NAS security context
+func (u *Ue) getRegistrationUpdateRequest(registrationType uint8) ([]byte, error) {
+	if u.guti5G == nil {
+		return nil, fmt.Errorf("error no 5G-GUTI assigned")
+	}
+	mobileIdentity5GS := buildGutiMobileIdentity5GS(*u.guti5G)
+	u.NasLog.Tracef("Mobile identity 5GS: %+v", mobileIdentity5GS)
+
+	ueSecurityCapability := buildUeSecurityCapability(u.cipheringAlgorithm, u.integrityAlgorithm)
+	u.NasLog.Tracef("UE security capability: %+v", ueSecurityCapability)
+
+	activePduSessionIdList := make([]uint8, 0, len(u.pduSessionList))
+	for _, pduSession := range u.pduSessionList {
+		if u.getGsmState(pduSession) == constant.UE_GSM_STATE_PDU_SESSION_ACTIVE {
+			activePduSessionIdList = append(activePduSessionIdList, pduSession.pduSessionId)
+		}
+	}
+	pduSessionStatus := nasType.NewPDUSessionStatus(nasMessage.RegistrationRequestPDUSessionStatusType)
+	pduSessionStatus.SetLen(2)
+	pduSessionStatus.Buffer = buildPduSessionStatusBitmap(activePduSessionIdList)
+
+	requestedNSSAI, err := buildRequestedNssai(u.requestedNssai)
+	if err != nil {
+		return nil, fmt.Errorf("error build requested NSSAI: %+v", err)
+	}
+	u.NasLog.Tracef("Requested NSSAI: %+v", requestedNSSAI)
+
+	registrationRequest, err := getUeRegistrationRequest(registrationType, u.ngKsi, false, &mobileIdentity5GS, requestedNSSAI, &ueSecurityCapability, u.get5GmmCapability(), nil, nil, pduSessionStatus)
+	if err != nil {
+		return nil, fmt.Errorf("error get ue registration request: %+v", err)
+	}
+	u.NasLog.Tracef("Get UE %s registration request, registration type %d: %+v", u.supi, registrationType, registrationRequest)
+	return registrationRequest, nil
+}
+
+// sent on the connected N1 right after the handover
+func (u *Ue) processMobilityRegistrationUpdate() error {
+	u.RanLog.Infof("Processing mobility registration update, TAC %s not in registration area %v", u.servingTac, u.taiList)
+
+	registrationRequest, err := u.getRegistrationUpdateRequest(nasMessage.RegistrationType5GSMobilityRegistrationUpdating)
+	if err != nil {
+		return err
+	}
+
+	encodedRegistrationRequest, err := encodeNasPduWithSecurity(registrationRequest, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode ue registration request: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded UE registration request: %+v", encodedRegistrationRequest)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedRegistrationRequest)
+	if err != nil {
+		return fmt.Errorf("error send ue registration request: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of UE %s registration request", n, u.supi)
+	u.NasLog.Debugln("Send UE mobility registration update request")
+
+	if err := u.waitForRegistrationUpdate(registrationRequest); err != nil {
+		return err
+	}
+
+	u.RanLog.Infof("UE %s mobility registration update complete", u.supi)
+	return nil
+}
+
+// the UE stays registered when T3510 expires
+func (u *Ue) waitForRegistrationUpdate(registrationRequest []byte) error {
+	u.initialNasMessage = registrationRequest
+	u.setGmmState(constant.UE_GMM_STATE_REGISTERED_INITIATED)
+
+	if err := u.runNasProcedure("T3510", constant.UE_T3510, 0, func() bool {
+		return u.getGmmState() == constant.UE_GMM_STATE_REGISTERED_INITIATED
+	}); err != nil {
+		if u.getGmmState() == constant.UE_GMM_STATE_REGISTERED_INITIATED {
+			u.setGmmState(constant.UE_GMM_STATE_REGISTERED)
+		}
+		return err
+	}
+	if gmmState := u.getGmmState(); gmmState != constant.UE_GMM_STATE_REGISTERED {
+		return fmt.Errorf("error registration update not accepted, 5GMM state: %s", gmmState)
+	}
+	return nil
+}
+
+// T3512 expired in CM-IDLE, back to CM-IDLE once the network releases the connection
+func (u *Ue) processPeriodicRegistrationUpdate() error {
+	u.RanLog.Infoln("Processing periodic registration update")
+
+	registrationRequest, err := u.getRegistrationUpdateRequest(nasMessage.RegistrationType5GSPeriodicRegistrationUpdating)
+	if err != nil {
+		return err
+	}
+
+	encodedRegistrationRequest, err := encodeNasPduWithSecurity(registrationRequest, nas.SecurityHeaderTypeIntegrityProtected, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode ue registration request: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded UE registration request: %+v", encodedRegistrationRequest)
+
+	// the previous N1 connection is already closed by the RAN
+	if err := u.ranControlPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		u.RanLog.Warnf("Error closing RAN connection: %v", err)
+	}
+	if err := u.connectToRanControlPlane(); err != nil {
+		return fmt.Errorf("error connect to ran control plane: %+v", err)
+	}
+	defer u.closeRanControlPlaneConn()
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedRegistrationRequest)
+	if err != nil {
+		return fmt.Errorf("error send ue registration request: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of UE %s registration request", n, u.supi)
+	u.NasLog.Debugln("Send UE periodic registration update request")
+
+	if err := u.waitForRegistrationUpdate(registrationRequest); err != nil {
+		return err
+	}
+
+	u.RanLog.Infof("UE %s periodic registration update complete", u.supi)
+	return nil
+}
+
+func (u *Ue) processPduSessionEstablishment(pduSession *pduSession) error {
+	u.PduLog.Infof("Processing PDU session %d establishment", pduSession.pduSessionId)
+
+	if err := u.checkAllowedSnssai(pduSession.sNssai); err != nil {
+		return fmt.Errorf("error pdu session %d not allowed: %+v", pduSession.pduSessionId, err)
+	}
+
+	// send pdu session establishment request
+	pduSessionEstablishmentRequest, err := getPduSessionEstablishmentRequest(pduSession.pduSessionId, pduSession.pduSessionType)
+	if err != nil {
+		return fmt.Errorf("error get pdu session establishment request: %+v", err)
+	}
+	u.NasLog.Tracef("PDU session establishment request: %+v", pduSessionEstablishmentRequest)
+
+	ulNasTransportPduSessionEstablishmentRequest, err := getUlNasTransportMessage(pduSessionEstablishmentRequest, pduSession.pduSessionId, nasMessage.ULNASTransportRequestTypeInitialRequest, pduSession.dnn, pduSession.sNssai)
+	if err != nil {
+		return fmt.Errorf("error get ul nas transport pdu session establishment request: %+v", err)
+	}
+	u.NasLog.Tracef("UL NAS transport pdu session establishment request: %+v", ulNasTransportPduSessionEstablishmentRequest)
+
+	encodedUlNasTransportPduSessionEstablishmentRequest, err := encodeNasPduWithSecurity(ulNasTransportPduSessionEstablishmentRequest, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode ul nas transport pdu session establishment request: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded UL NAS transport pdu session establishment request: %+v", encodedUlNasTransportPduSessionEstablishmentRequest)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedUlNasTransportPduSessionEstablishmentRequest)
+	if err != nil {
+		return fmt.Errorf("error send ul nas transport pdu session establishment request: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of UL NAS transport pdu session establishment request to RAN", n)
+	u.NasLog.Debugln("Send UL NAS transport pdu session establishment request to RAN")
+
+	u.setGsmState(pduSession, constant.UE_GSM_STATE_PDU_SESSION_ACTIVE_PENDING)
+
+	// a reject is returned as it is for its cause and back-off timer
+	if err := u.runNasProcedure("T3580", constant.UE_T3580, 0, func() bool {
+		return u.getGsmState(pduSession) == constant.UE_GSM_STATE_PDU_SESSION_ACTIVE_PENDING
+	}); err != nil {
+		u.setGsmState(pduSession, constant.UE_GSM_STATE_PDU_SESSION_INACTIVE)
+		return err
+	}
+	if gsmState := u.getGsmState(pduSession); gsmState != constant.UE_GSM_STATE_PDU_SESSION_ACTIVE {
+		return fmt.Errorf("error pdu session %d not established, 5GSM state: %s", pduSession.pduSessionId, gsmState)
+	}
+
+	u.PduLog.Infof("UE %s PDU session %d establishment complete", u.supi, pduSession.pduSessionId)
+	return nil
+}
+
+func (u *Ue) processUeDeregistration() error {
+	u.RanLog.Infoln("Processing UE deregistration")
+
+	mobileIdentity5GS, err := u.getSuciMobileIdentity5GS()
+	if err != nil {
+		return fmt.Errorf("error get suci mobile identity: %+v", err)
+	}
+	u.NasLog.Tracef("Mobile identity 5GS: %+v", mobileIdentity5GS)
 
 	// send ue deregistration request
 	deregistrationRequest, err := getUeDeRegistrationRequest(nasMessage.AccessType3GPP, 0x00, 0x04, mobileIdentity5GS)
@@ -582,105 +1299,237 @@ func (u *Ue) processUeDeregistration() error {
 	}
 	u.NasLog.Tracef("Encoded UE deregistration request: %+v", encodedDeregistrationRequest)
 
-	n, err := u.ranControlPlaneConn.Write(encodedDeregistrationRequest)
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedDeregistrationRequest)
 	if err != nil {
 		return fmt.Errorf("error send ue deregistration request: %+v", err)
 	}
 	u.NasLog.Tracef("Sent %d bytes of UE deregistration request to RAN", n)
 	u.NasLog.Debugln("Send UE deregistration request to RAN")
 
-	// receive ue deregistration accept
-	ueDeRegistrationAcceptRaw := make([]byte, 1024)
-	n, err = u.ranControlPlaneConn.Read(ueDeRegistrationAcceptRaw)
+	u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED_INITIATED)
+
+	// deregister locally when T3521 expires
+	err = u.runNasProcedure("T3521", constant.UE_T3521, 0, func() bool {
+		return u.getGmmState() == constant.UE_GMM_STATE_DEREGISTERED_INITIATED
+	})
+	u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED)
+	if err != nil {
+		return err
+	}
+
+	u.RanLog.Infoln("UE deregistration complete")
+	return nil
+}
+
+// TS 24.501 5.5.2.3
+func (u *Ue) processNetworkDeregistration(deregistrationRequest *nasMessage.DeregistrationRequestUETerminatedDeregistration) error {
+	reRegistrationRequired := deregistrationRequest.GetReRegistrationRequired() == nasMessage.ReRegistrationRequired
+	var cause uint8
+	if deregistrationRequest.Cause5GMM != nil {
+		cause = deregistrationRequest.GetCauseValue()
+	}
+	u.NasLog.Infof("Deregistered by the network, 5GMM cause: %s, re-registration required: %t", cause5GmmToString(cause), reRegistrationRequired)
+
+	deregistrationAccept, err := getUeTerminatedDeregistrationAccept()
 	if err != nil {
-		return fmt.Errorf("error read ue deregistration accept: %+v", err)
+		return fmt.Errorf("error get deregistration accept: %+v", err)
 	}
-	u.NasLog.Tracef("Received %d bytes of UE deregistration accept from RAN", n)
+	u.NasLog.Tracef("Deregistration accept: %+v", deregistrationAccept)
 
-	ueDeRegistrationAccept, err := nasDecode(u, nas.GetSecurityHeaderType(ueDeRegistrationAcceptRaw[:n]), ueDeRegistrationAcceptRaw[:n])
+	encodedDeregistrationAccept, err := encodeNasPduWithSecurity(deregistrationAccept, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
 	if err != nil {
-		return fmt.Errorf("error decode ue deregistration accept: %+v", err)
+		return fmt.Errorf("error encode deregistration accept: %+v", err)
 	}
-	if ueDeRegistrationAccept.GmmHeader.GetMessageType() != nas.MsgTypeDeregistrationAcceptUEOriginatingDeregistration {
-		return fmt.Errorf("error nas pdu message type: %+v, expected pdu session establishment accept", ueDeRegistrationAccept.GmmHeader.GetMessageType())
+	u.NasLog.Tracef("Encoded deregistration accept: %+v", encodedDeregistrationAccept)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedDeregistrationAccept)
+	if err != nil {
+		return fmt.Errorf("error send deregistration accept: %+v", err)
 	}
-	u.NasLog.Tracef("NAS UE deregistration accept: %+v", ueDeRegistrationAccept)
-	u.NasLog.Debugln("Receive NAS UE deregistration accept from RAN")
+	u.NasLog.Tracef("Sent %d bytes of Deregistration Accept to RAN", n)
+	u.NasLog.Debugln("Send Deregistration Accept to RAN")
 
-	u.RanLog.Infoln("UE deregistration complete")
+	u.setGmmState(constant.UE_GMM_STATE_DEREGISTERED)
+	u.releasePduSessionsLocally()
+
+	// handled like the registration reject
+	switch cause {
+	case nasMessage.Cause5GMMIllegalUE,
+		nasMessage.Cause5GMMIllegalME,
+		nasMessage.Cause5GMM5GSServicesNotAllowed,
+		nasMessage.Cause5GMMPLMNNotAllowed:
+		u.processAuthenticationReject()
+	}
+	if deregistrationRequest.T3346Value != nil {
+		u.startT3346(gprsTimer2ToDuration(deregistrationRequest.T3346Value.GetGPRSTimer2Value()))
+	}
+
+	u.reRegistrationRequired = reRegistrationRequired
+	u.UeLog.Infoln("UE deregistered by the network")
 	return nil
 }
 
-func (u *Ue) extractUeInformationFromNasPduSessionEstablishmentAccept(nasPduSessionEstablishmentAccept *nas.Message) error {
-	nasMessage, err := getNasPduFromNasPduSessionEstablishmentAccept(nasPduSessionEstablishmentAccept)
-	if err != nil {
-		return fmt.Errorf("error get nas pdu from nas pdu session establishment accept: %+v", err)
+// the tunnel devices are kept open but brought down
+func (u *Ue) releasePduSessionsLocally() {
+	for _, pduSession := range u.pduSessionList {
+		if u.getGsmState(pduSession) != constant.UE_GSM_STATE_PDU_SESSION_ACTIVE {
+			continue
+		}
+		u.setGsmState(pduSession, constant.UE_GSM_STATE_PDU_SESSION_INACTIVE)
+
+		if err := u.cleanUpTunnelDevice(pduSession); err != nil {
+			u.TunLog.Warnf("Error cleaning up tunnel device: %+v", err)
+		}
+		if err := pduSession.ranDataPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			u.RanLog.Warnf("Error closing RAN data plane connection: %v", err)
+		}
+		pduSession.pduSessionEstablishmentAccept = pduSessionEstablishmentAccept{}
+		u.PduLog.Infof("UE %s PDU session %d released locally", u.supi, pduSession.pduSessionId)
 	}
-	u.NasLog.Tracef("NAS message: %+v", nasMessage)
 
-	switch nasMessage.GsmHeader.GetMessageType() {
-	case nas.MsgTypePDUSessionEstablishmentAccept:
-		pduSessionEstablishmentAccept := nasMessage.PDUSessionEstablishmentAccept
+	if u.isNrdcEnabled() {
+		if err := u.dcRanDataPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			u.RanLog.Warnf("Error closing DC RAN data plane connection: %v", err)
+		}
+		u.rwLock.Lock()
+		u.nrdc.specifiedFlow = nil
+		u.rwLock.Unlock()
+	}
+}
 
-		pduAddress := pduSessionEstablishmentAccept.GetPDUAddressInformation()
-		u.pduSessionEstablishmentAccept.ueIp = fmt.Sprintf("%d.%d.%d.%d", pduAddress[0], pduAddress[1], pduAddress[2], pduAddress[3])
-		u.PduLog.Infof("PDU session UE IP: %s", u.pduSessionEstablishmentAccept.ueIp)
+// the pdu sessions are established again on the tunnel devices kept open
+func (u *Ue) processReRegistration(ctx context.Context) error {
+	if u.getGmmState() != constant.UE_GMM_STATE_DEREGISTERED {
+		return fmt.Errorf("unexpected re-registration in %s", u.getGmmState())
+	}
+	u.UeLog.Infoln("Processing re-registration")
 
-		u.pduSessionEstablishmentAccept.qosRule = pduSessionEstablishmentAccept.AuthorizedQosRules.GetQosRule()
-		u.nrdc.specifiedFlow = append(u.nrdc.specifiedFlow, util.GetQosRule(u.pduSessionEstablishmentAccept.qosRule, u.UeLogger)...)
-		u.PduLog.Infof("PDU session QoS rule: %+v", u.nrdc.specifiedFlow)
+	// the RAN releases the previous N1 connection
+	u.closeRanControlPlaneConn()
+	if err := u.connectToRanControlPlane(); err != nil {
+		return fmt.Errorf("error connect to ran control plane: %+v", err)
+	}
 
-		u.pduSessionEstablishmentAccept.dnn = pduSessionEstablishmentAccept.GetDNN()
-		u.PduLog.Infof("PDU session DNN: %s", u.pduSessionEstablishmentAccept.dnn)
+	if err := u.retryNasProcedure(ctx, u.processUeRegistration, func() error {
+		u.closeRanControlPlaneConn()
+		return u.connectToRanControlPlane()
+	}); err != nil {
+		return fmt.Errorf("error process ue registration: %+v", err)
+	}
 
-		u.pduSessionEstablishmentAccept.sst = pduSessionEstablishmentAccept.GetSST()
-		u.pduSessionEstablishmentAccept.sd = pduSessionEstablishmentAccept.GetSD()
-		u.PduLog.Infof("PDU session SNSSAI, sst: %d, sd: %s", u.pduSessionEstablishmentAccept.sst, fmt.Sprintf("%x%x%x", u.pduSessionEstablishmentAccept.sd[0], u.pduSessionEstablishmentAccept.sd[1], u.pduSessionEstablishmentAccept.sd[2]))
-	case nas.MsgTypePDUSessionReleaseCommand:
-		return fmt.Errorf("not implemented: PDUSessionReleaseCommand")
-	case nas.MsgTypePDUSessionEstablishmentReject:
-		return fmt.Errorf("not implemented: PDUSessionEstablishmentReject")
-	default:
-		return fmt.Errorf("not implemented: %+v", nasMessage.GsmHeader.GetMessageType())
+	for _, pduSession := range u.pduSessionList {
+		if err := u.retryNasProcedure(ctx, func() error {
+			return u.processPduSessionEstablishment(pduSession)
+		}, nil); err != nil {
+			return fmt.Errorf("error process pdu session %d establishment: %+v", pduSession.pduSessionId, err)
+		}
+
+		if err := u.connectToRanDataPlane(pduSession); err != nil {
+			return fmt.Errorf("error connect to ran data plane: %+v", err)
+		}
+		go u.readFromRanDataPlane(pduSession, pduSession.ranDataPlaneConn)
+		if u.isDefaultPduSession(pduSession) && u.isNrdcEnabled() {
+			go u.readFromDcRanDataPlane(pduSession, u.dcRanDataPlaneConn)
+		}
+
+		if err := configureUeTunnelDevice(pduSession.ueTunnelDeviceName, pduSession.ueIp); err != nil {
+			return fmt.Errorf("error configure ue tunnel device: %+v", err)
+		}
 	}
 
+	u.UeLog.Infoln("UE registered again")
 	return nil
 }
 
+func (u *Ue) storePduSessionEstablishmentAccept(pduSession *pduSession, pduSessionEstablishmentAccept *nasMessage.PDUSessionEstablishmentAccept) {
+	// the IPv4 address follows the 8 bytes IPv6 interface identifier for an IPv4v6 pdu session
+	pduAddress := pduSessionEstablishmentAccept.GetPDUAddressInformation()
+	ipv4Address := pduAddress[:4]
+	if pduSessionEstablishmentAccept.PDUAddress.GetPDUSessionTypeValue() == nasMessage.PDUSessionTypeIPv4IPv6 {
+		ipv4Address = pduAddress[8:12]
+	}
+	pduSession.pduSessionEstablishmentAccept.ueIp = fmt.Sprintf("%d.%d.%d.%d", ipv4Address[0], ipv4Address[1], ipv4Address[2], ipv4Address[3])
+	u.PduLog.Infof("PDU session %d UE IP: %s", pduSession.pduSessionId, pduSession.pduSessionEstablishmentAccept.ueIp)
+
+	pduSession.pduSessionEstablishmentAccept.qosRule = pduSessionEstablishmentAccept.AuthorizedQosRules.GetQosRule()
+	if u.isDefaultPduSession(pduSession) {
+		u.nrdc.specifiedFlow = append(u.nrdc.specifiedFlow, util.GetQosRule(pduSession.pduSessionEstablishmentAccept.qosRule, u.UeLogger)...)
+		u.PduLog.Infof("PDU session %d QoS rule: %+v", pduSession.pduSessionId, u.nrdc.specifiedFlow)
+	}
+
+	pduSession.pduSessionEstablishmentAccept.dnn = pduSessionEstablishmentAccept.GetDNN()
+	u.PduLog.Infof("PDU session %d DNN: %s", pduSession.pduSessionId, pduSession.pduSessionEstablishmentAccept.dnn)
+
+	pduSession.pduSessionEstablishmentAccept.sst = pduSessionEstablishmentAccept.GetSST()
+	pduSession.pduSessionEstablishmentAccept.sd = pduSessionEstablishmentAccept.GetSD()
+	u.PduLog.Infof("PDU session %d SNSSAI, sst: %d, sd: %s", pduSession.pduSessionId, pduSession.pduSessionEstablishmentAccept.sst, fmt.Sprintf("%x%x%x", pduSession.pduSessionEstablishmentAccept.sd[0], pduSession.pduSessionEstablishmentAccept.sd[1], pduSession.pduSessionEstablishmentAccept.sd[2]))
+}
+
 func (u *Ue) waitForRanMessage(ctx context.Context, wg *sync.WaitGroup) {
 	u.RanLog.Infoln("Waiting for RAN message")
 	wg.Add(1)
 
-	buffer := make([]byte, 1024)
+	// the RAN announces the release before closing the N1 connection
+	connectionReleased := false
+
 	for {
-		if err := u.ranControlPlaneConn.SetReadDeadline(time.Now().Add(1 * time.Second)); err != nil {
-			u.RanLog.Errorf("Error set read deadline: %+v", err)
-			goto STOP_WAITING
+		// re-register before handling the release of the old N1 connection
+		if u.reRegistrationRequired {
+			u.reRegistrationRequired = false
+			if err := u.processReRegistration(ctx); err != nil {
+				u.UeLog.Errorf("Error processing re-registration: %+v", err)
+			}
 		}
-		select {
-		case <-ctx.Done():
-			if err := u.ranControlPlaneConn.SetReadDeadline(time.Time{}); err != nil {
-				u.RanLog.Errorf("Error set read deadline: %+v", err)
+
+		var message ranMessage
+		if len(u.pendingRanMessageList) > 0 {
+			message, u.pendingRanMessageList = u.pendingRanMessageList[0], u.pendingRanMessageList[1:]
+		} else {
+			select {
+			case <-ctx.Done():
+				goto STOP_WAITING
+			case message = <-u.ranMessageChan:
 			}
-			goto STOP_WAITING
-		default:
-			n, err := u.ranControlPlaneConn.Read(buffer)
-			if err != nil {
-				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
-					goto STOP_WAITING
-				}
-				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					continue
-				}
-				u.RanLog.Warnf("Error read from ran control plane: %+v", err)
+		}
+
+		// replaced by a handover, a service request or a registration
+		if message.conn != u.ranControlPlaneConn {
+			u.RanLog.Tracef("Ignore message type %d of a previous RAN connection, error: %v", message.messageType, message.err)
+			continue
+		}
+
+		if message.err != nil {
+			if !errors.Is(message.err, net.ErrClosed) && !errors.Is(message.err, io.EOF) {
+				u.RanLog.Warnf("Error read from ran control plane: %+v", message.err)
+			}
+			if u.getGmmState() == constant.UE_GMM_STATE_DEREGISTERED {
+				u.RanLog.Infoln("N1 connection released after the deregistration by the network")
+				goto STOP_WAITING
 			}
+			if connectionReleased && u.waitInCmIdle(ctx) {
+				connectionReleased = false
+				continue
+			}
+			goto STOP_WAITING
+		}
 
-			switch string(buffer[:n]) {
-			case constant.UE_TUNNEL_UPDATE:
-				go u.updateDataPlane()
-			default:
-				u.RanLog.Warnf("Received unknown message from RAN: %+v", buffer[:n])
+		switch message.messageType {
+		case constant.RAN_UE_MESSAGE_TYPE_NAS:
+			if err := u.handleNasMessage(message.payload); err != nil {
+				u.NasLog.Errorf("Error processing downlink NAS message: %+v", err)
 			}
+		case constant.RAN_UE_MESSAGE_TYPE_TUNNEL_UPDATE:
+			go u.updateDataPlane(u.pduSessionList[0])
+		case constant.RAN_UE_MESSAGE_TYPE_CONNECTION_RELEASE:
+			connectionReleased = true
+			u.RanLog.Infoln("Connection released by RAN")
+		case constant.RAN_UE_MESSAGE_TYPE_HANDOVER_COMMAND:
+			// the handover command carries the addresses of the target RAN
+			if err := u.processHandover(string(message.payload)); err != nil {
+				u.RanLog.Errorf("Error processing handover: %+v", err)
+			}
+		default:
+			u.RanLog.Warnf("Received unknown message type %d from RAN: %+v", message.messageType, message.payload)
 		}
 	}
 STOP_WAITING:
@@ -688,23 +1537,459 @@ STOP_WAITING:
 	wg.Done()
 }
 
-func (u *Ue) setupTunnelDevice() error {
-	u.TunLog.Infoln("Setting up UE tunnel device")
+// camp in CM-IDLE until paged or uplink data, false if the UE is stopped
+func (u *Ue) waitInCmIdle(ctx context.Context) bool {
+	// a service request asked for in CM-CONNECTED is outdated
+	select {
+	case <-u.serviceRequestChan:
+	default:
+	}
+	u.cmIdle.Store(true)
+	u.RanLog.Infoln("UE moved to CM-IDLE")
+
+	if err := u.campOnRan(); err != nil {
+		u.RanLog.Warnf("Error camping on RAN, UE cannot be paged: %+v", err)
+	}
+
+	// a nil channel disables the periodic registration update
+	var t3512 *time.Timer
+	var t3512Chan <-chan time.Time
+	if u.t3512 > 0 {
+		t3512 = time.NewTimer(u.t3512)
+		defer t3512.Stop()
+		t3512Chan = t3512.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case serviceType := <-u.serviceRequestChan:
+			if u.runServiceRequest(serviceType) {
+				u.RanLog.Infoln("UE moved to CM-CONNECTED")
+				return true
+			}
+		case <-t3512Chan:
+			u.runPeriodicRegistrationUpdate()
+			if u.t3512 > 0 {
+				t3512.Reset(u.t3512)
+			}
+		case message := <-u.ranMessageChan:
+			// the periodic registration update closes its N1 connection
+			u.RanLog.Tracef("Ignore message type %d from RAN in CM-IDLE, error: %v", message.messageType, message.err)
+		}
+	}
+}
+
+func (u *Ue) runPeriodicRegistrationUpdate() {
+	if err := u.processPeriodicRegistrationUpdate(); err != nil {
+		u.NasLog.Errorf("Error processing periodic registration update: %+v", err)
+	}
+	u.pendingRanMessageList = nil
+
+	// the 5G-S-TMSI may be reassigned by the registration accept
+	if err := u.campOnRan(); err != nil {
+		u.RanLog.Warnf("Error camping on RAN, UE cannot be paged: %+v", err)
+	}
+}
+
+func (u *Ue) campOnRan() error {
+	if u.guti5G == nil {
+		return fmt.Errorf("no 5G-GUTI assigned")
+	}
+	u.closeIdleCampConn()
+
+	conn, err := util.TcpDialWithOptionalLocalAddress(u.ranControlPlaneIp, u.ranControlPlanePort, "")
+	if err != nil {
+		return fmt.Errorf("error dial ran control plane: %+v", err)
+	}
+	idleCampConn := util.NewRanUeConn(conn)
+
+	n, err := idleCampConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_IDLE_CAMP, []byte(u.get5GSTmsi()))
+	if err != nil {
+		if closeErr := conn.Close(); closeErr != nil {
+			u.RanLog.Errorf("Error closing RAN camping connection: %v", closeErr)
+		}
+		return fmt.Errorf("error send idle camp: %+v", err)
+	}
+	u.RanLog.Tracef("Sent %d bytes of idle camp to RAN", n)
+
+	u.idleCampConn = idleCampConn
+	go u.readFromIdleCampConn(idleCampConn)
+
+	u.RanLog.Debugf("Camping on RAN with 5G-S-TMSI %s", u.get5GSTmsi())
+	return nil
+}
+
+func (u *Ue) readFromIdleCampConn(idleCampConn *util.RanUeConn) {
+	for {
+		messageType, _, err := idleCampConn.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+				u.RanLog.Warnf("Error read from ran camping connection: %+v", err)
+			}
+			return
+		}
+
+		if messageType != constant.RAN_UE_MESSAGE_TYPE_PAGING {
+			u.RanLog.Warnf("Received unexpected message type %d on RAN camping connection", messageType)
+			continue
+		}
+		u.triggerServiceRequest(nasMessage.ServiceTypeMobileTerminatedServices)
+	}
+}
+
+func (u *Ue) closeIdleCampConn() {
+	if u.idleCampConn == nil {
+		return
+	}
+	if err := u.idleCampConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		u.RanLog.Warnf("Error closing RAN camping connection: %v", err)
+	}
+	u.idleCampConn = nil
+}
+
+// AMF Set ID, AMF Pointer and 5G-TMSI of the 5G-GUTI in hex
+func (u *Ue) get5GSTmsi() string {
+	return hex.EncodeToString(u.guti5G.Octet[5:11])
+}
+
+// a service request asked for while one is pending is dropped
+func (u *Ue) triggerServiceRequest(serviceType uint8) {
+	if !u.cmIdle.Load() {
+		return
+	}
+	select {
+	case u.serviceRequestChan <- serviceType:
+	default:
+	}
+}
+
+// true if back in CM-CONNECTED
+func (u *Ue) runServiceRequest(serviceType uint8) bool {
+	// TS 24.501 5.6.1.7, only a paging is answered while T3346 runs
+	if serviceType != nasMessage.ServiceTypeMobileTerminatedServices && time.Now().Before(u.t3346Expiry) {
+		u.NasLog.Debugf("T3346 running for %v, skip service request", time.Until(u.t3346Expiry).Round(time.Second))
+		return false
+	}
+
+	if err := u.processServiceRequest(serviceType); err != nil {
+		u.NasLog.Errorf("Error processing service request: %+v", err)
+
+		// the paging consumes the camping entry
+		if err := u.campOnRan(); err != nil {
+			u.RanLog.Warnf("Error camping on RAN, UE cannot be paged: %+v", err)
+		}
+		u.pendingRanMessageList = nil
+		return false
+	}
+	u.closeIdleCampConn()
+	return true
+}
+
+func (u *Ue) processServiceRequest(serviceType uint8) error {
+	u.RanLog.Infof("Processing service request, service type: %d", serviceType)
 
-	waterInterface, err := bringUpUeTunnelDevice(u.ueTunnelDeviceName, u.ueIp)
+	if u.guti5G == nil {
+		return fmt.Errorf("error no 5G-GUTI assigned")
+	}
+
+	// the previous N1 connection is already closed by the RAN
+	if err := u.ranControlPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		u.RanLog.Warnf("Error closing RAN connection: %v", err)
+	}
+	if err := u.connectToRanControlPlane(); err != nil {
+		return fmt.Errorf("error connect to ran control plane: %+v", err)
+	}
+
+	activePduSessionList := make([]*pduSession, 0, len(u.pduSessionList))
+	activePduSessionIdList := make([]uint8, 0, len(u.pduSessionList))
+	for _, pduSession := range u.pduSessionList {
+		if u.getGsmState(pduSession) == constant.UE_GSM_STATE_PDU_SESSION_ACTIVE {
+			activePduSessionList = append(activePduSessionList, pduSession)
+			activePduSessionIdList = append(activePduSessionIdList, pduSession.pduSessionId)
+		}
+	}
+
+	// send service request
+	serviceRequest, err := getServiceRequest(serviceType, u.ngKsi, *u.guti5G, activePduSessionIdList)
+	if err != nil {
+		u.closeRanControlPlaneConn()
+		return fmt.Errorf("error get service request: %+v", err)
+	}
+	u.NasLog.Tracef("Service request: %+v", serviceRequest)
+
+	encodedServiceRequest, err := encodeNasPduWithSecurity(serviceRequest, nas.SecurityHeaderTypeIntegrityProtected, u, true, false)
+	if err != nil {
+		u.closeRanControlPlaneConn()
+		return fmt.Errorf("error encode service request: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded service request: %+v", encodedServiceRequest)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedServiceRequest)
+	if err != nil {
+		u.closeRanControlPlaneConn()
+		return fmt.Errorf("error send service request: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of Service Request to RAN", n)
+	u.NasLog.Debugln("Send Service Request to RAN")
+
+	u.initialNasMessage = serviceRequest
+	u.setGmmState(constant.UE_GMM_STATE_SERVICE_REQUEST_INITIATED)
+
+	// stay registered in CM-IDLE without a service accept
+	if err := u.runNasProcedure("T3517", constant.UE_T3517, 0, func() bool {
+		return u.getGmmState() == constant.UE_GMM_STATE_SERVICE_REQUEST_INITIATED
+	}); err != nil {
+		u.setGmmState(constant.UE_GMM_STATE_REGISTERED)
+		u.closeRanControlPlaneConn()
+		return err
+	}
+
+	// register the data plane addresses for the new pdu session resources
+	for _, pduSession := range activePduSessionList {
+		if err := u.sendDataPlaneInitialPacket(pduSession); err != nil {
+			u.RanLog.Warnf("Error restoring PDU session %d user plane: %+v", pduSession.pduSessionId, err)
+		}
+	}
+
+	u.cmIdle.Store(false)
+
+	u.RanLog.Infof("UE %s service request complete", u.supi)
+	return nil
+}
+
+// the source RAN connections are closed once every connection is moved
+func (u *Ue) processHandover(handoverCommand string) error {
+	u.RanLog.Infof("Processing handover to RAN %s", handoverCommand)
+
+	addresses := strings.Fields(handoverCommand)
+	if len(addresses) != 2 {
+		return fmt.Errorf("error invalid handover command: %q", handoverCommand)
+	}
+	targetControlPlaneIp, targetControlPlanePort, err := splitHostPort(addresses[0])
+	if err != nil {
+		return fmt.Errorf("error parse target ran control plane address: %+v", err)
+	}
+	targetDataPlaneIp, targetDataPlanePort, err := splitHostPort(addresses[1])
+	if err != nil {
+		return fmt.Errorf("error parse target ran data plane address: %+v", err)
+	}
+
+	sourceControlPlaneConn := u.ranControlPlaneConn
+	sourceControlPlaneIp, sourceControlPlanePort := u.ranControlPlaneIp, u.ranControlPlanePort
+	sourceDataPlaneIp, sourceDataPlanePort := u.ranDataPlaneIp, u.ranDataPlanePort
+
+	u.ranControlPlaneIp, u.ranControlPlanePort = targetControlPlaneIp, targetControlPlanePort
+	u.ranDataPlaneIp, u.ranDataPlanePort = targetDataPlaneIp, targetDataPlanePort
+	if err := u.connectToRanControlPlane(); err != nil {
+		u.ranControlPlaneIp, u.ranControlPlanePort = sourceControlPlaneIp, sourceControlPlanePort
+		u.ranDataPlaneIp, u.ranDataPlanePort = sourceDataPlaneIp, sourceDataPlanePort
+		return fmt.Errorf("error connect to target ran control plane: %+v", err)
+	}
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_HANDOVER_COMPLETE, nil)
+	if err != nil {
+		return fmt.Errorf("error send handover complete: %+v", err)
+	}
+	u.RanLog.Tracef("Sent %d bytes of handover complete to RAN", n)
+
+	for _, pduSession := range u.pduSessionList {
+		if pduSession.ranDataPlaneConn == nil {
+			continue
+		}
+
+		conn, err := util.UdpDialWithOptionalLocalAddress(u.ranDataPlaneIp, u.ranDataPlanePort, u.localDataPlaneIp)
+		if err != nil {
+			u.RanLog.Errorf("Error connect to target ran data plane for PDU session %d: %+v", pduSession.pduSessionId, err)
+			continue
+		}
+		sourceDataPlaneConn := pduSession.ranDataPlaneConn
+		pduSession.ranDataPlaneConn = conn
+		go u.readFromRanDataPlane(pduSession, conn)
+
+		if err := u.sendDataPlaneInitialPacket(pduSession); err != nil {
+			u.RanLog.Errorf("Error send initial packet for PDU session %d: %+v", pduSession.pduSessionId, err)
+		}
+
+		if err := sourceDataPlaneConn.Close(); err != nil {
+			u.RanLog.Warnf("Error closing source RAN data plane connection: %v", err)
+		}
+		u.RanLog.Debugf("PDU session %d data plane moved to RAN %s:%d", pduSession.pduSessionId, u.ranDataPlaneIp, u.ranDataPlanePort)
+	}
+
+	if err := sourceControlPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		u.RanLog.Warnf("Error closing source RAN connection: %v", err)
+	}
+
+	u.RanLog.Infof("Handover to RAN %s:%d completed", u.ranControlPlaneIp, u.ranControlPlanePort)
+
+	if !u.isInRegistrationArea() {
+		if err := u.processMobilityRegistrationUpdate(); err != nil {
+			return fmt.Errorf("error process mobility registration update: %+v", err)
+		}
+	}
+	return nil
+}
+
+func splitHostPort(address string) (string, int, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, port, nil
+}
+
+func (u *Ue) closeRanControlPlaneConn() {
+	if err := u.ranControlPlaneConn.Close(); err != nil {
+		u.RanLog.Errorf("Error closing RAN connection: %v", err)
+	}
+}
+
+func (u *Ue) processPduSessionModification(pduSessionModificationCommand *nasMessage.PDUSessionModificationCommand) error {
+	pduSessionId := pduSessionModificationCommand.GetPDUSessionID()
+	u.PduLog.Infof("Processing PDU session %d modification", pduSessionId)
+
+	pduSession := u.getPduSession(pduSessionId)
+	if pduSession == nil {
+		return fmt.Errorf("error pdu session %d not found", pduSessionId)
+	}
+
+	// update qos rules
+	if pduSessionModificationCommand.AuthorizedQosRules != nil {
+		qosRule, err := util.UpdateQosRules(pduSession.pduSessionEstablishmentAccept.qosRule, pduSessionModificationCommand.AuthorizedQosRules.GetQosRule())
+		if err != nil {
+			return fmt.Errorf("error update qos rules: %+v", err)
+		}
+		pduSession.pduSessionEstablishmentAccept.qosRule = qosRule
+
+		if u.isDefaultPduSession(pduSession) {
+			u.rwLock.Lock()
+			u.nrdc.specifiedFlow = util.GetQosRule(qosRule, u.UeLogger)
+			u.rwLock.Unlock()
+			u.PduLog.Infof("PDU session %d QoS rule: %+v", pduSessionId, u.nrdc.specifiedFlow)
+		}
+	}
+
+	// send pdu session modification complete
+	pduSessionModificationComplete, err := getPduSessionModificationComplete(pduSessionId, pduSessionModificationCommand.GetPTI())
+	if err != nil {
+		return fmt.Errorf("error get pdu session modification complete: %+v", err)
+	}
+	u.NasLog.Tracef("PDU session modification complete: %+v", pduSessionModificationComplete)
+
+	ulNasTransportPduSessionModificationComplete, err := getUlNasTransportMessage(pduSessionModificationComplete, pduSessionId, 0, "", nil)
+	if err != nil {
+		return fmt.Errorf("error get ul nas transport pdu session modification complete: %+v", err)
+	}
+	u.NasLog.Tracef("UL NAS transport pdu session modification complete: %+v", ulNasTransportPduSessionModificationComplete)
+
+	encodedUlNasTransportPduSessionModificationComplete, err := encodeNasPduWithSecurity(ulNasTransportPduSessionModificationComplete, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode ul nas transport pdu session modification complete: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded UL NAS transport pdu session modification complete: %+v", encodedUlNasTransportPduSessionModificationComplete)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedUlNasTransportPduSessionModificationComplete)
+	if err != nil {
+		return fmt.Errorf("error send ul nas transport pdu session modification complete: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of UL NAS transport pdu session modification complete to RAN", n)
+	u.NasLog.Debugln("Send UL NAS transport pdu session modification complete to RAN")
+
+	u.PduLog.Infof("UE %s PDU session %d modification complete", u.supi, pduSessionId)
+	return nil
+}
+
+func (u *Ue) processPduSessionRelease(pduSessionReleaseCommand *nasMessage.PDUSessionReleaseCommand) error {
+	pduSessionId := pduSessionReleaseCommand.GetPDUSessionID()
+	u.PduLog.Infof("Processing PDU session %d release, 5GSM cause: %d", pduSessionId, pduSessionReleaseCommand.GetCauseValue())
+
+	pduSession := u.getPduSession(pduSessionId)
+	if pduSession == nil {
+		return fmt.Errorf("error pdu session %d not found", pduSessionId)
+	}
+
+	// send pdu session release complete
+	pduSessionReleaseComplete, err := getPduSessionReleaseComplete(pduSessionId, pduSessionReleaseCommand.GetPTI())
+	if err != nil {
+		return fmt.Errorf("error get pdu session release complete: %+v", err)
+	}
+	u.NasLog.Tracef("PDU session release complete: %+v", pduSessionReleaseComplete)
+
+	ulNasTransportPduSessionReleaseComplete, err := getUlNasTransportMessage(pduSessionReleaseComplete, pduSessionId, 0, "", nil)
+	if err != nil {
+		return fmt.Errorf("error get ul nas transport pdu session release complete: %+v", err)
+	}
+	u.NasLog.Tracef("UL NAS transport pdu session release complete: %+v", ulNasTransportPduSessionReleaseComplete)
+
+	encodedUlNasTransportPduSessionReleaseComplete, err := encodeNasPduWithSecurity(ulNasTransportPduSessionReleaseComplete, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, u, true, false)
+	if err != nil {
+		return fmt.Errorf("error encode ul nas transport pdu session release complete: %+v", err)
+	}
+	u.NasLog.Tracef("Encoded UL NAS transport pdu session release complete: %+v", encodedUlNasTransportPduSessionReleaseComplete)
+
+	n, err := u.ranControlPlaneConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, encodedUlNasTransportPduSessionReleaseComplete)
+	if err != nil {
+		return fmt.Errorf("error send ul nas transport pdu session release complete: %+v", err)
+	}
+	u.NasLog.Tracef("Sent %d bytes of UL NAS transport pdu session release complete to RAN", n)
+	u.NasLog.Debugln("Send UL NAS transport pdu session release complete to RAN")
+
+	// a pdu session released before its establishment has no tunnel device set up yet
+	gsmState := u.getGsmState(pduSession)
+	u.setGsmState(pduSession, constant.UE_GSM_STATE_PDU_SESSION_INACTIVE)
+	if gsmState != constant.UE_GSM_STATE_PDU_SESSION_ACTIVE {
+		u.PduLog.Infof("UE %s PDU session %d released in %s", u.supi, pduSessionId, gsmState)
+		return nil
+	}
+
+	// the tunnel device is kept open, only its address and route are removed
+	if err := u.cleanUpTunnelDevice(pduSession); err != nil {
+		return fmt.Errorf("error clean up tunnel device: %+v", err)
+	}
+
+	// closing the connection also stops its reader
+	if err := pduSession.ranDataPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		u.RanLog.Warnf("Error closing RAN data plane connection: %v", err)
+	}
+	if u.isDefaultPduSession(pduSession) {
+		if u.isNrdcEnabled() {
+			if err := u.dcRanDataPlaneConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				u.RanLog.Warnf("Error closing DC RAN data plane connection: %v", err)
+			}
+		}
+		u.rwLock.Lock()
+		u.nrdc.specifiedFlow = nil
+		u.rwLock.Unlock()
+	}
+	pduSession.pduSessionEstablishmentAccept = pduSessionEstablishmentAccept{}
+
+	u.PduLog.Infof("UE %s PDU session %d released", u.supi, pduSessionId)
+	return nil
+}
+
+func (u *Ue) setupTunnelDevice(pduSession *pduSession) error {
+	u.TunLog.Infof("Setting up UE tunnel device for PDU session %d", pduSession.pduSessionId)
+
+	waterInterface, err := bringUpUeTunnelDevice(pduSession.ueTunnelDeviceName, pduSession.ueIp)
 	if err != nil {
 		return fmt.Errorf("error bring up ue tunnel device: %+v", err)
 	}
 	u.TunLog.Debugln("Bring up ue tunnel device success")
 
-	u.ueTunnelDevice = waterInterface
+	pduSession.ueTunnelDevice = waterInterface
 
 	// go routine for read data from TUN
-	u.readFromTun = make(chan []byte)
+	pduSession.readFromTun = make(chan []byte)
 	go func() {
 		buffer := make([]byte, 4096)
 		for {
-			n, err := u.ueTunnelDevice.Read(buffer)
+			n, err := pduSession.ueTunnelDevice.Read(buffer)
 			if err != nil {
 				u.TunLog.Errorf("Error read from ue tunnel device: %+v", err)
 				return
@@ -716,62 +2001,67 @@ func (u *Ue) setupTunnelDevice() error {
 
 			tmp := make([]byte, n)
 			copy(tmp, buffer[:n])
-			u.readFromTun <- tmp
+			pduSession.readFromTun <- tmp
 		}
 	}()
 	u.TunLog.Debugln("Read from TUN started")
 
 	// go routing for read data from RAN
-	u.readFromRan = make(chan []byte, 2)
-	go func() {
-		buffer := make([]byte, 4096)
-		for {
-			n, err := u.ranDataPlaneConn.Read(buffer)
-			if err != nil {
-				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
-					u.TunLog.Debugln("RAN data plane connection closed")
-					return
-				}
-				u.RanLog.Errorf("Error read from ran data plane: %+v", err)
+	pduSession.readFromRan = make(chan []byte, 2)
+	go u.readFromRanDataPlane(pduSession, pduSession.ranDataPlaneConn)
+	u.TunLog.Debugln("Read from RAN started")
+
+	if u.isDefaultPduSession(pduSession) && u.isNrdcEnabled() {
+		go u.readFromDcRanDataPlane(pduSession, u.dcRanDataPlaneConn)
+		u.TunLog.Debugln("Read from DC RAN data plane started")
+	}
+
+	u.TunLog.Infof("UE tunnel device setup as %s", pduSession.ueTunnelDeviceName)
+	return nil
+}
+
+// the reader of the previous RAN stops on its own after a handover
+func (u *Ue) readFromRanDataPlane(pduSession *pduSession, ranDataPlaneConn net.Conn) {
+	buffer := make([]byte, 4096)
+	for {
+		n, err := ranDataPlaneConn.Read(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+				u.TunLog.Debugln("RAN data plane connection closed")
 				return
 			}
-
-			tmp := make([]byte, n)
-			copy(tmp, buffer[:n])
-			u.readFromRan <- tmp
+			u.RanLog.Errorf("Error read from ran data plane: %+v", err)
+			return
 		}
-	}()
-	u.TunLog.Debugln("Read from RAN started")
 
-	if u.isNrdcEnabled() {
-		go func() {
-			buffer := make([]byte, 4096)
-			for {
-				n, err := u.dcRanDataPlaneConn.Read(buffer)
-				if err != nil {
-					if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
-						u.TunLog.Debugln("DC RAN data plane connection closed")
-						return
-					}
-					u.RanLog.Errorf("Error read from dc ran data plane: %+v", err)
-				}
+		tmp := make([]byte, n)
+		copy(tmp, buffer[:n])
+		pduSession.readFromRan <- tmp
+	}
+}
 
-				tmp := make([]byte, n)
-				copy(tmp, buffer[:n])
-				u.readFromRan <- tmp
+func (u *Ue) readFromDcRanDataPlane(pduSession *pduSession, dcRanDataPlaneConn net.Conn) {
+	buffer := make([]byte, 4096)
+	for {
+		n, err := dcRanDataPlaneConn.Read(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+				u.TunLog.Debugln("DC RAN data plane connection closed")
+				return
 			}
-		}()
-		u.TunLog.Debugln("Read from DC RAN data plane started")
-	}
+			u.RanLog.Errorf("Error read from dc ran data plane: %+v", err)
+		}
 
-	u.TunLog.Infof("UE tunnel device setup as %s", u.ueTunnelDeviceName)
-	return nil
+		tmp := make([]byte, n)
+		copy(tmp, buffer[:n])
+		pduSession.readFromRan <- tmp
+	}
 }
 
-func (u *Ue) cleanUpTunnelDevice() error {
-	u.TunLog.Infoln("Cleaning up UE tunnel device")
+func (u *Ue) cleanUpTunnelDevice(pduSession *pduSession) error {
+	u.TunLog.Infof("Cleaning up UE tunnel device for PDU session %d", pduSession.pduSessionId)
 
-	if err := bringDownUeTunnelDevice(u.ueTunnelDeviceName); err != nil {
+	if err := bringDownUeTunnelDevice(pduSession.ueTunnelDeviceName); err != nil {
 		return fmt.Errorf("error bring down ue tunnel device: %+v", err)
 	}
 	u.TunLog.Debugln("Bring down ue tunnel device success")
@@ -780,17 +2070,26 @@ func (u *Ue) cleanUpTunnelDevice() error {
 	return nil
 }
 
-func (u *Ue) handleDataPlane(ctx context.Context, wg *sync.WaitGroup) {
+func (u *Ue) handleDataPlane(ctx context.Context, wg *sync.WaitGroup, pduSession *pduSession) {
 	wg.Add(1)
 
-	// forward data from TUN to RAN and RAN to TUN
+	// only the default pdu session is split by NR-DC
 	for {
 		select {
 		case <-ctx.Done():
 			goto HANDLE_DATA_PLANE_FINISH
-		case buffer := <-u.readFromTun:
-			if !u.isNrdcEnabled() {
-				n, err := u.ranDataPlaneConn.Write(buffer)
+		case buffer := <-pduSession.readFromTun:
+			// the pdu session may be released locally
+			if u.getGsmState(pduSession) != constant.UE_GSM_STATE_PDU_SESSION_ACTIVE {
+				continue
+			}
+			if u.cmIdle.Load() {
+				// dropped until the service request restores the user plane
+				u.triggerServiceRequest(nasMessage.ServiceTypeData)
+				continue
+			}
+			if !u.isDefaultPduSession(pduSession) || !u.isNrdcEnabled() {
+				n, err := pduSession.ranDataPlaneConn.Write(buffer)
 				if err != nil {
 					if errors.Is(err, net.ErrClosed) {
 						goto HANDLE_DATA_PLANE_FINISH
@@ -809,7 +2108,7 @@ func (u *Ue) handleDataPlane(ctx context.Context, wg *sync.WaitGroup) {
 					}
 					u.RanLog.Tracef("Sent %d bytes of data to DC RAN: %+v", n, buffer[:n])
 				} else {
-					n, err := u.ranDataPlaneConn.Write(buffer)
+					n, err := pduSession.ranDataPlaneConn.Write(buffer)
 					if err != nil {
 						if errors.Is(err, net.ErrClosed) {
 							goto HANDLE_DATA_PLANE_FINISH
@@ -819,8 +2118,8 @@ func (u *Ue) handleDataPlane(ctx context.Context, wg *sync.WaitGroup) {
 					u.RanLog.Tracef("Sent %d bytes of data to RAN: %+v", n, buffer[:n])
 				}
 			}
-		case buffer := <-u.readFromRan:
-			n, err := u.ueTunnelDevice.Write(buffer)
+		case buffer := <-pduSession.readFromRan:
+			n, err := pduSession.ueTunnelDevice.Write(buffer)
 			if err != nil {
 				u.TunLog.Warnf("Error write to ue tunnel device: %+v", err)
 			}
@@ -832,7 +2131,8 @@ HANDLE_DATA_PLANE_FINISH:
 	wg.Done()
 }
 
-func (u *Ue) updateDataPlane() {
+// NR-DC is only applied to the default pdu session
+func (u *Ue) updateDataPlane(pduSession *pduSession) {
 	u.TunLog.Infoln("Updating data plane")
 
 	u.rwLock.Lock()
@@ -853,23 +2153,7 @@ func (u *Ue) updateDataPlane() {
 		}
 		u.RanLog.Debugln("Sent initial packet to DC RAN data plane UDP server")
 
-		go func() {
-			buffer := make([]byte, 4096)
-			for {
-				n, err := u.dcRanDataPlaneConn.Read(buffer)
-				if err != nil {
-					if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
-						u.TunLog.Debugln("DC RAN data plane connection closed")
-						return
-					}
-					u.RanLog.Errorf("Error read from dc ran data plane: %+v", err)
-				}
-
-				tmp := make([]byte, n)
-				copy(tmp, buffer[:n])
-				u.readFromRan <- tmp
-			}
-		}()
+		go u.readFromDcRanDataPlane(pduSession, u.dcRanDataPlaneConn)
 		u.TunLog.Debugln("Read from DC RAN data plane started")
 
 		u.nrdc.enable = true
@@ -884,6 +2168,19 @@ func (u *Ue) updateDataPlane() {
 	}
 }
 
+func (u *Ue) getPduSession(pduSessionId uint8) *pduSession {
+	for _, pduSession := range u.pduSessionList {
+		if pduSession.pduSessionId == pduSessionId {
+			return pduSession
+		}
+	}
+	return nil
+}
+
+func (u *Ue) isDefaultPduSession(pduSession *pduSession) bool {
+	return len(u.pduSessionList) > 0 && u.pduSessionList[0] == pduSession
+}
+
 func (u *Ue) getBearerType() uint8 {
 	switch u.accessType {
 	case models.AccessType__3_GPP_ACCESS:
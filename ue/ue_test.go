@@ -0,0 +1,149 @@
+package ue
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/Alonza0314/free-ran-ue/logger"
+	"github.com/Alonza0314/free-ran-ue/util"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/go-playground/assert"
+)
+
+func newTestNetworkDeregistrationRequest(reRegistrationRequired uint8) ([]byte, error) {
+	m := nas.NewMessage()
+	m.GmmMessage = nas.NewGmmMessage()
+	m.GmmHeader.SetMessageType(nas.MsgTypeDeregistrationRequestUETerminatedDeregistration)
+
+	deregistrationRequest := nasMessage.NewDeregistrationRequestUETerminatedDeregistration(0)
+	deregistrationRequest.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(nasMessage.Epd5GSMobilityManagementMessage)
+	deregistrationRequest.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
+	deregistrationRequest.DeregistrationRequestMessageIdentity.SetMessageType(nas.MsgTypeDeregistrationRequestUETerminatedDeregistration)
+	deregistrationRequest.SpareHalfOctetAndDeregistrationType.SetAccessType(nasMessage.AccessType3GPP)
+	deregistrationRequest.SpareHalfOctetAndDeregistrationType.SetReRegistrationRequired(reRegistrationRequired)
+
+	m.GmmMessage.DeregistrationRequestUETerminatedDeregistration = deregistrationRequest
+
+	request := new(bytes.Buffer)
+	if err := m.GmmMessageEncode(request); err != nil {
+		return nil, err
+	}
+	return request.Bytes(), nil
+}
+
+var testWaitForRanMessageDeregistration = []struct {
+	name                   string
+	reRegistrationRequired uint8
+	expectedReRegistration bool
+}{
+	{
+		name:                   "deregistration with re-registration followed by release",
+		reRegistrationRequired: nasMessage.ReRegistrationRequired,
+		expectedReRegistration: true,
+	},
+	{
+		name:                   "deregistration without re-registration followed by release",
+		reRegistrationRequired: nasMessage.ReRegistrationNotRequired,
+		expectedReRegistration: false,
+	},
+}
+
+func TestWaitForRanMessageDeregistration(t *testing.T) {
+	for _, test := range testWaitForRanMessageDeregistration {
+		t.Run(test.name, func(t *testing.T) {
+			// the re-registration connects to this listener on a new N1 connection
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Error listen: %v", err)
+			}
+			defer func() {
+				if err := listener.Close(); err != nil {
+					t.Errorf("Error close listener: %v", err)
+				}
+			}()
+			acceptedChan := make(chan net.Conn, 1)
+			go func() {
+				if conn, err := listener.Accept(); err == nil {
+					acceptedChan <- conn
+				}
+			}()
+
+			ueLogger := logger.NewUeLogger("error", "", true)
+			ue := &Ue{
+				ranControlPlaneIp:   "127.0.0.1",
+				ranControlPlanePort: listener.Addr().(*net.TCPAddr).Port,
+				gmmState:            constant.UE_GMM_STATE_REGISTERED,
+				ranMessageChan:      make(chan ranMessage, 16),
+				stoppedChan:         make(chan struct{}),
+				UeLogger:            &ueLogger,
+			}
+			defer close(ue.stoppedChan)
+
+			ueConn, ranConn := net.Pipe()
+			ue.ranControlPlaneConn = util.NewRanUeConn(ueConn)
+			go ue.readFromRanControlPlane(ue.ranControlPlaneConn)
+
+			deregistrationRequest, err := newTestNetworkDeregistrationRequest(test.reRegistrationRequired)
+			if err != nil {
+				t.Fatalf("Error build deregistration request: %v", err)
+			}
+
+			// the release follows the deregistration request
+			ranErrChan := make(chan error, 1)
+			go func() {
+				ranUeConn := util.NewRanUeConn(ranConn)
+				defer func() {
+					_ = ranConn.Close()
+				}()
+				if _, err := ranUeConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, deregistrationRequest); err != nil {
+					ranErrChan <- err
+					return
+				}
+				if _, err := ranUeConn.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_CONNECTION_RELEASE, nil); err != nil {
+					ranErrChan <- err
+					return
+				}
+				_, _, err := ranUeConn.ReadMessage()
+				ranErrChan <- err
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			wg := sync.WaitGroup{}
+			loopDone := make(chan struct{})
+			go func() {
+				ue.waitForRanMessage(ctx, &wg)
+				close(loopDone)
+			}()
+
+			assert.Equal(t, <-ranErrChan, nil)
+
+			select {
+			case conn := <-acceptedChan:
+				assert.Equal(t, test.expectedReRegistration, true)
+				// the re-registration fails, the loop stops on the closed N1 connection
+				if err := conn.Close(); err != nil {
+					t.Errorf("Error close connection: %v", err)
+				}
+			case <-loopDone:
+				assert.Equal(t, test.expectedReRegistration, false)
+			case <-time.After(3 * time.Second):
+				t.Fatal("Neither re-registration nor stop of the receive loop after the release")
+			}
+
+			cancel()
+			select {
+			case <-loopDone:
+			case <-time.After(3 * time.Second):
+				t.Fatal("Receive loop not stopped")
+			}
+			assert.Equal(t, ue.getGmmState(), constant.UE_GMM_STATE_DEREGISTERED)
+		})
+	}
+}
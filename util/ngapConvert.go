@@ -2,8 +2,13 @@ package util
 
 import (
 	"encoding/hex"
+	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/Alonza0314/free-ran-ue/model"
+	"github.com/free5gc/aper"
+	"github.com/free5gc/ngap/ngapConvert"
 	"github.com/free5gc/ngap/ngapType"
 	"github.com/free5gc/openapi/models"
 )
@@ -65,6 +70,51 @@ func TaiToNgap(tai models.Tai) (ngapType.TAI, error) {
 	return ngapTai, nil
 }
 
+func GuamiToModels(guami ngapType.GUAMI) models.Guami {
+	plmnId := PlmnIdToModels(guami.PLMNIdentity)
+
+	return models.Guami{
+		PlmnId: &models.PlmnIdNid{
+			Mcc: plmnId.Mcc,
+			Mnc: plmnId.Mnc,
+		},
+		AmfId: ngapConvert.AmfIdToModels(guami.AMFRegionID.Value, guami.AMFSetID.Value, guami.AMFPointer.Value),
+	}
+}
+
+// the 5G-S-TMSI string is the hex of AMF Set ID (10 bits), AMF Pointer (6 bits) and 5G-TMSI (32 bits)
+func FiveGSTmsiToNgap(fiveGSTmsi string) (ngapType.FiveGSTMSI, error) {
+	var ngapFiveGSTmsi ngapType.FiveGSTMSI
+
+	fiveGSTmsiBytes, err := hex.DecodeString(fiveGSTmsi)
+	if err != nil {
+		return ngapFiveGSTmsi, err
+	}
+	if len(fiveGSTmsiBytes) != 6 {
+		return ngapFiveGSTmsi, fmt.Errorf("invalid 5G-S-TMSI length: %d", len(fiveGSTmsiBytes))
+	}
+
+	ngapFiveGSTmsi.AMFSetID.Value = aper.BitString{
+		Bytes:     []byte{fiveGSTmsiBytes[0], fiveGSTmsiBytes[1] & 0xc0},
+		BitLength: 10,
+	}
+	ngapFiveGSTmsi.AMFPointer.Value = aper.BitString{
+		Bytes:     []byte{fiveGSTmsiBytes[1] << 2},
+		BitLength: 6,
+	}
+	ngapFiveGSTmsi.FiveGTMSI.Value = fiveGSTmsiBytes[2:]
+	return ngapFiveGSTmsi, nil
+}
+
+func FiveGSTmsiToString(ngapFiveGSTmsi ngapType.FiveGSTMSI) string {
+	amfSetId := ngapFiveGSTmsi.AMFSetID.Value.Bytes
+	amfPointer := ngapFiveGSTmsi.AMFPointer.Value.Bytes
+	if len(amfSetId) != 2 || len(amfPointer) != 1 {
+		return ""
+	}
+	return hex.EncodeToString(append([]byte{amfSetId[0], amfSetId[1]&0xc0 | amfPointer[0]>>2}, ngapFiveGSTmsi.FiveGTMSI.Value...))
+}
+
 func SNssaiToModels(ngapSnssai ngapType.SNSSAI) (modelsSnssai models.Snssai) {
 	modelsSnssai.Sst = int32(ngapSnssai.SST.Value[0])
 	if ngapSnssai.SD != nil {
@@ -87,3 +137,82 @@ func SNssaiToNgap(modelsSnssai models.Snssai) (ngapType.SNSSAI, error) {
 	}
 	return ngapSnssai, nil
 }
+
+func PlmnSupportItemToModels(plmnSupportItem ngapType.PLMNSupportItem) models.PlmnSnssai {
+	plmnId := PlmnIdToModels(plmnSupportItem.PLMNIdentity)
+
+	snssaiList := make([]models.ExtSnssai, 0, len(plmnSupportItem.SliceSupportList.List))
+	for _, sliceSupportItem := range plmnSupportItem.SliceSupportList.List {
+		snssai := SNssaiToModels(sliceSupportItem.SNSSAI)
+		snssaiList = append(snssaiList, models.ExtSnssai{
+			Sst: snssai.Sst,
+			Sd:  snssai.Sd,
+		})
+	}
+
+	return models.PlmnSnssai{
+		PlmnId:     &plmnId,
+		SNssaiList: snssaiList,
+	}
+}
+
+func SupportedTaListToNgap(supportedTaList []model.SupportedTaIE) (ngapType.SupportedTAList, error) {
+	ngapSupportedTaList := ngapType.SupportedTAList{}
+
+	for _, supportedTa := range supportedTaList {
+		supportedTaItem := ngapType.SupportedTAItem{}
+		if tac, err := hex.DecodeString(supportedTa.Tac); err != nil {
+			return ngapSupportedTaList, err
+		} else {
+			supportedTaItem.TAC.Value = tac
+		}
+
+		for _, broadcastPlmn := range supportedTa.BroadcastPlmnList {
+			broadcastPlmnItem := ngapType.BroadcastPLMNItem{}
+			plmnId, err := PlmnIdToNgap(models.PlmnId{
+				Mcc: broadcastPlmn.PlmnId.Mcc,
+				Mnc: broadcastPlmn.PlmnId.Mnc,
+			})
+			if err != nil {
+				return ngapSupportedTaList, err
+			}
+			broadcastPlmnItem.PLMNIdentity = plmnId
+
+			for _, snssai := range broadcastPlmn.SnssaiList {
+				sst, err := strconv.Atoi(snssai.Sst)
+				if err != nil {
+					return ngapSupportedTaList, err
+				}
+				ngapSnssai, err := SNssaiToNgap(models.Snssai{
+					Sst: int32(sst),
+					Sd:  snssai.Sd,
+				})
+				if err != nil {
+					return ngapSupportedTaList, err
+				}
+				broadcastPlmnItem.TAISliceSupportList.List = append(broadcastPlmnItem.TAISliceSupportList.List, ngapType.SliceSupportItem{
+					SNSSAI: ngapSnssai,
+				})
+			}
+
+			supportedTaItem.BroadcastPLMNList.List = append(supportedTaItem.BroadcastPLMNList.List, broadcastPlmnItem)
+		}
+
+		ngapSupportedTaList.List = append(ngapSupportedTaList.List, supportedTaItem)
+	}
+
+	return ngapSupportedTaList, nil
+}
+
+// the 5QI is optional for a dynamic 5QI descriptor, false if it is absent
+func QosCharacteristicsToFiveQi(qosCharacteristics ngapType.QosCharacteristics) (int64, bool) {
+	switch qosCharacteristics.Present {
+	case ngapType.QosCharacteristicsPresentNonDynamic5QI:
+		return qosCharacteristics.NonDynamic5QI.FiveQI.Value, true
+	case ngapType.QosCharacteristicsPresentDynamic5QI:
+		if qosCharacteristics.Dynamic5QI.FiveQI != nil {
+			return qosCharacteristics.Dynamic5QI.FiveQI.Value, true
+		}
+	}
+	return 0, false
+}
@@ -3,7 +3,9 @@ package util_test
 import (
 	"testing"
 
+	"github.com/Alonza0314/free-ran-ue/model"
 	"github.com/Alonza0314/free-ran-ue/util"
+	"github.com/free5gc/ngap/ngapConvert"
 	"github.com/free5gc/ngap/ngapType"
 	"github.com/free5gc/openapi/models"
 	"github.com/go-playground/assert/v2"
@@ -108,3 +110,266 @@ func TestSnssaiToModels(t *testing.T) {
 		})
 	}
 }
+
+var testGuamiCases = []struct {
+	name        string
+	ngapGuami   ngapType.GUAMI
+	modelsGuami models.Guami
+}{
+	{
+		name: "testGuami",
+		ngapGuami: func() ngapType.GUAMI {
+			regionId, setId, ptrId := ngapConvert.AmfIdToNgap("cafe00")
+			return ngapType.GUAMI{
+				PLMNIdentity: ngapType.PLMNIdentity{
+					Value: []byte{0x02, 0xF8, 0x39},
+				},
+				AMFRegionID: ngapType.AMFRegionID{Value: regionId},
+				AMFSetID:    ngapType.AMFSetID{Value: setId},
+				AMFPointer:  ngapType.AMFPointer{Value: ptrId},
+			}
+		}(),
+		modelsGuami: models.Guami{
+			PlmnId: &models.PlmnIdNid{
+				Mcc: "208",
+				Mnc: "93",
+			},
+			AmfId: "cafe00",
+		},
+	},
+}
+
+func TestGuamiToModels(t *testing.T) {
+	for _, testCase := range testGuamiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			modelsGuami := util.GuamiToModels(testCase.ngapGuami)
+			assert.Equal(t, testCase.modelsGuami, modelsGuami)
+		})
+	}
+}
+
+var testFiveGSTmsiCases = []struct {
+	name           string
+	fiveGSTmsi     string
+	ngapFiveGSTmsi ngapType.FiveGSTMSI
+	expectedErr    bool
+}{
+	{
+		name:       "testFiveGSTmsi",
+		fiveGSTmsi: "fe0100000001",
+		ngapFiveGSTmsi: func() ngapType.FiveGSTMSI {
+			_, setId, ptrId := ngapConvert.AmfIdToNgap("cafe01")
+			return ngapType.FiveGSTMSI{
+				AMFSetID:   ngapType.AMFSetID{Value: setId},
+				AMFPointer: ngapType.AMFPointer{Value: ptrId},
+				FiveGTMSI:  ngapType.FiveGTMSI{Value: []byte{0x00, 0x00, 0x00, 0x01}},
+			}
+		}(),
+		expectedErr: false,
+	},
+	{
+		name:        "testFiveGSTmsiInvalidLength",
+		fiveGSTmsi:  "fe01000000",
+		expectedErr: true,
+	},
+	{
+		name:        "testFiveGSTmsiInvalidHex",
+		fiveGSTmsi:  "fe010000000g",
+		expectedErr: true,
+	},
+}
+
+func TestFiveGSTmsiToNgap(t *testing.T) {
+	for _, testCase := range testFiveGSTmsiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ngapFiveGSTmsi, err := util.FiveGSTmsiToNgap(testCase.fiveGSTmsi)
+			if testCase.expectedErr {
+				assert.NotEqual(t, nil, err)
+				return
+			}
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.ngapFiveGSTmsi, ngapFiveGSTmsi)
+			assert.Equal(t, testCase.fiveGSTmsi, util.FiveGSTmsiToString(ngapFiveGSTmsi))
+		})
+	}
+}
+
+var testPlmnSupportItemCases = []struct {
+	name              string
+	ngapPlmnSupport   ngapType.PLMNSupportItem
+	modelsPlmnSupport models.PlmnSnssai
+}{
+	{
+		name: "testPlmnSupportItem",
+		ngapPlmnSupport: ngapType.PLMNSupportItem{
+			PLMNIdentity: ngapType.PLMNIdentity{
+				Value: []byte{0x02, 0xF8, 0x39},
+			},
+			SliceSupportList: ngapType.SliceSupportList{
+				List: []ngapType.SliceSupportItem{
+					{
+						SNSSAI: ngapType.SNSSAI{
+							SST: ngapType.SST{Value: []byte{0x01}},
+							SD:  &ngapType.SD{Value: []byte{0x01, 0x02, 0x03}},
+						},
+					},
+					{
+						SNSSAI: ngapType.SNSSAI{
+							SST: ngapType.SST{Value: []byte{0x02}},
+						},
+					},
+				},
+			},
+		},
+		modelsPlmnSupport: models.PlmnSnssai{
+			PlmnId: &models.PlmnId{
+				Mcc: "208",
+				Mnc: "93",
+			},
+			SNssaiList: []models.ExtSnssai{
+				{
+					Sst: 1,
+					Sd:  "010203",
+				},
+				{
+					Sst: 2,
+				},
+			},
+		},
+	},
+}
+
+func TestPlmnSupportItemToModels(t *testing.T) {
+	for _, testCase := range testPlmnSupportItemCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			modelsPlmnSupport := util.PlmnSupportItemToModels(testCase.ngapPlmnSupport)
+			assert.Equal(t, testCase.modelsPlmnSupport, modelsPlmnSupport)
+		})
+	}
+}
+
+var testSupportedTaListCases = []struct {
+	name                string
+	supportedTaList     []model.SupportedTaIE
+	ngapSupportedTaList ngapType.SupportedTAList
+}{
+	{
+		name: "testSupportedTaList",
+		supportedTaList: []model.SupportedTaIE{
+			{
+				Tac: "000001",
+				BroadcastPlmnList: []model.BroadcastPlmnIE{
+					{
+						PlmnId: model.PlmnIdIE{
+							Mcc: "208",
+							Mnc: "93",
+						},
+						SnssaiList: []model.SnssaiIE{
+							{
+								Sst: "1",
+								Sd:  "010203",
+							},
+							{
+								Sst: "1",
+								Sd:  "112233",
+							},
+						},
+					},
+				},
+			},
+		},
+		ngapSupportedTaList: ngapType.SupportedTAList{
+			List: []ngapType.SupportedTAItem{
+				{
+					TAC: ngapType.TAC{
+						Value: []byte{0x00, 0x00, 0x01},
+					},
+					BroadcastPLMNList: ngapType.BroadcastPLMNList{
+						List: []ngapType.BroadcastPLMNItem{
+							{
+								PLMNIdentity: ngapType.PLMNIdentity{
+									Value: []byte{0x02, 0xF8, 0x39},
+								},
+								TAISliceSupportList: ngapType.SliceSupportList{
+									List: []ngapType.SliceSupportItem{
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{Value: []byte{0x01}},
+												SD:  &ngapType.SD{Value: []byte{0x01, 0x02, 0x03}},
+											},
+										},
+										{
+											SNSSAI: ngapType.SNSSAI{
+												SST: ngapType.SST{Value: []byte{0x01}},
+												SD:  &ngapType.SD{Value: []byte{0x11, 0x22, 0x33}},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestSupportedTaListToNgap(t *testing.T) {
+	for _, testCase := range testSupportedTaListCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ngapSupportedTaList, err := util.SupportedTaListToNgap(testCase.supportedTaList)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.ngapSupportedTaList, ngapSupportedTaList)
+		})
+	}
+}
+
+var testQosCharacteristicsCases = []struct {
+	name               string
+	qosCharacteristics ngapType.QosCharacteristics
+	expectedFiveQi     int64
+	expectedExists     bool
+}{
+	{
+		name: "non dynamic 5qi",
+		qosCharacteristics: ngapType.QosCharacteristics{
+			Present: ngapType.QosCharacteristicsPresentNonDynamic5QI,
+			NonDynamic5QI: &ngapType.NonDynamic5QIDescriptor{
+				FiveQI: ngapType.FiveQI{Value: 9},
+			},
+		},
+		expectedFiveQi: 9,
+		expectedExists: true,
+	},
+	{
+		name: "dynamic 5qi",
+		qosCharacteristics: ngapType.QosCharacteristics{
+			Present: ngapType.QosCharacteristicsPresentDynamic5QI,
+			Dynamic5QI: &ngapType.Dynamic5QIDescriptor{
+				FiveQI: &ngapType.FiveQI{Value: 82},
+			},
+		},
+		expectedFiveQi: 82,
+		expectedExists: true,
+	},
+	{
+		name: "dynamic 5qi without 5qi",
+		qosCharacteristics: ngapType.QosCharacteristics{
+			Present:    ngapType.QosCharacteristicsPresentDynamic5QI,
+			Dynamic5QI: &ngapType.Dynamic5QIDescriptor{},
+		},
+		expectedFiveQi: 0,
+		expectedExists: false,
+	},
+}
+
+func TestQosCharacteristicsToFiveQi(t *testing.T) {
+	for _, tc := range testQosCharacteristicsCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fiveQi, exists := util.QosCharacteristicsToFiveQi(tc.qosCharacteristics)
+			assert.Equal(t, tc.expectedFiveQi, fiveQi)
+			assert.Equal(t, tc.expectedExists, exists)
+		})
+	}
+}
@@ -35,3 +35,73 @@ func GetQosRule(ruleBytes []byte, logger *logger.UeLogger) []string {
 
 	return qosRules
 }
+
+// apply the operations of the updated rules on the current rules by QoS rule identifier, TS 24.501 9.11.4.13
+func UpdateQosRules(ruleBytes []byte, updateBytes []byte) ([]byte, error) {
+	var rules, updates nasType.QoSRules
+	if err := rules.UnmarshalBinary(ruleBytes); err != nil {
+		return nil, fmt.Errorf("unmarshal qos rules failed: %+v", err)
+	}
+	if err := updates.UnmarshalBinary(updateBytes); err != nil {
+		return nil, fmt.Errorf("unmarshal updated qos rules failed: %+v", err)
+	}
+
+	for _, u := range updates {
+		index := -1
+		for i, r := range rules {
+			if r.Identifier == u.Identifier {
+				index = i
+				break
+			}
+		}
+
+		switch u.Operation {
+		case nasType.OperationCodeCreateNewQoSRule:
+			if index == -1 {
+				rules = append(rules, u)
+			} else {
+				rules[index] = u
+			}
+			continue
+		case nasType.OperationCodeDeleteExistingQoSRule:
+			if index != -1 {
+				rules = append(rules[:index], rules[index+1:]...)
+			}
+			continue
+		}
+
+		if index == -1 {
+			return nil, fmt.Errorf("qos rule %d not found", u.Identifier)
+		}
+		r := &rules[index]
+
+		switch u.Operation {
+		case nasType.OperationCodeModifyExistingQoSRuleAndAddPacketFilters:
+			for _, p := range u.PacketFilterList {
+				r.PacketFilterList = append(deletePacketFilter(r.PacketFilterList, p.Identifier), p)
+			}
+		case nasType.OperationCodeModifyExistingQoSRuleAndReplaceAllPacketFilters:
+			r.PacketFilterList = u.PacketFilterList
+		case nasType.OperationCodeModifyExistingQoSRuleAndDeletePacketFilters:
+			for _, p := range u.PacketFilterList {
+				r.PacketFilterList = deletePacketFilter(r.PacketFilterList, p.Identifier)
+			}
+		case nasType.OperationCodeModifyExistingQoSRuleWithoutModifyingPacketFilters:
+		default:
+			return nil, fmt.Errorf("unsupported qos rule operation code: %d", u.Operation)
+		}
+		r.DQR, r.Precedence, r.Segregation, r.QFI = u.DQR, u.Precedence, u.Segregation, u.QFI
+	}
+
+	return rules.MarshalBinary()
+}
+
+func deletePacketFilter(packetFilterList nasType.PacketFilterList, identifier uint8) nasType.PacketFilterList {
+	filtered := make(nasType.PacketFilterList, 0, len(packetFilterList))
+	for _, p := range packetFilterList {
+		if p.Identifier != identifier {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
@@ -1,6 +1,7 @@
 package util_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/Alonza0314/free-ran-ue/util"
@@ -35,3 +36,69 @@ func TestGetQosRule(t *testing.T) {
 		})
 	}
 }
+
+var testUpdateQosRulesCases = []struct {
+	name          string
+	ruleBytes     []byte
+	updateBytes   []byte
+	expected      []string
+	expectedError error
+}{
+	{
+		name:      "delete rule",
+		ruleBytes: testQosRuleCases[0].ruleBytes,
+		updateBytes: []byte{
+			0x04, 0x00, 0x03, 0x40, 0x7f, 0x03,
+		},
+		expected: []string{
+			"10.1.0.3/32",
+		},
+		expectedError: nil,
+	},
+	{
+		name:      "create rule",
+		ruleBytes: testQosRuleCases[0].ruleBytes,
+		updateBytes: []byte{
+			0x05, 0x00, 0x0e, 0x21, 0x14, 0x09, 0x10, 0x08, 0x08, 0x08, 0x08, 0xff, 0xff, 0xff, 0xff, 0x7e, 0x04,
+		},
+		expected: []string{
+			"10.1.0.3/32",
+			"1.1.1.1/32",
+			"8.8.8.8/32",
+		},
+		expectedError: nil,
+	},
+	{
+		name:      "replace packet filters",
+		ruleBytes: testQosRuleCases[0].ruleBytes,
+		updateBytes: []byte{
+			0x03, 0x00, 0x0e, 0x81, 0x12, 0x09, 0x10, 0x09, 0x09, 0x09, 0x09, 0xff, 0xff, 0xff, 0xff, 0x80, 0x02,
+		},
+		expected: []string{
+			"9.9.9.9/32",
+			"1.1.1.1/32",
+		},
+		expectedError: nil,
+	},
+	{
+		name:      "modify unknown rule",
+		ruleBytes: testQosRuleCases[0].ruleBytes,
+		updateBytes: []byte{
+			0x09, 0x00, 0x03, 0xc0, 0x80, 0x02,
+		},
+		expected:      nil,
+		expectedError: errors.New("qos rule 9 not found"),
+	},
+}
+
+func TestUpdateQosRules(t *testing.T) {
+	for _, tc := range testUpdateQosRulesCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ruleBytes, err := util.UpdateQosRules(tc.ruleBytes, tc.updateBytes)
+			assert.Equal(t, tc.expectedError, err)
+			if err == nil {
+				assert.Equal(t, tc.expected, util.GetQosRule(ruleBytes, nil))
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+)
+
+// the control plane connection between RAN and UE carries one message per frame, a header of the message type and
+// the payload length keeps the messages apart when the stream merges or splits them
+type RanUeConn struct {
+	net.Conn
+
+	// a frame interrupted by the read deadline stays buffered until the next read
+	reader *bufio.Reader
+}
+
+func NewRanUeConn(conn net.Conn) *RanUeConn {
+	return &RanUeConn{
+		Conn:   conn,
+		reader: bufio.NewReaderSize(conn, constant.RAN_UE_MESSAGE_HEADER_LEN+constant.RAN_UE_MESSAGE_MAX_PAYLOAD_LEN),
+	}
+}
+
+func (c *RanUeConn) ReadMessage() (constant.RanUeMessageType, []byte, error) {
+	header, err := c.reader.Peek(constant.RAN_UE_MESSAGE_HEADER_LEN)
+	if err != nil {
+		return 0, nil, err
+	}
+	messageType := constant.RanUeMessageType(header[0])
+	payloadLen := int(binary.BigEndian.Uint16(header[1:]))
+
+	frame, err := c.reader.Peek(constant.RAN_UE_MESSAGE_HEADER_LEN + payloadLen)
+	if err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, payloadLen)
+	copy(payload, frame[constant.RAN_UE_MESSAGE_HEADER_LEN:])
+
+	if _, err := c.reader.Discard(len(frame)); err != nil {
+		return 0, nil, fmt.Errorf("error discard message: %v", err)
+	}
+	return messageType, payload, nil
+}
+
+// the message stays buffered for the next read
+func (c *RanUeConn) PeekMessageType() (constant.RanUeMessageType, error) {
+	header, err := c.reader.Peek(constant.RAN_UE_MESSAGE_HEADER_LEN)
+	if err != nil {
+		return 0, err
+	}
+	return constant.RanUeMessageType(header[0]), nil
+}
+
+// the frame is written at once, so the messages written by different goroutines are not interleaved
+func (c *RanUeConn) WriteMessage(messageType constant.RanUeMessageType, payload []byte) (int, error) {
+	if len(payload) > constant.RAN_UE_MESSAGE_MAX_PAYLOAD_LEN {
+		return 0, fmt.Errorf("payload of %d bytes exceeds the maximum of %d bytes", len(payload), constant.RAN_UE_MESSAGE_MAX_PAYLOAD_LEN)
+	}
+
+	frame := make([]byte, constant.RAN_UE_MESSAGE_HEADER_LEN, constant.RAN_UE_MESSAGE_HEADER_LEN+len(payload))
+	frame[0] = uint8(messageType)
+	binary.BigEndian.PutUint16(frame[1:], uint16(len(payload)))
+	frame = append(frame, payload...)
+
+	return c.Conn.Write(frame)
+}
@@ -0,0 +1,112 @@
+package util_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Alonza0314/free-ran-ue/constant"
+	"github.com/Alonza0314/free-ran-ue/util"
+	"github.com/go-playground/assert/v2"
+)
+
+func newTestRanUeConnPair(t *testing.T) (net.Conn, *util.RanUeConn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	defer func() {
+		if err := listener.Close(); err != nil {
+			t.Errorf("error closing listener: %v", err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("error dialing: %v", err)
+	}
+	acceptedConn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("error accepting: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = acceptedConn.Close()
+	})
+	return conn, util.NewRanUeConn(acceptedConn)
+}
+
+func TestRanUeConnMergedMessages(t *testing.T) {
+	conn, ranUeConn := newTestRanUeConnPair(t)
+
+	sender := util.NewRanUeConn(conn)
+	largeNasPdu := bytes.Repeat([]byte{0x7e}, 4096)
+	_, err := sender.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, largeNasPdu)
+	assert.Equal(t, nil, err)
+	_, err = sender.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_TUNNEL_UPDATE, nil)
+	assert.Equal(t, nil, err)
+	_, err = sender.WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, []byte{0x7e, 0x00, 0x55})
+	assert.Equal(t, nil, err)
+
+	messageType, payload, err := ranUeConn.ReadMessage()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, constant.RAN_UE_MESSAGE_TYPE_NAS, messageType)
+	assert.Equal(t, largeNasPdu, payload)
+
+	messageType, payload, err = ranUeConn.ReadMessage()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, constant.RAN_UE_MESSAGE_TYPE_TUNNEL_UPDATE, messageType)
+	assert.Equal(t, 0, len(payload))
+
+	messageType, payload, err = ranUeConn.ReadMessage()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, constant.RAN_UE_MESSAGE_TYPE_NAS, messageType)
+	assert.Equal(t, []byte{0x7e, 0x00, 0x55}, payload)
+}
+
+func TestRanUeConnSplitMessage(t *testing.T) {
+	conn, ranUeConn := newTestRanUeConnPair(t)
+
+	// the frame is cut in the middle of the payload, the read deadline expires before the rest arrives
+	frame := []byte{uint8(constant.RAN_UE_MESSAGE_TYPE_NAS), 0x00, 0x03, 0x7e, 0x00, 0x55}
+	_, err := conn.Write(frame[:4])
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, nil, ranUeConn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err = ranUeConn.ReadMessage()
+	netErr, ok := err.(net.Error)
+	assert.Equal(t, true, ok && netErr.Timeout())
+
+	_, err = conn.Write(frame[4:])
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, nil, ranUeConn.SetReadDeadline(time.Now().Add(time.Second)))
+	messageType, payload, err := ranUeConn.ReadMessage()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, constant.RAN_UE_MESSAGE_TYPE_NAS, messageType)
+	assert.Equal(t, []byte{0x7e, 0x00, 0x55}, payload)
+}
+
+func TestRanUeConnPayloadTooLarge(t *testing.T) {
+	conn, _ := newTestRanUeConnPair(t)
+
+	_, err := util.NewRanUeConn(conn).WriteMessage(constant.RAN_UE_MESSAGE_TYPE_NAS, make([]byte, constant.RAN_UE_MESSAGE_MAX_PAYLOAD_LEN+1))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestRanUeConnPeekMessageType(t *testing.T) {
+	conn, ranUeConn := newTestRanUeConnPair(t)
+
+	_, err := util.NewRanUeConn(conn).WriteMessage(constant.RAN_UE_MESSAGE_TYPE_IDLE_CAMP, []byte("0200400000001"))
+	assert.Equal(t, nil, err)
+
+	messageType, err := ranUeConn.PeekMessageType()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, constant.RAN_UE_MESSAGE_TYPE_IDLE_CAMP, messageType)
+
+	messageType, payload, err := ranUeConn.ReadMessage()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, constant.RAN_UE_MESSAGE_TYPE_IDLE_CAMP, messageType)
+	assert.Equal(t, []byte("0200400000001"), payload)
+}
@@ -0,0 +1,103 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// the latest SQN of each SUPI is kept in a YAML file shared by the UEs, the mutex serialises the UEs of one process
+// and the file lock the processes sharing the file
+var sequenceNumberFileMtx sync.Mutex
+
+func lockSequenceNumberFile(filePath string, how int) (*os.File, error) {
+	lockFile, err := os.OpenFile(filePath+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error open lock file: %v", err)
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), how); err != nil {
+		if closeErr := lockFile.Close(); closeErr != nil {
+			return nil, fmt.Errorf("error lock file: %v, error close lock file: %v", err, closeErr)
+		}
+		return nil, fmt.Errorf("error lock file: %v", err)
+	}
+	return lockFile, nil
+}
+
+func unlockSequenceNumberFile(lockFile *os.File) error {
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN); err != nil {
+		return fmt.Errorf("error unlock file: %v", err)
+	}
+	return lockFile.Close()
+}
+
+func loadSequenceNumbers(filePath string) (map[string]string, error) {
+	// an empty file is left by a crash between creating and writing it, it is read as no SQN stored yet
+	sequenceNumbers := make(map[string]string)
+	if err := LoadFromYaml(filePath, &sequenceNumbers); err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, io.EOF) {
+			return make(map[string]string), nil
+		}
+		return nil, fmt.Errorf("error load sequence number file: %v", err)
+	}
+	return sequenceNumbers, nil
+}
+
+// the SQN is not found when the file or the SUPI does not exist yet
+func LoadSequenceNumber(filePath string, supi string) (string, bool, error) {
+	sequenceNumberFileMtx.Lock()
+	defer sequenceNumberFileMtx.Unlock()
+
+	lockFile, err := lockSequenceNumberFile(filePath, syscall.LOCK_SH)
+	if err != nil {
+		return "", false, err
+	}
+
+	sequenceNumbers, err := loadSequenceNumbers(filePath)
+	if unlockErr := unlockSequenceNumberFile(lockFile); unlockErr != nil && err == nil {
+		err = unlockErr
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	sequenceNumber, exists := sequenceNumbers[supi]
+	return sequenceNumber, exists, nil
+}
+
+// the file is rewritten through a temporary file, so a reader never sees it half written
+func SaveSequenceNumber(filePath string, supi string, sequenceNumber string) error {
+	sequenceNumberFileMtx.Lock()
+	defer sequenceNumberFileMtx.Unlock()
+
+	lockFile, err := lockSequenceNumberFile(filePath, syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+
+	err = saveSequenceNumber(filePath, supi, sequenceNumber)
+	if unlockErr := unlockSequenceNumberFile(lockFile); unlockErr != nil && err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+func saveSequenceNumber(filePath string, supi string, sequenceNumber string) error {
+	sequenceNumbers, err := loadSequenceNumbers(filePath)
+	if err != nil {
+		return err
+	}
+	sequenceNumbers[supi] = sequenceNumber
+
+	tempFilePath := fmt.Sprintf("%s.%d.tmp", filePath, os.Getpid())
+	if err := SaveToYaml(tempFilePath, sequenceNumbers); err != nil {
+		return fmt.Errorf("error save sequence number file: %v", err)
+	}
+	if err := os.Rename(tempFilePath, filePath); err != nil {
+		return fmt.Errorf("error replace sequence number file: %v", err)
+	}
+	return nil
+}
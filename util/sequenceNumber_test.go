@@ -0,0 +1,99 @@
+package util_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Alonza0314/free-ran-ue/util"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestSequenceNumber(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "sqn.yaml")
+
+	_, exists, err := util.LoadSequenceNumber(filePath, "imsi-208930000000001")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, exists)
+
+	assert.Equal(t, nil, util.SaveSequenceNumber(filePath, "imsi-208930000000001", "000000000024"))
+	assert.Equal(t, nil, util.SaveSequenceNumber(filePath, "imsi-208930000000001", "000000000045"))
+
+	sequenceNumber, exists, err := util.LoadSequenceNumber(filePath, "imsi-208930000000001")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, exists)
+	assert.Equal(t, "000000000045", sequenceNumber)
+}
+
+func TestSequenceNumberConcurrentSave(t *testing.T) {
+	filePath, num := filepath.Join(t.TempDir(), "sqn.yaml"), 50
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < num; i += 1 {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			assert.Equal(t, nil, util.SaveSequenceNumber(filePath, fmt.Sprintf("imsi-2089300000%05d", index), fmt.Sprintf("%012x", index)))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < num; i += 1 {
+		sequenceNumber, exists, err := util.LoadSequenceNumber(filePath, fmt.Sprintf("imsi-2089300000%05d", i))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, exists)
+		assert.Equal(t, fmt.Sprintf("%012x", i), sequenceNumber)
+	}
+}
+
+var testLoadSequenceNumberCases = []struct {
+	name                   string
+	createFile             bool
+	fileContent            string
+	expectedSequenceNumber string
+	expectedExists         bool
+}{
+	{
+		name:           "testFileNotExist",
+		createFile:     false,
+		expectedExists: false,
+	},
+	{
+		name:           "testEmptyFile",
+		createFile:     true,
+		fileContent:    "",
+		expectedExists: false,
+	},
+	{
+		name:                   "testSupiExists",
+		createFile:             true,
+		fileContent:            "imsi-208930000000001: \"000000000045\"\n",
+		expectedSequenceNumber: "000000000045",
+		expectedExists:         true,
+	},
+}
+
+func TestLoadSequenceNumber(t *testing.T) {
+	for _, testCase := range testLoadSequenceNumberCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "sqn.yaml")
+			if testCase.createFile {
+				assert.Equal(t, nil, os.WriteFile(filePath, []byte(testCase.fileContent), 0o644))
+			}
+
+			sequenceNumber, exists, err := util.LoadSequenceNumber(filePath, "imsi-208930000000001")
+			assert.Equal(t, nil, err)
+			assert.Equal(t, testCase.expectedExists, exists)
+			assert.Equal(t, testCase.expectedSequenceNumber, sequenceNumber)
+
+			// the SQN is still saved after the file is read
+			assert.Equal(t, nil, util.SaveSequenceNumber(filePath, "imsi-208930000000001", "000000000046"))
+			sequenceNumber, exists, err = util.LoadSequenceNumber(filePath, "imsi-208930000000001")
+			assert.Equal(t, nil, err)
+			assert.Equal(t, true, exists)
+			assert.Equal(t, "000000000046", sequenceNumber)
+		})
+	}
+}
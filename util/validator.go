@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
 	"strconv"
 
+	"github.com/Alonza0314/free-ran-ue/constant"
 	"github.com/Alonza0314/free-ran-ue/model"
 	loggergoUtil "github.com/Alonza0314/logger-go/v2/util"
 	"github.com/free5gc/openapi/models"
@@ -45,6 +47,18 @@ func ValidateIp(ip string) error {
 	return nil
 }
 
+func ValidateIpList(ipList []string) error {
+	if len(ipList) == 0 {
+		return fmt.Errorf("invalid ip list: at least one ip address is required")
+	}
+	for i, ip := range ipList {
+		if err := ValidateIp(ip); err != nil {
+			return fmt.Errorf("invalid ip list[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
 func ValidatePort(port int) error {
 	if port < 1 || port > 65535 {
 		return fmt.Errorf("invalid port range: %d, range should be 1-65535", port)
@@ -88,6 +102,59 @@ func ValidateMsin(msin string) error {
 	return nil
 }
 
+// the check digit is verified by the Luhn algorithm, the AMF refuses the PEI otherwise
+func ValidateImei(imei string) error {
+	if err := ValidateIntStringWithLength(imei, 15); err != nil {
+		return err
+	}
+	sum := 0
+	for i := range imei {
+		digit := int(imei[i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("invalid imei: %s, check digit mismatch", imei)
+	}
+	return nil
+}
+
+func ValidateImeisv(imeisv string) error {
+	return ValidateIntStringWithLength(imeisv, 16)
+}
+
+func ValidateSuci(suci *model.SuciIE) error {
+	var homeNetworkPublicKeyLength []int
+	switch suci.ProtectionScheme {
+	case "", constant.SUCI_PROTECTION_SCHEME_NULL:
+		return nil
+	case constant.SUCI_PROTECTION_SCHEME_PROFILE_A:
+		homeNetworkPublicKeyLength = []int{32}
+	case constant.SUCI_PROTECTION_SCHEME_PROFILE_B:
+		// compressed or uncompressed secp256r1 point
+		homeNetworkPublicKeyLength = []int{33, 65}
+	default:
+		return fmt.Errorf("invalid protection scheme: %s, should be %s, %s or %s", suci.ProtectionScheme, constant.SUCI_PROTECTION_SCHEME_NULL, constant.SUCI_PROTECTION_SCHEME_PROFILE_A, constant.SUCI_PROTECTION_SCHEME_PROFILE_B)
+	}
+
+	if suci.HomeNetworkPublicKeyId < 0 || suci.HomeNetworkPublicKeyId > 255 {
+		return fmt.Errorf("invalid home network public key id: %d, should be between 0 and 255", suci.HomeNetworkPublicKeyId)
+	}
+	homeNetworkPublicKey, err := hex.DecodeString(suci.HomeNetworkPublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid home network public key, invalid hex string: %s", suci.HomeNetworkPublicKey)
+	}
+	if !slices.Contains(homeNetworkPublicKeyLength, len(homeNetworkPublicKey)) {
+		return fmt.Errorf("invalid home network public key length: %d bytes, should be %v bytes for %s", len(homeNetworkPublicKey), homeNetworkPublicKeyLength, suci.ProtectionScheme)
+	}
+	return nil
+}
+
 func ValidateAccessType(accessType models.AccessType) error {
 	switch accessType {
 	case models.AccessType__3_GPP_ACCESS:
@@ -107,6 +174,11 @@ func ValidateHexString(hexString string) error {
 }
 
 func ValidateAuthenticationSubscription(authenticationSubscription *model.AuthenticationSubscriptionIE) error {
+	switch models.AuthMethod(authenticationSubscription.AuthenticationMethod) {
+	case "", models.AuthMethod__5_G_AKA, models.AuthMethod_EAP_AKA_PRIME:
+	default:
+		return fmt.Errorf("invalid authentication method: %s, should be %s or %s", authenticationSubscription.AuthenticationMethod, models.AuthMethod__5_G_AKA, models.AuthMethod_EAP_AKA_PRIME)
+	}
 	if err := ValidateHexString(authenticationSubscription.EncPermanentKey); err != nil {
 		return fmt.Errorf("invalid enc permanent key, %s", err.Error())
 	}
@@ -148,6 +220,15 @@ func ValidateCipheringAlgorithm(cipheringAlgorithm *model.CipheringAlgorithmIE)
 }
 
 func ValidatePduSession(pduSession *model.PduSessionIE) error {
+	if pduSession.PduSessionId < 1 || pduSession.PduSessionId > 15 {
+		return fmt.Errorf("invalid pdu session id: %d, should be between 1 and 15", pduSession.PduSessionId)
+	}
+	if pduSession.PduSessionType != constant.PDU_SESSION_TYPE_IPV4 && pduSession.PduSessionType != constant.PDU_SESSION_TYPE_IPV4V6 {
+		return fmt.Errorf("invalid pdu session type: %s, should be %s or %s", pduSession.PduSessionType, constant.PDU_SESSION_TYPE_IPV4, constant.PDU_SESSION_TYPE_IPV4V6)
+	}
+	if pduSession.UeTunnelDevice == "" {
+		return fmt.Errorf("invalid pdu session ue tunnel device: empty name")
+	}
 	if err := ValidateIntStringWithLength(pduSession.Snssai.Sst, 1); err != nil {
 		return fmt.Errorf("invalid pdu session sst, %s", err.Error())
 	}
@@ -157,6 +238,28 @@ func ValidatePduSession(pduSession *model.PduSessionIE) error {
 	return nil
 }
 
+func ValidatePduSessionList(pduSessionList []model.PduSessionIE) error {
+	if len(pduSessionList) == 0 {
+		return fmt.Errorf("at least one pdu session is required")
+	}
+
+	pduSessionIdSet, ueTunnelDeviceSet := make(map[int]struct{}), make(map[string]struct{})
+	for i := range pduSessionList {
+		if err := ValidatePduSession(&pduSessionList[i]); err != nil {
+			return fmt.Errorf("pduSessionList[%d]: %s", i, err.Error())
+		}
+		if _, exists := pduSessionIdSet[pduSessionList[i].PduSessionId]; exists {
+			return fmt.Errorf("pduSessionList[%d]: duplicated pdu session id: %d", i, pduSessionList[i].PduSessionId)
+		}
+		pduSessionIdSet[pduSessionList[i].PduSessionId] = struct{}{}
+		if _, exists := ueTunnelDeviceSet[pduSessionList[i].UeTunnelDevice]; exists {
+			return fmt.Errorf("pduSessionList[%d]: duplicated ue tunnel device: %s", i, pduSessionList[i].UeTunnelDevice)
+		}
+		ueTunnelDeviceSet[pduSessionList[i].UeTunnelDevice] = struct{}{}
+	}
+	return nil
+}
+
 func ValidateNrdc(nrdc *model.NrdcIE) error {
 	if !nrdc.Enable {
 		return nil
@@ -202,6 +305,19 @@ func ValidateUeIe(ueIe *model.UeIE) error {
 	if err := ValidateMsin(ueIe.Msin); err != nil {
 		return fmt.Errorf("invalid ue msin, %s", err.Error())
 	}
+	if err := ValidateSuci(&ueIe.Suci); err != nil {
+		return fmt.Errorf("invalid ue suci, %s", err.Error())
+	}
+	if ueIe.Imei != "" {
+		if err := ValidateImei(ueIe.Imei); err != nil {
+			return fmt.Errorf("invalid ue imei, %s", err.Error())
+		}
+	}
+	if ueIe.Imeisv != "" {
+		if err := ValidateImeisv(ueIe.Imeisv); err != nil {
+			return fmt.Errorf("invalid ue imeisv, %s", err.Error())
+		}
+	}
 
 	if err := ValidateAccessType(ueIe.AccessType); err != nil {
 		return fmt.Errorf("invalid ue access type, %s", err.Error())
@@ -217,8 +333,11 @@ func ValidateUeIe(ueIe *model.UeIE) error {
 		return fmt.Errorf("invalid ue integrity algorithm, %s", err.Error())
 	}
 
-	if err := ValidatePduSession(&ueIe.PduSession); err != nil {
-		return fmt.Errorf("invalid ue pdu session, %s", err.Error())
+	if err := ValidateRequestedNssai(ueIe.RequestedNssai); err != nil {
+		return fmt.Errorf("invalid ue requested nssai, %s", err.Error())
+	}
+	if err := ValidatePduSessionList(ueIe.PduSessionList); err != nil {
+		return fmt.Errorf("invalid ue pdu session list, %s", err.Error())
 	}
 
 	if err := ValidateNrdc(&ueIe.Nrdc); err != nil {
@@ -237,13 +356,34 @@ func ValidateUe(ue *model.UeConfig) error {
 	return nil
 }
 
-func ValidateTaiIe(taiIe *model.TaiIE) error {
-	if err := ValidateHexString(taiIe.Tac); err != nil {
+func ValidateSupportedTaIe(supportedTaIe *model.SupportedTaIE) error {
+	if err := ValidateHexString(supportedTaIe.Tac); err != nil {
 		return fmt.Errorf("invalid tac: %s", err.Error())
 	}
 
-	if err := ValidatePlmnId(&taiIe.BroadcastPlmnId); err != nil {
-		return fmt.Errorf("invalid broadcastPlmnId: %s", err.Error())
+	if len(supportedTaIe.BroadcastPlmnList) == 0 {
+		return fmt.Errorf("invalid broadcastPlmnList: at least one broadcast PLMN is required")
+	}
+	for i := range supportedTaIe.BroadcastPlmnList {
+		if err := ValidateBroadcastPlmnIe(&supportedTaIe.BroadcastPlmnList[i]); err != nil {
+			return fmt.Errorf("invalid broadcastPlmnList[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+func ValidateBroadcastPlmnIe(broadcastPlmnIe *model.BroadcastPlmnIE) error {
+	if err := ValidatePlmnId(&broadcastPlmnIe.PlmnId); err != nil {
+		return fmt.Errorf("invalid plmnId: %s", err.Error())
+	}
+
+	if len(broadcastPlmnIe.SnssaiList) == 0 {
+		return fmt.Errorf("invalid snssaiList: at least one S-NSSAI is required")
+	}
+	for i := range broadcastPlmnIe.SnssaiList {
+		if err := ValidateSnssaiIe(&broadcastPlmnIe.SnssaiList[i]); err != nil {
+			return fmt.Errorf("invalid snssaiList[%d]: %s", i, err.Error())
+		}
 	}
 	return nil
 }
@@ -259,6 +399,51 @@ func ValidateSnssaiIe(snssai *model.SnssaiIE) error {
 	return nil
 }
 
+func ValidateRequestedNssai(requestedNssai []model.RequestedSnssaiIE) error {
+	if len(requestedNssai) > constant.UE_REQUESTED_NSSAI_MAX_LEN {
+		return fmt.Errorf("too many S-NSSAIs: %d, at most %d", len(requestedNssai), constant.UE_REQUESTED_NSSAI_MAX_LEN)
+	}
+	for i := range requestedNssai {
+		if err := ValidateRequestedSnssaiIe(&requestedNssai[i]); err != nil {
+			return fmt.Errorf("invalid requestedNssai[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// the SD is optional in the requested S-NSSAI, the mapped HPLMN SD is only encoded along with the SD
+func ValidateRequestedSnssaiIe(requestedSnssai *model.RequestedSnssaiIE) error {
+	if err := ValidateIntStringWithLength(requestedSnssai.Sst, 1); err != nil {
+		return fmt.Errorf("invalid sst, %s", err.Error())
+	}
+	if err := ValidateSd(requestedSnssai.Sd); err != nil {
+		return fmt.Errorf("invalid sd, %s", err.Error())
+	}
+
+	if mapped := requestedSnssai.MappedHplmnSnssai; mapped != nil {
+		if err := ValidateIntStringWithLength(mapped.Sst, 1); err != nil {
+			return fmt.Errorf("invalid mapped hplmn sst, %s", err.Error())
+		}
+		if err := ValidateSd(mapped.Sd); err != nil {
+			return fmt.Errorf("invalid mapped hplmn sd, %s", err.Error())
+		}
+		if mapped.Sd != "" && requestedSnssai.Sd == "" {
+			return fmt.Errorf("invalid mapped hplmn sd: %s, sd is required along with it", mapped.Sd)
+		}
+	}
+	return nil
+}
+
+func ValidateSd(sd string) error {
+	if sd == "" {
+		return nil
+	}
+	if decoded, err := hex.DecodeString(sd); err != nil || len(decoded) != 3 {
+		return fmt.Errorf("invalid sd: %s, should be 3 octets in hex", sd)
+	}
+	return nil
+}
+
 func ValidateApiIe(apiIe *model.ApiIE) error {
 	if err := ValidateIp(apiIe.Ip); err != nil {
 		return fmt.Errorf("invalid ip: %s", err.Error())
@@ -291,12 +476,55 @@ func ValidateXnInterfaceIe(xnIe *model.XnInterfaceIE) error {
 	return nil
 }
 
+func ValidateAmfN2Ie(amfN2Ie *model.AmfN2IE) error {
+	if err := ValidateIpList(amfN2Ie.IpList); err != nil {
+		return fmt.Errorf("invalid ipList: %s", err.Error())
+	}
+	if err := ValidatePort(amfN2Ie.Port); err != nil {
+		return fmt.Errorf("invalid port: %s", err.Error())
+	}
+	return nil
+}
+
+func ValidateSctpIe(sctpIe *model.SctpIE) error {
+	if sctpIe.NumOutStreams < 0 || sctpIe.NumOutStreams > 65535 {
+		return fmt.Errorf("invalid numOutStreams: %d, range should be 0-65535", sctpIe.NumOutStreams)
+	}
+	if sctpIe.MaxInStreams < 0 || sctpIe.MaxInStreams > 65535 {
+		return fmt.Errorf("invalid maxInStreams: %d, range should be 0-65535", sctpIe.MaxInStreams)
+	}
+	if sctpIe.MaxInitRetries < 0 || sctpIe.MaxInitRetries > 65535 {
+		return fmt.Errorf("invalid maxInitRetries: %d, range should be 0-65535", sctpIe.MaxInitRetries)
+	}
+	if sctpIe.HeartbeatInterval < 0 {
+		return fmt.Errorf("invalid heartbeatInterval: %d, should not be negative", sctpIe.HeartbeatInterval)
+	}
+	if sctpIe.RtoInitial < 0 {
+		return fmt.Errorf("invalid rtoInitial: %d, should not be negative", sctpIe.RtoInitial)
+	}
+	if sctpIe.RtoMin < 0 {
+		return fmt.Errorf("invalid rtoMin: %d, should not be negative", sctpIe.RtoMin)
+	}
+	if sctpIe.RtoMax < 0 {
+		return fmt.Errorf("invalid rtoMax: %d, should not be negative", sctpIe.RtoMax)
+	}
+	if sctpIe.RtoMin != 0 && sctpIe.RtoMax != 0 && sctpIe.RtoMin > sctpIe.RtoMax {
+		return fmt.Errorf("invalid rtoMin: %d, should not be greater than rtoMax %d", sctpIe.RtoMin, sctpIe.RtoMax)
+	}
+	return nil
+}
+
 func ValidateGnbIe(gnbIe *model.GnbIE) error {
-	if err := ValidateIp(gnbIe.AmfN2Ip); err != nil {
-		return fmt.Errorf("invalid gnb amfN2Ip: %s", err.Error())
+	if len(gnbIe.AmfN2List) == 0 {
+		return fmt.Errorf("invalid gnb amfN2List: at least one AMF is required")
 	}
-	if err := ValidateIp(gnbIe.RanN2Ip); err != nil {
-		return fmt.Errorf("invalid gnb ranN2Ip: %s", err.Error())
+	for i := range gnbIe.AmfN2List {
+		if err := ValidateAmfN2Ie(&gnbIe.AmfN2List[i]); err != nil {
+			return fmt.Errorf("invalid gnb amfN2List[%d]: %s", i, err.Error())
+		}
+	}
+	if err := ValidateIpList(gnbIe.RanN2IpList); err != nil {
+		return fmt.Errorf("invalid gnb ranN2IpList: %s", err.Error())
 	}
 	if err := ValidateIp(gnbIe.UpfN3Ip); err != nil {
 		return fmt.Errorf("invalid gnb upfN3Ip: %s", err.Error())
@@ -311,9 +539,6 @@ func ValidateGnbIe(gnbIe *model.GnbIE) error {
 		return fmt.Errorf("invalid gnb ranDataPlaneIp: %s", err.Error())
 	}
 
-	if err := ValidatePort(gnbIe.AmfN2Port); err != nil {
-		return fmt.Errorf("invalid gnb amfN2Port: %s", err.Error())
-	}
 	if err := ValidatePort(gnbIe.RanN2Port); err != nil {
 		return fmt.Errorf("invalid gnb ranN2Port: %s", err.Error())
 	}
@@ -330,6 +555,14 @@ func ValidateGnbIe(gnbIe *model.GnbIE) error {
 		return fmt.Errorf("invalid gnb ranDataPlanePort: %s", err.Error())
 	}
 
+	if err := ValidateSctpIe(&gnbIe.Sctp); err != nil {
+		return fmt.Errorf("invalid gnb sctp: %s", err.Error())
+	}
+
+	if gnbIe.UeInactivityTimer < 0 {
+		return fmt.Errorf("invalid gnb ueInactivityTimer: %d, should not be negative", gnbIe.UeInactivityTimer)
+	}
+
 	if err := ValidateHexString(gnbIe.GnbId); err != nil {
 		return fmt.Errorf("invalid gnb gnbId: %s", err.Error())
 	}
@@ -338,12 +571,13 @@ func ValidateGnbIe(gnbIe *model.GnbIE) error {
 		return fmt.Errorf("invalid gnb plmn id, %s", err.Error())
 	}
 
-	if err := ValidateTaiIe(&gnbIe.Tai); err != nil {
-		return fmt.Errorf("invalid gnb tai: %s", err.Error())
+	if len(gnbIe.SupportedTaList) == 0 {
+		return fmt.Errorf("invalid gnb supportedTaList: at least one TA is required")
 	}
-
-	if err := ValidateSnssaiIe(&gnbIe.Snssai); err != nil {
-		return fmt.Errorf("invalid gnb snssai: %s", err.Error())
+	for i := range gnbIe.SupportedTaList {
+		if err := ValidateSupportedTaIe(&gnbIe.SupportedTaList[i]); err != nil {
+			return fmt.Errorf("invalid gnb supportedTaList[%d]: %s", i, err.Error())
+		}
 	}
 
 	if err := ValidateApiIe(&gnbIe.Api); err != nil {
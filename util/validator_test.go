@@ -224,6 +224,110 @@ func TestValidateMsin(t *testing.T) {
 	}
 }
 
+var testValidateImeiCases = []struct {
+	name          string
+	imei          string
+	expectedError error
+}{
+	{
+		name:          "testValidImei",
+		imei:          "356938035643809",
+		expectedError: nil,
+	},
+	{
+		name:          "testInvalidCheckDigitImei",
+		imei:          "356938035643803",
+		expectedError: fmt.Errorf("invalid imei: 356938035643803, check digit mismatch"),
+	},
+	{
+		name:          "testInvalidLengthImei",
+		imei:          "35693803564380",
+		expectedError: fmt.Errorf("invalid int string: 35693803564380, length should be 15"),
+	},
+}
+
+func TestValidateImei(t *testing.T) {
+	for _, testCase := range testValidateImeiCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := util.ValidateImei(testCase.imei)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
+var testValidateSuciCases = []struct {
+	name          string
+	suci          model.SuciIE
+	expectedError error
+}{
+	{
+		name:          "testValidDefaultNullScheme",
+		suci:          model.SuciIE{},
+		expectedError: nil,
+	},
+	{
+		name: "testValidProfileA",
+		suci: model.SuciIE{
+			ProtectionScheme:       "profileA",
+			HomeNetworkPublicKey:   "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+			HomeNetworkPublicKeyId: 1,
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testValidProfileB",
+		suci: model.SuciIE{
+			ProtectionScheme:       "profileB",
+			HomeNetworkPublicKey:   "0472da71976234ce833a6907425867b82e074d44ef907dfb4b3e21c1c2256ebcd15a7ded52fcbb097a4ed250e036c7b9c8c7004c4eedc4f068cd7bf8d3f900e3b4",
+			HomeNetworkPublicKeyId: 2,
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testInvalidProtectionScheme",
+		suci: model.SuciIE{
+			ProtectionScheme: "profileC",
+		},
+		expectedError: fmt.Errorf("invalid protection scheme: profileC, should be null, profileA or profileB"),
+	},
+	{
+		name: "testInvalidHomeNetworkPublicKeyId",
+		suci: model.SuciIE{
+			ProtectionScheme:       "profileA",
+			HomeNetworkPublicKey:   "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+			HomeNetworkPublicKeyId: 256,
+		},
+		expectedError: fmt.Errorf("invalid home network public key id: 256, should be between 0 and 255"),
+	},
+	{
+		name: "testInvalidHomeNetworkPublicKeyHex",
+		suci: model.SuciIE{
+			ProtectionScheme:       "profileA",
+			HomeNetworkPublicKey:   "xyz",
+			HomeNetworkPublicKeyId: 1,
+		},
+		expectedError: fmt.Errorf("invalid home network public key, invalid hex string: xyz"),
+	},
+	{
+		name: "testInvalidHomeNetworkPublicKeyLength",
+		suci: model.SuciIE{
+			ProtectionScheme:       "profileB",
+			HomeNetworkPublicKey:   "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+			HomeNetworkPublicKeyId: 2,
+		},
+		expectedError: fmt.Errorf("invalid home network public key length: 32 bytes, should be [33 65] bytes for profileB"),
+	},
+}
+
+func TestValidateSuci(t *testing.T) {
+	for _, testCase := range testValidateSuciCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := util.ValidateSuci(&testCase.suci)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
 var testValidateAccessTypeCases = []struct {
 	name          string
 	accessType    string
@@ -310,6 +414,28 @@ var testValidateAuthenticationSubscriptionCases = []struct {
 		},
 		expectedError: fmt.Errorf("invalid authentication management field, invalid int string: 80000, length should be 4"),
 	},
+	{
+		name: "testValidEapAkaPrimeAuthenticationSubscription",
+		authenticationSubscription: model.AuthenticationSubscriptionIE{
+			AuthenticationMethod:          "EAP_AKA_PRIME",
+			EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+			EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+			AuthenticationManagementField: "8000",
+			SequenceNumber:                "000000000023",
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testInvalidAuthenticationMethod",
+		authenticationSubscription: model.AuthenticationSubscriptionIE{
+			AuthenticationMethod:          "EAP_TLS",
+			EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+			EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+			AuthenticationManagementField: "8000",
+			SequenceNumber:                "000000000023",
+		},
+		expectedError: fmt.Errorf("invalid authentication method: EAP_TLS, should be 5G_AKA or EAP_AKA_PRIME"),
+	},
 }
 
 func TestValidateAuthenticationSubscription(t *testing.T) {
@@ -452,33 +578,70 @@ var testValidatePduSessionCases = []struct {
 	{
 		name: "testValidPduSession",
 		pduSession: model.PduSessionIE{
-			Dnn: "internet",
+			PduSessionId:   1,
+			PduSessionType: "IPv4",
+			Dnn:            "internet",
 			Snssai: model.SnssaiIE{
 				Sst: "1",
 				Sd:  "010203",
 			},
+			UeTunnelDevice: "ueTun",
 		},
 		expectedError: nil,
 	},
+	{
+		name: "testInvalidIdPduSession",
+		pduSession: model.PduSessionIE{
+			PduSessionId:   16,
+			PduSessionType: "IPv4",
+			Dnn:            "internet",
+			Snssai: model.SnssaiIE{
+				Sst: "1",
+				Sd:  "010203",
+			},
+			UeTunnelDevice: "ueTun",
+		},
+		expectedError: fmt.Errorf("invalid pdu session id: 16, should be between 1 and 15"),
+	},
+	{
+		name: "testInvalidTypePduSession",
+		pduSession: model.PduSessionIE{
+			PduSessionId:   1,
+			PduSessionType: "Ethernet",
+			Dnn:            "internet",
+			Snssai: model.SnssaiIE{
+				Sst: "1",
+				Sd:  "010203",
+			},
+			UeTunnelDevice: "ueTun",
+		},
+		expectedError: fmt.Errorf("invalid pdu session type: Ethernet, should be IPv4 or IPv4v6"),
+	},
 	{
 		name: "testInvalidSstNilPduSession",
 		pduSession: model.PduSessionIE{
-			Dnn: "internet",
+			PduSessionId:   1,
+			PduSessionType: "IPv4",
+			Dnn:            "internet",
 			Snssai: model.SnssaiIE{
 				Sst: "z",
 				Sd:  "010203",
 			},
+			UeTunnelDevice: "ueTun",
 		},
 		expectedError: fmt.Errorf("invalid pdu session sst, invalid int string: z"),
 	},
 	{
 		name: "testInvalidSdNilPduSession",
 		pduSession: model.PduSessionIE{
-			Dnn: "internet",
+			PduSessionId:   1,
+			PduSessionType: "IPv4",
+			Dnn:            "internet",
 			Snssai: model.SnssaiIE{
 				Sst: "1",
 				Sd:  "zzzzzz",
 			},
+			UeTunnelDevice: "ueTun",
 		},
 		expectedError: fmt.Errorf("invalid pdu session sd, invalid hex string: zzzzzz"),
 	},
@@ -493,6 +656,87 @@ func TestValidatePduSession(t *testing.T) {
 	}
 }
 
+var testValidatePduSessionListCases = []struct {
+	name           string
+	pduSessionList []model.PduSessionIE
+	expectedError  error
+}{
+	{
+		name: "testValidPduSessionList",
+		pduSessionList: []model.PduSessionIE{
+			{
+				PduSessionId:   1,
+				PduSessionType: "IPv4",
+				Dnn:            "internet",
+				Snssai:         model.SnssaiIE{Sst: "1", Sd: "010203"},
+				UeTunnelDevice: "ueTun",
+			},
+			{
+				PduSessionId:   2,
+				PduSessionType: "IPv4v6",
+				Dnn:            "ims",
+				Snssai:         model.SnssaiIE{Sst: "1", Sd: "112233"},
+				UeTunnelDevice: "ueTunIms",
+			},
+		},
+		expectedError: nil,
+	},
+	{
+		name:           "testEmptyPduSessionList",
+		pduSessionList: []model.PduSessionIE{},
+		expectedError:  fmt.Errorf("at least one pdu session is required"),
+	},
+	{
+		name: "testDuplicatedIdPduSessionList",
+		pduSessionList: []model.PduSessionIE{
+			{
+				PduSessionId:   1,
+				PduSessionType: "IPv4",
+				Dnn:            "internet",
+				Snssai:         model.SnssaiIE{Sst: "1", Sd: "010203"},
+				UeTunnelDevice: "ueTun",
+			},
+			{
+				PduSessionId:   1,
+				PduSessionType: "IPv4",
+				Dnn:            "ims",
+				Snssai:         model.SnssaiIE{Sst: "1", Sd: "112233"},
+				UeTunnelDevice: "ueTunIms",
+			},
+		},
+		expectedError: fmt.Errorf("pduSessionList[1]: duplicated pdu session id: 1"),
+	},
+	{
+		name: "testDuplicatedUeTunnelDevicePduSessionList",
+		pduSessionList: []model.PduSessionIE{
+			{
+				PduSessionId:   1,
+				PduSessionType: "IPv4",
+				Dnn:            "internet",
+				Snssai:         model.SnssaiIE{Sst: "1", Sd: "010203"},
+				UeTunnelDevice: "ueTun",
+			},
+			{
+				PduSessionId:   2,
+				PduSessionType: "IPv4",
+				Dnn:            "ims",
+				Snssai:         model.SnssaiIE{Sst: "1", Sd: "112233"},
+				UeTunnelDevice: "ueTun",
+			},
+		},
+		expectedError: fmt.Errorf("pduSessionList[1]: duplicated ue tunnel device: ueTun"),
+	},
+}
+
+func TestValidatePduSessionList(t *testing.T) {
+	for _, testCase := range testValidatePduSessionListCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := util.ValidatePduSessionList(testCase.pduSessionList)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
 var testValidateNrdcCases = []struct {
 	name          string
 	nrdc          model.NrdcIE
@@ -597,14 +841,18 @@ var testValidateUeIeCases = []struct {
 				Nia2: true,
 				Nia3: false,
 			},
-			PduSession: model.PduSessionIE{
-				Dnn: "internet",
-				Snssai: model.SnssaiIE{
-					Sst: "1",
-					Sd:  "010203",
+			PduSessionList: []model.PduSessionIE{
+				{
+					PduSessionId:   1,
+					PduSessionType: "IPv4",
+					Dnn:            "internet",
+					Snssai: model.SnssaiIE{
+						Sst: "1",
+						Sd:  "010203",
+					},
+					UeTunnelDevice: "ueTun0",
 				},
 			},
-			UeTunnelDevice: "ueTun0",
 		},
 		expectedError: nil,
 	},
@@ -640,11 +888,16 @@ var testValidateUeIeCases = []struct {
 				Nia2: true,
 				Nia3: false,
 			},
-			PduSession: model.PduSessionIE{
-				Dnn: "internet",
-				Snssai: model.SnssaiIE{
-					Sst: "1",
-					Sd:  "010203",
+			PduSessionList: []model.PduSessionIE{
+				{
+					PduSessionId:   1,
+					PduSessionType: "IPv4",
+					Dnn:            "internet",
+					Snssai: model.SnssaiIE{
+						Sst: "1",
+						Sd:  "010203",
+					},
+					UeTunnelDevice: "ueTun0",
 				},
 			},
 			Nrdc: model.NrdcIE{
@@ -655,7 +908,6 @@ var testValidateUeIeCases = []struct {
 				},
 				DcLocalDataPlaneIp: "10.0.3.2",
 			},
-			UeTunnelDevice: "ueTun0",
 		},
 		expectedError: nil,
 	},
@@ -691,11 +943,16 @@ var testValidateUeIeCases = []struct {
 				Nia2: true,
 				Nia3: false,
 			},
-			PduSession: model.PduSessionIE{
-				Dnn: "internet",
-				Snssai: model.SnssaiIE{
-					Sst: "1",
-					Sd:  "010203",
+			PduSessionList: []model.PduSessionIE{
+				{
+					PduSessionId:   1,
+					PduSessionType: "IPv4",
+					Dnn:            "internet",
+					Snssai: model.SnssaiIE{
+						Sst: "1",
+						Sd:  "010203",
+					},
+					UeTunnelDevice: "ueTun0",
 				},
 			},
 			Nrdc: model.NrdcIE{
@@ -706,7 +963,6 @@ var testValidateUeIeCases = []struct {
 				},
 				DcLocalDataPlaneIp: "10.0.3.2",
 			},
-			UeTunnelDevice: "ueTun0",
 		},
 		expectedError: nil,
 	},
@@ -721,50 +977,125 @@ func TestValidateUeIe(t *testing.T) {
 	}
 }
 
-var testValidateTaiIeCases = []struct {
+var testValidateSupportedTaIeCases = []struct {
 	name          string
-	tai           model.TaiIE
+	supportedTa   model.SupportedTaIE
 	expectedError error
 }{
 	{
-		name: "testValidTaiIe",
-		tai: model.TaiIE{
+		name: "testValidSupportedTaIe",
+		supportedTa: model.SupportedTaIE{
 			Tac: "000001",
-			BroadcastPlmnId: model.PlmnIdIE{
-				Mcc: "208",
-				Mnc: "93",
+			BroadcastPlmnList: []model.BroadcastPlmnIE{
+				{
+					PlmnId: model.PlmnIdIE{
+						Mcc: "208",
+						Mnc: "93",
+					},
+					SnssaiList: []model.SnssaiIE{
+						{
+							Sst: "1",
+							Sd:  "010203",
+						},
+						{
+							Sst: "1",
+							Sd:  "112233",
+						},
+					},
+				},
+				{
+					PlmnId: model.PlmnIdIE{
+						Mcc: "466",
+						Mnc: "92",
+					},
+					SnssaiList: []model.SnssaiIE{
+						{
+							Sst: "2",
+							Sd:  "010203",
+						},
+					},
+				},
 			},
 		},
 		expectedError: nil,
 	},
 	{
 		name: "testInvalidTac",
-		tai: model.TaiIE{
+		supportedTa: model.SupportedTaIE{
 			Tac: "zzzzzzzz",
-			BroadcastPlmnId: model.PlmnIdIE{
-				Mcc: "208",
-				Mnc: "93",
-			},
 		},
 		expectedError: fmt.Errorf("invalid tac: invalid hex string: zzzzzzzz"),
 	},
+	{
+		name: "testEmptyBroadcastPlmnList",
+		supportedTa: model.SupportedTaIE{
+			Tac:               "000001",
+			BroadcastPlmnList: []model.BroadcastPlmnIE{},
+		},
+		expectedError: fmt.Errorf("invalid broadcastPlmnList: at least one broadcast PLMN is required"),
+	},
 	{
 		name: "testInvalidBroadcastPlmnId",
-		tai: model.TaiIE{
+		supportedTa: model.SupportedTaIE{
 			Tac: "000001",
-			BroadcastPlmnId: model.PlmnIdIE{
-				Mcc: "208",
-				Mnc: "930",
+			BroadcastPlmnList: []model.BroadcastPlmnIE{
+				{
+					PlmnId: model.PlmnIdIE{
+						Mcc: "208",
+						Mnc: "930",
+					},
+				},
 			},
 		},
-		expectedError: fmt.Errorf("invalid broadcastPlmnId: invalid mnc: 930, mnc should be 2 digits"),
+		expectedError: fmt.Errorf("invalid broadcastPlmnList[0]: invalid plmnId: invalid mnc: 930, mnc should be 2 digits"),
+	},
+	{
+		name: "testEmptySnssaiList",
+		supportedTa: model.SupportedTaIE{
+			Tac: "000001",
+			BroadcastPlmnList: []model.BroadcastPlmnIE{
+				{
+					PlmnId: model.PlmnIdIE{
+						Mcc: "208",
+						Mnc: "93",
+					},
+					SnssaiList: []model.SnssaiIE{},
+				},
+			},
+		},
+		expectedError: fmt.Errorf("invalid broadcastPlmnList[0]: invalid snssaiList: at least one S-NSSAI is required"),
+	},
+	{
+		name: "testInvalidSnssai",
+		supportedTa: model.SupportedTaIE{
+			Tac: "000001",
+			BroadcastPlmnList: []model.BroadcastPlmnIE{
+				{
+					PlmnId: model.PlmnIdIE{
+						Mcc: "208",
+						Mnc: "93",
+					},
+					SnssaiList: []model.SnssaiIE{
+						{
+							Sst: "1",
+							Sd:  "010203",
+						},
+						{
+							Sst: "z",
+							Sd:  "010203",
+						},
+					},
+				},
+			},
+		},
+		expectedError: fmt.Errorf("invalid broadcastPlmnList[0]: invalid snssaiList[1]: invalid sst, invalid int string: z"),
 	},
 }
 
-func TestValidateTaiIe(t *testing.T) {
-	for _, tc := range testValidateTaiIeCases {
+func TestValidateSupportedTaIe(t *testing.T) {
+	for _, tc := range testValidateSupportedTaIeCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := util.ValidateTaiIe(&tc.tai)
+			err := util.ValidateSupportedTaIe(&tc.supportedTa)
 			if tc.expectedError != nil {
 				assert.EqualError(t, err, tc.expectedError.Error())
 			} else {
@@ -818,6 +1149,60 @@ func TestValidateSnssaiIe(t *testing.T) {
 	}
 }
 
+var testValidateRequestedSnssaiIeCases = []struct {
+	name            string
+	requestedSnssai model.RequestedSnssaiIE
+	expectedError   error
+}{
+	{
+		name: "testValidSstOnly",
+		requestedSnssai: model.RequestedSnssaiIE{
+			Sst: "1",
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testValidMappedHplmnSnssai",
+		requestedSnssai: model.RequestedSnssaiIE{
+			Sst: "1",
+			Sd:  "010203",
+			MappedHplmnSnssai: &model.SnssaiIE{
+				Sst: "2",
+				Sd:  "112233",
+			},
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testInvalidSdLength",
+		requestedSnssai: model.RequestedSnssaiIE{
+			Sst: "1",
+			Sd:  "0102",
+		},
+		expectedError: fmt.Errorf("invalid sd, invalid sd: 0102, should be 3 octets in hex"),
+	},
+	{
+		name: "testInvalidMappedHplmnSdWithoutSd",
+		requestedSnssai: model.RequestedSnssaiIE{
+			Sst: "1",
+			MappedHplmnSnssai: &model.SnssaiIE{
+				Sst: "2",
+				Sd:  "112233",
+			},
+		},
+		expectedError: fmt.Errorf("invalid mapped hplmn sd: 112233, sd is required along with it"),
+	},
+}
+
+func TestValidateRequestedSnssaiIe(t *testing.T) {
+	for _, testCase := range testValidateRequestedSnssaiIeCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := util.ValidateRequestedSnssaiIe(&testCase.requestedSnssai)
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
 var testValidateApiIeCases = []struct {
 	name          string
 	api           model.ApiIE
@@ -944,6 +1329,126 @@ func TestValidateXnInterfaceIe(t *testing.T) {
 	}
 }
 
+var testValidateAmfN2IeCases = []struct {
+	name          string
+	amfN2         model.AmfN2IE
+	expectedError error
+}{
+	{
+		name: "testValidAmfN2Ie",
+		amfN2: model.AmfN2IE{
+			IpList: []string{"10.0.1.1"},
+			Port:   38412,
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testValidMultiHomingAmfN2Ie",
+		amfN2: model.AmfN2IE{
+			IpList: []string{"10.0.1.1", "10.0.4.1"},
+			Port:   38412,
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testEmptyIpList",
+		amfN2: model.AmfN2IE{
+			IpList: []string{},
+			Port:   38412,
+		},
+		expectedError: fmt.Errorf("invalid ipList: invalid ip list: at least one ip address is required"),
+	},
+	{
+		name: "testInvalidIp",
+		amfN2: model.AmfN2IE{
+			IpList: []string{"10.0.1.1", "10.0.1.1.1"},
+			Port:   38412,
+		},
+		expectedError: fmt.Errorf("invalid ipList: invalid ip list[1]: invalid ip address: 10.0.1.1.1"),
+	},
+	{
+		name: "testInvalidPort",
+		amfN2: model.AmfN2IE{
+			IpList: []string{"10.0.1.1"},
+			Port:   0,
+		},
+		expectedError: fmt.Errorf("invalid port: invalid port range: 0, range should be 1-65535"),
+	},
+}
+
+func TestValidateAmfN2Ie(t *testing.T) {
+	for _, tc := range testValidateAmfN2IeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := util.ValidateAmfN2Ie(&tc.amfN2)
+			if tc.expectedError != nil {
+				assert.EqualError(t, err, tc.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+var testValidateSctpIeCases = []struct {
+	name          string
+	sctp          model.SctpIE
+	expectedError error
+}{
+	{
+		name:          "testDefaultSctpIe",
+		sctp:          model.SctpIE{},
+		expectedError: nil,
+	},
+	{
+		name: "testValidSctpIe",
+		sctp: model.SctpIE{
+			NumOutStreams:     4,
+			MaxInStreams:      4,
+			MaxInitRetries:    5,
+			HeartbeatInterval: 1000,
+			RtoInitial:        500,
+			RtoMin:            100,
+			RtoMax:            1000,
+		},
+		expectedError: nil,
+	},
+	{
+		name: "testInvalidNumOutStreams",
+		sctp: model.SctpIE{
+			NumOutStreams: 65536,
+		},
+		expectedError: fmt.Errorf("invalid numOutStreams: 65536, range should be 0-65535"),
+	},
+	{
+		name: "testInvalidHeartbeatInterval",
+		sctp: model.SctpIE{
+			HeartbeatInterval: -1,
+		},
+		expectedError: fmt.Errorf("invalid heartbeatInterval: -1, should not be negative"),
+	},
+	{
+		name: "testRtoMinGreaterThanRtoMax",
+		sctp: model.SctpIE{
+			RtoMin: 1000,
+			RtoMax: 100,
+		},
+		expectedError: fmt.Errorf("invalid rtoMin: 1000, should not be greater than rtoMax 100"),
+	},
+}
+
+func TestValidateSctpIe(t *testing.T) {
+	for _, tc := range testValidateSctpIeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := util.ValidateSctpIe(&tc.sctp)
+			if tc.expectedError != nil {
+				assert.EqualError(t, err, tc.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 var testValidateGnbIeCases = []struct {
 	name          string
 	gnbIe         model.GnbIE
@@ -952,13 +1457,17 @@ var testValidateGnbIeCases = []struct {
 	{
 		name: "testValidGnbIe",
 		gnbIe: model.GnbIE{
-			AmfN2Ip:             "10.0.1.1",
-			RanN2Ip:             "10.0.1.2",
+			AmfN2List: []model.AmfN2IE{
+				{
+					IpList: []string{"10.0.1.1"},
+					Port:   38412,
+				},
+			},
+			RanN2IpList:         []string{"10.0.1.2"},
 			UpfN3Ip:             "10.0.1.1",
 			RanN3Ip:             "10.0.1.2",
 			RanControlPlaneIp:   "10.0.2.1",
 			RanDataPlaneIp:      "10.0.2.1",
-			AmfN2Port:           38412,
 			RanN2Port:           38413,
 			UpfN3Port:           2152,
 			RanN3Port:           2152,
@@ -970,17 +1479,25 @@ var testValidateGnbIeCases = []struct {
 				Mcc: "208",
 				Mnc: "93",
 			},
-			Tai: model.TaiIE{
-				Tac: "000001",
-				BroadcastPlmnId: model.PlmnIdIE{
-					Mcc: "208",
-					Mnc: "93",
+			SupportedTaList: []model.SupportedTaIE{
+				{
+					Tac: "000001",
+					BroadcastPlmnList: []model.BroadcastPlmnIE{
+						{
+							PlmnId: model.PlmnIdIE{
+								Mcc: "208",
+								Mnc: "93",
+							},
+							SnssaiList: []model.SnssaiIE{
+								{
+									Sst: "1",
+									Sd:  "010203",
+								},
+							},
+						},
+					},
 				},
 			},
-			Snssai: model.SnssaiIE{
-				Sst: "1",
-				Sd:  "010203",
-			},
 			Api: model.ApiIE{
 				Ip:   "10.0.1.2",
 				Port: 40104,
@@ -991,13 +1508,17 @@ var testValidateGnbIeCases = []struct {
 	{
 		name: "testDcStaticgNB",
 		gnbIe: model.GnbIE{
-			AmfN2Ip:             "10.0.1.1",
-			RanN2Ip:             "10.0.1.2",
+			AmfN2List: []model.AmfN2IE{
+				{
+					IpList: []string{"10.0.1.1"},
+					Port:   38412,
+				},
+			},
+			RanN2IpList:         []string{"10.0.1.2"},
 			UpfN3Ip:             "10.0.1.1",
 			RanN3Ip:             "10.0.1.2",
 			RanControlPlaneIp:   "10.0.2.1",
 			RanDataPlaneIp:      "10.0.2.1",
-			AmfN2Port:           38412,
 			RanN2Port:           38413,
 			UpfN3Port:           2152,
 			RanN3Port:           2152,
@@ -1009,17 +1530,25 @@ var testValidateGnbIeCases = []struct {
 				Mcc: "208",
 				Mnc: "93",
 			},
-			Tai: model.TaiIE{
-				Tac: "000001",
-				BroadcastPlmnId: model.PlmnIdIE{
-					Mcc: "208",
-					Mnc: "93",
+			SupportedTaList: []model.SupportedTaIE{
+				{
+					Tac: "000001",
+					BroadcastPlmnList: []model.BroadcastPlmnIE{
+						{
+							PlmnId: model.PlmnIdIE{
+								Mcc: "208",
+								Mnc: "93",
+							},
+							SnssaiList: []model.SnssaiIE{
+								{
+									Sst: "1",
+									Sd:  "010203",
+								},
+							},
+						},
+					},
 				},
 			},
-			Snssai: model.SnssaiIE{
-				Sst: "1",
-				Sd:  "010203",
-			},
 			StaticNrdc: true,
 			XnInterface: model.XnInterfaceIE{
 				Enable:       true,
@@ -1038,13 +1567,17 @@ var testValidateGnbIeCases = []struct {
 	{
 		name: "testDcDynamicgNB",
 		gnbIe: model.GnbIE{
-			AmfN2Ip:             "10.0.1.1",
-			RanN2Ip:             "10.0.1.2",
+			AmfN2List: []model.AmfN2IE{
+				{
+					IpList: []string{"10.0.1.1"},
+					Port:   38412,
+				},
+			},
+			RanN2IpList:         []string{"10.0.1.2"},
 			UpfN3Ip:             "10.0.1.1",
 			RanN3Ip:             "10.0.1.2",
 			RanControlPlaneIp:   "10.0.2.1",
 			RanDataPlaneIp:      "10.0.2.1",
-			AmfN2Port:           38412,
 			RanN2Port:           38413,
 			UpfN3Port:           2152,
 			RanN3Port:           2152,
@@ -1056,17 +1589,25 @@ var testValidateGnbIeCases = []struct {
 				Mcc: "208",
 				Mnc: "93",
 			},
-			Tai: model.TaiIE{
-				Tac: "000001",
-				BroadcastPlmnId: model.PlmnIdIE{
-					Mcc: "208",
-					Mnc: "93",
+			SupportedTaList: []model.SupportedTaIE{
+				{
+					Tac: "000001",
+					BroadcastPlmnList: []model.BroadcastPlmnIE{
+						{
+							PlmnId: model.PlmnIdIE{
+								Mcc: "208",
+								Mnc: "93",
+							},
+							SnssaiList: []model.SnssaiIE{
+								{
+									Sst: "1",
+									Sd:  "010203",
+								},
+							},
+						},
+					},
 				},
 			},
-			Snssai: model.SnssaiIE{
-				Sst: "1",
-				Sd:  "010203",
-			},
 			StaticNrdc: false,
 			XnInterface: model.XnInterfaceIE{
 				Enable:       true,
@@ -1082,6 +1623,65 @@ var testValidateGnbIeCases = []struct {
 		},
 		expectedError: nil,
 	},
+	{
+		name: "testNegativeUeInactivityTimer",
+		gnbIe: model.GnbIE{
+			AmfN2List: []model.AmfN2IE{
+				{
+					IpList: []string{"10.0.1.1"},
+					Port:   38412,
+				},
+			},
+			RanN2IpList:         []string{"10.0.1.2"},
+			UpfN3Ip:             "10.0.1.1",
+			RanN3Ip:             "10.0.1.2",
+			RanControlPlaneIp:   "10.0.2.1",
+			RanDataPlaneIp:      "10.0.2.1",
+			RanN2Port:           38413,
+			UpfN3Port:           2152,
+			RanN3Port:           2152,
+			RanControlPlanePort: 31413,
+			RanDataPlanePort:    31414,
+			UeInactivityTimer:   -1,
+			GnbId:               "000314",
+			GnbName:             "gNB",
+			PlmnId: model.PlmnIdIE{
+				Mcc: "208",
+				Mnc: "93",
+			},
+			SupportedTaList: []model.SupportedTaIE{
+				{
+					Tac: "000001",
+					BroadcastPlmnList: []model.BroadcastPlmnIE{
+						{
+							PlmnId: model.PlmnIdIE{
+								Mcc: "208",
+								Mnc: "93",
+							},
+							SnssaiList: []model.SnssaiIE{
+								{
+									Sst: "1",
+									Sd:  "010203",
+								},
+							},
+						},
+					},
+				},
+			},
+			Api: model.ApiIE{
+				Ip:   "10.0.1.2",
+				Port: 40104,
+			},
+		},
+		expectedError: fmt.Errorf("invalid gnb ueInactivityTimer: -1, should not be negative"),
+	},
+	{
+		name: "testEmptyAmfN2List",
+		gnbIe: model.GnbIE{
+			AmfN2List: []model.AmfN2IE{},
+		},
+		expectedError: fmt.Errorf("invalid gnb amfN2List: at least one AMF is required"),
+	},
 }
 
 func TestValidateGnbIe(t *testing.T) {